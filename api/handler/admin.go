@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DisableUser godoc
+// @Summary Disable or re-enable a user
+// @Description Disabled users cannot log in or refresh tokens. Requires the users:disable permission.
+// @Tags Admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.DisableUser true "Disabled flag"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/disable [patch]
+func (h *Handler) DisableUser(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Unauthorized", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	userID := c.Param("id")
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		handleResponseLog(c, h.Log, "Invalid user ID format", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.DisableUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "Error binding JSON", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Services.UserService.SetUserDisabled(c.Request.Context(), *authInfo, userID, req.Disabled)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Error disabling user", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, models.SuccessResponse{Message: "user updated"})
+}
+
+// UpdateUserRole godoc
+// @Summary Change a user's role
+// @Description Promote a member to admin or demote an admin. Requires the users:update_role permission.
+// @Tags Admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.UpdateUserRole true "New role (admin or member)"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/role [patch]
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Unauthorized", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	userID := c.Param("id")
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		handleResponseLog(c, h.Log, "Invalid user ID format", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.UpdateUserRole
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "Error binding JSON", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Services.UserService.UpdateUserRole(c.Request.Context(), *authInfo, userID, req.Role)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Error updating user role", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, models.SuccessResponse{Message: "user role updated"})
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"todo/api/models"
 
@@ -16,6 +18,7 @@ import (
 // @Success 200 {object} models.AuthResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
 // @Router /auth/login [post]
 func (h *Handler) Login(c *gin.Context) {
 	var creds models.LoginRequest
@@ -25,19 +28,210 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	// Use the AuthService to authenticate the user
-	authResponse, err := h.Services.AuthService.Login(creds.Email, creds.Password)
-	if err != nil {
+	authResponse, err := h.Services.AuthService.Login(c.Request.Context(), creds.Email, creds.Password, creds.DeviceID)
+	switch {
+	case errors.Is(err, models.ErrInvalidCredentials), errors.Is(err, models.ErrUserDisabled):
 		handleErrorResponse(c, err, "Login: authentication", http.StatusUnauthorized)
 		return
+	case err != nil:
+		handleErrorResponse(c, err, "Login: issuing tokens", http.StatusInternalServerError)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Login successful", http.StatusOK, authResponse)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access/refresh token pair. Each refresh token can only be used once.
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "Refresh: binding JSON", http.StatusBadRequest)
+		return
 	}
 
-	// Store authInfo in context
-	authInfo := &models.AuthInfo{
-		UserID: authResponse.User.ID,
-		Email:  creds.Email,
-		Role:   authResponse.User.Role, // If applicable
+	authResponse, err := h.Services.AuthService.Refresh(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrInvalidRefreshToken), errors.Is(err, models.ErrRefreshTokenReused), errors.Is(err, models.ErrUserDisabled):
+		handleErrorResponse(c, err, "Refresh: checking refresh token", http.StatusUnauthorized)
+		return
+	case err != nil:
+		handleErrorResponse(c, err, "Refresh: rotating tokens", http.StatusInternalServerError)
+		return
 	}
-	c.Set("authInfo", authInfo)
 
-	handleResponseLog(c, h.Log, "Login successful", http.StatusOK, authResponse)
+	handleResponseLog(c, h.Log, "Refresh successful", http.StatusOK, authResponse)
+}
+
+// UserRegister godoc
+// @Summary Register
+// @Description Start a signup. A one-time password is emailed to confirm the address.
+// @Description While it is pending, the email cannot be registered again.
+// @Accept json
+// @Produce json
+// @Param user body models.RegisterRequest true "User data"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/register [post]
+func (h *Handler) UserRegister(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "UserRegister: binding JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Services.AuthService.Register(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrInvalidInput):
+		handleResponseLog(c, h.Log, "invalid registration data", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, models.ErrEmailTaken):
+		handleResponseLog(c, h.Log, "email already registered", http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, models.ErrRegistrationPending):
+		handleResponseLog(c, h.Log, "registration already pending", http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		handleErrorResponse(c, err, "UserRegister: registering", http.StatusInternalServerError)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Registration started", http.StatusOK, models.SuccessResponse{Message: "confirmation code sent to " + req.Email})
+}
+
+// UserRegisterConfirm godoc
+// @Summary Confirm registration
+// @Description Confirm a signup with the emailed one-time password and create the user
+// @Accept json
+// @Produce json
+// @Param confirm body models.RegisterConfirm true "Email and OTP"
+// @Success 201 {object} models.GetUser
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/register-confirm [post]
+func (h *Handler) UserRegisterConfirm(c *gin.Context) {
+	var req models.RegisterConfirm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "UserRegisterConfirm: binding JSON", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Services.AuthService.RegisterConfirm(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrInvalidOTP):
+		handleResponseLog(c, h.Log, "invalid otp", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, models.ErrTooManyOTPAttempts):
+		handleResponseLog(c, h.Log, "too many otp attempts", http.StatusTooManyRequests, models.ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		handleErrorResponse(c, err, "UserRegisterConfirm: creating user", http.StatusInternalServerError)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "User registered", http.StatusCreated, user)
+}
+
+// ForgotPassword godoc
+// @Summary Forgot password
+// @Description Email a password reset link. The response is the same whether or not the email is registered.
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "ForgotPassword: binding JSON", http.StatusBadRequest)
+		return
+	}
+
+	// The reset is sent after answering and failures are only logged, so
+	// neither the response nor its timing tells which emails exist
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		if err := h.Services.AuthService.ForgotPassword(ctx, req); err != nil {
+			h.Log.Printf("Error in ForgotPassword: %v", err)
+		}
+	}()
+
+	handleResponseLog(c, h.Log, "Password reset requested", http.StatusOK, models.SuccessResponse{Message: "if the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password with a reset token. All existing sessions of the user are revoked.
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "ResetPassword: binding JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Services.AuthService.ResetPassword(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrInvalidInput), errors.Is(err, models.ErrInvalidResetToken):
+		handleResponseLog(c, h.Log, "invalid password reset", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		handleErrorResponse(c, err, "ResetPassword: updating password", http.StatusInternalServerError)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Password reset", http.StatusOK, models.SuccessResponse{Message: "password has been reset"})
+}
+
+// ChangePasswordUser godoc
+// @Summary Change password
+// @Description Set a new password by logging in with the current one. All existing sessions of the user are revoked.
+// @Accept json
+// @Produce json
+// @Param request body models.ChangePassword true "Email, current and new password"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/user [patch]
+func (h *Handler) ChangePasswordUser(c *gin.Context) {
+	var req models.ChangePassword
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "ChangePasswordUser: binding JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Services.AuthService.ChangePassword(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrInvalidInput):
+		handleResponseLog(c, h.Log, "invalid new password", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, models.ErrInvalidCredentials), errors.Is(err, models.ErrUserDisabled):
+		handleErrorResponse(c, err, "ChangePasswordUser: authentication", http.StatusUnauthorized)
+		return
+	case err != nil:
+		handleErrorResponse(c, err, "ChangePasswordUser: updating password", http.StatusInternalServerError)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Password changed", http.StatusOK, models.SuccessResponse{Message: "password has been changed"})
 }
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddChecklistItem godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/checklist [POST]
+// @Summary		add checklist item
+// @Description This api appends an open item to the checklist of a task and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		item body models.CreateChecklistItem true "Checklist item"
+// @Success		201  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) AddChecklistItem(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.CreateChecklistItem{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.AddChecklistItem(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while adding checklist item", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, task)
+}
+
+// UpdateChecklistItem godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/checklist/{item_id} [PATCH]
+// @Summary		update checklist item
+// @Description This api renames or toggles a checklist item; fields left out are kept. It returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		item_id path string true "Checklist Item ID"
+// @Param		item body models.UpdateChecklistItem true "Checklist item fields"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) UpdateChecklistItem(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(c.Param("item_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid checklist item id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := models.UpdateChecklistItem{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = itemID
+
+	task, err := h.Services.TaskService.UpdateChecklistItem(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating checklist item", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// DeleteChecklistItem godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/checklist/{item_id} [DELETE]
+// @Summary		delete checklist item
+// @Description This api removes an item from the checklist of a task and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		item_id path string true "Checklist Item ID"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) DeleteChecklistItem(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(c.Param("item_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid checklist item id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.DeleteChecklistItem(c.Request.Context(), *authInfo, c.Param("id"), itemID)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while deleting checklist item", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// ReorderChecklist godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/checklist [PUT]
+// @Summary		reorder checklist
+// @Description This api puts the checklist of a task in the given order, which must list every item once, and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		order body models.ReorderChecklist true "Checklist item IDs in their new order"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) ReorderChecklist(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.ReorderChecklist{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.ReorderChecklist(c.Request.Context(), *authInfo, c.Param("id"), req.ItemIDs)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while reordering checklist", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDigestSettings godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications/digest [GET]
+// @Summary		get digest settings
+// @Description This api returns when the user is emailed the digest of their tasks. Users who never turned it on get none
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Success		200  {object}  models.DigestSettings
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetDigestSettings(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	settings, err := h.Services.DigestService.GetSettings(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting digest settings", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, settings)
+}
+
+// UpdateDigestSettings godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications/digest [PUT]
+// @Summary		update digest settings
+// @Description This api replaces the user's digest settings. The frequency is off, daily or weekly; the digest is sent at send_at, an HH:MM time in the timezone, which defaults to the one of the notification preferences, and weekly ones on the weekday. The digest lists overdue tasks, tasks due today or this week and tasks completed in the day or week before. It is only sent by email, when the digest event goes there, and held back during quiet hours
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Param		settings body models.UpdateDigestSettings true "Digest settings"
+// @Success		200  {object}  models.DigestSettings
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) UpdateDigestSettings(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.UpdateDigestSettings{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	settings, err := h.Services.DigestService.UpdateSettings(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating digest settings", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, settings)
+}
+
+// PreviewDigest godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications/digest/preview [GET]
+// @Summary		preview the digest
+// @Description This api renders the digest the user would be emailed now, as plain text and HTML. Users who have the digest off see the daily one
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Success		200  {object}  models.DigestPreview
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) PreviewDigest(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	preview, err := h.Services.DigestService.Preview(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while previewing digest", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, preview)
+}
+
+// Unsubscribe godoc
+// @Summary Unsubscribe from the digest
+// @Description Turn off the digest emails with the token of the unsubscribe link they carry. No sign in is needed.
+// @Accept json
+// @Produce json
+// @Param request body models.UnsubscribeRequest true "Unsubscribe token"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/unsubscribe [post]
+func (h *Handler) Unsubscribe(c *gin.Context) {
+	var req models.UnsubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, err, "Unsubscribe: binding JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Services.DigestService.Unsubscribe(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrInvalidUnsubscribe):
+		handleResponseLog(c, h.Log, "invalid unsubscribe", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		handleErrorResponse(c, err, "Unsubscribe: turning off digest", http.StatusInternalServerError)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Unsubscribed", http.StatusOK, models.SuccessResponse{Message: "digest emails have been turned off"})
+}
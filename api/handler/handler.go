@@ -2,13 +2,18 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"todo/api/models"
+	"todo/pkg/taskquery"
 	"todo/service"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Handler structure
@@ -50,6 +55,37 @@ func handleErrorResponse(c *gin.Context, err error, context string, statusCode i
 	c.JSON(statusCode, errorResponse)
 }
 
+// errorStatus maps service errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidInput),
+		errors.Is(err, models.ErrInvalidLabel),
+		errors.Is(err, models.ErrInvalidCursor),
+		errors.Is(err, models.ErrInvalidOTP):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrEmailTaken),
+		errors.Is(err, models.ErrRegistrationPending):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrTooManyOTPAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, models.ErrUserNotFound),
+		errors.Is(err, models.ErrTaskNotFound),
+		errors.Is(err, models.ErrTaskListNotFound),
+		errors.Is(err, models.ErrLabelNotFound),
+		errors.Is(err, models.ErrSmartListNotFound),
+		errors.Is(err, models.ErrChecklistItemNotFound),
+		errors.Is(err, models.ErrWebhookNotFound),
+		errors.Is(err, models.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrNotificationFailed):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // getAuthInfo retrieves authentication information from the context
 func getAuthInfo(c *gin.Context) (*models.AuthInfo, error) {
 	authInfo, exists := c.Get("authInfo")
@@ -84,3 +120,162 @@ func ParseLimitQueryParam(c *gin.Context) (uint64, error) {
 	}
 	return limit, nil
 }
+
+// ParsePaginationQueryParams reads "limit" and either the "after" cursor
+// handed out as next_cursor or, for compatibility, the "page" number.
+func ParsePaginationQueryParams(c *gin.Context) (models.Pagination, error) {
+	limit, err := ParseLimitQueryParam(c)
+	if err != nil {
+		return models.Pagination{}, err
+	}
+
+	if after := c.Query("after"); after != "" {
+		cursor, err := models.DecodeCursor(after)
+		if err != nil {
+			return models.Pagination{}, err
+		}
+		return models.Pagination{Limit: limit, After: &cursor}, nil
+	}
+
+	page, err := ParsePageQueryParam(c)
+	if err != nil {
+		return models.Pagination{}, err
+	}
+	return models.Pagination{Limit: limit, Offset: (page - 1) * limit}, nil
+}
+
+// newPagedResponse wraps a page of a listing. The page number is only
+// reported to clients paging by number.
+func newPagedResponse(data interface{}, page models.Pagination, info models.PageInfo) models.PagedResponse {
+	resp := models.PagedResponse{
+		Data:       data,
+		TotalCount: info.TotalCount,
+		Limit:      int64(page.Limit),
+		NextCursor: info.NextCursor,
+	}
+	if page.After == nil && page.Limit > 0 {
+		resp.Page = int64(page.Offset/page.Limit + 1)
+	}
+	return resp
+}
+
+// ParseDryRunQueryParam reads the optional "dry_run" query param.
+func ParseDryRunQueryParam(c *gin.Context) (bool, error) {
+	dryRunStr := c.Query("dry_run")
+	if dryRunStr == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(dryRunStr)
+}
+
+// ParseLabelFilterQueryParam reads the comma separated "labels" and the
+// "label_match" query params into a label filter.
+func ParseLabelFilterQueryParam(c *gin.Context) (models.LabelFilter, error) {
+	filter := models.LabelFilter{Match: c.DefaultQuery("label_match", models.LabelMatchAny)}
+
+	labelsStr := c.Query("labels")
+	if labelsStr == "" {
+		return filter, nil
+	}
+
+	for _, idStr := range strings.Split(labelsStr, ",") {
+		id, err := primitive.ObjectIDFromHex(strings.TrimSpace(idStr))
+		if err != nil {
+			return models.LabelFilter{}, fmt.Errorf("invalid label id %q", idStr)
+		}
+		filter.LabelIDs = append(filter.LabelIDs, id)
+	}
+	return filter, nil
+}
+
+// ParseTaskFilterQueryParams reads the task listing filters: "search",
+// "labels" and "label_match", "task_list_id" (comma separated), "completed",
+// "overdue", "no_due_date", the "due_before", "due_after", "created_before",
+// "created_after", "updated_before" and "updated_after" times and "sort".
+func ParseTaskFilterQueryParams(c *gin.Context) (models.TaskFilter, error) {
+	var err error
+	filter := models.TaskFilter{Search: c.Query("search")}
+
+	if filter.Labels, err = ParseLabelFilterQueryParam(c); err != nil {
+		return models.TaskFilter{}, err
+	}
+
+	if idsStr := c.Query("task_list_id"); idsStr != "" {
+		for _, idStr := range strings.Split(idsStr, ",") {
+			id, err := primitive.ObjectIDFromHex(strings.TrimSpace(idStr))
+			if err != nil {
+				return models.TaskFilter{}, fmt.Errorf("invalid task list id %q", idStr)
+			}
+			filter.TaskListIDs = append(filter.TaskListIDs, id)
+		}
+	}
+
+	if completedStr := c.Query("completed"); completedStr != "" {
+		completed, err := strconv.ParseBool(completedStr)
+		if err != nil {
+			return models.TaskFilter{}, fmt.Errorf("invalid completed %q", completedStr)
+		}
+		filter.Completed = &completed
+	}
+	if filter.Overdue, err = parseFlagQueryParam(c, "overdue"); err != nil {
+		return models.TaskFilter{}, err
+	}
+	if filter.NoDueDate, err = parseFlagQueryParam(c, "no_due_date"); err != nil {
+		return models.TaskFilter{}, err
+	}
+
+	times := map[string]**time.Time{
+		"due_before":     &filter.DueBefore,
+		"due_after":      &filter.DueAfter,
+		"created_before": &filter.CreatedBefore,
+		"created_after":  &filter.CreatedAfter,
+		"updated_before": &filter.UpdatedBefore,
+		"updated_after":  &filter.UpdatedAfter,
+	}
+	for name, field := range times {
+		if *field, err = parseTimeQueryParam(c, name); err != nil {
+			return models.TaskFilter{}, err
+		}
+	}
+
+	if q := c.Query("q"); q != "" {
+		if filter.Query, err = taskquery.Parse(q); err != nil {
+			return models.TaskFilter{}, err
+		}
+	}
+
+	if filter.Sort, err = models.ParseTaskSort(c.Query("sort")); err != nil {
+		return models.TaskFilter{}, err
+	}
+	return filter, nil
+}
+
+func parseFlagQueryParam(c *gin.Context, name string) (bool, error) {
+	flagStr := c.Query(name)
+	if flagStr == "" {
+		return false, nil
+	}
+
+	flag, err := strconv.ParseBool(flagStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", name, flagStr)
+	}
+	return flag, nil
+}
+
+// parseTimeQueryParam reads an optional RFC 3339 time or a plain date,
+// which means midnight UTC.
+func parseTimeQueryParam(c *gin.Context, name string) (*time.Time, error) {
+	timeStr := c.Query(name)
+	if timeStr == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, timeStr); err != nil {
+			return nil, fmt.Errorf("invalid %s %q, want an RFC 3339 time or a date", name, timeStr)
+		}
+	}
+	return &t, nil
+}
@@ -5,6 +5,7 @@ import (
 	"todo/api/models"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreateLabel godoc
@@ -15,11 +16,10 @@ import (
 // @Tags		label
 // @Accept		json
 // @Produce		json
-// @Param		label body models.CreateLabelRequest true "Label data"
+// @Param		label body models.CreateLabel true "Label data"
 // @Success		201  {object}  models.Label
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) CreateLabel(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -27,20 +27,19 @@ func (h *Handler) CreateLabel(c *gin.Context) {
 		return
 	}
 
-	req := models.CreateLabelRequest{}
+	req := models.CreateLabel{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
-	req.UserID = authInfo.UserID
 
-	label, err := h.Services.Label().CreateLabel(c.Request.Context(), req)
+	label, err := h.Services.LabelService.CreateLabel(c.Request.Context(), *authInfo, req)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating label", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating label", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Succes", http.StatusOK, label)
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, label)
 }
 
 // GetLabel godoc
@@ -53,11 +52,11 @@ func (h *Handler) CreateLabel(c *gin.Context) {
 // @Produce		json
 // @Param		id path string true "Label ID"
 // @Success		200  {object}  models.Label
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) GetLabel(c *gin.Context) {
-	_, err := getAuthInfo(c)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
 		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
 		return
@@ -69,9 +68,9 @@ func (h *Handler) GetLabel(c *gin.Context) {
 		return
 	}
 
-	label, err := h.Services.Label().GetLabel(c.Request.Context(), labelID)
+	label, err := h.Services.LabelService.GetLabelByID(c.Request.Context(), *authInfo, labelID)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting label", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting label", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -87,11 +86,11 @@ func (h *Handler) GetLabel(c *gin.Context) {
 // @Accept		json
 // @Produce		json
 // @Param		id path string true "Label ID"
-// @Param		label body models.UpdateLabelRequest true "Label data"
+// @Param		label body models.UpdateLabel true "Label data"
 // @Success		200  {object}  models.Label
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) UpdateLabel(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -99,27 +98,23 @@ func (h *Handler) UpdateLabel(c *gin.Context) {
 		return
 	}
 
-	labelID := c.Param("id")
-	if labelID == "" {
-		handleResponseLog(c, h.Log, "label id is empty", http.StatusBadRequest, "")
+	labelID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid label id", http.StatusBadRequest, err.Error())
 		return
 	}
 
-	updateReq := models.UpdateLabelRequest{}
+	updateReq := models.UpdateLabel{}
 
 	if err := c.ShouldBindJSON(&updateReq); err != nil {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
+	updateReq.ID = labelID
 
-	if authInfo.UserID != updateReq.UserID {
-		handleResponseLog(c, h.Log, "user can't update another user's label", http.StatusUnauthorized, "")
-		return
-	}
-
-	label, err := h.Services.Label().UpdateLabel(c.Request.Context(), updateReq)
+	label, err := h.Services.LabelService.UpdateLabel(c.Request.Context(), *authInfo, updateReq)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating label", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating label", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -135,12 +130,12 @@ func (h *Handler) UpdateLabel(c *gin.Context) {
 // @Accept		json
 // @Produce		json
 // @Param		id path string true "Label ID"
-// @Success		200  {object}  string
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Success		200  {object}  models.SuccessResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) DeleteLabel(c *gin.Context) {
-	_, err := getAuthInfo(c)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
 		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
 		return
@@ -152,30 +147,30 @@ func (h *Handler) DeleteLabel(c *gin.Context) {
 		return
 	}
 
-	msg, err := h.Services.Label().DeleteLabel(c.Request.Context(), labelID)
+	err = h.Services.LabelService.DeleteLabel(c.Request.Context(), *authInfo, labelID)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting label", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting label", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "label was successfully deleted", http.StatusOK, msg)
+	handleResponseLog(c, h.Log, "label was successfully deleted", http.StatusOK, models.SuccessResponse{Message: "label was successfully deleted"})
 }
 
 // GetAllLabels godoc
 // @Security ApiKeyAuth
 // @Router		/label [GET]
 // @Summary		get all labels
-// @Description This api gets all labels and returns label list
+// @Description This api gets the caller's labels
 // @Tags		label
 // @Accept		json
 // @Produce		json
 // @Param		search query string false "Search by name"
-// @Param		page   query int   false "Page Number"
+// @Param		after  query string false "Cursor from next_cursor of the previous page"
+// @Param		page   query int   false "Page Number, ignored with after"
 // @Param		limit  query int   false "Limit"
-// @Success		200  {object}  models.GetAllLabelsResponse
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) GetAllLabels(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -183,26 +178,19 @@ func (h *Handler) GetAllLabels(c *gin.Context) {
 		return
 	}
 
-	page, err := ParsePageQueryParam(c)
+	page, err := ParsePaginationQueryParams(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page query param", http.StatusBadRequest, err.Error())
-		return
-	}
-
-	limit, err := ParseLimitQueryParam(c)
-	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit query param", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
 	search := c.Query("search")
 
-	allLabels, err := h.Services.Label().GetAllLabels(c.Request.Context(), authInfo.UserID, search, page, limit)
+	labels, info, err := h.Services.LabelService.ListLabels(c.Request.Context(), *authInfo, search, page)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting labels", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting labels", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Succes", http.StatusOK, allLabels)
-
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(labels, page, info))
 }
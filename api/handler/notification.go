@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationSettings godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications [GET]
+// @Summary		get notification settings
+// @Description This api returns where the user's notifications go. Users who never changed them get email only
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Success		200  {object}  models.NotificationSettings
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetNotificationSettings(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	settings, err := h.Services.NotificationService.GetSettings(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting notification settings", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, settings)
+}
+
+// UpdateNotificationSettings godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications [PUT]
+// @Summary		update notification settings
+// @Description This api replaces the user's notification settings. The channels are email, telegram and webhook; telegram needs the telegram_chat_id of the chat to send to, which the bot must be a member of, and webhook needs the webhook_url to post to
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Param		settings body models.UpdateNotificationSettings true "Notification settings"
+// @Success		200  {object}  models.NotificationSettings
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) UpdateNotificationSettings(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.UpdateNotificationSettings{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	settings, err := h.Services.NotificationService.UpdateSettings(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating notification settings", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, settings)
+}
+
+// SendTestNotification godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications/test [POST]
+// @Summary		send a test notification
+// @Description This api sends a test message over every channel the user enabled. A channel that fails is reported with its error
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Success		200  {object}  models.SuccessResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		502  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) SendTestNotification(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.Services.NotificationService.SendTest(c.Request.Context(), *authInfo, c.Param("id")); err != nil {
+		handleResponseLog(c, h.Log, "error while sending test notification", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "test notification was sent", http.StatusOK, models.SuccessResponse{Message: "test notification was sent"})
+}
+
+// GetNotificationPreferences godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications/preferences [GET]
+// @Summary		get notification preferences
+// @Description This api returns which events the user is notified of over which channels, and their quiet hours
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Success		200  {object}  models.NotificationPreferences
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	preferences, err := h.Services.NotificationService.GetPreferences(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting notification preferences", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, preferences)
+}
+
+// UpdateNotificationPreferences godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/notifications/preferences [PUT]
+// @Summary		update notification preferences
+// @Description This api replaces the user's notification preferences. events maps reminder, shared_list, mention and digest to the channels they are sent over; events left out go over every enabled channel and an empty list turns one off. During the quiet_hours, given as HH:MM in the user's timezone, notifications that can wait are held back until they end; reminders of tasks due before then are sent anyway
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Param		preferences body models.UpdateNotificationPreferences true "Notification preferences"
+// @Success		200  {object}  models.NotificationPreferences
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.UpdateNotificationPreferences{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	preferences, err := h.Services.NotificationService.UpdatePreferences(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating notification preferences", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, preferences)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetRecurrence godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/recurrence [PUT]
+// @Summary		make task repeat
+// @Description This api makes a task repeat by an RFC 5545 RRULE, such as FREQ=WEEKLY;BYDAY=MO, starting at its due date, and returns task. Completing the task creates the next occurrence
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		recurrence body models.SetRecurrence true "Recurrence rule and IANA time zone"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) SetRecurrence(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.SetRecurrence{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.SetRecurrence(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while setting task recurrence", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// EndRecurrence godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/recurrence [DELETE]
+// @Summary		end task series
+// @Description This api stops a task from repeating, keeping the task, and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) EndRecurrence(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.EndRecurrence(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while ending task recurrence", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// SkipOccurrence godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/recurrence/skip [POST]
+// @Summary		skip occurrence
+// @Description This api moves a recurring task to its next occurrence without completing it and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) SkipOccurrence(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.SkipOccurrence(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while skipping occurrence", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// GetOccurrences godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/recurrence/occurrences [GET]
+// @Summary		preview occurrences
+// @Description This api lists the due dates of the next occurrences of a recurring task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id    path  string true  "Task ID"
+// @Param		count query int    false "Number of occurrences, 5 by default and at most 100"
+// @Success		200  {object}  models.Occurrences
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetOccurrences(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	count := 0
+	if value := c.Query("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count < 1 {
+			handleResponseLog(c, h.Log, "invalid count", http.StatusBadRequest, models.ErrorResponse{Error: "count must be a positive number"})
+			return
+		}
+	}
+
+	occurrences, err := h.Services.TaskService.PreviewOccurrences(c.Request.Context(), *authInfo, c.Param("id"), count)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while previewing occurrences", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, occurrences)
+}
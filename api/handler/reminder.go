@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReminders godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/reminders [GET]
+// @Summary		get task reminders
+// @Description This api returns the reminders of a task with their delivery status, the earliest first
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Success		200  {array}   models.Reminder
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetReminders(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	reminders, err := h.Services.TaskService.GetReminders(c.Request.Context(), *authInfo, c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting task reminders", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, reminders)
+}
+
+// SetReminders godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/reminders [PUT]
+// @Summary		set task reminders
+// @Description This api replaces the reminders of a task, given in minutes before its due date, such as 1440 for a day and 60 for an hour, and returns them. Reminders that are kept are not sent again; an empty list removes them all
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		reminders body models.SetReminders true "Minutes before the due date"
+// @Success		200  {array}   models.Reminder
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) SetReminders(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.SetReminders{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reminders, err := h.Services.TaskService.SetReminders(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while setting task reminders", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, reminders)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search godoc
+// @Security ApiKeyAuth
+// @Router		/search [GET]
+// @Summary		search
+// @Description This api searches the caller's tasks, task lists and labels by words, titles weighing more than descriptions, and returns the best matches first with highlighted snippets
+// @Tags		search
+// @Accept		json
+// @Produce		json
+// @Param		q     query string true  "Words to search for"
+// @Param		type  query string false "Comma separated types: task, task_list, label; all by default"
+// @Param		limit query int    false "Maximum number of results, 20 by default, at most 100"
+// @Success		200  {object}  models.SearchResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) Search(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	query := c.Query("q")
+
+	var types []string
+	if typesStr := c.Query("type"); typesStr != "" {
+		for _, typ := range strings.Split(typesStr, ",") {
+			types = append(types, strings.TrimSpace(typ))
+		}
+	}
+
+	var limit int64
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err = strconv.ParseInt(limitStr, 10, 64); err != nil {
+			handleResponseLog(c, h.Log, "invalid limit", http.StatusBadRequest, models.ErrorResponse{Error: "invalid limit " + strconv.Quote(limitStr)})
+			return
+		}
+	}
+
+	results, err := h.Services.SearchService.Search(c.Request.Context(), *authInfo, query, types, limit)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while searching", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, models.SearchResponse{Query: query, Results: results})
+}
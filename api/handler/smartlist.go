@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSmartList godoc
+// @Security ApiKeyAuth
+// @Router		/smart-list [POST]
+// @Summary		create smart list
+// @Description This api saves a task query, such as due:<7d AND label:work AND NOT completed, as a smart list and returns it
+// @Tags		smart-list
+// @Accept		json
+// @Produce		json
+// @Param		smart_list body models.CreateSmartList true "Smart list data"
+// @Success		201  {object}  models.SmartList
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) CreateSmartList(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.CreateSmartList{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	smartList, err := h.Services.SmartListService.CreateSmartList(c.Request.Context(), *authInfo, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while creating smart list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, smartList)
+}
+
+// GetSmartList godoc
+// @Security ApiKeyAuth
+// @Router		/smart-list/{id} [GET]
+// @Summary		get smart list by id
+// @Description This api gets smart list by its id and returns smart list
+// @Tags		smart-list
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Smart List ID"
+// @Success		200  {object}  models.SmartList
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetSmartList(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	smartListID := c.Param("id")
+	if smartListID == "" {
+		handleResponseLog(c, h.Log, "smart list id is empty", http.StatusBadRequest, "")
+		return
+	}
+
+	smartList, err := h.Services.SmartListService.GetSmartListByID(c.Request.Context(), *authInfo, smartListID)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting smart list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, smartList)
+}
+
+// UpdateSmartList godoc
+// @Security ApiKeyAuth
+// @Router		/smart-list/{id} [PATCH]
+// @Summary		update smart list by id
+// @Description This api updates smart list by its id and returns smart list
+// @Tags		smart-list
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Smart List ID"
+// @Param		smart_list body models.UpdateSmartList true "Smart list data"
+// @Success		200  {object}  models.SmartList
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) UpdateSmartList(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	smartListID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid smart list id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updateReq := models.UpdateSmartList{}
+
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+	updateReq.ID = smartListID
+
+	smartList, err := h.Services.SmartListService.UpdateSmartList(c.Request.Context(), *authInfo, updateReq)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating smart list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, smartList)
+}
+
+// DeleteSmartList godoc
+// @Security ApiKeyAuth
+// @Router		/smart-list/{id} [DELETE]
+// @Summary		delete smart list by id
+// @Description This api deletes smart list by its id and returns message. The tasks it shows are kept.
+// @Tags		smart-list
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Smart List ID"
+// @Success		200  {object}  models.SuccessResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) DeleteSmartList(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	smartListID := c.Param("id")
+	if smartListID == "" {
+		handleResponseLog(c, h.Log, "smart list id is empty", http.StatusBadRequest, "")
+		return
+	}
+
+	err = h.Services.SmartListService.DeleteSmartList(c.Request.Context(), *authInfo, smartListID)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while deleting smart list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "smart list was successfully deleted", http.StatusOK, models.SuccessResponse{Message: "smart list was successfully deleted"})
+}
+
+// GetAllSmartLists godoc
+// @Security ApiKeyAuth
+// @Router		/smart-list [GET]
+// @Summary		get all smart lists
+// @Description This api gets the caller's smart lists
+// @Tags		smart-list
+// @Accept		json
+// @Produce		json
+// @Param		after query string false "Cursor from next_cursor of the previous page"
+// @Param		page  query int    false "Page Number, ignored with after"
+// @Param		limit query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetAllSmartLists(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	smartLists, info, err := h.Services.SmartListService.ListSmartLists(c.Request.Context(), *authInfo, page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting smart lists", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(smartLists, page, info))
+}
+
+// GetSmartListTasks godoc
+// @Security ApiKeyAuth
+// @Router		/smart-list/{id}/tasks [GET]
+// @Summary		evaluate smart list
+// @Description This api gets the tasks matching a smart list's query across the caller's task lists, in the smart list's order
+// @Tags		smart-list
+// @Accept		json
+// @Produce		json
+// @Param		id    path  string true  "Smart List ID"
+// @Param		after query string false "Cursor from next_cursor of the previous page"
+// @Param		page  query int    false "Page Number, ignored with after"
+// @Param		limit query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetSmartListTasks(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tasks, info, err := h.Services.SmartListService.ListSmartListTasks(c.Request.Context(), *authInfo, c.Param("id"), page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting smart list tasks", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(tasks, page, info))
+}
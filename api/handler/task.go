@@ -5,21 +5,22 @@ import (
 	"todo/api/models"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreateTask godoc
 // @Security ApiKeyAuth
 // @Router		/task [POST]
 // @Summary		create task
-// @Description This api creates task and returns task
+// @Description This api creates task in one of the caller's task lists and returns task
 // @Tags		task
 // @Accept		json
 // @Produce		json
 // @Param		task body models.CreateTask true "Task data"
 // @Success		201  {object}  models.Task
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) CreateTask(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -32,16 +33,14 @@ func (h *Handler) CreateTask(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
-	req.UserID = authInfo.UserID
 
-	task, err := h.Services.Task().CreateTask(c.Request.Context(), req)
+	task, err := h.Services.TaskService.CreateTask(c.Request.Context(), *authInfo, req)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating task", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
-
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, task)
 }
 
 // GetTask godoc
@@ -54,11 +53,11 @@ func (h *Handler) CreateTask(c *gin.Context) {
 // @Produce		json
 // @Param		id path string true "Task ID"
 // @Success		200  {object}  models.Task
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) GetTask(c *gin.Context) {
-	_, err := getAuthInfo(c)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
 		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
 		return
@@ -70,9 +69,9 @@ func (h *Handler) GetTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.Services.Task().GetTask(c.Request.Context(), taskID)
+	task, err := h.Services.TaskService.GetTaskByID(c.Request.Context(), *authInfo, taskID)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting task", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -90,9 +89,9 @@ func (h *Handler) GetTask(c *gin.Context) {
 // @Param		id path string true "Task ID"
 // @Param		task body models.UpdateTask true "Task data"
 // @Success		200  {object}  models.Task
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) UpdateTask(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -100,9 +99,9 @@ func (h *Handler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	taskID := c.Param("id")
-	if taskID == "" {
-		handleResponseLog(c, h.Log, "task id is empty", http.StatusBadRequest, "")
+	taskID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid task id", http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -112,15 +111,11 @@ func (h *Handler) UpdateTask(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
+	updateReq.ID = taskID
 
-	if authInfo.UserID != updateReq.UserID {
-		handleResponseLog(c, h.Log, "user can't update another user's task", http.StatusUnauthorized, "")
-		return
-	}
-
-	task, err := h.Services.Task().UpdateTask(c.Request.Context(), updateReq)
+	task, err := h.Services.TaskService.UpdateTask(c.Request.Context(), *authInfo, updateReq)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating task", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
@@ -135,12 +130,12 @@ func (h *Handler) UpdateTask(c *gin.Context) {
 // @Accept		json
 // @Produce		json
 // @Param		id path string true "Task ID"
-// @Success		200  {object}  string
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Success		200  {object}  models.SuccessResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) DeleteTask(c *gin.Context) {
-	_, err := getAuthInfo(c)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
 		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
 		return
@@ -152,12 +147,179 @@ func (h *Handler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	msg, err := h.Services.Task().DeleteTask(c.Request.Context(), taskID)
+	err = h.Services.TaskService.DeleteTask(c.Request.Context(), *authInfo, taskID)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting task", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "task was successfully deleted", http.StatusOK, msg)
+	handleResponseLog(c, h.Log, "task was successfully deleted", http.StatusOK, models.SuccessResponse{Message: "task was successfully deleted"})
 }
 
+// AddTaskLabels godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/labels [POST]
+// @Summary		add labels to task
+// @Description This api attaches the caller's labels to a task and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		labels body models.TaskLabels true "Label IDs"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) AddTaskLabels(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.TaskLabels{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.AddTaskLabels(c.Request.Context(), *authInfo, c.Param("id"), req.LabelIDs)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while adding task labels", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// RemoveTaskLabel godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/labels/{label_id} [DELETE]
+// @Summary		remove label from task
+// @Description This api detaches a label from a task and returns task
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "Task ID"
+// @Param		label_id path string true "Label ID"
+// @Success		200  {object}  models.Task
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) RemoveTaskLabel(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	labelID, err := primitive.ObjectIDFromHex(c.Param("label_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid label id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.Services.TaskService.RemoveTaskLabels(c.Request.Context(), *authInfo, c.Param("id"), []primitive.ObjectID{labelID})
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while removing task label", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, task)
+}
+
+// GetAllTasks godoc
+// @Security ApiKeyAuth
+// @Router		/task [GET]
+// @Summary		get all tasks
+// @Description This api gets the caller's tasks across their task lists, filtered and sorted
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		task_list_id   query string false "Comma separated task list IDs, all of the caller's lists by default"
+// @Param		search         query string false "Search by title"
+// @Param		labels         query string false "Comma separated label IDs"
+// @Param		label_match    query string false "Label match mode: any (default) or all"
+// @Param		completed      query bool   false "Only completed or only open tasks"
+// @Param		overdue        query bool   false "Only open tasks that are past their due date"
+// @Param		no_due_date    query bool   false "Only tasks without a due date"
+// @Param		due_before     query string false "RFC 3339 time or date"
+// @Param		due_after      query string false "RFC 3339 time or date"
+// @Param		created_before query string false "RFC 3339 time or date"
+// @Param		created_after  query string false "RFC 3339 time or date"
+// @Param		updated_before query string false "RFC 3339 time or date"
+// @Param		updated_after  query string false "RFC 3339 time or date"
+// @Param		q              query string false "Task query, such as due:<7d AND label:work AND NOT completed"
+// @Param		sort           query string false "Comma separated fields, - for descending: created_at, updated_at, due_date, title, completed"
+// @Param		after          query string false "Cursor from next_cursor of the previous page"
+// @Param		page           query int    false "Page Number, ignored with after"
+// @Param		limit          query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetAllTasks(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	filter, err := ParseTaskFilterQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing filter query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tasks, info, err := h.Services.TaskService.ListTasks(c.Request.Context(), *authInfo, filter, page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting tasks", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(tasks, page, info))
+}
+
+// GetSubtasks godoc
+// @Security ApiKeyAuth
+// @Router		/task/{id}/subtasks [GET]
+// @Summary		get subtasks
+// @Description This api gets the subtasks of a task, oldest first
+// @Tags		task
+// @Accept		json
+// @Produce		json
+// @Param		id    path  string true  "Task ID"
+// @Param		after query string false "Cursor from next_cursor of the previous page"
+// @Param		page  query int    false "Page Number, ignored with after"
+// @Param		limit query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetSubtasks(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tasks, info, err := h.Services.TaskService.ListSubtasks(c.Request.Context(), *authInfo, c.Param("id"), page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting subtasks", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(tasks, page, info))
+}
@@ -5,6 +5,7 @@ import (
 	"todo/api/models"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreateTaskList godoc
@@ -17,9 +18,8 @@ import (
 // @Produce		json
 // @Param		task-list body models.CreateTaskList true "Task List data"
 // @Success		201  {object}  models.TaskList
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) CreateTaskList(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -32,16 +32,14 @@ func (h *Handler) CreateTaskList(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
-	req.UserID = authInfo.UserID
 
-	taskList, err := h.Services.Tasklist().CreateTaskList(c.Request.Context(), req)
+	taskList, err := h.Services.TaskListService.CreateTaskList(c.Request.Context(), *authInfo, req)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating task list", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating task list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Succes", http.StatusOK, taskList)
-
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, taskList)
 }
 
 // GetTaskList godoc
@@ -53,12 +51,12 @@ func (h *Handler) CreateTaskList(c *gin.Context) {
 // @Accept		json
 // @Produce		json
 // @Param		id path string true "Task List ID"
-// @Success		200  {object}  models.GetTaskListResponse
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Success		200  {object}  models.TaskList
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) GetTaskList(c *gin.Context) {
-	_, err := getAuthInfo(c)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
 		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
 		return
@@ -70,9 +68,9 @@ func (h *Handler) GetTaskList(c *gin.Context) {
 		return
 	}
 
-	taskList, err := h.Services.Tasklist().GetTaskList(c.Request.Context(), taskListID)
+	taskList, err := h.Services.TaskListService.GetTaskListByID(c.Request.Context(), *authInfo, taskListID)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting task list", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting task list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -90,9 +88,9 @@ func (h *Handler) GetTaskList(c *gin.Context) {
 // @Param		id path string true "Task List ID"
 // @Param		task-list body models.UpdateTaskList true "Task List data"
 // @Success		200  {object}  models.TaskList
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) UpdateTaskList(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -100,9 +98,9 @@ func (h *Handler) UpdateTaskList(c *gin.Context) {
 		return
 	}
 
-	taskListID := c.Param("id")
-	if taskListID == "" {
-		handleResponseLog(c, h.Log, "task list id is empty", http.StatusBadRequest, "")
+	taskListID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid task list id", http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -112,15 +110,11 @@ func (h *Handler) UpdateTaskList(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
+	updateReq.ID = taskListID
 
-	if authInfo.UserID != updateReq.UserID {
-		handleResponseLog(c, h.Log, "user can't update another user's task list", http.StatusUnauthorized, "")
-		return
-	}
-
-	taskList, err := h.Services.Tasklist().UpdateTaskList(c.Request.Context(), updateReq)
+	taskList, err := h.Services.TaskListService.UpdateTaskList(c.Request.Context(), *authInfo, updateReq)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating task list", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating task list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 	handleResponseLog(c, h.Log, "Succes", http.StatusOK, taskList)
@@ -130,17 +124,19 @@ func (h *Handler) UpdateTaskList(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Router		/task-list/{id} [DELETE]
 // @Summary		delete task list by id
-// @Description This api deletes task list by its id and returns message
+// @Description This api deletes task list by its id together with its tasks and returns what was removed.
+// @Description With dry_run=true nothing is deleted and the response shows what would be removed.
 // @Tags		task-list
 // @Accept		json
 // @Produce		json
-// @Param		id path string true "Task List ID"
-// @Success		200  {object}  string
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Param		id      path  string true  "Task List ID"
+// @Param		dry_run query bool   false "Only report what would be deleted"
+// @Success		200  {object}  models.DeleteSummary
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) DeleteTaskList(c *gin.Context) {
-	_, err := getAuthInfo(c)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
 		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
 		return
@@ -152,30 +148,35 @@ func (h *Handler) DeleteTaskList(c *gin.Context) {
 		return
 	}
 
-	msg, err := h.Services.Tasklist().DeleteTaskList(c.Request.Context(), taskListID)
+	dryRun, err := ParseDryRunQueryParam(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing dry_run query param", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := h.Services.TaskListService.DeleteTaskList(c.Request.Context(), *authInfo, taskListID, dryRun)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting task list", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting task list", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "task list was successfully deleted", http.StatusOK, msg)
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, summary)
 }
 
 // GetAllTaskLists godoc
 // @Security ApiKeyAuth
 // @Router		/task-list [GET]
 // @Summary		get all task lists
-// @Description This api gets all task lists and returns task list
+// @Description This api gets the caller's task lists
 // @Tags		task-list
 // @Accept		json
 // @Produce		json
-// @Param		search query string false "Search by name"
-// @Param		page   query int   false "Page Number"
+// @Param		after  query string false "Cursor from next_cursor of the previous page"
+// @Param		page   query int   false "Page Number, ignored with after"
 // @Param		limit  query int   false "Limit"
-// @Success		200  {object}  models.GetAllTaskListsResponse
-// @Failure		400  {object}  models.Response
-// @Failure		404  {object}  models.Response
-// @Failure		500  {object}  models.Response
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
 func (h *Handler) GetAllTaskLists(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -183,26 +184,72 @@ func (h *Handler) GetAllTaskLists(c *gin.Context) {
 		return
 	}
 
-	page, err := ParsePageQueryParam(c)
+	page, err := ParsePaginationQueryParams(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page query param", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	limit, err := ParseLimitQueryParam(c)
+	taskLists, info, err := h.Services.TaskListService.ListTaskLists(c.Request.Context(), *authInfo, page)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit query param", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while getting task lists", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	search := c.Query("search")
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(taskLists, page, info))
+}
 
-	allTaskLists, err := h.Services.Tasklist().GetAllTaskLists(c.Request.Context(), authInfo.UserID, search, page, limit)
+// GetTaskListTasks godoc
+// @Security ApiKeyAuth
+// @Router		/task-list/{id}/tasks [GET]
+// @Summary		get tasks of task list
+// @Description This api gets the tasks of a task list, with the filters and sorting of GET /task
+// @Tags		task-list
+// @Accept		json
+// @Produce		json
+// @Param		id          path  string true  "Task List ID"
+// @Param		search      query string false "Search by title"
+// @Param		labels      query string false "Comma separated label IDs"
+// @Param		label_match query string false "Label match mode: any (default) or all"
+// @Param		sort        query string false "Comma separated fields, - for descending: created_at, updated_at, due_date, title, completed"
+// @Param		after       query string false "Cursor from next_cursor of the previous page"
+// @Param		page        query int    false "Page Number, ignored with after"
+// @Param		limit       query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetTaskListTasks(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting task lists", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Succes", http.StatusOK, allTaskLists)
+	filter, err := ParseTaskFilterQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing filter query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	taskListID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting tasks", http.StatusNotFound, models.ErrorResponse{Error: models.ErrTaskListNotFound.Error()})
+		return
+	}
+	filter.TaskListIDs = []primitive.ObjectID{taskListID}
+
+	tasks, info, err := h.Services.TaskService.ListTasks(c.Request.Context(), *authInfo, filter, page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting tasks", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
 
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(tasks, page, info))
 }
@@ -18,7 +18,7 @@ import (
 // @Failure 400 {object} models.ErrorResponse "Invalid user ID format"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 404 {object} models.ErrorResponse "User not found"
-// @Router /users/{id} [get]
+// @Router /user/{id} [get]
 func (h *Handler) GetUser(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -32,15 +32,14 @@ func (h *Handler) GetUser(c *gin.Context) {
 		return
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
 		handleResponseLog(c, h.Log, "Invalid user ID format", http.StatusBadRequest, err.Error())
 		return
 	}
 
-	user, err := h.Services.User().GetUser(c.Request.Context(), objectID)
+	user, err := h.Services.UserService.GetUserByID(c.Request.Context(), *authInfo, userID)
 	if err != nil {
-		handleErrorResponse(c, err, "GetUser", http.StatusInternalServerError)
+		handleResponseLog(c, h.Log, "Error getting user", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -49,7 +48,8 @@ func (h *Handler) GetUser(c *gin.Context) {
 
 // UpdateUser updates user information by ID
 // @Summary Update user by ID
-// @Description Update user details
+// @Description Update user details. A new email is only taken over once the
+// @Description code sent to it is confirmed with POST /user/{id}/email-confirm.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -59,7 +59,8 @@ func (h *Handler) GetUser(c *gin.Context) {
 // @Failure 400 {object} models.ErrorResponse "Invalid user ID format"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 404 {object} models.ErrorResponse "User not found"
-// @Router /users/{id} [put]
+// @Failure 409 {object} models.ErrorResponse "Email taken or change already pending"
+// @Router /user/{id} [patch]
 func (h *Handler) UpdateUser(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -85,31 +86,66 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	if authInfo.UserID != updateReq.ID {
-		handleResponseLog(c, h.Log, "Unauthorized: cannot update another user's profile", http.StatusUnauthorized, "")
+	updateReq.ID = objectID
+
+	updatedUser, err := h.Services.UserService.UpdateUser(c.Request.Context(), *authInfo, updateReq)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Error updating user", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	updatedUser, err := h.Services.User().UpdateUser(c.Request.Context(), objectID, updateReq)
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, updatedUser)
+}
+
+// ConfirmEmailChange switches a user to the email they asked for
+// @Summary Confirm email change
+// @Description Confirm a new email with the code sent to it by PATCH /user/{id}.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param confirm body models.ConfirmEmailChange true "New email and code"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse "Invalid code"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Email taken"
+// @Failure 429 {object} models.ErrorResponse "Too many attempts"
+// @Router /user/{id}/email-confirm [post]
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
-		handleErrorResponse(c, err, "UpdateUser", http.StatusInternalServerError)
+		handleResponseLog(c, h.Log, "Unauthorized", http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Success", http.StatusOK, updatedUser)
+	req := models.ConfirmEmailChange{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "Error binding JSON", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.Services.UserService.ConfirmEmailChange(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Error confirming email change", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, user)
 }
 
 // DeleteUser removes a user by ID
 // @Summary Delete user by ID
-// @Description Delete a user from the system
+// @Description Delete a user together with their task lists, tasks, labels and tokens.
+// @Description With dry_run=true nothing is deleted and the response shows what would be removed.
 // @Tags Users
 // @Produce json
 // @Param id path string true "User ID"
-// @Success 200 {string} string "User deleted successfully"
+// @Param dry_run query bool false "Only report what would be deleted"
+// @Success 200 {object} models.DeleteSummary
 // @Failure 400 {object} models.ErrorResponse "Invalid user ID format"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 404 {object} models.ErrorResponse "User not found"
-// @Router /users/{id} [delete]
+// @Router /user/{id} [delete]
 func (h *Handler) DeleteUser(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -123,37 +159,39 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
 		handleResponseLog(c, h.Log, "Invalid user ID format", http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if authInfo.UserID != userID {
-		handleResponseLog(c, h.Log, "Unauthorized: cannot delete another user's profile", http.StatusUnauthorized, "")
+	dryRun, err := ParseDryRunQueryParam(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "Error parsing dry_run", http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = h.Services.User().DeleteUser(c.Request.Context(), objectID)
+	summary, err := h.Services.UserService.DeleteUser(c.Request.Context(), *authInfo, userID, dryRun)
 	if err != nil {
-		handleErrorResponse(c, err, "DeleteUser", http.StatusInternalServerError)
+		handleResponseLog(c, h.Log, "Error deleting user", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Success", http.StatusOK, "User deleted successfully")
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, summary)
 }
 
-// GetAllUsers retrieves all users with optional pagination and search
+// GetAllUsers retrieves all users with pagination. Admin only.
 // @Summary Retrieve all users
-// @Description Get a list of all users
-// @Tags Users
+// @Description Get a list of all users. Requires the users:read_all permission.
+// @Tags Admin
+// @Security ApiKeyAuth
 // @Produce json
-// @Param page query int false "Page number" default(1)
+// @Param after query string false "Cursor from next_cursor of the previous page"
+// @Param page query int false "Page number, ignored with after" default(1)
 // @Param limit query int false "Number of users per page" default(10)
-// @Param search query string false "Search term"
-// @Success 200 {array} models.User
+// @Success 200 {object} models.PagedResponse
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Router /users [get]
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Router /admin/users [get]
 func (h *Handler) GetAllUsers(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -161,27 +199,19 @@ func (h *Handler) GetAllUsers(c *gin.Context) {
 		return
 	}
 
-	page, err := ParsePageQueryParam(c)
+	page, err := ParsePaginationQueryParams(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "Error parsing page", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "Error parsing pagination", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	limit, err := ParseLimitQueryParam(c)
+	users, info, err := h.Services.UserService.ListUsers(c.Request.Context(), *authInfo, page)
 	if err != nil {
-		handleResponseLog(c, h.Log, "Error parsing limit", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "Error listing users", errorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	search := c.Query("search")
-
-	allUsers, err := h.Services.User().GetAllUsers(c.Request.Context(), search, page, limit)
-	if err != nil {
-		handleErrorResponse(c, err, "GetAllUsers", http.StatusInternalServerError)
-		return
-	}
-
-	handleResponseLog(c, h.Log, "Success", http.StatusOK, allUsers)
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, newPagedResponse(users, page, info))
 }
 
 // GetUserTaskLists retrieves all task lists for a user
@@ -190,14 +220,14 @@ func (h *Handler) GetAllUsers(c *gin.Context) {
 // @Tags Users
 // @Produce json
 // @Param id path string true "User ID"
-// @Param page query int false "Page number" default(1)
+// @Param after query string false "Cursor from next_cursor of the previous page"
+// @Param page query int false "Page number, ignored with after" default(1)
 // @Param limit query int false "Number of task lists per page" default(10)
-// @Param search query string false "Search term"
-// @Success 200 {array} models.TaskList
+// @Success 200 {object} models.PagedResponse
 // @Failure 400 {object} models.ErrorResponse "Invalid user ID format"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 404 {object} models.ErrorResponse "User not found"
-// @Router /users/{id}/tasklists [get]
+// @Router /user/{id}/task-lists [get]
 func (h *Handler) GetUserTaskLists(c *gin.Context) {
 	authInfo, err := getAuthInfo(c)
 	if err != nil {
@@ -211,36 +241,27 @@ func (h *Handler) GetUserTaskLists(c *gin.Context) {
 		return
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
 		handleResponseLog(c, h.Log, "Invalid user ID format", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	if authInfo.UserID != userID {
-		handleResponseLog(c, h.Log, "Unauthorized: cannot get another user's task lists", http.StatusUnauthorized, "")
+		handleResponseLog(c, h.Log, "User not found", http.StatusNotFound, models.ErrorResponse{Error: models.ErrUserNotFound.Error()})
 		return
 	}
 
-	page, err := ParsePageQueryParam(c)
+	page, err := ParsePaginationQueryParams(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "Error parsing page", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "Error parsing pagination", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	limit, err := ParseLimitQueryParam(c)
-	if err != nil {
-		handleResponseLog(c, h.Log, "Error parsing limit", http.StatusBadRequest, err.Error())
-		return
-	}
-
-	search := c.Query("search")
-
-	allTaskLists, err := h.Services.User().GetUserTaskLists(c.Request.Context(), objectID, search, page, limit)
+	allTaskLists, info, err := h.Services.TaskListService.ListTaskLists(c.Request.Context(), *authInfo, page)
 	if err != nil {
-		handleErrorResponse(c, err, "GetUserTaskLists", http.StatusInternalServerError)
+		handleErrorResponse(c, err, "GetUserTaskLists", errorStatus(err))
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Success", http.StatusOK, allTaskLists)
+	handleResponseLog(c, h.Log, "Success", http.StatusOK, newPagedResponse(allTaskLists, page, info))
 }
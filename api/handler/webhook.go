@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"net/http"
+	"todo/api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateWebhook godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks [POST]
+// @Summary		create webhook
+// @Description This api registers an endpoint the user's events are posted to, such as task.created, task.completed or task_list.deleted. Every delivery is signed: the X-Todo-Signature header is "sha256=" and the hex HMAC-SHA256, keyed with the webhook's secret, of the X-Todo-Timestamp header, a dot and the body. The secret is only returned here
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "User ID"
+// @Param		webhook body models.CreateWebhook true "Webhook"
+// @Success		201  {object}  models.Webhook
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.CreateWebhook{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	webhook, err := h.Services.WebhookService.CreateWebhook(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while creating webhook", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, webhook)
+}
+
+// GetWebhooks godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks [GET]
+// @Summary		get webhooks
+// @Description This api gets the user's webhooks, the oldest first
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id    path  string true  "User ID"
+// @Param		after query string false "Cursor from next_cursor of the previous page"
+// @Param		page  query int    false "Page Number, ignored with after"
+// @Param		limit query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	webhooks, info, err := h.Services.WebhookService.ListWebhooks(c.Request.Context(), *authInfo, c.Param("id"), page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting webhooks", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(webhooks, page, info))
+}
+
+// GetWebhook godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks/{webhook_id} [GET]
+// @Summary		get webhook
+// @Description This api gets one of the user's webhooks, without its secret
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id         path string true "User ID"
+// @Param		webhook_id path string true "Webhook ID"
+// @Success		200  {object}  models.Webhook
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetWebhook(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	webhook, err := h.Services.WebhookService.GetWebhook(c.Request.Context(), *authInfo, c.Param("id"), c.Param("webhook_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting webhook", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks/{webhook_id} [PUT]
+// @Summary		update webhook
+// @Description This api replaces the endpoint, events and description of a webhook. Setting active to false pauses it: no events are queued for it and its pending deliveries fail; leaving active out keeps it as it is. The secret is kept
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id         path string true "User ID"
+// @Param		webhook_id path string true "Webhook ID"
+// @Param		webhook body models.UpdateWebhook true "Webhook"
+// @Success		200  {object}  models.Webhook
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "invalid webhook id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := models.UpdateWebhook{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = webhookID
+
+	webhook, err := h.Services.WebhookService.UpdateWebhook(c.Request.Context(), *authInfo, c.Param("id"), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating webhook", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks/{webhook_id} [DELETE]
+// @Summary		delete webhook
+// @Description This api deletes a webhook with its delivery log. Deliveries not posted yet are dropped
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id         path string true "User ID"
+// @Param		webhook_id path string true "Webhook ID"
+// @Success		200  {object}  models.SuccessResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = h.Services.WebhookService.DeleteWebhook(c.Request.Context(), *authInfo, c.Param("id"), c.Param("webhook_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while deleting webhook", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "webhook was successfully deleted", http.StatusOK, models.SuccessResponse{Message: "webhook was successfully deleted"})
+}
+
+// GetWebhookDeliveries godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks/{webhook_id}/deliveries [GET]
+// @Summary		get webhook deliveries
+// @Description This api gets the delivery log of a webhook, the latest first: the event posted, its status (pending, succeeded or failed), the attempts made, what the endpoint answered last and when the next attempt is. Failed posts are retried with exponential backoff
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id         path  string true  "User ID"
+// @Param		webhook_id path  string true  "Webhook ID"
+// @Param		after      query string false "Cursor from next_cursor of the previous page"
+// @Param		page       query int    false "Page Number, ignored with after"
+// @Param		limit      query int    false "Limit"
+// @Success		200  {object}  models.PagedResponse
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := ParsePaginationQueryParams(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing pagination query params", http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	deliveries, info, err := h.Services.WebhookService.ListDeliveries(c.Request.Context(), *authInfo, c.Param("id"), c.Param("webhook_id"), page)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting webhook deliveries", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusOK, newPagedResponse(deliveries, page, info))
+}
+
+// RedeliverWebhook godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver [POST]
+// @Summary		redeliver webhook delivery
+// @Description This api queues the event of a delivery again, to be posted right away. The new delivery has the same body and event id, and its redelivery_of is the delivery repeated. Paused webhooks cannot redeliver
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id          path string true "User ID"
+// @Param		webhook_id  path string true "Webhook ID"
+// @Param		delivery_id path string true "Delivery ID"
+// @Success		201  {object}  models.WebhookDelivery
+// @Failure		400  {object}  models.ErrorResponse
+// @Failure		404  {object}  models.ErrorResponse
+// @Failure		500  {object}  models.ErrorResponse
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	delivery, err := h.Services.WebhookService.Redeliver(c.Request.Context(), *authInfo, c.Param("id"), c.Param("webhook_id"), c.Param("delivery_id"))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while redelivering webhook delivery", errorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Succes", http.StatusCreated, delivery)
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 type AuthInfo struct {
 	UserID string
 	Email  string
@@ -9,6 +15,9 @@ type AuthInfo struct {
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// DeviceID names the session being logged in. Without one the server
+	// makes one up and returns it.
+	DeviceID string `json:"device_id"`
 }
 
 type RegisterRequest struct {
@@ -18,8 +27,32 @@ type RegisterRequest struct {
 }
 
 type AuthResponse struct {
-	Token string  `json:"token"`
-	User  GetUser `json:"user"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	// DeviceID is the device the tokens belong to, to send along when
+	// refreshing them.
+	DeviceID string  `json:"device_id"`
+	User     GetUser `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	DeviceID     string `json:"device_id"`
+}
+
+// RefreshToken is the server-side record of an issued refresh token. Only the
+// SHA-256 hash of the token is stored. Every token minted by rotating another
+// one shares its FamilyID, so a reused token can revoke the whole chain.
+type RefreshToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	FamilyID  string             `json:"family_id" bson:"family_id"`
+	DeviceID  string             `json:"device_id" bson:"device_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	Used      bool               `json:"used" bson:"used"`
+	Revoked   bool               `json:"revoked" bson:"revoked"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at,omitempty"`
 }
 
 type ForgotPasswordRequest struct {
@@ -31,10 +64,35 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
+// PasswordReset is the server-side record of an emailed reset token. Only
+// the SHA-256 hash of the token is kept and it can be used once.
+type PasswordReset struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	Used      bool               `json:"used" bson:"used"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at,omitempty"`
+}
+
 type RegisterConfirm struct {
-	Email string     `json:"email"`
-	Otp   string     `json:"otp"`
-	User  CreateUser `json:"user"`
+	Email string `json:"email"`
+	Otp   string `json:"otp"`
+}
+
+// PendingRegistration holds a signup, or a user's change of email, until
+// its emailed OTP is confirmed. The password is already bcrypt hashed and the
+// OTP is stored hashed as well. UserID is only set for email changes.
+type PendingRegistration struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID       *primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Username     string              `json:"username" bson:"username"`
+	Email        string              `json:"email" bson:"email"`
+	PasswordHash string              `json:"-" bson:"password_hash"`
+	OtpHash      string              `json:"-" bson:"otp_hash"`
+	Attempts     int                 `json:"attempts" bson:"attempts"`
+	ExpiresAt    time.Time           `json:"expires_at" bson:"expires_at"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at,omitempty"`
 }
 
 type ChangePassword struct {
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxChecklistItems bounds the checklist of a task.
+const MaxChecklistItems = 100
+
+// ChecklistItem is a step of a task. A task keeps its items in the order the
+// user put them in.
+type ChecklistItem struct {
+	ID    primitive.ObjectID `json:"id" bson:"_id"`
+	Title string             `json:"title" bson:"title"`
+	Done  bool               `json:"done" bson:"done"`
+}
+
+type CreateChecklistItem struct {
+	Title string `json:"title" binding:"required"`
+}
+
+// UpdateChecklistItem changes the fields that are set, so toggling an item
+// only needs Done.
+type UpdateChecklistItem struct {
+	ID    primitive.ObjectID `json:"-"`
+	Title *string            `json:"title"`
+	Done  *bool              `json:"done"`
+}
+
+// ReorderChecklist lists every item of a checklist in its new order.
+type ReorderChecklist struct {
+	ItemIDs []primitive.ObjectID `json:"item_ids" binding:"required"`
+}
+
+// ReorderChecklistItems returns the items of a checklist in the order of itemIDs,
+// which must list every item exactly once.
+func ReorderChecklistItems(checklist []ChecklistItem, itemIDs []primitive.ObjectID) ([]ChecklistItem, error) {
+	byID := make(map[primitive.ObjectID]ChecklistItem, len(checklist))
+	for _, item := range checklist {
+		byID[item.ID] = item
+	}
+
+	reordered := make([]ChecklistItem, 0, len(checklist))
+	for _, id := range itemIDs {
+		item, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: the new order must list every checklist item once", ErrInvalidInput)
+		}
+		delete(byID, id)
+		reordered = append(reordered, item)
+	}
+	if len(byID) > 0 {
+		return nil, fmt.Errorf("%w: the new order must list every checklist item once", ErrInvalidInput)
+	}
+	return reordered, nil
+}
+
+// SubtaskCount counts the subtasks of a task.
+type SubtaskCount struct {
+	Total     int
+	Completed int
+}
+
+// TaskProgress reports how much of a task is done, such as "3/5 done".
+type TaskProgress struct {
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Summary string `json:"summary"`
+}
+
+// NewTaskProgress counts the done items of a checklist and the completed
+// subtasks. It returns nil when there is nothing to count.
+func NewTaskProgress(checklist []ChecklistItem, subtasks SubtaskCount) *TaskProgress {
+	progress := TaskProgress{Done: subtasks.Completed, Total: subtasks.Total + len(checklist)}
+	for _, item := range checklist {
+		if item.Done {
+			progress.Done++
+		}
+	}
+	if progress.Total == 0 {
+		return nil
+	}
+	progress.Summary = fmt.Sprintf("%d/%d done", progress.Done, progress.Total)
+	return &progress
+}
@@ -0,0 +1,15 @@
+package models
+
+// DeleteSummary reports how many documents a cascading delete removed, or
+// would remove when DryRun is set.
+type DeleteSummary struct {
+	DryRun         bool  `json:"dry_run"`
+	Users          int64 `json:"users"`
+	TaskLists      int64 `json:"task_lists"`
+	Tasks          int64 `json:"tasks"`
+	Labels         int64 `json:"labels"`
+	RefreshTokens  int64 `json:"refresh_tokens"`
+	PasswordResets int64 `json:"password_resets"`
+	SmartLists     int64 `json:"smart_lists"`
+	Reminders      int64 `json:"reminders"`
+}
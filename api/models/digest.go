@@ -0,0 +1,146 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How often a user gets the digest.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSettings tell when a user is emailed the digest of their tasks.
+// Users who never saved any get DefaultDigestSettings.
+type DigestSettings struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"_id"`
+	// Frequency is off, daily or weekly.
+	Frequency string `json:"frequency" bson:"frequency"`
+	// SendAt is the "15:04" clock time the digest is sent at.
+	SendAt string `json:"send_at" bson:"send_at"`
+	// Weekday is the day weekly digests are sent on, such as "monday".
+	Weekday string `json:"weekday" bson:"weekday"`
+	// Timezone is the IANA time zone SendAt is in.
+	Timezone string `json:"timezone" bson:"timezone"`
+	// NextAt is when the next digest is sent, nil while digests are off.
+	NextAt     *time.Time `json:"next_at,omitempty" bson:"next_at,omitempty"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty" bson:"last_sent_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty" bson:"updated_at"`
+}
+
+// DefaultDigestSettings sends no digest. Turned on, it comes daily at 7 in
+// the morning UTC, or on Mondays when weekly.
+func DefaultDigestSettings(userID primitive.ObjectID) DigestSettings {
+	return DigestSettings{UserID: userID, Frequency: DigestOff, SendAt: "07:00", Weekday: "monday", Timezone: "UTC"}
+}
+
+type UpdateDigestSettings struct {
+	UserID    primitive.ObjectID `json:"-"`
+	Frequency string             `json:"frequency" binding:"required"`
+	SendAt    string             `json:"send_at"`
+	Weekday   string             `json:"weekday"`
+	Timezone  string             `json:"timezone"`
+	// NextAt is worked out from the others when the settings are saved.
+	NextAt *time.Time `json:"-"`
+}
+
+// UnsubscribeRequest carries the token of the unsubscribe link in a digest.
+type UnsubscribeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DigestDelivery is the outcome of a digest that fell due.
+type DigestDelivery struct {
+	UserID primitive.ObjectID
+	// ScheduledAt is the NextAt the digest fell due at. The outcome is not
+	// recorded when the settings have been saved since.
+	ScheduledAt time.Time
+	// SentAt is nil when the digest was skipped or could not be sent.
+	SentAt *time.Time
+	// NextAt is when the digest is sent next.
+	NextAt time.Time
+}
+
+// Weekdays are the names Weekday accepts, in the order of time.Weekday.
+var Weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// Location returns the time zone of the settings, UTC when it is unknown.
+func (s DigestSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Next returns when the first digest after t is sent, or nil while digests
+// are off.
+func (s DigestSettings) Next(t time.Time) *time.Time {
+	if s.Frequency != DigestDaily && s.Frequency != DigestWeekly {
+		return nil
+	}
+	sendAt, err := time.Parse("15:04", s.SendAt)
+	if err != nil {
+		return nil
+	}
+	weekday := time.Monday
+	for i, name := range Weekdays {
+		if strings.EqualFold(name, s.Weekday) {
+			weekday = time.Weekday(i)
+		}
+	}
+
+	loc := s.Location()
+	local := t.In(loc)
+	for days := 0; days <= 7; days++ {
+		// Built from the date, so the clock time holds across DST changes
+		day := local.AddDate(0, 0, days)
+		next := time.Date(day.Year(), day.Month(), day.Day(), sendAt.Hour(), sendAt.Minute(), 0, 0, loc)
+		if !next.After(t) || (s.Frequency == DigestWeekly && next.Weekday() != weekday) {
+			continue
+		}
+		return &next
+	}
+	return nil
+}
+
+// Digest is the summary of a user's tasks sent by email. Tasks are grouped
+// by the list they are in; lists without any are left out.
+type Digest struct {
+	User      User
+	Frequency string
+	// Date is the local time the digest is made at.
+	Date  time.Time
+	Lists []DigestList
+	// UnsubscribeURL turns the digest off without signing in.
+	UnsubscribeURL string
+}
+
+// DigestList holds the tasks of one list in a Digest.
+type DigestList struct {
+	TaskList TaskList
+	// Overdue are open tasks that were due before the digest was made.
+	Overdue []Task
+	// Due are open tasks due later today, or within the week for weekly
+	// digests.
+	Due []Task
+	// Completed are tasks completed yesterday, or in the past week for
+	// weekly digests.
+	Completed []Task
+}
+
+// Empty reports whether the digest has no tasks at all.
+func (d Digest) Empty() bool {
+	return len(d.Lists) == 0
+}
+
+// DigestPreview is a digest rendered as it would be emailed.
+type DigestPreview struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
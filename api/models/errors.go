@@ -0,0 +1,33 @@
+package models
+
+import "errors"
+
+var (
+	ErrInvalidInput          = errors.New("invalid input")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrInvalidRefreshToken   = errors.New("invalid refresh token")
+	ErrRefreshTokenReused    = errors.New("refresh token has already been used")
+	ErrInvalidAccessToken    = errors.New("invalid access token")
+	ErrSessionEnded          = errors.New("session has ended, log in again")
+	ErrEmailTaken            = errors.New("email is already registered")
+	ErrRegistrationPending   = errors.New("a code was already sent to this email, confirm it or wait for it to expire")
+	ErrInvalidOTP            = errors.New("invalid or expired otp")
+	ErrTooManyOTPAttempts    = errors.New("too many otp attempts, register again")
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
+	ErrForbidden             = errors.New("forbidden")
+	ErrUserDisabled          = errors.New("user is disabled")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrTaskNotFound          = errors.New("task not found")
+	ErrTaskListNotFound      = errors.New("task list not found")
+	ErrLabelNotFound         = errors.New("label not found")
+	ErrInvalidLabel          = errors.New("label does not exist or belongs to another user")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrSmartListNotFound     = errors.New("smart list not found")
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	ErrReminderNotFound      = errors.New("reminder not found")
+	ErrNotificationFailed    = errors.New("notification could not be sent")
+	ErrDigestNotFound        = errors.New("digest not found")
+	ErrInvalidUnsubscribe    = errors.New("invalid or expired unsubscribe link")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+)
@@ -16,13 +16,13 @@ type Label struct {
 }
 
 type CreateLabel struct {
-	UserID primitive.ObjectID `json:"user_id"`
+	UserID primitive.ObjectID `json:"-"`
 	Name   string             `json:"name"`
 	Color  string             `json:"color"`
 }
 
 type UpdateLabel struct {
-	ID    primitive.ObjectID `json:"id"`
+	ID    primitive.ObjectID `json:"-"`
 	Name  string             `json:"name"`
 	Color string             `json:"color"`
 }
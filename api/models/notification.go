@@ -0,0 +1,138 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationSettings tell where a user's notifications go. Users who never
+// saved any get DefaultNotificationSettings.
+type NotificationSettings struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"_id"`
+	// Channels lists the enabled channels: email, telegram and webhook.
+	Channels []string `json:"channels" bson:"channels"`
+	// TelegramChatID is the chat the user linked to receive Telegram
+	// messages in.
+	TelegramChatID string    `json:"telegram_chat_id" bson:"telegram_chat_id"`
+	WebhookURL     string    `json:"webhook_url" bson:"webhook_url"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty" bson:"updated_at"`
+}
+
+// DefaultNotificationSettings sends a user's notifications by email.
+func DefaultNotificationSettings(userID primitive.ObjectID) NotificationSettings {
+	return NotificationSettings{UserID: userID, Channels: []string{"email"}}
+}
+
+type UpdateNotificationSettings struct {
+	UserID         primitive.ObjectID `json:"-"`
+	Channels       []string           `json:"channels" binding:"required"`
+	TelegramChatID string             `json:"telegram_chat_id"`
+	WebhookURL     string             `json:"webhook_url"`
+}
+
+// Events a user can be notified of.
+const (
+	EventReminder   = "reminder"
+	EventSharedList = "shared_list"
+	EventMention    = "mention"
+	EventDigest     = "digest"
+)
+
+// NotificationEvents lists every event.
+var NotificationEvents = []string{EventReminder, EventSharedList, EventMention, EventDigest}
+
+// NotificationPreferences tell which notifications a user wants over which
+// channels, and when they must not be disturbed. They are kept with the
+// NotificationSettings of the user.
+type NotificationPreferences struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"_id"`
+	// Events maps an event to the channels it is sent over, out of the ones
+	// the user enabled. Events left out are sent over all of them; an empty
+	// list turns the event off.
+	Events map[string][]string `json:"events" bson:"events"`
+	// Timezone is the IANA time zone the quiet hours are in.
+	Timezone string `json:"timezone" bson:"timezone"`
+	// QuietHours is nil when the user can be notified at any time.
+	QuietHours *QuietHours `json:"quiet_hours" bson:"quiet_hours"`
+	UpdatedAt  time.Time   `json:"updated_at,omitempty" bson:"updated_at"`
+}
+
+// QuietHours is a daily period, from Start until End, during which
+// notifications that can wait are held back. Both are "15:04" clock times;
+// a Start after End spans midnight.
+type QuietHours struct {
+	Start string `json:"start" bson:"start"`
+	End   string `json:"end" bson:"end"`
+}
+
+// DefaultNotificationPreferences sends every event over every enabled
+// channel, at any time.
+func DefaultNotificationPreferences(userID primitive.ObjectID) NotificationPreferences {
+	return NotificationPreferences{UserID: userID, Events: map[string][]string{}, Timezone: "UTC"}
+}
+
+type UpdateNotificationPreferences struct {
+	UserID     primitive.ObjectID  `json:"-"`
+	Events     map[string][]string `json:"events"`
+	Timezone   string              `json:"timezone"`
+	QuietHours *QuietHours         `json:"quiet_hours"`
+}
+
+// EventChannels returns the channels of enabled that event is sent over.
+func (p NotificationPreferences) EventChannels(event string, enabled []string) []string {
+	wanted, ok := p.Events[event]
+	if !ok {
+		return enabled
+	}
+	channels := []string{}
+	for _, channel := range enabled {
+		for _, w := range wanted {
+			if channel == w {
+				channels = append(channels, channel)
+				break
+			}
+		}
+	}
+	return channels
+}
+
+// QuietUntil reports whether t falls in the quiet hours, and if so when they
+// end.
+func (p NotificationPreferences) QuietUntil(t time.Time) (time.Time, bool) {
+	if p.QuietHours == nil {
+		return time.Time{}, false
+	}
+	start, err := time.Parse("15:04", p.QuietHours.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", p.QuietHours.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := t.In(loc)
+	clock := func(day time.Time, c time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), 0, 0, loc)
+	}
+	startToday, endToday := clock(local, start), clock(local, end)
+	switch {
+	case !startToday.After(endToday):
+		// Within a day, such as 13:00 to 15:00
+		if !local.Before(startToday) && local.Before(endToday) {
+			return endToday, true
+		}
+	case !local.Before(startToday):
+		// Past the start of quiet hours spanning midnight
+		return clock(local.AddDate(0, 0, 1), end), true
+	case local.Before(endToday):
+		// Before the end of the ones that began yesterday
+		return endToday, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cursor marks a position in a listing. Listings are sorted by their sort keys
+// and then by ID, so the sort key values and ID of the last item a client has
+// seen are enough to continue after it, even when items are added meanwhile.
+//
+// Listings in the default order only need CreatedAt. For task listings with
+// another order, Sort records that order and the remaining fields hold the
+// task's values for it.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+	Sort      string
+	UpdatedAt time.Time
+	DueDate   time.Time
+	Title     string
+	Completed bool
+}
+
+type cursorJSON struct {
+	CreatedAt time.Time          `json:"t"`
+	ID        primitive.ObjectID `json:"id"`
+	Sort      string             `json:"s,omitempty"`
+	UpdatedAt *time.Time         `json:"u,omitempty"`
+	DueDate   *time.Time         `json:"d,omitempty"`
+	Title     string             `json:"ti,omitempty"`
+	Completed bool               `json:"c,omitempty"`
+}
+
+// Encode returns the opaque form of the cursor handed out as next_cursor.
+func (c Cursor) Encode() string {
+	cj := cursorJSON{CreatedAt: c.CreatedAt, ID: c.ID, Sort: c.Sort, Title: c.Title, Completed: c.Completed}
+	if !c.UpdatedAt.IsZero() {
+		cj.UpdatedAt = &c.UpdatedAt
+	}
+	if !c.DueDate.IsZero() {
+		cj.DueDate = &c.DueDate
+	}
+
+	data, _ := json.Marshal(cj)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cj cursorJSON
+	if err := json.Unmarshal(data, &cj); err != nil || cj.ID.IsZero() {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	c := Cursor{CreatedAt: cj.CreatedAt, ID: cj.ID, Sort: cj.Sort, Title: cj.Title, Completed: cj.Completed}
+	if cj.UpdatedAt != nil {
+		c.UpdatedAt = *cj.UpdatedAt
+	}
+	if cj.DueDate != nil {
+		c.DueDate = *cj.DueDate
+	}
+	return c, nil
+}
+
+// Value returns the cursor's value for a sort field.
+func (c Cursor) Value(field string) interface{} {
+	switch field {
+	case SortUpdatedAt:
+		return c.UpdatedAt
+	case SortDueDate:
+		return c.DueDate
+	case SortTitle:
+		return c.Title
+	case SortCompleted:
+		return c.Completed
+	default:
+		return c.CreatedAt
+	}
+}
+
+// Pagination selects a slice of a listing. With After set only the items
+// following the cursor are considered; Offset then skips items the way page
+// numbers used to. A Limit of 0 means no limit.
+type Pagination struct {
+	Limit  uint64
+	Offset uint64
+	After  *Cursor
+}
+
+// PageInfo describes a page returned by a listing.
+type PageInfo struct {
+	TotalCount int64
+	// NextCursor continues after the page; it is empty on the last page.
+	NextCursor string
+}
+
+// Fields listings can be sorted by. Every listing supports SortCreatedAt;
+// the others only apply to tasks.
+const (
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortDueDate   = "due_date"
+	SortTitle     = "title"
+	SortCompleted = "completed"
+)
+
+// SortKey orders a listing by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort is the order of listings that do not ask for one. Ties are
+// always broken by ID.
+var DefaultSort = []SortKey{{Field: SortCreatedAt}}
+
+// ParseTaskSort parses a comma separated list of task fields, each prefixed
+// with "-" for descending order, such as "due_date,-created_at".
+func ParseTaskSort(s string) ([]SortKey, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	keys := []SortKey{}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		key := SortKey{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field, key.Desc = key.Field[1:], true
+		}
+
+		switch key.Field {
+		case SortCreatedAt, SortUpdatedAt, SortDueDate, SortTitle, SortCompleted:
+		default:
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidInput, key.Field)
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("%w: %q is sorted by twice", ErrInvalidInput, key.Field)
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// FormatSort is the inverse of ParseTaskSort.
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Desc {
+			parts = append(parts, "-"+key.Field)
+		} else {
+			parts = append(parts, key.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Cursor returns the position right after the user in a listing.
+func (u User) Cursor() Cursor { return Cursor{CreatedAt: u.CreatedAt, ID: u.ID} }
+
+// Cursor returns the position right after the task list in a listing.
+func (tl TaskList) Cursor() Cursor { return Cursor{CreatedAt: tl.CreatedAt, ID: tl.ID} }
+
+// Cursor returns the position right after the task in a listing in the
+// default order.
+func (t Task) Cursor() Cursor { return Cursor{CreatedAt: t.CreatedAt, ID: t.ID} }
+
+// SortCursor returns the position right after the task in a listing sorted
+// by keys. Only the values the order needs are kept, so cursors stay short.
+func (t Task) SortCursor(keys []SortKey) Cursor {
+	c := t.Cursor()
+	c.Sort = FormatSort(keys)
+	for _, key := range keys {
+		switch key.Field {
+		case SortUpdatedAt:
+			c.UpdatedAt = t.UpdatedAt
+		case SortDueDate:
+			c.DueDate = t.DueDate
+		case SortTitle:
+			c.Title = t.Title
+		case SortCompleted:
+			c.Completed = t.Completed
+		}
+	}
+	return c
+}
+
+// Cursor returns the position right after the label in a listing.
+func (l Label) Cursor() Cursor { return Cursor{CreatedAt: l.CreatedAt, ID: l.ID} }
+
+// Cursor returns the position right after the smart list in a listing.
+func (sl SmartList) Cursor() Cursor { return Cursor{CreatedAt: sl.CreatedAt, ID: sl.ID} }
+
+// Cursor returns the position right after the webhook in a listing.
+func (w Webhook) Cursor() Cursor { return Cursor{CreatedAt: w.CreatedAt, ID: w.ID} }
+
+// Cursor returns the position right after the delivery in the delivery log.
+func (d WebhookDelivery) Cursor() Cursor { return Cursor{CreatedAt: d.CreatedAt, ID: d.ID} }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Recurrence makes a task repeat. Completing the task creates the next
+// occurrence, due at the first time the rule produces after its due date,
+// and the series carries on from there.
+type Recurrence struct {
+	// Rule is an RFC 5545 RRULE value, such as FREQ=WEEKLY;BYDAY=MO,WE.
+	Rule string `json:"rule" bson:"rule"`
+	// Timezone is the IANA time zone the rule is expanded in; empty is UTC.
+	Timezone string `json:"timezone" bson:"timezone"`
+	// Start is the due date of the first occurrence of the series, which
+	// COUNT and INTERVAL count from. It is set when the rule is.
+	Start time.Time `json:"start" bson:"start"`
+}
+
+// SetRecurrence is the body that makes a task repeat from its due date.
+type SetRecurrence struct {
+	Rule     string `json:"rule" binding:"required"`
+	Timezone string `json:"timezone"`
+}
+
+// Occurrences previews the next due dates of a recurring task.
+type Occurrences struct {
+	Occurrences []time.Time `json:"occurrences"`
+}
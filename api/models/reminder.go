@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Delivery states of a reminder.
+const (
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	// ReminderFailed is set once every attempt to send a reminder failed.
+	ReminderFailed = "failed"
+	// ReminderSkipped is set on reminders that fell due after their task was
+	// completed or its due date had passed.
+	ReminderSkipped = "skipped"
+)
+
+// Reminder notifies the owner of a task some time before the task is due.
+type Reminder struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id"`
+	TaskID primitive.ObjectID `json:"task_id" bson:"task_id"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// Before is how many minutes before the due date the reminder fires.
+	Before int `json:"before_minutes" bson:"before_minutes"`
+	// RemindAt is when the reminder fires. It is nil while the task has no
+	// due date.
+	RemindAt  *time.Time `json:"remind_at,omitempty" bson:"remind_at,omitempty"`
+	Status    string     `json:"status" bson:"status"`
+	Attempts  int        `json:"attempts" bson:"attempts"`
+	LastError string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// SetReminders is the body of the set reminders endpoint. An empty list
+// removes the reminders of the task.
+type SetReminders struct {
+	// Before lists how many minutes before the due date to remind, such as
+	// 1440 for a day and 60 for an hour.
+	Before []int `json:"before_minutes" binding:"required"`
+}
+
+// ReminderDelivery is the outcome of an attempt to send a reminder.
+type ReminderDelivery struct {
+	ID primitive.ObjectID
+	// RemindAt is the time the reminder was due at. A reminder that has been
+	// moved since is left as it is.
+	RemindAt  time.Time
+	Status    string
+	Attempts  int
+	LastError string
+	SentAt    *time.Time
+	// DeferredTo, when set, moves the reminder to fall due again then, such
+	// as once the user's quiet hours are over.
+	DeferredTo *time.Time
+}
+
+// ReminderTime returns when a reminder firing before minutes before dueDate
+// is due, or nil when there is no due date.
+func ReminderTime(dueDate time.Time, before int) *time.Time {
+	if dueDate.IsZero() {
+		return nil
+	}
+	at := dueDate.Add(-time.Duration(before) * time.Minute)
+	return &at
+}
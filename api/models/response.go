@@ -14,4 +14,5 @@ type PagedResponse struct {
     TotalCount int64       `json:"total_count"`
     Page       int64       `json:"page"`
     Limit      int64       `json:"limit"`
+    NextCursor string      `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of items a search returns.
+const (
+	SearchTypeTask     = "task"
+	SearchTypeTaskList = "task_list"
+	SearchTypeLabel    = "label"
+)
+
+// SearchTypes lists every kind of item, in the order results of equal score
+// are returned.
+var SearchTypes = []string{SearchTypeTask, SearchTypeTaskList, SearchTypeLabel}
+
+// MaxSearchTerms bounds how many words of a search are used.
+const MaxSearchTerms = 10
+
+// SearchQuery asks a storage for the items of a user matching any of Terms.
+// Titles weigh more than descriptions. Each type returns at most Limit items.
+type SearchQuery struct {
+	Terms []string
+	Types []string
+	Limit int64
+}
+
+// SearchResult is a task, task list or label matching a search. Scores rank
+// the results of one search, higher first; they mean nothing across searches
+// or storage backends.
+type SearchResult struct {
+	Type       string              `json:"type"`
+	ID         primitive.ObjectID  `json:"id"`
+	TaskListID *primitive.ObjectID `json:"task_list_id,omitempty"`
+	Title      string              `json:"title"`
+	// Snippet is the part of the item that matched, HTML-escaped, with the
+	// search terms wrapped in <mark>.
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+	// Body is the searched text besides the title, the snippet is cut from it.
+	Body string `json:"-"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchWords splits text into lowercase words of letters and digits.
+// Everything else separates words.
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchTerms returns the distinct words of a search, at most
+// MaxSearchTerms of them. Since only letters and digits are kept, no search
+// operator of any backend survives.
+func SearchTerms(search string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range SearchWords(search) {
+		if !seen[word] && len(terms) < MaxSearchTerms {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
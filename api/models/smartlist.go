@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SmartList is a saved task query, evaluated over all of its owner's task
+// lists whenever it is opened.
+type SmartList struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name      string             `json:"name" bson:"name"`
+	Query     string             `json:"query" bson:"query"`
+	Sort      string             `json:"sort" bson:"sort"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at,omitempty"`
+}
+
+type CreateSmartList struct {
+	UserID primitive.ObjectID `json:"-"`
+	Name   string             `json:"name" binding:"required"`
+	Query  string             `json:"query" binding:"required"`
+	Sort   string             `json:"sort"`
+}
+
+type UpdateSmartList struct {
+	ID    primitive.ObjectID `json:"-"`
+	Name  string             `json:"name" binding:"required"`
+	Query string             `json:"query" binding:"required"`
+	Sort  string             `json:"sort"`
+}
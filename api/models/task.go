@@ -1,42 +1,121 @@
 package models
 
 import (
-    "time"
+	"time"
+	"todo/pkg/taskquery"
 
-    "go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Task struct {
-    ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-    TaskListID  primitive.ObjectID `json:"task_list_id" bson:"task_list_id"`
-    Title       string             `json:"title" bson:"title"`
-    Description string             `json:"description" bson:"description"`
-    DueDate     time.Time          `json:"due_date" bson:"due_date"`
-    Completed   bool               `json:"completed" bson:"completed"`
-    CreatedAt   time.Time          `json:"created_at" bson:"created_at,omitempty"`
-    UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at,omitempty"`
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TaskListID primitive.ObjectID `json:"task_list_id" bson:"task_list_id"`
+	// ParentID is set on subtasks. Subtasks live in the list of their parent
+	// and cannot have subtasks of their own.
+	ParentID    *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Title       string              `json:"title" bson:"title"`
+	Description string              `json:"description" bson:"description"`
+	DueDate     time.Time           `json:"due_date" bson:"due_date"`
+	Recurrence  *Recurrence         `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	Completed   bool                `json:"completed" bson:"completed"`
+	// CompletedAt is when the task was last completed, nil while it is open.
+	CompletedAt *time.Time           `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	LabelIDs    []primitive.ObjectID `json:"label_ids" bson:"label_ids"`
+	Checklist   []ChecklistItem      `json:"checklist" bson:"checklist"`
+	// Progress counts the done checklist items and completed subtasks. It is
+	// filled in when a task is read and left out when there are neither.
+	Progress  *TaskProgress `json:"progress,omitempty" bson:"-"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at" bson:"updated_at,omitempty"`
 }
 
 type CreateTask struct {
-    TaskListID  primitive.ObjectID `json:"task_list_id"`
-    Title       string             `json:"title"`
-    Description string             `json:"description"`
-    DueDate     time.Time          `json:"due_date"`
+	TaskListID primitive.ObjectID `json:"task_list_id"`
+	// ParentID makes the task a subtask; TaskListID may then be left out.
+	ParentID    *primitive.ObjectID  `json:"parent_id"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	DueDate     time.Time            `json:"due_date"`
+	LabelIDs    []primitive.ObjectID `json:"label_ids"`
+	// Recurrence makes the task repeat; its Start is set from DueDate.
+	Recurrence *Recurrence `json:"recurrence"`
 }
 
 type UpdateTask struct {
-    ID          primitive.ObjectID `json:"id"`
-    Title       string             `json:"title"`
-    Description string             `json:"description"`
-    DueDate     time.Time          `json:"due_date"`
-    Completed   bool               `json:"completed"`
+	ID          primitive.ObjectID `json:"-"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	DueDate     time.Time          `json:"due_date"`
+	Completed   bool               `json:"completed"`
+	// CompleteSubtasks completes every subtask when the task is completed.
+	CompleteSubtasks bool `json:"complete_subtasks"`
+}
+
+// TaskLabels is the body of the add/remove task labels endpoints.
+type TaskLabels struct {
+	LabelIDs []primitive.ObjectID `json:"label_ids" binding:"required"`
+}
+
+const (
+	// LabelMatchAny matches tasks that have at least one of the labels.
+	LabelMatchAny = "any"
+	// LabelMatchAll matches tasks that have every one of the labels.
+	LabelMatchAll = "all"
+)
+
+// LabelFilter narrows a task listing down to tasks carrying the given labels.
+// An empty LabelIDs disables the filter.
+type LabelFilter struct {
+	LabelIDs []primitive.ObjectID
+	Match    string
+}
+
+// TaskFilter narrows a task listing down. Zero fields do not filter, and
+// every set field must match. Time ranges are exclusive, and due date ranges
+// leave out tasks without a due date.
+type TaskFilter struct {
+	// TaskListIDs are the lists to list tasks from. It must not be empty.
+	TaskListIDs []primitive.ObjectID
+	// Search matches the title case-insensitively.
+	Search    string
+	Labels    LabelFilter
+	Completed *bool
+	// Overdue keeps tasks that are not completed and were due before now.
+	Overdue bool
+	// NoDueDate keeps tasks without a due date.
+	NoDueDate     bool
+	DueBefore     *time.Time
+	DueAfter      *time.Time
+	CreatedBefore *time.Time
+	CreatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	// CompletedBefore and CompletedAfter keep tasks completed in a period.
+	CompletedBefore *time.Time
+	CompletedAfter  *time.Time
+	// ParentID keeps the subtasks of a task.
+	ParentID *primitive.ObjectID
+	// Query is a parsed smart list query whose labels and lists have been
+	// resolved to IDs.
+	Query taskquery.Expr
+	// Sort orders the listing; empty means DefaultSort.
+	Sort []SortKey
+}
+
+// SortKeys returns the order of the listing.
+func (f TaskFilter) SortKeys() []SortKey {
+	if len(f.Sort) == 0 {
+		return DefaultSort
+	}
+	return f.Sort
 }
 
 type GetTask struct {
-    ID          primitive.ObjectID `json:"id"`
-    TaskListID  primitive.ObjectID `json:"task_list_id"`
-    Title       string             `json:"title"`
-    Description string             `json:"description"`
-    DueDate     time.Time          `json:"due_date"`
-    Completed   bool               `json:"completed"`
+	ID          primitive.ObjectID   `json:"id"`
+	TaskListID  primitive.ObjectID   `json:"task_list_id"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	DueDate     time.Time            `json:"due_date"`
+	Completed   bool                 `json:"completed"`
+	LabelIDs    []primitive.ObjectID `json:"label_ids"`
 }
@@ -16,13 +16,13 @@ type TaskList struct {
 }
 
 type CreateTaskList struct {
-	UserID      primitive.ObjectID `json:"user_id"`
+	UserID      primitive.ObjectID `json:"-"`
 	Title       string             `json:"title"`
 	Description string             `json:"description"`
 }
 
 type UpdateTaskList struct {
-	ID          primitive.ObjectID `json:"id"`
+	ID          primitive.ObjectID `json:"-"`
 	Title       string             `json:"title"`
 	Description string             `json:"description"`
 }
@@ -7,24 +7,29 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"` // MongoDB ObjectID
-	Username  string             `json:"username" bson:"username"`
-	Email     string             `json:"email" bson:"email"`
-	Password  string             `json:"password" bson:"password"`
-	CreatedAt time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"` // MongoDB ObjectID
+	Username     string             `json:"username" bson:"username"`
+	Email        string             `json:"email" bson:"email"`
+	Password     string             `json:"-" bson:"password"`
+	Role         string             `json:"role" bson:"role"`
+	Disabled     bool               `json:"disabled" bson:"disabled"`
+	TokenVersion int                `json:"-" bson:"token_version"` // Carried by access tokens, bumped to end them all
+	CreatedAt    time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 type CreateUser struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Role     string `json:"-"`
 }
 
 type GetUser struct {
 	ID       primitive.ObjectID `json:"id"` // Returning the ID as an ObjectID to maintain consistency
 	Username string             `json:"username"`
 	Email    string             `json:"email"`
+	Role     string             `json:"role"`
 }
 
 type UpdateUser struct {
@@ -33,6 +38,21 @@ type UpdateUser struct {
 	Email    string             `json:"email"`
 }
 
+// ConfirmEmailChange confirms the new email of a user with the OTP emailed
+// to it.
+type ConfirmEmailChange struct {
+	Email string `json:"email"`
+	Otp   string `json:"otp"`
+}
+
+type UpdateUserRole struct {
+	Role string `json:"role"`
+}
+
+type DisableUser struct {
+	Disabled bool `json:"disabled"`
+}
+
 type GetAllUsersRequest struct {
 	Search string `json:"search"`
 	Page   int64  `json:"page"`
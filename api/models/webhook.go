@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Events a webhook can subscribe to.
+const (
+	WebhookTaskCreated   = "task.created"
+	WebhookTaskUpdated   = "task.updated"
+	WebhookTaskCompleted = "task.completed"
+	WebhookTaskDeleted   = "task.deleted"
+
+	WebhookTaskListCreated = "task_list.created"
+	WebhookTaskListUpdated = "task_list.updated"
+	WebhookTaskListDeleted = "task_list.deleted"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{
+	WebhookTaskCreated, WebhookTaskUpdated, WebhookTaskCompleted, WebhookTaskDeleted,
+	WebhookTaskListCreated, WebhookTaskListUpdated, WebhookTaskListDeleted,
+}
+
+// Delivery states of a webhook delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	// DeliveryFailed is set once every attempt to post a delivery failed, or
+	// when its webhook was paused.
+	DeliveryFailed = "failed"
+)
+
+// Webhook is an endpoint of a user that the events they subscribed to are
+// posted to.
+type Webhook struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	URL         string             `json:"url" bson:"url"`
+	Events      []string           `json:"events" bson:"events"`
+	Description string             `json:"description" bson:"description"`
+	// Secret signs the deliveries. It is only shown when the webhook is
+	// created.
+	Secret string `json:"secret,omitempty" bson:"secret"`
+	// Active is false while the webhook is paused; no events are queued for
+	// it then.
+	Active    bool      `json:"active" bson:"active"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CreateWebhook is the body of the create webhook endpoint.
+type CreateWebhook struct {
+	UserID      primitive.ObjectID `json:"-"`
+	URL         string             `json:"url" binding:"required"`
+	Events      []string           `json:"events" binding:"required"`
+	Description string             `json:"description"`
+	Secret      string             `json:"-"`
+}
+
+// UpdateWebhook is the body of the update webhook endpoint. Leaving Active
+// out keeps the webhook as active or paused as it was.
+type UpdateWebhook struct {
+	ID          primitive.ObjectID `json:"-"`
+	URL         string             `json:"url" binding:"required"`
+	Events      []string           `json:"events" binding:"required"`
+	Description string             `json:"description"`
+	Active      *bool              `json:"active"`
+}
+
+// WebhookEvent is the body posted to a webhook.
+type WebhookEvent struct {
+	// ID identifies the event. Redeliveries keep it, so receivers can drop
+	// the ones they have seen.
+	ID        primitive.ObjectID `json:"id"`
+	Event     string             `json:"event"`
+	CreatedAt time.Time          `json:"created_at"`
+	// Data is the task or task list the event is about, as it was after
+	// the change, or before it for deletions.
+	Data any `json:"data"`
+}
+
+// WebhookDelivery is an event queued for, or posted to, a webhook.
+type WebhookDelivery struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	WebhookID primitive.ObjectID `json:"webhook_id" bson:"webhook_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	EventID   primitive.ObjectID `json:"event_id" bson:"event_id"`
+	Event     string             `json:"event" bson:"event"`
+	// Payload is the WebhookEvent posted, kept as sent so redeliveries
+	// post the same body.
+	Payload json.RawMessage `json:"payload" bson:"payload" swaggertype:"object"`
+	Status  string          `json:"status" bson:"status"`
+	// Attempts counts the posts made so far.
+	Attempts int `json:"attempts" bson:"attempts"`
+	// NextAttemptAt is when the delivery is posted next. It is nil once it
+	// succeeded or failed.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	// ResponseStatus and ResponseBody are what the endpoint answered last,
+	// the body cut short.
+	ResponseStatus int    `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ResponseBody   string `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	// RedeliveryOf is the delivery this one repeats, for manual redeliveries.
+	RedeliveryOf *primitive.ObjectID `json:"redelivery_of,omitempty" bson:"redelivery_of,omitempty"`
+	DeliveredAt  *time.Time          `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// EnqueueDelivery queues an event for a webhook.
+type EnqueueDelivery struct {
+	WebhookID     primitive.ObjectID
+	UserID        primitive.ObjectID
+	EventID       primitive.ObjectID
+	Event         string
+	Payload       json.RawMessage
+	RedeliveryOf  *primitive.ObjectID
+	NextAttemptAt time.Time
+}
+
+// WebhookAttempt is the outcome of posting a delivery.
+type WebhookAttempt struct {
+	ID primitive.ObjectID
+	// ScheduledAt is the time the delivery was due at. A delivery that has
+	// been finished or moved since is left as it is.
+	ScheduledAt    time.Time
+	Status         string
+	Attempts       int
+	LastError      string
+	ResponseStatus int
+	ResponseBody   string
+	DeliveredAt    *time.Time
+	// NextAttemptAt is when a delivery still pending is tried again.
+	NextAttemptAt *time.Time
+}
+
+// DeliverySort is the order of the delivery log: the latest first.
+var DeliverySort = []SortKey{{Field: SortCreatedAt, Desc: true}}
@@ -3,9 +3,12 @@ package api
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"todo/api/handler"
-	"todo/pkg/logger"
+	"todo/api/models"
+	"todo/pkg/rbac"
 	"todo/service"
 
 	"github.com/gin-gonic/gin"
@@ -18,21 +21,23 @@ import (
 // Server structure
 type Server struct {
 	Router  *gin.Engine
-	Handler handler.Handler
-	Log     logger.ILogger
+	Handler *handler.Handler
+	Log     *log.Logger
 }
 
 // New initializes a new API server
 // @title           Swagger Example API
 // @version         1.0
 // @description     This is a sample server for a todo application.
+// @BasePath        /api
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
-func New(services *service.Service, log logger.ILogger) Server {
+func New(services *service.Service, log *log.Logger) Server {
 	router := gin.Default()
 
 	h := handler.NewHandler(services, log)
+	authMiddleware := newAuthMiddleware(services.AuthService)
 
 	// Swagger documentation route
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -41,38 +46,79 @@ func New(services *service.Service, log logger.ILogger) Server {
 	{
 		authGroup := apiGroup.Group("/auth")
 		{
-			authGroup.POST("/login", h.LoginUser)
+			authGroup.POST("/login", h.Login)
+			authGroup.POST("/refresh", h.Refresh)
 			authGroup.POST("/register", h.UserRegister)
 			authGroup.POST("/register-confirm", h.UserRegisterConfirm)
+			authGroup.POST("/forgot-password", h.ForgotPassword)
+			authGroup.POST("/reset-password", h.ResetPassword)
+			authGroup.POST("/unsubscribe", h.Unsubscribe)
 			authGroup.PATCH("/user", h.ChangePasswordUser)
 		}
-		userGroup := apiGroup.Group("/user")
+		userGroup := apiGroup.Group("/user", authMiddleware)
 		{
 			userGroup.GET("/:id", h.GetUser)
 			userGroup.PATCH("/:id", h.UpdateUser)
+			userGroup.POST("/:id/email-confirm", h.ConfirmEmailChange)
 			userGroup.DELETE("/:id", h.DeleteUser)
-			userGroup.GET("", h.GetAllUsers)
 			userGroup.GET("/:id/task-lists", h.GetUserTaskLists)
+			userGroup.GET("/:id/notifications", h.GetNotificationSettings)
+			userGroup.PUT("/:id/notifications", h.UpdateNotificationSettings)
+			userGroup.POST("/:id/notifications/test", h.SendTestNotification)
+			userGroup.GET("/:id/notifications/preferences", h.GetNotificationPreferences)
+			userGroup.PUT("/:id/notifications/preferences", h.UpdateNotificationPreferences)
+			userGroup.GET("/:id/notifications/digest", h.GetDigestSettings)
+			userGroup.PUT("/:id/notifications/digest", h.UpdateDigestSettings)
+			userGroup.GET("/:id/notifications/digest/preview", h.PreviewDigest)
+			userGroup.POST("/:id/webhooks", h.CreateWebhook)
+			userGroup.GET("/:id/webhooks", h.GetWebhooks)
+			userGroup.GET("/:id/webhooks/:webhook_id", h.GetWebhook)
+			userGroup.PUT("/:id/webhooks/:webhook_id", h.UpdateWebhook)
+			userGroup.DELETE("/:id/webhooks/:webhook_id", h.DeleteWebhook)
+			userGroup.GET("/:id/webhooks/:webhook_id/deliveries", h.GetWebhookDeliveries)
+			userGroup.POST("/:id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
 		}
 
-		taskGroup := apiGroup.Group("/task")
+		adminGroup := apiGroup.Group("/admin", authMiddleware)
+		{
+			adminGroup.GET("/users", requirePermission(rbac.UsersReadAll), h.GetAllUsers)
+			adminGroup.PATCH("/users/:id/disable", requirePermission(rbac.UsersDisable), h.DisableUser)
+			adminGroup.PATCH("/users/:id/role", requirePermission(rbac.UsersUpdateRole), h.UpdateUserRole)
+		}
+
+		taskGroup := apiGroup.Group("/task", authMiddleware)
 		{
 			taskGroup.POST("", h.CreateTask)
+			taskGroup.GET("", h.GetAllTasks)
 			taskGroup.GET("/:id", h.GetTask)
 			taskGroup.PATCH("/:id", h.UpdateTask)
 			taskGroup.DELETE("/:id", h.DeleteTask)
+			taskGroup.POST("/:id/labels", h.AddTaskLabels)
+			taskGroup.DELETE("/:id/labels/:label_id", h.RemoveTaskLabel)
+			taskGroup.GET("/:id/subtasks", h.GetSubtasks)
+			taskGroup.POST("/:id/checklist", h.AddChecklistItem)
+			taskGroup.PUT("/:id/checklist", h.ReorderChecklist)
+			taskGroup.PATCH("/:id/checklist/:item_id", h.UpdateChecklistItem)
+			taskGroup.DELETE("/:id/checklist/:item_id", h.DeleteChecklistItem)
+			taskGroup.PUT("/:id/recurrence", h.SetRecurrence)
+			taskGroup.DELETE("/:id/recurrence", h.EndRecurrence)
+			taskGroup.POST("/:id/recurrence/skip", h.SkipOccurrence)
+			taskGroup.GET("/:id/recurrence/occurrences", h.GetOccurrences)
+			taskGroup.GET("/:id/reminders", h.GetReminders)
+			taskGroup.PUT("/:id/reminders", h.SetReminders)
 		}
 
-		taskListGroup := apiGroup.Group("/task-list")
+		taskListGroup := apiGroup.Group("/task-list", authMiddleware)
 		{
 			taskListGroup.POST("", h.CreateTaskList)
 			taskListGroup.GET("/:id", h.GetTaskList)
 			taskListGroup.PATCH("/:id", h.UpdateTaskList)
 			taskListGroup.DELETE("/:id", h.DeleteTaskList)
 			taskListGroup.GET("", h.GetAllTaskLists)
+			taskListGroup.GET("/:id/tasks", h.GetTaskListTasks)
 		}
 
-		labelGroup := apiGroup.Group("/label")
+		labelGroup := apiGroup.Group("/label", authMiddleware)
 		{
 			labelGroup.POST("", h.CreateLabel)
 			labelGroup.GET("/:id", h.GetLabel)
@@ -80,6 +126,18 @@ func New(services *service.Service, log logger.ILogger) Server {
 			labelGroup.DELETE("/:id", h.DeleteLabel)
 			labelGroup.GET("", h.GetAllLabels)
 		}
+
+		apiGroup.GET("/search", authMiddleware, h.Search)
+
+		smartListGroup := apiGroup.Group("/smart-list", authMiddleware)
+		{
+			smartListGroup.POST("", h.CreateSmartList)
+			smartListGroup.GET("/:id", h.GetSmartList)
+			smartListGroup.PATCH("/:id", h.UpdateSmartList)
+			smartListGroup.DELETE("/:id", h.DeleteSmartList)
+			smartListGroup.GET("", h.GetAllSmartLists)
+			smartListGroup.GET("/:id/tasks", h.GetSmartListTasks)
+		}
 	}
 
 	return Server{
@@ -94,14 +152,58 @@ func (s *Server) Run(addr string) {
 	s.Router.Run(addr)
 }
 
-// authMiddleware handles authentication checks
-func authMiddleware(c *gin.Context) {
-	auth := c.GetHeader("Authorization")
-	if auth == "" {
-		c.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized"))
-		return
+// newAuthMiddleware returns a middleware that verifies the Bearer token and
+// stores the caller's models.AuthInfo in the context under "authInfo".
+func newAuthMiddleware(auth service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || tokenStr == "" {
+			abortUnauthorized(c, errors.New("missing bearer token"))
+			return
+		}
+
+		authInfo, err := auth.Authenticate(c.Request.Context(), tokenStr)
+		switch {
+		case errors.Is(err, models.ErrInvalidAccessToken), errors.Is(err, models.ErrSessionEnded), errors.Is(err, models.ErrUserDisabled):
+			abortUnauthorized(c, err)
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		c.Set("authInfo", &authInfo)
+		c.Next()
+	}
+}
+
+// requirePermission only lets requests through when the caller's role grants
+// permission. It must run after authMiddleware.
+func requirePermission(permission rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authInfo, exists := c.Get("authInfo")
+		if !exists {
+			abortUnauthorized(c, errors.New("authentication information not found"))
+			return
+		}
+
+		if !rbac.HasPermission(authInfo.(*models.AuthInfo).Role, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "forbidden",
+				Message: "missing permission " + string(permission),
+			})
+			return
+		}
+		c.Next()
 	}
-	c.Next()
+}
+
+// abortUnauthorized stops the request with a 401 response
+func abortUnauthorized(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+		Error:   "unauthorized",
+		Message: err.Error(),
+	})
 }
 
 // logMiddleware logs request headers
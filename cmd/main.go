@@ -11,8 +11,16 @@ import (
 	"time"
 	"todo/api"
 	"todo/config"
+	"todo/pkg/logger"
+	"todo/pkg/notify"
+	"todo/pkg/smtp"
+	"todo/pkg/webhook"
 	"todo/service"
 	"todo/storage"
+	"todo/storage/memory"
+	"todo/storage/mongodb"
+	"todo/storage/postgres"
+	"todo/storage/sqlite"
 )
 
 func main() {
@@ -21,25 +29,27 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not load config: %v", err)
 	}
+	if err := config.LoadSignedKey(cfg); err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
 
-	// Initialize storage (MongoDB connection)
-	store, err := storage.NewStorage(cfg.DBUri)
+	// Initialize storage
+	store, err := newStorage(cfg)
 	if err != nil {
 		log.Fatalf("could not connect to database: %v", err)
 	}
 
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("could not set up email: %v", err)
+	}
+
 	// Initialize services
-	userService := service.NewUserService(store.User())
-	taskService := service.NewTaskService(store.Task())
-	taskListService := service.NewTaskListService(store.TaskList())
-	labelService := service.NewLabelService(store.Label())
-
-	// Create a new Service struct as a pointer
-	services := &service.Service{
-		UserService:     userService,
-		TaskService:     taskService,
-		TaskListService: taskListService,
-		LabelService:    labelService,
+	notifier := newNotifier(cfg, mailer)
+	services := service.NewService(store, notifier, mailer)
+
+	if err := services.UserService.BootstrapAdmin(context.Background(), config.AdminEmail); err != nil {
+		log.Fatalf("could not bootstrap admin user: %v", err)
 	}
 
 	r := api.New(services, log.Default())
@@ -47,13 +57,33 @@ func main() {
 	// Create a new server
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
-		Handler: r,
+		Handler: r.Router,
 	}
 
 	// Channel to listen for interrupt signals
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Send task reminders, digests and webhook deliveries in the background
+	// until the server stops
+	scheduler := service.NewReminderScheduler(store, notifier, cfg.ReminderInterval, logger.New("reminders"))
+	digestScheduler := service.NewDigestScheduler(store, notifier, cfg.DigestInterval, logger.New("digests"))
+	webhookScheduler := service.NewWebhookScheduler(store, webhook.NewClient(10*time.Second), cfg.WebhookInterval, logger.New("webhooks"))
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone, digestSchedulerDone, webhookSchedulerDone := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(schedulerCtx)
+	}()
+	go func() {
+		defer close(digestSchedulerDone)
+		digestScheduler.Run(schedulerCtx)
+	}()
+	go func() {
+		defer close(webhookSchedulerDone)
+		webhookScheduler.Run(schedulerCtx)
+	}()
+
 	// Run server in a goroutine
 	go func() {
 		fmt.Printf("Server is running on port %s\n", cfg.Port)
@@ -74,5 +104,119 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Let the schedulers record the reminder, digest or delivery they may be
+	// sending
+	stopScheduler()
+	select {
+	case <-schedulerDone:
+	case <-ctx.Done():
+		log.Println("Reminder scheduler did not stop in time")
+	}
+	select {
+	case <-digestSchedulerDone:
+	case <-ctx.Done():
+		log.Println("Digest scheduler did not stop in time")
+	}
+	select {
+	case <-webhookSchedulerDone:
+	case <-ctx.Done():
+		log.Println("Webhook scheduler did not stop in time")
+	}
+
 	log.Println("Server exiting")
 }
+
+// newNotifier registers a notifier for every channel the configuration
+// enables. Email is always on; Telegram needs a bot token.
+func newNotifier(cfg *config.Config, mailer *smtp.Mailer) *notify.Dispatcher {
+	dispatcher := notify.NewDispatcher()
+	dispatcher.Register(notify.ChannelEmail, notify.NewSMTP(mailer))
+	dispatcher.Register(notify.ChannelWebhook, notify.NewWebhook(10*time.Second))
+	if cfg.TelegramBotToken != "" {
+		dispatcher.Register(notify.ChannelTelegram, notify.NewTelegram(cfg.TelegramBotToken, cfg.TelegramAPIURL))
+	} else {
+		log.Println("TELEGRAM_BOT_TOKEN is not set, Telegram notifications are off")
+	}
+	return dispatcher
+}
+
+// newMailer builds the mailer sending email through the transport selected
+// by MAIL_TRANSPORT.
+func newMailer() (*smtp.Mailer, error) {
+	var transport smtp.Transport
+	switch config.MailTransport {
+	case config.MailSMTP:
+		transport = &smtp.SMTPTransport{
+			Host:     config.SmtpServer,
+			Port:     config.SmtpPort,
+			Username: config.SmtpUsername,
+			Password: config.SmtpPassword,
+			Security: config.SmtpSecurity,
+			Timeout:  30 * time.Second,
+		}
+	case config.MailFile:
+		log.Printf("Writing emails to %s instead of sending them", config.MailDir)
+		transport = smtp.FileSink{Dir: config.MailDir}
+	case config.MailMemory:
+		log.Println("Keeping emails in memory, none are sent")
+		transport = &smtp.MemorySink{}
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", config.MailTransport)
+	}
+
+	from := config.MailFrom
+	if from == "" {
+		// Without a configured sender, emails come from this placeholder
+		from = "TODO App <todo@localhost>"
+	}
+	return smtp.NewMailer(from, transport)
+}
+
+// newStorage builds the storage backend selected by cfg.Storage.
+func newStorage(cfg *config.Config) (*storage.Storage, error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		log.Println("Using in-memory storage, data is lost on restart")
+		return memory.NewStorage(), nil
+	case config.StorageMongoDB:
+		db, err := mongodb.Connect(cfg.DBUri)
+		if err != nil {
+			return nil, err
+		}
+		// Instances started together wait on the migration lock, so only
+		// one of them applies the pending migrations
+		applied, err := mongodb.NewMigrator(db).Up(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("could not migrate mongodb: %w", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d: %s", migration.Version, migration.Description)
+		}
+		return mongodb.NewStorage(db, logger.New("todo")), nil
+	case config.StoragePostgres:
+		ctx := context.Background()
+		db, err := postgres.Connect(ctx, cfg.PostgresURL)
+		if err != nil {
+			return nil, err
+		}
+		// The schema is versioned with the code, so bring it up to date on start
+		if err := postgres.Migrate(ctx, db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("could not migrate postgres: %w", err)
+		}
+		return postgres.NewStorage(db, logger.New("todo")), nil
+	case config.StorageSQLite:
+		ctx := context.Background()
+		db, err := sqlite.Open(cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := sqlite.Migrate(ctx, db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("could not migrate sqlite: %w", err)
+		}
+		return sqlite.NewStorage(db, logger.New("todo")), nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
@@ -0,0 +1,88 @@
+// Command migrate applies and rolls back the MongoDB migrations defined in
+// storage/mongodb.
+//
+//	go run ./cmd/migrate up        apply all pending migrations
+//	go run ./cmd/migrate down [N]  roll back the last N migrations (default 1)
+//	go run ./cmd/migrate status    list migrations and whether they are applied
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"todo/config"
+	"todo/storage/mongodb"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+
+	db, err := mongodb.Connect(cfg.DBUri)
+	if err != nil {
+		log.Fatalf("could not connect to database: %v", err)
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	migrator := mongodb.NewMigrator(db)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d: %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			log.Fatalf("migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Nothing to migrate.")
+		}
+	case "down":
+		n := 1
+		if len(os.Args) > 2 {
+			n, err = strconv.Atoi(os.Args[2])
+			if err != nil || n < 1 {
+				usage()
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, n)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %d: %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			log.Fatalf("rollback failed: %v", err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("Nothing to roll back.")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("could not read migrations: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-28s  %s\n", status.Version, state, status.Description)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up | down [N] | status")
+	os.Exit(2)
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 func ValidateEmail(email string) (bool, error) {
@@ -41,6 +43,18 @@ func ValidatePhone(phone string) (bool, error) {
 	return true, nil
 }
 
+// ValidateUsername checks that a username is not blank and at most 64
+// characters long.
+func ValidateUsername(username string) error {
+	if strings.TrimSpace(username) == "" {
+		return errors.New("username must not be empty")
+	}
+	if utf8.RuneCountInString(username) > 64 {
+		return errors.New("username must be at most 64 characters")
+	}
+	return nil
+}
+
 func ValidatePassword(password string) error {
 	if len(password) < 8 {
 		return errors.New("password must be at least 8 characters")
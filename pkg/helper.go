@@ -1,10 +1,9 @@
 package pkg
 
 import (
-	"bytes"
-	"encoding/json"
-	"math/rand"
-	"net/http"
+	"crypto/rand"
+	"math/big"
+	"strconv"
 
 	"github.com/google/uuid"
 )
@@ -13,43 +12,12 @@ func GenerateUUID() string {
 	return uuid.New().String()
 }
 
-func TelegramBotFunc(msg interface{}) (string, error) {
-
-	botToken := "7027904551:AAEgUYBeU9Aar0tVi5lEzLUwjK3LJ0HqfNA"
-	chatID := "-4194845131"
-
-	messageBytes, err := json.Marshal(msg)
-	if err != nil {
-		return "", err
-	}
-	message := string(messageBytes)
-
-	payload := struct {
-		ChatID string `json:"chat_id"`
-		Text   string `json:"text"`
-	}{
-		ChatID: chatID,
-		Text:   message,
-	}
-
-	data, err := json.Marshal(payload)
+// GenerateOTP returns a random 6 digit one-time password read from
+// crypto/rand.
+func GenerateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(900000))
 	if err != nil {
 		return "", err
 	}
-
-	url := "https://api.telegram.org/bot" + botToken + "/sendMessage"
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	success := "Response Status:" + resp.Status
-	return success, nil
-}
-
-func GenerateOTP() int {
-	
-
-	return rand.Intn(900000) + 100000
+	return strconv.FormatInt(n.Int64()+100000, 10), nil
 }
@@ -2,10 +2,26 @@ package jwt
 
 import (
 	"fmt"
-	"todo/config"
 	"time"
+	"todo/config"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const (
+	AccessTokenType        = "access"
+	RefreshTokenType       = "refresh"
+	PasswordResetTokenType = "password_reset"
+	UnsubscribeTokenType   = "unsubscribe"
+
+	// RefreshTokenTTL is how long a refresh token stays valid.
+	RefreshTokenTTL = 10 * 24 * time.Hour
+	// PasswordResetTokenTTL is how long a password reset link stays valid.
+	PasswordResetTokenTTL = 30 * time.Minute
+	// UnsubscribeTokenTTL is how long the unsubscribe link of an email stays
+	// valid.
+	UnsubscribeTokenTTL = 90 * 24 * time.Hour
 )
 
 func GenJWT(m map[interface{}]interface{}) (string, string, error) {
@@ -26,12 +42,16 @@ func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 	}
 
 	claims["iss"] = "user"
+	claims["typ"] = AccessTokenType
 	claims["iat"] = time.Now().Unix()
 	claims["exp"] = time.Now().AddDate(0, 0, 1).Unix()
 
 	rClaims["iss"] = "user"
+	rClaims["typ"] = RefreshTokenType
+	// jti keeps two refresh tokens minted in the same second distinct
+	rClaims["jti"] = uuid.New().String()
 	rClaims["iat"] = time.Now().Unix()
-	rClaims["exp"] = time.Now().AddDate(0, 0, 10).Unix()
+	rClaims["exp"] = time.Now().Add(RefreshTokenTTL).Unix()
 
 	accessTokenString, err := accessToken.SignedString(config.SignedKey)
 	if err != nil {
@@ -48,23 +68,54 @@ func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 	return accessTokenString, refreshTokenString, nil
 }
 
+// GenPasswordResetToken signs a short-lived token that allows userID to set a
+// new password.
+func GenPasswordResetToken(userID string) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+
+	claims["iss"] = "user"
+	claims["typ"] = PasswordResetTokenType
+	claims["jti"] = uuid.New().String()
+	claims["user_id"] = userID
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(PasswordResetTokenTTL).Unix()
+
+	tokenString, err := token.SignedString(config.SignedKey)
+	if err != nil {
+		return "", fmt.Errorf("reset_token generating error: %s", err)
+	}
+	return tokenString, nil
+}
+
+// GenUnsubscribeToken signs a long-lived token that turns off the digest
+// emails of userID without signing in.
+func GenUnsubscribeToken(userID string) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+
+	claims["iss"] = "user"
+	claims["typ"] = UnsubscribeTokenType
+	claims["user_id"] = userID
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(UnsubscribeTokenTTL).Unix()
+
+	tokenString, err := token.SignedString(config.SignedKey)
+	if err != nil {
+		return "", fmt.Errorf("unsubscribe_token generating error: %s", err)
+	}
+	return tokenString, nil
+}
+
 func ExtractClaims(tokenStr string) (jwt.MapClaims, error) {
-	var (
-		token *jwt.Token
-		err   error
-	)
-	token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// check token signing method etc
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 		return config.SignedKey, nil
 	})
 	if err != nil {
-		token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// check token signing method etc
-			return config.SignedKey, nil
-		})
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
@@ -72,7 +123,10 @@ func ExtractClaims(tokenStr string) (jwt.MapClaims, error) {
 		err = fmt.Errorf("invalid JWT Token")
 		return nil, err
 	}
+
+	// jwt.Parse only checks "exp" when it is present, so require it here.
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("token is expired")
+	}
 	return claims, nil
 }
-
-
@@ -0,0 +1,12 @@
+package notify
+
+import (
+	"net/http"
+	"time"
+)
+
+// NewLoopbackWebhook returns a Webhook that may connect to any address, for
+// receivers started with httptest, which listen on loopback.
+func NewLoopbackWebhook(timeout time.Duration) *Webhook {
+	return &Webhook{client: &http.Client{Timeout: timeout}}
+}
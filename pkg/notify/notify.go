@@ -0,0 +1,134 @@
+// Package notify delivers messages to users over email, Telegram and
+// webhooks. Each channel has a Notifier; a Dispatcher sends a message over
+// every channel the recipient enabled. Messages are rendered from the
+// templates in templates/.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Channels a recipient can enable.
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+	ChannelWebhook  = "webhook"
+)
+
+// Channels lists every channel, in the order they are tried.
+var Channels = []string{ChannelEmail, ChannelTelegram, ChannelWebhook}
+
+// ErrChannelUnavailable is returned for a channel the server has not
+// configured, such as Telegram without a bot token.
+var ErrChannelUnavailable = errors.New("channel is not available")
+
+// Recipient is where a user's messages go.
+type Recipient struct {
+	// Channels lists the channels the user enabled.
+	Channels       []string
+	Email          string
+	TelegramChatID string
+	WebhookURL     string
+}
+
+// Message is a rendered notification.
+type Message struct {
+	Subject string
+	Text    string
+	// HTML is an optional HTML version of Text. Only email sends it.
+	HTML string
+}
+
+// Notifier sends a message to a recipient over one channel, or over several
+// for a Dispatcher.
+type Notifier interface {
+	Notify(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Dispatcher sends messages over the channels recipients enabled, each with
+// the notifier registered for it.
+type Dispatcher struct {
+	notifiers map[string]Notifier
+}
+
+// NewDispatcher returns a dispatcher without any channels.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{notifiers: map[string]Notifier{}}
+}
+
+// Register makes notifier deliver the messages of channel.
+func (d *Dispatcher) Register(channel string, notifier Notifier) {
+	d.notifiers[channel] = notifier
+}
+
+// Available reports whether a notifier is registered for channel.
+func (d *Dispatcher) Available(channel string) bool {
+	_, ok := d.notifiers[channel]
+	return ok
+}
+
+// Notify sends msg over every channel of the recipient. A failing channel
+// does not stop the others; the failures are returned as a *SendError.
+func (d *Dispatcher) Notify(ctx context.Context, to Recipient, msg Message) error {
+	sendErr := &SendError{}
+	for _, channel := range to.Channels {
+		notifier, ok := d.notifiers[channel]
+		if !ok {
+			sendErr.Failed = append(sendErr.Failed, ChannelError{Channel: channel, Err: ErrChannelUnavailable})
+			continue
+		}
+		if err := notifier.Notify(ctx, to, msg); err != nil {
+			sendErr.Failed = append(sendErr.Failed, ChannelError{Channel: channel, Err: err})
+			continue
+		}
+		sendErr.Delivered = append(sendErr.Delivered, channel)
+	}
+
+	if len(sendErr.Failed) > 0 {
+		return sendErr
+	}
+	return nil
+}
+
+// ChannelError is the failure of one channel.
+type ChannelError struct {
+	Channel string
+	Err     error
+}
+
+func (e ChannelError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Channel, e.Err)
+}
+
+// SendError reports the channels a Dispatcher could not deliver over, and
+// the ones it did deliver over.
+type SendError struct {
+	Failed    []ChannelError
+	Delivered []string
+}
+
+func (e *SendError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for _, failed := range e.Failed {
+		parts = append(parts, failed.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap lets errors.Is look through the failures of the channels.
+func (e *SendError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, failed := range e.Failed {
+		errs = append(errs, failed.Err)
+	}
+	return errs
+}
+
+// Partial reports whether some channel delivered the message despite the
+// failures.
+func (e *SendError) Partial() bool {
+	return len(e.Delivered) > 0
+}
@@ -0,0 +1,197 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"todo/api/models"
+	"todo/pkg/notify"
+	"todo/pkg/notify/notifytest"
+	"todo/pkg/safehttp"
+)
+
+var ctx = context.Background()
+
+func TestTelegram(t *testing.T) {
+	server := notifytest.NewTelegramServer("secret-token")
+	defer server.Close()
+	telegram := notify.NewTelegram("secret-token", server.URL)
+
+	err := telegram.Notify(ctx, notify.Recipient{TelegramChatID: "42"}, notify.Message{Subject: "Subject", Text: "Text"})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	want := []notifytest.TelegramMessage{{ChatID: "42", Text: "Subject\n\nText"}}
+	if got := server.Messages(); !slices.Equal(got, want) {
+		t.Errorf("Messages: got %+v, want %+v", got, want)
+	}
+
+	server.FailChat("13")
+	err = telegram.Notify(ctx, notify.Recipient{TelegramChatID: "13"}, notify.Message{Text: "Text"})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("Notify(unknown chat): got %v, want chat not found", err)
+	}
+
+	if err := telegram.Notify(ctx, notify.Recipient{}, notify.Message{Text: "Text"}); err == nil {
+		t.Error("Notify(no chat): got nil error")
+	}
+	if got := len(server.Messages()); got != 1 {
+		t.Errorf("Messages: got %d, want 1", got)
+	}
+}
+
+func TestTelegramLongMessage(t *testing.T) {
+	server := notifytest.NewTelegramServer("token")
+	defer server.Close()
+
+	text := strings.Repeat("é", 5000)
+	err := notify.NewTelegram("token", server.URL).Notify(ctx, notify.Recipient{TelegramChatID: "42"}, notify.Message{Text: text})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	got := []rune(server.Messages()[0].Text)
+	if len(got) != 4096 || got[len(got)-1] != '…' {
+		t.Errorf("Notify: sent %d characters ending in %q, want 4096 ending in …", len(got), got[len(got)-1])
+	}
+}
+
+func TestTelegramRedactsToken(t *testing.T) {
+	server := notifytest.NewTelegramServer("secret-token")
+	server.Close()
+
+	err := notify.NewTelegram("secret-token", server.URL).Notify(ctx, notify.Recipient{TelegramChatID: "42"}, notify.Message{Text: "Text"})
+	if err == nil {
+		t.Fatal("Notify(closed server): got nil error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Notify(closed server): error %q contains the token", err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	server := notifytest.NewWebhookServer()
+	defer server.Close()
+	webhook := notify.NewLoopbackWebhook(time.Second)
+	to := notify.Recipient{WebhookURL: server.URL}
+
+	if err := webhook.Notify(ctx, to, notify.Message{Subject: "Subject", Text: "Text", HTML: "<p>Text</p>"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	payloads := server.Payloads()
+	if len(payloads) != 1 || payloads[0].Subject != "Subject" || payloads[0].Text != "Text" || payloads[0].SentAt.IsZero() {
+		t.Errorf("Payloads: got %+v", payloads)
+	}
+
+	server.SetStatus(http.StatusInternalServerError)
+	if err := webhook.Notify(ctx, to, notify.Message{Text: "Text"}); err == nil {
+		t.Error("Notify(failing receiver): got nil error")
+	}
+	if err := webhook.Notify(ctx, notify.Recipient{}, notify.Message{Text: "Text"}); err == nil {
+		t.Error("Notify(no URL): got nil error")
+	}
+}
+
+func TestWebhookRefusesLoopback(t *testing.T) {
+	server := notifytest.NewWebhookServer()
+	defer server.Close()
+
+	err := notify.NewWebhook(time.Second).Notify(ctx, notify.Recipient{WebhookURL: server.URL}, notify.Message{Text: "Text"})
+	if !errors.Is(err, safehttp.ErrForbiddenAddress) {
+		t.Errorf("Notify(loopback): got %v, want %v", err, safehttp.ErrForbiddenAddress)
+	}
+	if got := len(server.Payloads()); got != 0 {
+		t.Errorf("Payloads: got %d, want 0", got)
+	}
+}
+
+func TestDispatcherPartialFailure(t *testing.T) {
+	server := notifytest.NewTelegramServer("token")
+	defer server.Close()
+	server.FailChat("13")
+
+	email := &notifytest.Recorder{}
+	dispatcher := notify.NewDispatcher()
+	dispatcher.Register(notify.ChannelEmail, email)
+	dispatcher.Register(notify.ChannelTelegram, notify.NewTelegram("token", server.URL))
+
+	to := notify.Recipient{
+		Channels:       []string{notify.ChannelEmail, notify.ChannelTelegram, notify.ChannelWebhook},
+		Email:          "alice@example.com",
+		TelegramChatID: "13",
+	}
+	err := dispatcher.Notify(ctx, to, notify.Message{Subject: "Subject", Text: "Text"})
+
+	var sendErr *notify.SendError
+	if !errors.As(err, &sendErr) {
+		t.Fatalf("Notify: got %v, want a *SendError", err)
+	}
+	if !sendErr.Partial() || !slices.Equal(sendErr.Delivered, []string{notify.ChannelEmail}) {
+		t.Errorf("Delivered: got %v, want [email]", sendErr.Delivered)
+	}
+	failed := []string{}
+	for _, channelErr := range sendErr.Failed {
+		failed = append(failed, channelErr.Channel)
+	}
+	if !slices.Equal(failed, []string{notify.ChannelTelegram, notify.ChannelWebhook}) {
+		t.Errorf("Failed: got %v, want [telegram webhook]", failed)
+	}
+	if !errors.Is(err, notify.ErrChannelUnavailable) {
+		t.Errorf("Notify: %v does not wrap %v", err, notify.ErrChannelUnavailable)
+	}
+	if sent := email.Sent(); len(sent) != 1 || sent[0].Message.Subject != "Subject" {
+		t.Errorf("Sent: got %+v", sent)
+	}
+
+	// Every channel delivering is not an error
+	to.Channels, to.TelegramChatID = []string{notify.ChannelEmail, notify.ChannelTelegram}, "42"
+	if err := dispatcher.Notify(ctx, to, notify.Message{Text: "Text"}); err != nil {
+		t.Errorf("Notify: %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	due := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	msg, err := notify.Render("reminder", models.Task{Title: "Pay rent", Description: "Before noon", DueDate: due})
+	if err != nil {
+		t.Fatalf("Render(reminder): %v", err)
+	}
+	want := notify.Message{
+		Subject: "Reminder: Pay rent",
+		Text:    "\"Pay rent\" is due Fri, 01 Mar 2024 09:30 UTC.\n\nBefore noon",
+	}
+	if msg != want {
+		t.Errorf("Render(reminder): got %+v, want %+v", msg, want)
+	}
+
+	digest := models.Digest{
+		User:      models.User{Username: "alice"},
+		Frequency: "daily",
+		Date:      due,
+		Lists: []models.DigestList{{
+			TaskList: models.TaskList{Title: "Home"},
+			Due:      []models.Task{{Title: "<b>Clean</b>", DueDate: due}},
+		}},
+		UnsubscribeURL: "https://example.com/unsubscribe",
+	}
+	msg, err = notify.Render("digest", digest)
+	if err != nil {
+		t.Fatalf("Render(digest): %v", err)
+	}
+	if msg.Subject != "Your tasks for Fri, 01 Mar" {
+		t.Errorf("Render(digest): got subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "<b>Clean</b>") {
+		t.Errorf("Render(digest): text %q lacks the task", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "&lt;b&gt;Clean&lt;/b&gt;") || strings.Contains(msg.HTML, "<b>Clean") {
+		t.Errorf("Render(digest): html %q does not escape the task", msg.HTML)
+	}
+
+	if _, err := notify.Render("missing", nil); err == nil {
+		t.Error("Render(missing): got nil error")
+	}
+}
@@ -0,0 +1,160 @@
+// Package notifytest provides stand-ins for the services pkg/notify talks
+// to, so notifications can be tested without a network.
+package notifytest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"todo/pkg/notify"
+)
+
+// TelegramMessage is a message the Telegram stand-in received.
+type TelegramMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// TelegramServer is a local stand-in for the sendMessage method of the
+// Telegram Bot API. Pass its URL to notify.NewTelegram.
+type TelegramServer struct {
+	*httptest.Server
+
+	token string
+
+	mu       sync.Mutex
+	messages []TelegramMessage
+	unknown  map[string]bool
+}
+
+// NewTelegramServer starts a stand-in accepting requests of the bot with
+// token. Close it when done.
+func NewTelegramServer(token string) *TelegramServer {
+	s := &TelegramServer{token: token, unknown: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// FailChat makes messages to chatID fail the way they do for a chat the bot
+// is not a member of.
+func (s *TelegramServer) FailChat(chatID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unknown[chatID] = true
+}
+
+// Messages returns the messages delivered so far.
+func (s *TelegramServer) Messages() []TelegramMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TelegramMessage(nil), s.messages...)
+}
+
+func (s *TelegramServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/bot"+s.token+"/sendMessage" {
+		telegramReply(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	msg := TelegramMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil || msg.ChatID == "" || msg.Text == "" {
+		telegramReply(w, http.StatusBadRequest, "Bad Request: message text is empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.unknown[msg.ChatID] {
+		telegramReply(w, http.StatusBadRequest, "Bad Request: chat not found")
+		return
+	}
+	s.messages = append(s.messages, msg)
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `{"ok":true,"result":{}}`)
+}
+
+func telegramReply(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": status, "description": description})
+}
+
+// WebhookServer is a local webhook receiver recording what is posted to it.
+type WebhookServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	payloads []notify.WebhookPayload
+}
+
+// NewWebhookServer starts a receiver answering 204. Close it when done.
+func NewWebhookServer() *WebhookServer {
+	s := &WebhookServer{status: http.StatusNoContent}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// SetStatus makes the receiver answer with status from now on.
+func (s *WebhookServer) SetStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// Payloads returns the payloads received so far.
+func (s *WebhookServer) Payloads() []notify.WebhookPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]notify.WebhookPayload(nil), s.payloads...)
+}
+
+func (s *WebhookServer) handle(w http.ResponseWriter, r *http.Request) {
+	payload := notify.WebhookPayload{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status >= 200 && s.status <= 299 {
+		s.payloads = append(s.payloads, payload)
+	}
+	w.WriteHeader(s.status)
+}
+
+// Sent is a message a Recorder was asked to send.
+type Sent struct {
+	To      notify.Recipient
+	Message notify.Message
+}
+
+// Recorder is a notifier recording messages instead of sending them.
+type Recorder struct {
+	// Err, when set, is returned instead of recording.
+	Err error
+
+	mu   sync.Mutex
+	sent []Sent
+}
+
+func (r *Recorder) Notify(ctx context.Context, to notify.Recipient, msg notify.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	r.sent = append(r.sent, Sent{To: to, Message: msg})
+	return nil
+}
+
+// Sent returns the messages recorded so far.
+func (r *Recorder) Sent() []Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Sent(nil), r.sent...)
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"todo/pkg/smtp"
+)
+
+// SMTP emails messages with a pkg/smtp mailer.
+type SMTP struct {
+	mailer *smtp.Mailer
+}
+
+// NewSMTP returns a notifier sending through mailer.
+func NewSMTP(mailer *smtp.Mailer) *SMTP {
+	return &SMTP{mailer: mailer}
+}
+
+func (s *SMTP) Notify(ctx context.Context, to Recipient, msg Message) error {
+	if to.Email == "" {
+		return errors.New("recipient has no email address")
+	}
+	return s.mailer.Send(ctx, smtp.Message{
+		To:      []string{to.Email},
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+	})
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TelegramAPIURL is the address of the Telegram Bot API.
+const TelegramAPIURL = "https://api.telegram.org"
+
+// telegramMaxText is the longest message the Bot API accepts, in characters.
+const telegramMaxText = 4096
+
+// Telegram sends messages through a Telegram bot to the chat each user
+// linked.
+type Telegram struct {
+	token  string
+	apiURL string
+	client *http.Client
+}
+
+// NewTelegram returns a notifier sending as the bot with token through the
+// Bot API at apiURL, TelegramAPIURL outside of tests.
+func NewTelegram(token, apiURL string) *Telegram {
+	return &Telegram{
+		token:  token,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Telegram) Notify(ctx context.Context, to Recipient, msg Message) error {
+	if to.TelegramChatID == "" {
+		return errors.New("recipient has not linked a Telegram chat")
+	}
+
+	text := msg.Text
+	if msg.Subject != "" {
+		text = msg.Subject + "\n\n" + text
+	}
+	if runes := []rune(text); len(runes) > telegramMaxText {
+		text = string(runes[:telegramMaxText-1]) + "…"
+	}
+
+	data, err := json.Marshal(struct {
+		ChatID string `json:"chat_id"`
+		Text   string `json:"text"`
+	}{ChatID: to.TelegramChatID, Text: text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.apiURL+"/bot"+t.token+"/sendMessage", bytes.NewReader(data))
+	if err != nil {
+		return t.redact(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return t.redact(err)
+	}
+	defer resp.Body.Close()
+
+	result := struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	if !result.OK {
+		return errors.New(result.Description)
+	}
+	return nil
+}
+
+// redact keeps the bot token, which is part of the request URL, out of
+// errors that end up in logs.
+func (t *Telegram) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, t.token, "<token>")
+		return urlErr
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), t.token, "<token>"))
+}
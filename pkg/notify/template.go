@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"embed"
+	"fmt"
+	"text/template"
+	"time"
+	"todo/pkg/smtp"
+)
+
+//go:embed templates/*.tmpl templates/*.html
+var templateFS embed.FS
+
+// templates holds the messages of templates/, rendered the way pkg/smtp
+// renders its emails.
+var templates = smtp.MustParseTemplates(templateFS, "templates", templateFuncs)
+
+var templateFuncs = template.FuncMap{
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format("Mon, 02 Jan 2006 15:04 MST")
+	},
+	// localtime formats a time in its own location, for data that has
+	// already been moved to the recipient's time zone
+	"localtime": func(t time.Time) string {
+		return t.Format("Mon, 02 Jan 15:04")
+	},
+}
+
+// Render builds the message of template name from data, with an HTML
+// version when there is one.
+func Render(name string, data any) (Message, error) {
+	msg, err := templates.Render(name, data)
+	if err != nil {
+		return Message{}, fmt.Errorf("notify: %w", err)
+	}
+	return Message{Subject: msg.Subject, Text: msg.Text, HTML: msg.HTML}, nil
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"todo/pkg/safehttp"
+)
+
+// Webhook posts messages as JSON to the URL each user configured.
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook returns a notifier giving each request timeout to complete. It
+// only connects to public addresses, see safehttp.
+func NewWebhook(timeout time.Duration) *Webhook {
+	return &Webhook{client: safehttp.NewClient(timeout)}
+}
+
+// WebhookPayload is the body posted to a webhook.
+type WebhookPayload struct {
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+func (w *Webhook) Notify(ctx context.Context, to Recipient, msg Message) error {
+	if to.WebhookURL == "" {
+		return errors.New("recipient has no webhook URL")
+	}
+
+	data, err := json.Marshal(WebhookPayload{Subject: msg.Subject, Text: msg.Text, SentAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, to.WebhookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package rbac
+
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+type Permission string
+
+const (
+	UsersReadAll    Permission = "users:read_all"
+	UsersDeleteAny  Permission = "users:delete_any"
+	UsersDisable    Permission = "users:disable"
+	UsersUpdateRole Permission = "users:update_role"
+)
+
+// rolePermissions lists what each role may do beyond acting on its own
+// resources. Members have no extra permissions.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		UsersReadAll,
+		UsersDeleteAny,
+		UsersDisable,
+		UsersUpdateRole,
+	},
+	RoleMember: {},
+}
+
+// HasPermission reports whether role grants permission.
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role is a known role.
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
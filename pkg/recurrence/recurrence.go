@@ -0,0 +1,110 @@
+// Package recurrence expands the RFC 5545 recurrence rules of repeating
+// tasks, such as
+//
+//	FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR    every weekday
+//	FREQ=MONTHLY;BYDAY=1MO             the first Monday of every month
+//	FREQ=WEEKLY;INTERVAL=2;COUNT=10    every other week, ten times
+//
+// A rule is expanded in a time zone from the first occurrence of its series,
+// so a task due at 09:00 stays due at 09:00 local time across daylight saving
+// changes, and COUNT and INTERVAL count from the start of the series.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Time zones are looked up by name, whether or not the host has them
+	_ "time/tzdata"
+
+	"github.com/teambition/rrule-go"
+)
+
+// Rule is a parsed recurrence rule anchored to the start of its series.
+type Rule struct {
+	rrule *rrule.RRule
+}
+
+// Parse parses an RRULE value, with or without the "RRULE:" prefix, for a
+// series whose first occurrence is start. tz is the IANA name of the time
+// zone the rule is expanded in; an empty tz means UTC.
+//
+// Rules repeating more often than daily are rejected, and so are rules with
+// their own DTSTART, since the series start is the task's due date.
+func Parse(rule, tz string, start time.Time) (*Rule, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil || strings.EqualFold(tz, "local") {
+		return nil, fmt.Errorf("unknown time zone %q", tz)
+	}
+
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if strings.ContainsAny(rule, "\r\n") || strings.Contains(strings.ToUpper(rule), "DTSTART") {
+		return nil, errors.New("the rule cannot set DTSTART; the series starts at the due date")
+	}
+	option, err := rrule.StrToROptionInLocation(rule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %v", err)
+	}
+
+	switch {
+	case option.Freq > rrule.DAILY:
+		return nil, fmt.Errorf("a task cannot repeat %s", strings.ToLower(option.Freq.String()))
+	case option.Count > 0 && !option.Until.IsZero():
+		return nil, errors.New("a rule cannot have both COUNT and UNTIL")
+	case option.Count < 0 || option.Interval < 0:
+		return nil, errors.New("COUNT and INTERVAL cannot be negative")
+	}
+
+	option.Dtstart = start.In(loc)
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %v", err)
+	}
+	return &Rule{rrule: r}, nil
+}
+
+// String returns the rule in canonical form, without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	return r.rrule.OrigOptions.RRuleString()
+}
+
+// After returns the first occurrence after t. It reports false when the
+// series ends before then.
+func (r *Rule) After(t time.Time) (time.Time, bool) {
+	next := r.rrule.After(t, false)
+	return next, !next.IsZero()
+}
+
+// Next returns up to n occurrences after t, fewer when the series ends.
+func (r *Rule) Next(t time.Time, n int) []time.Time {
+	occurrences := []time.Time{}
+	first, ok := r.After(t)
+	if !ok || n <= 0 {
+		return occurrences
+	}
+
+	// The rule started at one of its occurrences expands to the rest of the
+	// series, less the occurrences COUNT already used up before it
+	option := r.rrule.OrigOptions
+	option.Dtstart = first
+	if option.Count > 0 {
+		option.Count -= len(r.rrule.Between(r.rrule.GetDTStart(), first, true)) - 1
+	}
+	rest, err := rrule.NewRRule(option)
+	if err != nil {
+		// Parse accepted the same options, so this is not expected
+		return append(occurrences, first)
+	}
+
+	iterate := rest.Iterator()
+	for len(occurrences) < n {
+		occurrence, ok := iterate()
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
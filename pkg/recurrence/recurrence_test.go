@@ -0,0 +1,223 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var newYork = mustLoad("America/New_York")
+
+func mustLoad(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+func at(loc *time.Location, month time.Month, day, hour int) time.Time {
+	return time.Date(2024, month, day, hour, 0, 0, 0, loc)
+}
+
+func TestParse(t *testing.T) {
+	start := at(time.UTC, time.January, 1, 9)
+
+	tests := []struct {
+		rule, tz string
+		want     string
+	}{
+		{"FREQ=DAILY", "", "FREQ=DAILY"},
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "UTC", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{" FREQ=MONTHLY;BYDAY=1MO ", "Europe/Berlin", "FREQ=MONTHLY;BYDAY=+1MO"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=10", "America/New_York", "FREQ=WEEKLY;INTERVAL=2;COUNT=10"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule, tt.tz, start)
+		if err != nil {
+			t.Errorf("Parse(%q, %q): %v", tt.rule, tt.tz, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q, %q).String() = %q, want %q", tt.rule, tt.tz, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	start := at(time.UTC, time.January, 1, 9)
+
+	tests := []struct {
+		rule, tz string
+		want     string
+	}{
+		{"FREQ=DAILY", "Mars/Olympus_Mons", "unknown time zone"},
+		{"FREQ=DAILY", "Local", "unknown time zone"},
+		{"DTSTART:20240101T090000Z\nRRULE:FREQ=DAILY", "", "cannot set DTSTART"},
+		{"FREQ=DAILY;DTSTART=20240101T090000Z", "", "cannot set DTSTART"},
+		{"FREQ=DAILY\r\nFREQ=WEEKLY", "", "cannot set DTSTART"},
+		{"FREQ=HOURLY", "", "cannot repeat hourly"},
+		{"FREQ=MINUTELY;INTERVAL=30", "", "cannot repeat minutely"},
+		{"FREQ=SECONDLY", "", "cannot repeat secondly"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20240201T000000Z", "", "both COUNT and UNTIL"},
+		{"FREQ=DAILY;INTERVAL=-1", "", "cannot be negative"},
+		{"FREQ=DAILY;COUNT=-2", "", "cannot be negative"},
+		{"FREQ=SOMETIMES", "", "invalid rule"},
+		{"BYDAY=MO", "", "invalid rule"},
+		{"", "", "invalid rule"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.rule, tt.tz, start)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q, %q): got %v, want an error containing %q", tt.rule, tt.tz, err, tt.want)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{"the start itself is skipped", "FREQ=DAILY", at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 2, 9), true},
+		{"between occurrences", "FREQ=WEEKLY", at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 3, 0), at(time.UTC, time.January, 8, 9), true},
+		{"before the start", "FREQ=DAILY", at(time.UTC, time.January, 10, 9), at(time.UTC, time.January, 1, 0), at(time.UTC, time.January, 10, 9), true},
+		{"past the last of COUNT", "FREQ=DAILY;COUNT=3", at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 3, 9), time.Time{}, false},
+		{"past UNTIL", "FREQ=DAILY;UNTIL=20240105T090000Z", at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 5, 9), time.Time{}, false},
+		{"across spring forward", "FREQ=DAILY", at(newYork, time.March, 9, 9), at(newYork, time.March, 9, 9), at(newYork, time.March, 10, 9), true},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule, tt.start.Location().String(), tt.start)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, ok := rule.After(tt.after)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%s: After(%v) = %v, %v, want %v, %v", tt.name, tt.after, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		n     int
+		want  []time.Time
+	}{
+		{
+			"every weekday", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			at(time.UTC, time.March, 1, 9), at(time.UTC, time.March, 1, 9), 3,
+			[]time.Time{at(time.UTC, time.March, 4, 9), at(time.UTC, time.March, 5, 9), at(time.UTC, time.March, 6, 9)},
+		},
+		{
+			"first Monday of the month", "FREQ=MONTHLY;BYDAY=1MO",
+			at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 1, 9), 3,
+			[]time.Time{at(time.UTC, time.February, 5, 9), at(time.UTC, time.March, 4, 9), at(time.UTC, time.April, 1, 9)},
+		},
+		{
+			"the 31st skips shorter months", "FREQ=MONTHLY",
+			at(time.UTC, time.January, 31, 9), at(time.UTC, time.February, 1, 0), 3,
+			[]time.Time{at(time.UTC, time.March, 31, 9), at(time.UTC, time.May, 31, 9), at(time.UTC, time.July, 31, 9)},
+		},
+		{
+			"INTERVAL and COUNT count from the start", "FREQ=WEEKLY;INTERVAL=2;COUNT=5",
+			at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 20, 0), 10,
+			[]time.Time{at(time.UTC, time.January, 29, 9), at(time.UTC, time.February, 12, 9), at(time.UTC, time.February, 26, 9)},
+		},
+		{
+			"every other week on two days", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=6",
+			at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 2, 0), 10,
+			[]time.Time{at(time.UTC, time.January, 3, 9), at(time.UTC, time.January, 15, 9), at(time.UTC, time.January, 17, 9), at(time.UTC, time.January, 29, 9), at(time.UTC, time.January, 31, 9)},
+		},
+		{
+			"until", "FREQ=DAILY;UNTIL=20240105T090000Z",
+			at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 3, 9), 10,
+			[]time.Time{at(time.UTC, time.January, 4, 9), at(time.UTC, time.January, 5, 9)},
+		},
+		{
+			"9:00 stays 9:00 across spring forward", "FREQ=DAILY",
+			at(newYork, time.March, 9, 9), at(newYork, time.March, 9, 9), 2,
+			[]time.Time{at(newYork, time.March, 10, 9), at(newYork, time.March, 11, 9)},
+		},
+		{
+			"9:00 stays 9:00 across fall back", "FREQ=WEEKLY",
+			at(newYork, time.October, 27, 9), at(newYork, time.October, 27, 9), 1,
+			[]time.Time{at(newYork, time.November, 3, 9)},
+		},
+		{
+			"none asked for", "FREQ=DAILY",
+			at(time.UTC, time.January, 1, 9), at(time.UTC, time.January, 1, 9), 0,
+			[]time.Time{},
+		},
+		{
+			"the series has ended", "FREQ=DAILY;COUNT=2",
+			at(time.UTC, time.January, 1, 9), at(time.UTC, time.February, 1, 0), 3,
+			[]time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule, tt.start.Location().String(), tt.start)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := rule.Next(tt.after, tt.n)
+		if !equalTimes(got, tt.want) {
+			t.Errorf("%s: Next(%v, %d) = %v, want %v", tt.name, tt.after, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestNextMatchesSeries checks Next, which expands the rule from the first
+// occurrence after t, against the whole series expanded from its start.
+func TestNextMatchesSeries(t *testing.T) {
+	rules := []string{
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=WEEKLY;INTERVAL=3;BYDAY=FR;WKST=SU",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH,SA;COUNT=20",
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=31;COUNT=8",
+		"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=3",
+		"FREQ=DAILY;BYHOUR=8,20;UNTIL=20240401T000000Z",
+	}
+	start := time.Date(2024, time.January, 31, 8, 0, 0, 0, newYork)
+
+	for _, raw := range rules {
+		rule, err := Parse(raw, "America/New_York", start)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		all := rule.rrule.Between(start.AddDate(-1, 0, 0), start.AddDate(20, 0, 0), true)
+
+		for days := 0; days < 400; days += 17 {
+			after := start.AddDate(0, 0, days)
+			want := []time.Time{}
+			for _, occurrence := range all {
+				if occurrence.After(after) && len(want) < 6 {
+					want = append(want, occurrence)
+				}
+			}
+			if got := rule.Next(after, 6); !equalTimes(got, want) {
+				t.Errorf("%s: Next(%v, 6) = %v, want %v", raw, after, got, want)
+			}
+		}
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+// Package safehttp builds HTTP clients for URLs that users supply, such as
+// webhooks. Their connections may only reach public addresses, so a user
+// cannot make the server call into its own network and read the answer.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a connection would reach an address
+// that is not public.
+var ErrForbiddenAddress = errors.New("address is not public")
+
+// nonPublic lists the ranges that net/netip has no predicate for.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, with broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which embeds IPv4
+	netip.MustParsePrefix("2002::/16"),     // 6to4, which embeds IPv4
+}
+
+// IsPublic reports whether addr can be reached on the internet, which rules
+// out loopback, private, link-local (such as the 169.254.169.254 metadata
+// service), multicast and reserved addresses.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control is a net.Dialer Control function refusing connections to
+// addresses that are not public. It runs after the host name is resolved,
+// so a name pointing at a private address is refused as well.
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// NewClient returns a client giving each request timeout to complete whose
+// connections are checked by Control. Proxies set in the environment are
+// ignored, as they would connect on the client's behalf.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package safehttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":                true,
+		"2606:4700::1111":        true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"fe80::1":                false,
+		"fd00::1":                false,
+		"0.0.0.0":                false,
+		"100.64.0.1":             false,
+		"224.0.0.1":              false,
+		"255.255.255.255":        false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	}
+	for raw, want := range tests {
+		if got := IsPublic(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("IsPublic(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Get(%s): got %v, want %v", server.URL, err, ErrForbiddenAddress)
+	}
+	if called {
+		t.Error("the loopback server was called")
+	}
+}
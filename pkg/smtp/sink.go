@@ -0,0 +1,70 @@
+package smtp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSink writes each message to an .eml file in Dir instead of sending it,
+// so emails can be opened in a mail client during local development.
+type FileSink struct {
+	Dir string
+}
+
+func (s FileSink) Send(ctx context.Context, from string, to []string, data []byte) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	// Names sort in the order the messages were sent
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(random))
+	return os.WriteFile(filepath.Join(s.Dir, name), data, 0o644)
+}
+
+// SentMessage is a message kept by a MemorySink.
+type SentMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// MemorySink keeps the messages it is given instead of sending them, for
+// tests and for running without a mail server.
+type MemorySink struct {
+	mu       sync.Mutex
+	messages []SentMessage
+}
+
+func (s *MemorySink) Send(ctx context.Context, from string, to []string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, SentMessage{
+		From: from,
+		To:   append([]string(nil), to...),
+		Data: append([]byte(nil), data...),
+	})
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (s *MemorySink) Messages() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage(nil), s.messages...)
+}
+
+// Reset forgets the messages sent so far.
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
@@ -1,27 +1,184 @@
+// Package smtp builds and sends email. A Mailer encodes a Message as MIME,
+// with a multipart/alternative body when it has an HTML version, and hands
+// it to a Transport: an SMTP server, a directory of .eml files or memory.
+// Messages are rendered from the templates in templates/.
 package smtp
 
 import (
-	"net/smtp"
-	"todo/config"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
 )
 
-func SendMail(toEmail string, msg string) error {
+// Message is an email to send.
+type Message struct {
+	To      []string
+	Subject string
+	// Text is the plain text body. Mail clients that cannot show HTML fall
+	// back to it.
+	Text string
+	// HTML is an optional HTML version of Text.
+	HTML string
+	// Headers are added to the ones the Mailer sets, such as
+	// List-Unsubscribe. They cannot replace those.
+	Headers map[string]string
+}
+
+// Transport delivers an encoded message.
+type Transport interface {
+	Send(ctx context.Context, from string, to []string, data []byte) error
+}
 
-	from := config.SmtpUsername
-	to := []string{toEmail}
-	subject := "Register for TODO AP"
-	message := msg
+// Mailer sends messages from one address through a Transport.
+type Mailer struct {
+	from      mail.Address
+	transport Transport
+	now       func() time.Time
+}
 
-	body := "To: " + to[0] + "\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"\r\n" + message
+// NewMailer returns a mailer sending from, an address such as
+// "TODO App <todo@example.com>", through transport.
+func NewMailer(from string, transport Transport) (*Mailer, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("smtp: invalid sender %q: %w", from, err)
+	}
+	return &Mailer{from: *address, transport: transport, now: time.Now}, nil
+}
 
-	auth := smtp.PlainAuth("", config.SmtpUsername, config.SmtpPassword, config.SmtpServer)
+// Send encodes msg and delivers it to its recipients.
+func (m *Mailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("smtp: message has no recipient")
+	}
+	to := make([]*mail.Address, 0, len(msg.To))
+	for _, recipient := range msg.To {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("smtp: invalid recipient %q: %w", recipient, err)
+		}
+		to = append(to, address)
+	}
+	for key := range msg.Headers {
+		if key == "" || strings.ContainsAny(key, ": \t\r\n") {
+			return fmt.Errorf("smtp: invalid header name %q", key)
+		}
+	}
 
-	err := smtp.SendMail(config.SmtpServer+":"+config.SmtpPort, auth, from, to, []byte(body))
+	data, err := m.encode(msg, to)
 	if err != nil {
 		return err
 	}
+	envelope := make([]string, 0, len(to))
+	for _, address := range to {
+		envelope = append(envelope, address.Address)
+	}
+	return m.transport.Send(ctx, m.from.Address, envelope, data)
+}
+
+// headerValue keeps a value on a single line: a line break would end the
+// header and let the value add others.
+var headerValue = strings.NewReplacer("\r", " ", "\n", " ")
+
+// encode builds the MIME message of msg, addressed to to.
+func (m *Mailer) encode(msg Message, to []*mail.Address) ([]byte, error) {
+	messageID, err := m.messageID()
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	header := textproto.MIMEHeader{}
+	for key, value := range msg.Headers {
+		header.Set(key, headerValue.Replace(value))
+	}
+	header.Set("From", m.from.String())
+	recipients := make([]string, 0, len(to))
+	for _, address := range to {
+		recipients = append(recipients, address.String())
+	}
+	header.Set("To", strings.Join(recipients, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("UTF-8", headerValue.Replace(msg.Subject)))
+	header.Set("Date", m.now().Format(time.RFC1123Z))
+	header.Set("Message-Id", messageID)
+	header.Set("Mime-Version", "1.0")
+
+	var body bytes.Buffer
+	switch {
+	case msg.HTML == "":
+		header.Set("Content-Type", "text/plain; charset=UTF-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		if err := writeQuotedPrintable(&body, msg.Text); err != nil {
+			return nil, err
+		}
+	default:
+		writer := multipart.NewWriter(&body)
+		header.Set("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+		// Clients show the last part they understand, so HTML goes last
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain; charset=UTF-8", msg.Text},
+			{"text/html; charset=UTF-8", msg.HTML},
+		} {
+			w, err := writer.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeQuotedPrintable(w, part.body); err != nil {
+				return nil, err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	var data bytes.Buffer
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&data, "%s: %s\r\n", key, header.Get(key))
+	}
+	data.WriteString("\r\n")
+	data.Write(body.Bytes())
+	return data.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID in the domain of the sender.
+func (m *Mailer) messageID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(m.from.Address, "@"); at >= 0 {
+		domain = m.from.Address[at+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", m.now().UnixNano(), hex.EncodeToString(random), domain), nil
+}
+
+// writeQuotedPrintable writes text quoted-printable encoded, which keeps
+// lines short and non-ASCII characters intact over any server.
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return err
+	}
+	return qp.Close()
 }
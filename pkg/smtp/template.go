@@ -0,0 +1,94 @@
+package smtp
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl templates/*.html
+var templateFS embed.FS
+
+var templates = MustParseTemplates(templateFS, "templates", nil)
+
+// Templates renders messages from a directory holding one .tmpl file per
+// message, named after it, that defines a "subject" and a "text" template.
+// A .html file named like the .tmpl file adds an HTML version defining an
+// "html" template, and html/template escapes what it is given for where it
+// goes in the page.
+type Templates struct {
+	text map[string]*template.Template
+	html map[string]*htmltemplate.Template
+}
+
+// MustParseTemplates parses the templates in dir of fsys, which may call
+// funcs. It panics if they do not parse, so use it on embedded files only.
+func MustParseTemplates(fsys fs.FS, dir string, funcs template.FuncMap) *Templates {
+	t := &Templates{
+		text: map[string]*template.Template{},
+		html: map[string]*htmltemplate.Template{},
+	}
+
+	files, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		t.text[name] = template.Must(template.New(name).Funcs(funcs).ParseFS(fsys, file))
+	}
+
+	files, err = fs.Glob(fsys, path.Join(dir, "*.html"))
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		t.html[name] = htmltemplate.Must(htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).ParseFS(fsys, file))
+	}
+	return t
+}
+
+// Render builds the message of template name from data, with an HTML
+// version when there is one. The message has no recipients.
+func (t *Templates) Render(name string, data any) (Message, error) {
+	set, ok := t.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown template %q", name)
+	}
+
+	var subject, text strings.Builder
+	if err := set.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := set.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, err
+	}
+	msg := Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+	}
+
+	if htmlSet, ok := t.html[name]; ok {
+		var html strings.Builder
+		if err := htmlSet.ExecuteTemplate(&html, "html", data); err != nil {
+			return Message{}, err
+		}
+		msg.HTML = strings.TrimSpace(html.String())
+	}
+	return msg, nil
+}
+
+// Render builds the email of template name from data, addressed to to.
+func Render(name string, data any, to ...string) (Message, error) {
+	msg, err := templates.Render(name, data)
+	if err != nil {
+		return Message{}, fmt.Errorf("smtp: %w", err)
+	}
+	msg.To = to
+	return msg, nil
+}
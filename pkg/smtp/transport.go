@@ -0,0 +1,110 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// How an SMTP transport secures the connection to the server.
+const (
+	// SecurityStartTLS upgrades a plain connection with STARTTLS, usually
+	// on port 587. The server must support it.
+	SecurityStartTLS = "starttls"
+	// SecurityTLS connects over TLS from the start, usually on port 465.
+	SecurityTLS = "tls"
+	// SecurityNone sends in the clear. Only meant for local relays; the
+	// credentials are refused over it unless the server is localhost.
+	SecurityNone = "none"
+)
+
+// SMTPTransport delivers messages to an SMTP server.
+type SMTPTransport struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// Security is SecurityStartTLS, SecurityTLS or SecurityNone.
+	Security string
+	// Timeout bounds a whole delivery, from dialing to QUIT. Zero leaves it
+	// to the context.
+	Timeout time.Duration
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, from string, to []string, data []byte) error {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if t.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: t.Host}); err != nil {
+			return err
+		}
+	}
+	if t.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (t *SMTPTransport) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(t.Host, t.Port)
+	switch t.Security {
+	case SecurityTLS:
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: t.Host}}
+		return dialer.DialContext(ctx, "tcp", addr)
+	case SecurityStartTLS, SecurityNone:
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("smtp: unknown security %q", t.Security)
+	}
+}
@@ -0,0 +1,321 @@
+package taskquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength bounds the length of a query, which also bounds how deeply it
+// can nest.
+const MaxLength = 1000
+
+// SyntaxError reports where a query could not be parsed.
+type SyntaxError struct {
+	// Pos is the byte offset of the problem in the query.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenWord
+)
+
+// token is a parenthesis or a word. A word with a colon is split into its
+// field and value, and Quoted tells whether the value was in quotes.
+type token struct {
+	kind   tokenKind
+	pos    int
+	field  string
+	value  string
+	quoted bool
+}
+
+// keyword reports whether the token is the operator name, in any case.
+func (t token) keyword(name string) bool {
+	return t.kind == tokenWord && !t.quoted && t.field == "" && strings.EqualFold(t.value, name)
+}
+
+func lex(query string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(query); {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: i})
+			i++
+		default:
+			tok := token{kind: tokenWord, pos: i}
+			end := i
+			for end < len(query) {
+				r, size := utf8.DecodeRuneInString(query[end:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+					break
+				}
+				end += size
+			}
+			tok.value = query[i:end]
+			if field, value, ok := strings.Cut(tok.value, ":"); ok {
+				tok.field, tok.value = strings.ToLower(field), value
+			}
+
+			// A quoted value: "buy milk" or label:"on hold"
+			if end < len(query) && query[end] == '"' && tok.value == "" {
+				closing := strings.IndexByte(query[end+1:], '"')
+				if closing < 0 {
+					return nil, &SyntaxError{Pos: end, Msg: "unterminated quote"}
+				}
+				tok.value, tok.quoted = query[end+1:end+1+closing], true
+				end += closing + 2
+			}
+			if end == i {
+				return nil, &SyntaxError{Pos: i, Msg: "unexpected quote"}
+			}
+			tokens = append(tokens, tok)
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(query)}), nil
+}
+
+// Parse parses a query into an expression.
+func Parse(query string) (Expr, error) {
+	if len(query) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("query is longer than %d characters", MaxLength)}
+	}
+
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: 0, Msg: "empty query"}
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "unexpected " + describe(tok)}
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token { return p.tokens[p.next] }
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("OR") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.keyword("AND") {
+			p.advance()
+		} else if tok.kind == tokenEOF || tok.kind == tokenRParen || tok.keyword("OR") {
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	tok := p.advance()
+	switch {
+	case tok.keyword("NOT"):
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	case tok.kind == tokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: "expected ) to close the ( at position " + strconv.Itoa(tok.pos)}
+		}
+		return expr, nil
+	case tok.kind == tokenWord && !tok.keyword("AND") && !tok.keyword("OR"):
+		return parseTerm(tok)
+	default:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a term, got " + describe(tok)}
+	}
+}
+
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenLParen:
+		return "("
+	case tokenRParen:
+		return ")"
+	}
+	if tok.field != "" {
+		return fmt.Sprintf("%q", tok.field+":"+tok.value)
+	}
+	return fmt.Sprintf("%q", tok.value)
+}
+
+func parseTerm(tok token) (Expr, error) {
+	fail := func(format string, args ...interface{}) (Expr, error) {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	switch tok.field {
+	case "":
+		switch {
+		case tok.quoted:
+			return &Title{Text: tok.value}, nil
+		case strings.EqualFold(tok.value, "completed"):
+			return &Completed{Value: true}, nil
+		case strings.EqualFold(tok.value, "overdue"):
+			return &Overdue{}, nil
+		}
+		return &Title{Text: tok.value}, nil
+	case "title":
+		return &Title{Text: tok.value}, nil
+	case "completed":
+		value, err := strconv.ParseBool(tok.value)
+		if err != nil {
+			return fail("completed takes true or false, got %q", tok.value)
+		}
+		return &Completed{Value: value}, nil
+	case "label", "list":
+		if tok.value == "" {
+			return fail("%s needs a name", tok.field)
+		}
+		if tok.field == "label" {
+			return &Label{Name: tok.value}, nil
+		}
+		return &List{Name: tok.value}, nil
+	case "due", "created", "updated":
+		if tok.field == "due" && !tok.quoted && strings.EqualFold(tok.value, "none") {
+			return &NoDueDate{}, nil
+		}
+		field := map[string]string{"due": FieldDueDate, "created": FieldCreatedAt, "updated": FieldUpdatedAt}[tok.field]
+		return parseComparison(tok, field)
+	default:
+		return fail("unknown field %q", tok.field)
+	}
+}
+
+// parseComparison parses the value of a time field: an operator and a time,
+// or a day on its own.
+func parseComparison(tok token, field string) (Expr, error) {
+	value := tok.value
+	op := ""
+	for _, candidate := range []string{OpLessEqual, OpGreaterEqual, OpLess, OpGreater} {
+		if strings.HasPrefix(value, candidate) {
+			op, value = candidate, value[len(candidate):]
+			break
+		}
+	}
+
+	t, isDay, err := parseTime(value)
+	if err != nil {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("%s: %v", tok.field, err)}
+	}
+	if op != "" {
+		return &Compare{Field: field, Op: op, Time: t}, nil
+	}
+	if !isDay {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("%s: %q needs an operator such as < or >=", tok.field, value)}
+	}
+
+	// A whole day
+	next := t
+	if next.Day {
+		next.Days++
+	} else {
+		next.Absolute = next.Absolute.AddDate(0, 0, 1)
+	}
+	return &And{
+		Left:  &Compare{Field: field, Op: OpGreaterEqual, Time: t},
+		Right: &Compare{Field: field, Op: OpLess, Time: next},
+	}, nil
+}
+
+var dayNames = map[string]int{"yesterday": -1, "today": 0, "tomorrow": 1}
+
+var offsetUnits = map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+
+// parseTime parses a day name, a date, an RFC 3339 time or an offset, and
+// reports whether the time is the start of a day.
+func parseTime(value string) (Time, bool, error) {
+	if days, ok := dayNames[strings.ToLower(value)]; ok {
+		return Time{Day: true, Days: days}, true, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return Time{Absolute: t}, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return Time{Absolute: t}, false, nil
+	}
+
+	if len(value) > 1 {
+		if unit, ok := offsetUnits[value[len(value)-1]]; ok {
+			n, err := strconv.Atoi(value[:len(value)-1])
+			// Keeps the offset far from overflowing a time.Duration
+			if err == nil && n >= -100000 && n <= 100000 {
+				return Time{Relative: true, Offset: time.Duration(n) * unit}, false, nil
+			}
+		}
+	}
+	return Time{}, false, fmt.Errorf("invalid time %q", value)
+}
@@ -0,0 +1,192 @@
+package taskquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// show renders a tree in prefix form so expected trees fit on one line.
+func show(expr Expr) string {
+	switch e := expr.(type) {
+	case *And:
+		return "(AND " + show(e.Left) + " " + show(e.Right) + ")"
+	case *Or:
+		return "(OR " + show(e.Left) + " " + show(e.Right) + ")"
+	case *Not:
+		return "(NOT " + show(e.Expr) + ")"
+	case *Completed:
+		return fmt.Sprintf("completed:%t", e.Value)
+	case *Overdue:
+		return "overdue"
+	case *NoDueDate:
+		return "due:none"
+	case *Title:
+		return fmt.Sprintf("title:%q", e.Text)
+	case *Label:
+		return fmt.Sprintf("label:%q", e.Name)
+	case *List:
+		return fmt.Sprintf("list:%q", e.Name)
+	case *Compare:
+		return e.Field + e.Op + showTime(e.Time)
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+func showTime(t Time) string {
+	switch {
+	case t.Relative && t.Offset < 0:
+		return "now" + t.Offset.String()
+	case t.Relative:
+		return "now+" + t.Offset.String()
+	case t.Day:
+		return fmt.Sprintf("day%+d", t.Days)
+	}
+	return t.Absolute.Format(time.RFC3339)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`due:<7d AND label:work AND NOT completed`, `(AND (AND due_date<now+168h0m0s label:"work") (NOT completed:true))`},
+
+		// AND binds tighter than OR, terms next to each other are ANDed
+		{`a b OR c`, `(OR (AND title:"a" title:"b") title:"c")`},
+		{`a OR b c`, `(OR title:"a" (AND title:"b" title:"c"))`},
+		{`a OR b OR c`, `(OR (OR title:"a" title:"b") title:"c")`},
+		{`a AND (b OR c)`, `(AND title:"a" (OR title:"b" title:"c"))`},
+		{`not a and b or c`, `(OR (AND (NOT title:"a") title:"b") title:"c")`},
+		{`NOT NOT overdue`, `(NOT (NOT overdue))`},
+		{`NOT (a OR b)`, `(NOT (OR title:"a" title:"b"))`},
+		{`(((a)))`, `title:"a"`},
+		{`a(b)`, `(AND title:"a" title:"b")`},
+
+		// Terms
+		{`COMPLETED`, `completed:true`},
+		{`completed:false`, `completed:false`},
+		{`overdue`, `overdue`},
+		{`due:none`, `due:none`},
+		{`"buy milk"`, `title:"buy milk"`},
+		{`"and"`, `title:"and"`},
+		{`title:AND`, `title:"AND"`},
+		{`Title:x`, `title:"x"`},
+		{`label:"on hold"`, `label:"on hold"`},
+		{`list:Inbox`, `list:"Inbox"`},
+		{`héllo wörld`, `(AND title:"héllo" title:"wörld")`},
+
+		// Times
+		{`due:today`, `(AND due_date>=day+0 due_date<day+1)`},
+		{`due:Yesterday`, `(AND due_date>=day-1 due_date<day+0)`},
+		{`due:>=tomorrow`, `due_date>=day+1`},
+		{`created:2024-05-01`, `(AND created_at>=2024-05-01T00:00:00Z created_at<2024-05-02T00:00:00Z)`},
+		{`updated:>=2024-05-01T10:00:00+02:00`, `updated_at>=2024-05-01T10:00:00+02:00`},
+		{`due:<=-12h`, `due_date<=now-12h0m0s`},
+		{`due:>2w`, `due_date>now+336h0m0s`},
+		{`due:<100000d`, `due_date<now+2400000h0m0s`},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := show(expr); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{``, 0, "empty query"},
+		{`   `, 0, "empty query"},
+		{`a AND`, 5, "expected a term, got end of query"},
+		{`NOT`, 3, "expected a term, got end of query"},
+		{`OR a`, 0, `expected a term, got "OR"`},
+		{`a AND OR b`, 6, `expected a term, got "OR"`},
+		{`()`, 1, "expected a term, got )"},
+		{`(a OR b`, 7, "expected ) to close the ( at position 0"},
+		{`a (b (c)`, 8, "expected ) to close the ( at position 2"},
+		{`a )`, 2, "unexpected )"},
+		{`label:"on hold`, 6, "unterminated quote"},
+		{`x foo:bar`, 2, `unknown field "foo"`},
+		{`x completed:maybe`, 2, `completed takes true or false, got "maybe"`},
+		{`label:`, 0, "label needs a name"},
+		{`list:""`, 0, "list needs a name"},
+		{`due:soon`, 0, `due: invalid time "soon"`},
+		{`due:"none"`, 0, `due: invalid time "none"`},
+		{`due:<100001d`, 0, `due: invalid time "100001d"`},
+		{`created:<`, 0, `created: invalid time ""`},
+		{`due:7d`, 0, `due: "7d" needs an operator such as < or >=`},
+		{`updated:2024-05-01T10:00:00Z`, 0, `updated: "2024-05-01T10:00:00Z" needs an operator such as < or >=`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q): got %v, want a SyntaxError", tt.query, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("Parse(%q): got %q at %d, want %q at %d", tt.query, syntaxErr.Msg, syntaxErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestParseMaxLength(t *testing.T) {
+	if _, err := Parse(strings.Repeat("a", MaxLength)); err != nil {
+		t.Errorf("Parse of %d characters: %v", MaxLength, err)
+	}
+
+	_, err := Parse(strings.Repeat("(", MaxLength+1))
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Pos != MaxLength {
+		t.Fatalf("Parse of %d characters: got %v", MaxLength+1, err)
+	}
+	if want := "query error at position 1000: query is longer than 1000 characters"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	expr, err := Parse(`a OR NOT (label:x list:y)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	Walk(expr, func(e Expr) { got = append(got, fmt.Sprintf("%T", e)) })
+	want := "*taskquery.Or *taskquery.Title *taskquery.Not *taskquery.And *taskquery.Label *taskquery.List"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Walk visited %v, want %s", got, want)
+	}
+}
+
+func TestTimeResolve(t *testing.T) {
+	// Late on May 1st in New York is already May 2nd in UTC
+	now := time.Date(2024, 5, 1, 22, 30, 0, 0, time.FixedZone("EDT", -4*60*60))
+	absolute := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		time Time
+		want time.Time
+	}{
+		{Time{Relative: true, Offset: -12 * time.Hour}, now.Add(-12 * time.Hour)},
+		{Time{Day: true}, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{Time{Day: true, Days: -1}, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{Time{Absolute: absolute}, absolute},
+	}
+	for _, tt := range tests {
+		if got := tt.time.Resolve(now); !got.Equal(tt.want) {
+			t.Errorf("%+v.Resolve(now) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+}
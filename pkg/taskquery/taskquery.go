@@ -0,0 +1,143 @@
+// Package taskquery parses the query language of smart lists, such as
+//
+//	due:<7d AND label:work AND NOT completed
+//
+// into an expression tree. Terms next to each other are joined with AND;
+// AND binds tighter than OR, and parentheses group. The terms are:
+//
+//	completed, completed:true|false   completion
+//	overdue                           open tasks past their due date
+//	due:none                          tasks without a due date
+//	due:<op><time>, due:<day>         due date; also created: and updated:
+//	label:<name>, list:<name>         label or task list, by name
+//	title:<text>, <text>              title contains text, ignoring case
+//
+// where <op> is one of < <= > >=, <time> is a day, an RFC 3339 time or an
+// offset from now such as 7d, -12h or 2w, and <day> is today, tomorrow,
+// yesterday or a date such as 2024-05-01. A day without an operator matches
+// the whole day, in UTC. Values with spaces are quoted: label:"on hold".
+//
+// Storage backends translate the tree into their own queries. Label and List
+// hold names until the caller fills in the IDs they stand for.
+package taskquery
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Expr is a node of a parsed query.
+type Expr interface {
+	expr()
+}
+
+// And matches tasks matching both sides.
+type And struct{ Left, Right Expr }
+
+// Or matches tasks matching either side.
+type Or struct{ Left, Right Expr }
+
+// Not matches tasks that do not match Expr.
+type Not struct{ Expr Expr }
+
+// Completed matches tasks whose completion is Value.
+type Completed struct{ Value bool }
+
+// Overdue matches open tasks whose due date has passed.
+type Overdue struct{}
+
+// NoDueDate matches tasks without a due date.
+type NoDueDate struct{}
+
+// Title matches tasks whose title contains Text, ignoring case.
+type Title struct{ Text string }
+
+// Label matches tasks carrying any of IDs, the labels named Name. Without
+// IDs it matches nothing.
+type Label struct {
+	Name string
+	IDs  []primitive.ObjectID
+}
+
+// List matches tasks in any of IDs, the task lists named Name. Without IDs
+// it matches nothing.
+type List struct {
+	Name string
+	IDs  []primitive.ObjectID
+}
+
+// Fields a Compare can apply to. They match the storage field names.
+const (
+	FieldDueDate   = "due_date"
+	FieldCreatedAt = "created_at"
+	FieldUpdatedAt = "updated_at"
+)
+
+// Comparison operators.
+const (
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+)
+
+// Compare matches tasks whose Field compares to Time with Op. Comparisons of
+// FieldDueDate never match tasks without a due date.
+type Compare struct {
+	Field string
+	Op    string
+	Time  Time
+}
+
+func (*And) expr()       {}
+func (*Or) expr()        {}
+func (*Not) expr()       {}
+func (*Completed) expr() {}
+func (*Overdue) expr()   {}
+func (*NoDueDate) expr() {}
+func (*Title) expr()     {}
+func (*Label) expr()     {}
+func (*List) expr()      {}
+func (*Compare) expr()   {}
+
+// Time is a point in time that may be relative to when the query runs, so a
+// saved query keeps meaning the same thing.
+type Time struct {
+	// Absolute is used when the time is neither relative nor a day offset.
+	Absolute time.Time
+	// Relative makes the time now plus Offset.
+	Relative bool
+	Offset   time.Duration
+	// Day makes the time the start of today, in UTC, plus Days days.
+	Day  bool
+	Days int
+}
+
+// Resolve returns the time for a query running at now.
+func (t Time) Resolve(now time.Time) time.Time {
+	switch {
+	case t.Relative:
+		return now.Add(t.Offset)
+	case t.Day:
+		year, month, day := now.UTC().Date()
+		return time.Date(year, month, day+t.Days, 0, 0, 0, 0, time.UTC)
+	default:
+		return t.Absolute
+	}
+}
+
+// Walk calls fn for every node of expr, parents first.
+func Walk(expr Expr, fn func(Expr)) {
+	fn(expr)
+	switch e := expr.(type) {
+	case *And:
+		Walk(e.Left, fn)
+		Walk(e.Right, fn)
+	case *Or:
+		Walk(e.Left, fn)
+		Walk(e.Right, fn)
+	case *Not:
+		Walk(e.Expr, fn)
+	}
+}
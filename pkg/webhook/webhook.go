@@ -0,0 +1,125 @@
+// Package webhook posts signed event deliveries to the endpoints users
+// registered.
+//
+// Every delivery carries the headers below. The signature is "sha256="
+// followed by the hex HMAC-SHA256, keyed with the webhook's secret, of the
+// timestamp header, a dot and the body. Receivers recompute it to check the
+// delivery came from us, and reject old timestamps to stop replays.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"todo/pkg/safehttp"
+)
+
+// Headers set on every delivery.
+const (
+	HeaderEvent     = "X-Todo-Event"
+	HeaderDelivery  = "X-Todo-Delivery"
+	HeaderTimestamp = "X-Todo-Timestamp"
+	HeaderSignature = "X-Todo-Signature"
+)
+
+// MaxResponseBody bounds how much of an endpoint's answer is kept.
+const MaxResponseBody = 1024
+
+// NewSecret returns a random secret to sign a webhook's deliveries with.
+func NewSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(random), nil
+}
+
+// Sign returns the signature of body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the one of body sent at timestamp,
+// and timestamp is no further than tolerance from now.
+func Verify(secret, signature string, timestamp time.Time, body []byte, tolerance time.Duration) bool {
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// Delivery is a request to post.
+type Delivery struct {
+	ID     string
+	Event  string
+	URL    string
+	Secret string
+	Body   []byte
+}
+
+// Response is what an endpoint answered.
+type Response struct {
+	Status int
+	// Body is the start of the answer, up to MaxResponseBody bytes.
+	Body string
+}
+
+// Client posts deliveries.
+type Client struct {
+	client *http.Client
+}
+
+// NewClient returns a client giving each delivery timeout to complete. It
+// only connects to public addresses, see safehttp. Redirects are not
+// followed: a webhook that moved must be updated.
+func NewClient(timeout time.Duration) *Client {
+	client := safehttp.NewClient(timeout)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Client{client: client}
+}
+
+// Post signs and posts delivery. It fails unless the endpoint answered with
+// a 2xx status; the response is returned whenever there was one.
+func (c *Client) Post(ctx context.Context, delivery Delivery) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return Response{}, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, delivery.Body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBody))
+	// The body is stored as text, which must be valid UTF-8 without NULs
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(body), "\uFFFD"), "\x00", "\uFFFD")
+	response := Response{Status: resp.StatusCode, Body: text}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return response, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return response, nil
+}
@@ -2,32 +2,418 @@ package service
 
 import (
 	"context"
-	"todo/api/models" // Import the password package
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/config"
+	"todo/pkg"
+	"todo/pkg/check"
+	"todo/pkg/jwt"
 	"todo/pkg/password"
-	"todo/storage/mongodb"
+	"todo/pkg/rbac"
+	"todo/pkg/smtp"
+	"todo/storage"
+)
+
+const (
+	otpTTL         = 10 * time.Minute
+	otpMaxAttempts = 5
 )
 
 type AuthService interface {
-	Login(ctx context.Context, username, password string) (models.User, error)
+	Login(ctx context.Context, email, password, deviceID string) (models.AuthResponse, error)
+	Refresh(ctx context.Context, req models.RefreshRequest) (models.AuthResponse, error)
+	Register(ctx context.Context, req models.RegisterRequest) error
+	RegisterConfirm(ctx context.Context, req models.RegisterConfirm) (models.GetUser, error)
+	ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error
+	ChangePassword(ctx context.Context, req models.ChangePassword) error
+	// Authenticate checks an access token against the current state of its
+	// user and returns who the caller is, with the role they have now.
+	Authenticate(ctx context.Context, accessToken string) (models.AuthInfo, error)
 }
 
 type authService struct {
-	userRepo mongodb.UserRepo
+	userRepo         storage.UserStorage
+	tokenRepo        storage.TokenStorage
+	registrationRepo storage.RegistrationStorage
+	resetRepo        storage.PasswordResetStorage
+	mailer           *smtp.Mailer
 }
 
-func NewAuthService(userRepo mongodb.UserRepo) AuthService {
-	return &authService{userRepo: userRepo}
+func NewAuthService(userRepo storage.UserStorage, tokenRepo storage.TokenStorage, registrationRepo storage.RegistrationStorage, resetRepo storage.PasswordResetStorage, mailer *smtp.Mailer) AuthService {
+	return &authService{userRepo: userRepo, tokenRepo: tokenRepo, registrationRepo: registrationRepo, resetRepo: resetRepo, mailer: mailer}
 }
 
-func (s *authService) Login(ctx context.Context, username, password string) (models.User, error) {
-	user, err := s.userRepo.GetUser(ctx, username)
+func (s *authService) Login(ctx context.Context, email, pass, deviceID string) (models.AuthResponse, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return models.AuthResponse{}, models.ErrInvalidCredentials
+	}
+
+	if err := password.CompareHashAndPassword(user.Password, pass); err != nil {
+		return models.AuthResponse{}, models.ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return models.AuthResponse{}, models.ErrUserDisabled
+	}
+
+	// Clients without a device ID get a new one, so their sessions stay apart
+	if deviceID == "" {
+		deviceID = pkg.GenerateUUID()
+	}
+	// A fresh login replaces whatever session the device had before
+	if err := s.tokenRepo.RevokeDeviceTokens(ctx, user.ID.Hex(), deviceID); err != nil {
+		return models.AuthResponse{}, err
+	}
+
+	return s.issueTokens(ctx, user, pkg.GenerateUUID(), deviceID)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// can be used once; presenting one that was already rotated revokes every
+// token in its family, logging out both the attacker and the victim.
+func (s *authService) Refresh(ctx context.Context, req models.RefreshRequest) (models.AuthResponse, error) {
+	claims, err := jwt.ExtractClaims(req.RefreshToken)
+	if err != nil {
+		return models.AuthResponse{}, models.ErrInvalidRefreshToken
+	}
+	if typ, _ := claims["typ"].(string); typ != jwt.RefreshTokenType {
+		return models.AuthResponse{}, models.ErrInvalidRefreshToken
+	}
+
+	stored, err := s.tokenRepo.GetRefreshToken(ctx, hashToken(req.RefreshToken))
 	if err != nil {
-		return models.User{}, err
+		return models.AuthResponse{}, models.ErrInvalidRefreshToken
+	}
+	if stored.Revoked || stored.DeviceID != req.DeviceID {
+		return models.AuthResponse{}, models.ErrInvalidRefreshToken
+	}
+
+	marked, err := s.tokenRepo.MarkRefreshTokenUsed(ctx, stored.ID.Hex())
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+	if stored.Used || !marked {
+		if err := s.tokenRepo.RevokeTokenFamily(ctx, stored.FamilyID); err != nil {
+			return models.AuthResponse{}, err
+		}
+		return models.AuthResponse{}, models.ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.GetUser(ctx, stored.UserID.Hex())
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+	if user.Disabled {
+		return models.AuthResponse{}, models.ErrUserDisabled
+	}
+
+	return s.issueTokens(ctx, user, stored.FamilyID, stored.DeviceID)
+}
+
+// Register validates the signup, keeps it as a pending registration and
+// emails a one-time password that RegisterConfirm checks.
+func (s *authService) Register(ctx context.Context, req models.RegisterRequest) error {
+	if err := check.ValidateUsername(req.Username); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	if _, err := check.ValidateEmail(req.Email); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	if err := check.ValidatePassword(req.Password); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+
+	if _, err := s.userRepo.GetUserByEmail(ctx, req.Email); err == nil {
+		return models.ErrEmailTaken
+	}
+
+	passwordHash, err := password.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	otp, err := pkg.GenerateOTP()
+	if err != nil {
+		return err
+	}
+	otpHash, err := password.HashPassword(otp)
+	if err != nil {
+		return err
+	}
+
+	err = s.registrationRepo.UpsertPendingRegistration(ctx, models.PendingRegistration{
+		Username:     req.Username,
+		Email:        req.Email,
+		PasswordHash: passwordHash,
+		OtpHash:      otpHash,
+		ExpiresAt:    time.Now().Add(otpTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := smtp.Render("register", struct {
+		Code    string
+		Minutes int
+	}{otp, int(otpTTL.Minutes())}, req.Email)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// RegisterConfirm checks the emailed OTP and creates the user.
+func (s *authService) RegisterConfirm(ctx context.Context, req models.RegisterConfirm) (models.GetUser, error) {
+	reg, err := s.registrationRepo.GetPendingRegistration(ctx, req.Email)
+	// Email changes are confirmed by the user making them
+	if err != nil || reg.UserID != nil {
+		return models.GetUser{}, models.ErrInvalidOTP
+	}
+	if err := redeemOTP(ctx, s.registrationRepo, reg, req.Otp); err != nil {
+		return models.GetUser{}, err
+	}
+
+	role := rbac.RoleMember
+	if config.AdminEmail != "" && strings.EqualFold(reg.Email, config.AdminEmail) {
+		role = rbac.RoleAdmin
+	}
+
+	user, err := s.userRepo.CreateUser(ctx, models.CreateUser{
+		Username: reg.Username,
+		Email:    reg.Email,
+		Password: reg.PasswordHash,
+		Role:     role,
+	})
+	if err != nil {
+		return models.GetUser{}, err
+	}
+
+	if err := s.registrationRepo.DeletePendingRegistration(ctx, req.Email); err != nil {
+		return models.GetUser{}, err
+	}
+
+	return models.GetUser{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Role:     user.Role,
+	}, nil
+}
+
+// redeemOTP checks otp against a pending registration, using up one of its
+// attempts first so that guesses made in parallel count too. The registration
+// is dropped once it expires or runs out of attempts.
+func redeemOTP(ctx context.Context, repo storage.RegistrationStorage, reg models.PendingRegistration, otp string) error {
+	if time.Now().After(reg.ExpiresAt) {
+		if err := repo.DeletePendingRegistration(ctx, reg.Email); err != nil {
+			return err
+		}
+		return models.ErrInvalidOTP
+	}
+
+	ok, err := repo.IncrementRegistrationAttempts(ctx, reg.Email, otpMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := repo.DeletePendingRegistration(ctx, reg.Email); err != nil {
+			return err
+		}
+		return models.ErrTooManyOTPAttempts
 	}
 
-	if err :=  password.CompareHashAndPassword(user.Password, password); err != nil {
-		return models.User{}, models.ErrInvalidCredentials // Use your defined error constant
+	if err := password.CompareHashAndPassword(reg.OtpHash, otp); err != nil {
+		return models.ErrInvalidOTP
 	}
+	return nil
+}
+
+// ForgotPassword emails a password reset link. Unknown emails are silently
+// ignored so the endpoint does not reveal which addresses are registered.
+func (s *authService) ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := jwt.GenPasswordResetToken(user.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	err = s.resetRepo.CreatePasswordReset(ctx, models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(jwt.PasswordResetTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := config.AppURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg, err := smtp.Render("password_reset", struct {
+		Link    string
+		Minutes int
+	}{link, int(jwt.PasswordResetTokenTTL.Minutes())}, user.Email)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// ResetPassword sets a new password using a reset token and logs the user
+// out of every device by revoking their refresh tokens and bumping their
+// token version, which ends their access tokens.
+func (s *authService) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+	claims, err := jwt.ExtractClaims(req.Token)
+	if err != nil {
+		return models.ErrInvalidResetToken
+	}
+	if typ, _ := claims["typ"].(string); typ != jwt.PasswordResetTokenType {
+		return models.ErrInvalidResetToken
+	}
+
+	// Validate before redeeming so a weak password doesn't burn the token
+	if err := check.ValidatePassword(req.NewPassword); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+
+	reset, err := s.resetRepo.UsePasswordReset(ctx, hashToken(req.Token))
+	if err != nil {
+		return models.ErrInvalidResetToken
+	}
+
+	passwordHash, err := password.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, reset.UserID.Hex(), passwordHash); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.RevokeUserTokens(ctx, reset.UserID.Hex()); err != nil {
+		return err
+	}
+	return s.userRepo.BumpTokenVersion(ctx, reset.UserID.Hex())
+}
+
+// ChangePassword sets a new password for the user logging in with their
+// email and current password, and logs them out of every device like
+// ResetPassword does.
+func (s *authService) ChangePassword(ctx context.Context, req models.ChangePassword) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Login)
+	if err != nil {
+		return models.ErrInvalidCredentials
+	}
+	if err := password.CompareHashAndPassword(user.Password, req.OldPassword); err != nil {
+		return models.ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return models.ErrUserDisabled
+	}
+
+	if err := check.ValidatePassword(req.NewPassword); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	passwordHash, err := password.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID.Hex(), passwordHash); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.RevokeUserTokens(ctx, user.ID.Hex()); err != nil {
+		return err
+	}
+	return s.userRepo.BumpTokenVersion(ctx, user.ID.Hex())
+}
+
+// Authenticate verifies an access token and loads its user, so that disabling
+// a user, changing their role or ending their sessions takes effect on their
+// next request rather than when the token expires.
+func (s *authService) Authenticate(ctx context.Context, accessToken string) (models.AuthInfo, error) {
+	claims, err := jwt.ExtractClaims(accessToken)
+	if err != nil {
+		return models.AuthInfo{}, fmt.Errorf("%w: %v", models.ErrInvalidAccessToken, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != jwt.AccessTokenType {
+		return models.AuthInfo{}, fmt.Errorf("%w: not an access token", models.ErrInvalidAccessToken)
+	}
+	userID, _ := claims["user_id"].(string)
+	if userID == "" {
+		return models.AuthInfo{}, fmt.Errorf("%w: token has no user_id claim", models.ErrInvalidAccessToken)
+	}
+
+	user, err := s.userRepo.GetUser(ctx, userID)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return models.AuthInfo{}, models.ErrSessionEnded
+	}
+	if err != nil {
+		return models.AuthInfo{}, err
+	}
+	if user.Disabled {
+		return models.AuthInfo{}, models.ErrUserDisabled
+	}
+	// JSON numbers decode as float64
+	if version, _ := claims["ver"].(float64); int(version) != user.TokenVersion {
+		return models.AuthInfo{}, models.ErrSessionEnded
+	}
+
+	return models.AuthInfo{
+		UserID: userID,
+		Email:  user.Email,
+		Role:   user.Role,
+	}, nil
+}
+
+// issueTokens mints an access/refresh pair and records the refresh token.
+func (s *authService) issueTokens(ctx context.Context, user models.User, familyID, deviceID string) (models.AuthResponse, error) {
+	accessToken, refreshToken, err := jwt.GenJWT(map[interface{}]interface{}{
+		"user_id": user.ID.Hex(),
+		"email":   user.Email,
+		"role":    user.Role,
+		"ver":     user.TokenVersion,
+	})
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+
+	err = s.tokenRepo.CreateRefreshToken(ctx, models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		DeviceID:  deviceID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(jwt.RefreshTokenTTL),
+	})
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+
+	return models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		DeviceID:     deviceID,
+		User: models.GetUser{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     user.Role,
+		},
+	}, nil
+}
 
-	return user, nil
+// hashToken returns the hex encoded SHA-256 of a token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
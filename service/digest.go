@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/config"
+	"todo/pkg/jwt"
+	"todo/pkg/notify"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DigestService interface {
+	GetSettings(ctx context.Context, actor models.AuthInfo, userID string) (models.DigestSettings, error)
+	UpdateSettings(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateDigestSettings) (models.DigestSettings, error)
+	// Preview renders the digest the user would be sent now.
+	Preview(ctx context.Context, actor models.AuthInfo, userID string) (models.DigestPreview, error)
+	// Unsubscribe turns off the digests of the user an unsubscribe token was
+	// made for.
+	Unsubscribe(ctx context.Context, req models.UnsubscribeRequest) error
+}
+
+type digestService struct {
+	repo             storage.DigestStorage
+	notificationRepo storage.NotificationStorage
+	userRepo         storage.UserStorage
+	builder          digestBuilder
+}
+
+func NewDigestService(repo storage.DigestStorage, notificationRepo storage.NotificationStorage, userRepo storage.UserStorage, taskRepo storage.TaskStorage, taskListRepo storage.TaskListStorage) DigestService {
+	return &digestService{
+		repo:             repo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		builder:          digestBuilder{taskRepo: taskRepo, taskListRepo: taskListRepo},
+	}
+}
+
+// GetSettings returns the actor's own digest settings.
+func (ds *digestService) GetSettings(ctx context.Context, actor models.AuthInfo, userID string) (models.DigestSettings, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.DigestSettings{}, err
+	}
+	return ds.repo.GetDigestSettings(ctx, id)
+}
+
+// UpdateSettings replaces the actor's digest settings and schedules the next
+// digest. The time zone defaults to the one of the notification preferences.
+func (ds *digestService) UpdateSettings(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateDigestSettings) (models.DigestSettings, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.DigestSettings{}, err
+	}
+	req.UserID = id
+
+	defaults := models.DefaultDigestSettings(id)
+	if req.SendAt == "" {
+		req.SendAt = defaults.SendAt
+	}
+	if req.Weekday == "" {
+		req.Weekday = defaults.Weekday
+	}
+	if req.Timezone == "" {
+		preferences, err := ds.notificationRepo.GetNotificationPreferences(ctx, id)
+		if err != nil {
+			return models.DigestSettings{}, err
+		}
+		req.Timezone = preferences.Timezone
+	}
+	if err := checkDigestSettings(&req); err != nil {
+		return models.DigestSettings{}, err
+	}
+
+	settings := models.DigestSettings{Frequency: req.Frequency, SendAt: req.SendAt, Weekday: req.Weekday, Timezone: req.Timezone}
+	req.NextAt = settings.Next(time.Now())
+	return ds.repo.SaveDigestSettings(ctx, req)
+}
+
+func (ds *digestService) Preview(ctx context.Context, actor models.AuthInfo, userID string) (models.DigestPreview, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.DigestPreview{}, err
+	}
+	user, err := ds.userRepo.GetUser(ctx, id.Hex())
+	if err != nil {
+		return models.DigestPreview{}, err
+	}
+	settings, err := ds.repo.GetDigestSettings(ctx, id)
+	if err != nil {
+		return models.DigestPreview{}, err
+	}
+	// Users who have not turned the digest on yet see the daily one
+	if settings.Frequency == models.DigestOff {
+		settings.Frequency = models.DigestDaily
+	}
+
+	digest, err := ds.builder.build(ctx, user, settings, time.Now())
+	if err != nil {
+		return models.DigestPreview{}, err
+	}
+	msg, err := notify.Render("digest", digest)
+	if err != nil {
+		return models.DigestPreview{}, err
+	}
+	return models.DigestPreview{Subject: msg.Subject, Text: msg.Text, HTML: msg.HTML}, nil
+}
+
+// Unsubscribe keeps the send time of the digest, so turning it back on
+// brings it back as it was.
+func (ds *digestService) Unsubscribe(ctx context.Context, req models.UnsubscribeRequest) error {
+	claims, err := jwt.ExtractClaims(req.Token)
+	if err != nil {
+		return models.ErrInvalidUnsubscribe
+	}
+	if typ, _ := claims["typ"].(string); typ != jwt.UnsubscribeTokenType {
+		return models.ErrInvalidUnsubscribe
+	}
+	userID, _ := claims["user_id"].(string)
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrInvalidUnsubscribe
+	}
+	if _, err := ds.userRepo.GetUser(ctx, userID); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return models.ErrInvalidUnsubscribe
+		}
+		return err
+	}
+
+	settings, err := ds.repo.GetDigestSettings(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = ds.repo.SaveDigestSettings(ctx, models.UpdateDigestSettings{
+		UserID:    id,
+		Frequency: models.DigestOff,
+		SendAt:    settings.SendAt,
+		Weekday:   settings.Weekday,
+		Timezone:  settings.Timezone,
+	})
+	return err
+}
+
+// checkDigestSettings validates req and puts the send time and weekday in
+// canonical form.
+func checkDigestSettings(req *models.UpdateDigestSettings) error {
+	switch req.Frequency {
+	case models.DigestOff, models.DigestDaily, models.DigestWeekly:
+	default:
+		return fmt.Errorf("%w: frequency must be off, daily or weekly", models.ErrInvalidInput)
+	}
+
+	sendAt, err := time.Parse("15:04", req.SendAt)
+	if err != nil {
+		return fmt.Errorf("%w: send_at must be an HH:MM time", models.ErrInvalidInput)
+	}
+	req.SendAt = sendAt.Format("15:04")
+
+	req.Weekday = strings.ToLower(req.Weekday)
+	if !isWeekday(req.Weekday) {
+		return fmt.Errorf("%w: unknown weekday %q", models.ErrInvalidInput, req.Weekday)
+	}
+
+	if _, err := time.LoadLocation(req.Timezone); err != nil || strings.EqualFold(req.Timezone, "local") {
+		return fmt.Errorf("%w: unknown time zone %q", models.ErrInvalidInput, req.Timezone)
+	}
+	return nil
+}
+
+func isWeekday(weekday string) bool {
+	for _, known := range models.Weekdays {
+		if weekday == known {
+			return true
+		}
+	}
+	return false
+}
+
+// digestBuilder gathers the tasks of a user's digest.
+type digestBuilder struct {
+	taskRepo     storage.TaskStorage
+	taskListRepo storage.TaskListStorage
+}
+
+// build makes the digest of user at now. The day, or the week for weekly
+// digests, starts at midnight in the time zone of the settings: open tasks
+// due before it ends are listed, and tasks completed in the one before it.
+func (b digestBuilder) build(ctx context.Context, user models.User, settings models.DigestSettings, now time.Time) (models.Digest, error) {
+	loc := settings.Location()
+	local := now.In(loc)
+	days := 1
+	if settings.Frequency == models.DigestWeekly {
+		days = 7
+	}
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	periodEnd, previousStart := today.AddDate(0, 0, days), today.AddDate(0, 0, -days)
+
+	token, err := jwt.GenUnsubscribeToken(user.ID.Hex())
+	if err != nil {
+		return models.Digest{}, err
+	}
+	digest := models.Digest{
+		User:           user,
+		Frequency:      settings.Frequency,
+		Date:           local,
+		Lists:          []models.DigestList{},
+		UnsubscribeURL: config.AppURL + "/unsubscribe?token=" + url.QueryEscape(token),
+	}
+
+	taskLists, _, err := b.taskListRepo.GetAllTaskLists(ctx, user.ID.Hex(), models.Pagination{})
+	if err != nil || len(taskLists) == 0 {
+		return digest, err
+	}
+	taskListIDs := make([]primitive.ObjectID, 0, len(taskLists))
+	for _, taskList := range taskLists {
+		taskListIDs = append(taskListIDs, taskList.ID)
+	}
+
+	notCompleted := false
+	byDueDate := []models.SortKey{{Field: models.SortDueDate}}
+	due, _, err := b.taskRepo.GetAllTasks(ctx, models.TaskFilter{
+		TaskListIDs: taskListIDs, Completed: &notCompleted, DueBefore: &periodEnd, Sort: byDueDate,
+	}, models.Pagination{})
+	if err != nil {
+		return models.Digest{}, err
+	}
+	completed, _, err := b.taskRepo.GetAllTasks(ctx, models.TaskFilter{
+		TaskListIDs: taskListIDs, CompletedAfter: &previousStart, CompletedBefore: &today, Sort: byDueDate,
+	}, models.Pagination{})
+	if err != nil {
+		return models.Digest{}, err
+	}
+
+	lists := make(map[primitive.ObjectID]*models.DigestList, len(taskLists))
+	for _, taskList := range taskLists {
+		lists[taskList.ID] = &models.DigestList{TaskList: taskList}
+	}
+	for _, task := range due {
+		task.DueDate = task.DueDate.In(loc)
+		list := lists[task.TaskListID]
+		if task.DueDate.Before(now) {
+			list.Overdue = append(list.Overdue, task)
+		} else {
+			list.Due = append(list.Due, task)
+		}
+	}
+	for _, task := range completed {
+		task.DueDate = task.DueDate.In(loc)
+		list := lists[task.TaskListID]
+		list.Completed = append(list.Completed, task)
+	}
+
+	for _, taskList := range taskLists {
+		list := lists[taskList.ID]
+		if len(list.Overdue)+len(list.Due)+len(list.Completed) > 0 {
+			digest.Lists = append(digest.Lists, *list)
+		}
+	}
+	return digest, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"todo/pkg/notify"
+	"todo/storage"
+)
+
+// Settings of the digest scheduler.
+const (
+	// DigestBatchSize bounds the digests sent per poll; the rest wait for the
+	// next one.
+	DigestBatchSize = 100
+	// DigestGracePeriod is how long after its time a late digest is still
+	// sent, such as one that fell due while the server was down.
+	DigestGracePeriod = 2 * time.Hour
+)
+
+// DigestScheduler emails users the digest of their tasks at the time they
+// chose. Each digest is sent once: a failed one is not tried again, as the
+// next digest lists the same open tasks.
+type DigestScheduler struct {
+	digestRepo       storage.DigestStorage
+	userRepo         storage.UserStorage
+	notificationRepo storage.NotificationStorage
+	builder          digestBuilder
+	notifier         notify.Notifier
+	interval         time.Duration
+	log              logger.ILogger
+}
+
+// NewDigestScheduler returns a scheduler polling store every interval and
+// sending through notifier by email.
+func NewDigestScheduler(store *storage.Storage, notifier notify.Notifier, interval time.Duration, log logger.ILogger) *DigestScheduler {
+	return &DigestScheduler{
+		digestRepo:       store.DigestRepo,
+		userRepo:         store.UserRepo,
+		notificationRepo: store.NotificationRepo,
+		builder:          digestBuilder{taskRepo: store.TaskRepo, taskListRepo: store.TaskListRepo},
+		notifier:         notifier,
+		interval:         interval,
+		log:              log,
+	}
+}
+
+// Run polls for due digests until ctx is cancelled. A digest being sent when
+// that happens is finished and recorded before Run returns.
+func (s *DigestScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll sends the digests that are due and returns how many were sent.
+func (s *DigestScheduler) Poll(ctx context.Context) int {
+	due, err := s.digestRepo.DueDigests(ctx, time.Now(), DigestBatchSize)
+	if err != nil {
+		s.log.Error("Error polling digests", logger.Error(err))
+		return 0
+	}
+
+	sent := 0
+	for _, settings := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		// Once sent, a digest must be recorded even if we are shutting down
+		if s.deliver(context.WithoutCancel(ctx), settings) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// deliver sends one digest, or skips it when it is not wanted or empty, and
+// schedules the next. It reports whether the digest was sent.
+func (s *DigestScheduler) deliver(ctx context.Context, settings models.DigestSettings) bool {
+	userID := settings.UserID.Hex()
+	now := time.Now()
+	next := settings.Next(now)
+	if next == nil {
+		// Settings are checked when they are saved
+		s.log.Error("Error scheduling digest", logger.String("user_id", userID), logger.String("send_at", settings.SendAt))
+		return false
+	}
+	delivery := models.DigestDelivery{UserID: settings.UserID, ScheduledAt: *settings.NextAt, NextAt: *next}
+
+	user, err := s.userRepo.GetUser(ctx, userID)
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		s.log.Error("Error loading user of digest", logger.String("user_id", userID), logger.Error(err))
+		return false
+	}
+	userMissing := err != nil
+	notificationSettings, err := s.notificationRepo.GetNotificationSettings(ctx, settings.UserID)
+	if err != nil {
+		s.log.Error("Error loading notification settings of digest", logger.String("user_id", userID), logger.Error(err))
+		return false
+	}
+	preferences, err := s.notificationRepo.GetNotificationPreferences(ctx, settings.UserID)
+	if err != nil {
+		s.log.Error("Error loading notification preferences of digest", logger.String("user_id", userID), logger.Error(err))
+		return false
+	}
+	to := recipient(user, notificationSettings)
+	// The digest is an email, whatever else the user gets notified over
+	to.Channels = []string{}
+	for _, channel := range preferences.EventChannels(models.EventDigest, notificationSettings.Channels) {
+		if channel == notify.ChannelEmail {
+			to.Channels = append(to.Channels, channel)
+		}
+	}
+	quietUntil, quiet := preferences.QuietUntil(now)
+
+	switch {
+	case userMissing, user.Disabled, now.Sub(delivery.ScheduledAt) > DigestGracePeriod, len(to.Channels) == 0:
+	case quiet:
+		// The digest waits until the quiet hours are over
+		delivery.NextAt = quietUntil
+	default:
+		digest, err := s.builder.build(ctx, user, settings, now)
+		if err != nil {
+			s.log.Error("Error building digest", logger.String("user_id", userID), logger.Error(err))
+			return false
+		}
+		if digest.Empty() {
+			break
+		}
+		if err := s.send(ctx, to, digest); err != nil {
+			s.log.Warning("Error sending digest", logger.String("user_id", userID), logger.Error(err))
+			break
+		}
+		delivery.SentAt = &now
+	}
+
+	err = s.digestRepo.RecordDigest(ctx, delivery)
+	if err != nil && !errors.Is(err, models.ErrDigestNotFound) {
+		s.log.Error("Error recording digest", logger.String("user_id", userID), logger.Error(err))
+	}
+	return delivery.SentAt != nil
+}
+
+// send renders digest and emails it to to.
+func (s *DigestScheduler) send(ctx context.Context, to notify.Recipient, digest models.Digest) error {
+	msg, err := notify.Render("digest", digest)
+	if err != nil {
+		return err
+	}
+	return s.notifier.Notify(ctx, to, msg)
+}
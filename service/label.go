@@ -3,41 +3,70 @@ package service
 import (
 	"context"
 	"todo/api/models"
-	"todo/storage/mongodb"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type LabelService interface {
-	CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error)
-	GetLabelByID(ctx context.Context, id string) (models.Label, error)
-	UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error)
-	DeleteLabel(ctx context.Context, id string) error
-	ListLabels(ctx context.Context, page, limit uint64) ([]models.Label, int64, error)
+	CreateLabel(ctx context.Context, actor models.AuthInfo, req models.CreateLabel) (models.Label, error)
+	GetLabelByID(ctx context.Context, actor models.AuthInfo, id string) (models.Label, error)
+	UpdateLabel(ctx context.Context, actor models.AuthInfo, req models.UpdateLabel) (models.Label, error)
+	DeleteLabel(ctx context.Context, actor models.AuthInfo, id string) error
+	ListLabels(ctx context.Context, actor models.AuthInfo, search string, page models.Pagination) ([]models.Label, models.PageInfo, error)
 }
 
 type labelService struct {
-	repo mongodb.LabelRepo
+	repo storage.LabelStorage
 }
 
-func NewLabelService(repo mongodb.LabelRepo) LabelService {
+func NewLabelService(repo storage.LabelStorage) LabelService {
 	return &labelService{repo: repo}
 }
 
-func (ls *labelService) CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error) {
+func (ls *labelService) CreateLabel(ctx context.Context, actor models.AuthInfo, req models.CreateLabel) (models.Label, error) {
+	userID, err := primitive.ObjectIDFromHex(actor.UserID)
+	if err != nil {
+		return models.Label{}, models.ErrUserNotFound
+	}
+	req.UserID = userID
+
 	return ls.repo.CreateLabel(ctx, req)
 }
 
-func (ls *labelService) GetLabelByID(ctx context.Context, id string) (models.Label, error) {
-	return ls.repo.GetLabel(ctx, id)
+func (ls *labelService) GetLabelByID(ctx context.Context, actor models.AuthInfo, id string) (models.Label, error) {
+	return ls.ownedLabel(ctx, actor, id)
 }
 
-func (ls *labelService) UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error) {
+func (ls *labelService) UpdateLabel(ctx context.Context, actor models.AuthInfo, req models.UpdateLabel) (models.Label, error) {
+	if _, err := ls.ownedLabel(ctx, actor, req.ID.Hex()); err != nil {
+		return models.Label{}, err
+	}
 	return ls.repo.UpdateLabel(ctx, req)
 }
 
-func (ls *labelService) DeleteLabel(ctx context.Context, id string) error {
+func (ls *labelService) DeleteLabel(ctx context.Context, actor models.AuthInfo, id string) error {
+	if _, err := ls.ownedLabel(ctx, actor, id); err != nil {
+		return err
+	}
 	return ls.repo.DeleteLabel(ctx, id)
 }
 
-func (ls *labelService) ListLabels(ctx context.Context, page, limit uint64) ([]models.Label, int64, error) {
-	return ls.repo.GetAllLabels(ctx, page, limit)
+func (ls *labelService) ListLabels(ctx context.Context, actor models.AuthInfo, search string, page models.Pagination) ([]models.Label, models.PageInfo, error) {
+	return listPage(page, func(page models.Pagination) ([]models.Label, int64, error) {
+		return ls.repo.GetAllLabels(ctx, actor.UserID, search, page)
+	}, models.Label.Cursor)
+}
+
+// ownedLabel loads a label and checks that actor owns it. Labels of other
+// users are reported as not found.
+func (ls *labelService) ownedLabel(ctx context.Context, actor models.AuthInfo, id string) (models.Label, error) {
+	label, err := ls.repo.GetLabel(ctx, id)
+	if err != nil {
+		return models.Label{}, err
+	}
+	if label.UserID.Hex() != actor.UserID {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+	return label, nil
 }
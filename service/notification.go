@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/notify"
+	"todo/pkg/safehttp"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxWebhookURLLength bounds the webhook URL a user can set.
+const MaxWebhookURLLength = 2048
+
+// telegramChatIDPattern matches numeric chat IDs, negative for groups, and
+// the @username of public channels.
+var telegramChatIDPattern = regexp.MustCompile(`^(-?[0-9]{1,20}|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+type NotificationService interface {
+	GetSettings(ctx context.Context, actor models.AuthInfo, userID string) (models.NotificationSettings, error)
+	UpdateSettings(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateNotificationSettings) (models.NotificationSettings, error)
+	// SendTest sends a test message over every channel the user enabled.
+	SendTest(ctx context.Context, actor models.AuthInfo, userID string) error
+	GetPreferences(ctx context.Context, actor models.AuthInfo, userID string) (models.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error)
+}
+
+type notificationService struct {
+	repo       storage.NotificationStorage
+	userRepo   storage.UserStorage
+	dispatcher *notify.Dispatcher
+}
+
+func NewNotificationService(repo storage.NotificationStorage, userRepo storage.UserStorage, dispatcher *notify.Dispatcher) NotificationService {
+	return &notificationService{repo: repo, userRepo: userRepo, dispatcher: dispatcher}
+}
+
+// GetSettings returns the actor's own settings. Other users look the same as
+// missing ones.
+func (ns *notificationService) GetSettings(ctx context.Context, actor models.AuthInfo, userID string) (models.NotificationSettings, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.NotificationSettings{}, err
+	}
+	return ns.repo.GetNotificationSettings(ctx, id)
+}
+
+// UpdateSettings replaces the actor's settings. Enabling Telegram needs the
+// chat to send to, and enabling webhooks the URL to post to.
+func (ns *notificationService) UpdateSettings(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateNotificationSettings) (models.NotificationSettings, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.NotificationSettings{}, err
+	}
+	req.UserID = id
+
+	if err := ns.checkSettings(req); err != nil {
+		return models.NotificationSettings{}, err
+	}
+	return ns.repo.SaveNotificationSettings(ctx, req)
+}
+
+func (ns *notificationService) SendTest(ctx context.Context, actor models.AuthInfo, userID string) error {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return err
+	}
+	user, err := ns.userRepo.GetUser(ctx, id.Hex())
+	if err != nil {
+		return err
+	}
+	settings, err := ns.repo.GetNotificationSettings(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(settings.Channels) == 0 {
+		return fmt.Errorf("%w: no notification channel is enabled", models.ErrInvalidInput)
+	}
+
+	msg, err := notify.Render("test", user)
+	if err != nil {
+		return err
+	}
+	if err := ns.dispatcher.Notify(ctx, recipient(user, settings), msg); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrNotificationFailed, err)
+	}
+	return nil
+}
+
+// GetPreferences returns the actor's own preferences.
+func (ns *notificationService) GetPreferences(ctx context.Context, actor models.AuthInfo, userID string) (models.NotificationPreferences, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	return ns.repo.GetNotificationPreferences(ctx, id)
+}
+
+// UpdatePreferences replaces the actor's preferences. The time zone
+// defaults to UTC.
+func (ns *notificationService) UpdatePreferences(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	req.UserID = id
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if err := ns.checkPreferences(req); err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	return ns.repo.SaveNotificationPreferences(ctx, req)
+}
+
+func (ns *notificationService) checkSettings(req models.UpdateNotificationSettings) error {
+	seen := map[string]bool{}
+	for _, channel := range req.Channels {
+		if !isChannel(channel) {
+			return fmt.Errorf("%w: unknown channel %q", models.ErrInvalidInput, channel)
+		}
+		if seen[channel] {
+			return fmt.Errorf("%w: channel %q is listed twice", models.ErrInvalidInput, channel)
+		}
+		seen[channel] = true
+		if !ns.dispatcher.Available(channel) {
+			return fmt.Errorf("%w: %s notifications are not available on this server", models.ErrInvalidInput, channel)
+		}
+	}
+
+	if req.TelegramChatID != "" && !telegramChatIDPattern.MatchString(req.TelegramChatID) {
+		return fmt.Errorf("%w: telegram_chat_id must be a numeric chat ID or a @channel name", models.ErrInvalidInput)
+	}
+	if seen[notify.ChannelTelegram] && req.TelegramChatID == "" {
+		return fmt.Errorf("%w: link a Telegram chat to enable Telegram notifications", models.ErrInvalidInput)
+	}
+
+	if req.WebhookURL != "" {
+		if err := checkWebhookURL("webhook_url", req.WebhookURL); err != nil {
+			return err
+		}
+	}
+	if seen[notify.ChannelWebhook] && req.WebhookURL == "" {
+		return fmt.Errorf("%w: set a webhook_url to enable webhook notifications", models.ErrInvalidInput)
+	}
+	return nil
+}
+
+func (ns *notificationService) checkPreferences(req models.UpdateNotificationPreferences) error {
+	for event, channels := range req.Events {
+		if !isEvent(event) {
+			return fmt.Errorf("%w: unknown event %q", models.ErrInvalidInput, event)
+		}
+		seen := map[string]bool{}
+		for _, channel := range channels {
+			if !isChannel(channel) {
+				return fmt.Errorf("%w: unknown channel %q for %s", models.ErrInvalidInput, channel, event)
+			}
+			if seen[channel] {
+				return fmt.Errorf("%w: channel %q is listed twice for %s", models.ErrInvalidInput, channel, event)
+			}
+			seen[channel] = true
+		}
+	}
+
+	if _, err := time.LoadLocation(req.Timezone); err != nil || strings.EqualFold(req.Timezone, "local") {
+		return fmt.Errorf("%w: unknown time zone %q", models.ErrInvalidInput, req.Timezone)
+	}
+
+	if quiet := req.QuietHours; quiet != nil {
+		start, err := time.Parse("15:04", quiet.Start)
+		if err != nil {
+			return fmt.Errorf("%w: quiet hours start at an HH:MM time", models.ErrInvalidInput)
+		}
+		end, err := time.Parse("15:04", quiet.End)
+		if err != nil {
+			return fmt.Errorf("%w: quiet hours end at an HH:MM time", models.ErrInvalidInput)
+		}
+		if start.Equal(end) {
+			return fmt.Errorf("%w: quiet hours cannot start and end at the same time", models.ErrInvalidInput)
+		}
+		// Stored in canonical form, so "7:00" and "07:00" are the same
+		quiet.Start, quiet.End = start.Format("15:04"), end.Format("15:04")
+	}
+	return nil
+}
+
+// checkWebhookURL validates the URL in field that events are posted to.
+func checkWebhookURL(field, raw string) error {
+	if len(raw) > MaxWebhookURLLength {
+		return fmt.Errorf("%w: %s is longer than %d characters", models.ErrInvalidInput, field, MaxWebhookURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s must be an http or https URL", models.ErrInvalidInput, field)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: %s must not contain credentials", models.ErrInvalidInput, field)
+	}
+	// Names are checked once they resolve, when connecting; this only turns
+	// away the URLs that can never work
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); (err == nil && !safehttp.IsPublic(addr)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("%w: %s must point to a public address", models.ErrInvalidInput, field)
+	}
+	return nil
+}
+
+func isEvent(event string) bool {
+	for _, known := range models.NotificationEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+func isChannel(channel string) bool {
+	for _, known := range notify.Channels {
+		if channel == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ownUserID parses userID, which must be the actor's own.
+func ownUserID(actor models.AuthInfo, userID string) (primitive.ObjectID, error) {
+	if actor.UserID != userID {
+		return primitive.NilObjectID, models.ErrUserNotFound
+	}
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, models.ErrUserNotFound
+	}
+	return id, nil
+}
+
+// recipient is where the notifications of user go.
+func recipient(user models.User, settings models.NotificationSettings) notify.Recipient {
+	return notify.Recipient{
+		Channels:       settings.Channels,
+		Email:          user.Email,
+		TelegramChatID: settings.TelegramChatID,
+		WebhookURL:     settings.WebhookURL,
+	}
+}
+
+// delivered reports whether a message reached the user despite err, over at
+// least one of their channels.
+func delivered(err error) bool {
+	var sendErr *notify.SendError
+	return err == nil || (errors.As(err, &sendErr) && sendErr.Partial())
+}
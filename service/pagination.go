@@ -0,0 +1,24 @@
+package service
+
+import "todo/api/models"
+
+// listPage asks list for one item more than the page holds, so a cursor to
+// the next page is only handed out when there is one.
+func listPage[T any](page models.Pagination, list func(models.Pagination) ([]T, int64, error), cursor func(T) models.Cursor) ([]T, models.PageInfo, error) {
+	query := page
+	if query.Limit > 0 {
+		query.Limit++
+	}
+
+	items, count, err := list(query)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	info := models.PageInfo{TotalCount: count}
+	if page.Limit > 0 && uint64(len(items)) > page.Limit {
+		items = items[:page.Limit]
+		info.NextCursor = cursor(items[len(items)-1]).Encode()
+	}
+	return items, info, nil
+}
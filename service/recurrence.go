@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/recurrence"
+)
+
+// Bounds of the number of occurrences previewed.
+const (
+	DefaultOccurrences = 5
+	MaxOccurrences     = 100
+)
+
+// SetRecurrence makes a task repeat by a rule, starting a new series at its
+// due date.
+func (ts *taskService) SetRecurrence(ctx context.Context, actor models.AuthInfo, taskID string, req models.SetRecurrence) (models.Task, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	rec, err := newRecurrence(req.Rule, req.Timezone, task.DueDate)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task, err = ts.repo.SetTaskRecurrence(ctx, taskID, rec)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
+}
+
+// EndRecurrence ends the series of a task. The task itself is kept.
+func (ts *taskService) EndRecurrence(ctx context.Context, actor models.AuthInfo, taskID string) (models.Task, error) {
+	if _, err := ts.ownedTask(ctx, actor, taskID); err != nil {
+		return models.Task{}, err
+	}
+	task, err := ts.repo.SetTaskRecurrence(ctx, taskID, nil)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
+}
+
+// SkipOccurrence moves a recurring task on to its next occurrence without
+// completing it.
+func (ts *taskService) SkipOccurrence(ctx context.Context, actor models.AuthInfo, taskID string) (models.Task, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	rule, err := recurrenceRule(task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	next, ok := rule.After(occurrenceTime(task))
+	if !ok {
+		return models.Task{}, fmt.Errorf("%w: the series has no further occurrences", models.ErrInvalidInput)
+	}
+
+	updated, err := ts.repo.UpdateTask(ctx, models.UpdateTask{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		DueDate:     next,
+		Completed:   task.Completed,
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := ts.rescheduleReminders(ctx, task, updated); err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, updated)
+}
+
+// PreviewOccurrences lists the due dates of the next occurrences of a
+// recurring task, in its time zone.
+func (ts *taskService) PreviewOccurrences(ctx context.Context, actor models.AuthInfo, taskID string, count int) (models.Occurrences, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return models.Occurrences{}, err
+	}
+	rule, err := recurrenceRule(task)
+	if err != nil {
+		return models.Occurrences{}, err
+	}
+
+	if count <= 0 {
+		count = DefaultOccurrences
+	}
+	count = min(count, MaxOccurrences)
+	return models.Occurrences{Occurrences: rule.Next(occurrenceTime(task), count)}, nil
+}
+
+// createNextOccurrence creates the occurrence following a completed task,
+// with its title, description, labels, reminders and an open copy of its
+// checklist. The series moves on to the new task, so completing the old one
+// again does not create another. It returns the completed task.
+func (ts *taskService) createNextOccurrence(ctx context.Context, actor models.AuthInfo, task models.Task) (models.Task, error) {
+	rule, err := recurrenceRule(task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if next, ok := rule.After(occurrenceTime(task)); ok {
+		created, err := ts.repo.CreateTask(ctx, models.CreateTask{
+			TaskListID:  task.TaskListID,
+			ParentID:    task.ParentID,
+			Title:       task.Title,
+			Description: task.Description,
+			DueDate:     next,
+			LabelIDs:    task.LabelIDs,
+			Recurrence:  task.Recurrence,
+		})
+		if err != nil {
+			return models.Task{}, err
+		}
+		for _, item := range task.Checklist {
+			if _, err := ts.repo.AddChecklistItem(ctx, created.ID.Hex(), models.CreateChecklistItem{Title: item.Title}); err != nil {
+				return models.Task{}, err
+			}
+		}
+		if err := ts.copyReminders(ctx, task, created); err != nil {
+			return models.Task{}, err
+		}
+		created, err = ts.repo.GetTask(ctx, created.ID.Hex())
+		if err != nil {
+			return models.Task{}, err
+		}
+		if _, err := ts.publish(ctx, actor, models.WebhookTaskCreated, created); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return ts.repo.SetTaskRecurrence(ctx, task.ID.Hex(), nil)
+}
+
+// newRecurrence validates a rule for a series starting at start and returns
+// the recurrence to store, with the rule in canonical form.
+func newRecurrence(rule, timezone string, start time.Time) (*models.Recurrence, error) {
+	if start.IsZero() {
+		return nil, fmt.Errorf("%w: a recurring task needs a due date", models.ErrInvalidInput)
+	}
+	parsed, err := recurrence.Parse(rule, timezone, start)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	return &models.Recurrence{Rule: parsed.String(), Timezone: timezone, Start: start}, nil
+}
+
+// recurrenceRule parses the rule of a recurring task.
+func recurrenceRule(task models.Task) (*recurrence.Rule, error) {
+	if task.Recurrence == nil {
+		return nil, fmt.Errorf("%w: the task does not repeat", models.ErrInvalidInput)
+	}
+	return recurrence.Parse(task.Recurrence.Rule, task.Recurrence.Timezone, task.Recurrence.Start)
+}
+
+// occurrenceTime is the time the next occurrence of a task follows: its due
+// date, or now once the due date has been cleared.
+func occurrenceTime(task models.Task) time.Time {
+	if task.DueDate.IsZero() {
+		return time.Now()
+	}
+	return task.DueDate
+}
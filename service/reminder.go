@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bounds of the reminders of a task.
+const (
+	MaxReminders = 10
+	// MaxReminderBefore is the earliest a reminder may fire, four weeks
+	// before the due date, in minutes.
+	MaxReminderBefore = 4 * 7 * 24 * 60
+)
+
+// GetReminders lists the reminders of a task, the earliest first.
+func (ts *taskService) GetReminders(ctx context.Context, actor models.AuthInfo, taskID string) ([]models.Reminder, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return ts.reminderRepo.GetReminders(ctx, task.ID)
+}
+
+// SetReminders replaces the reminders of a task. Reminders can be set before
+// the task has a due date; they fire once it gets one.
+func (ts *taskService) SetReminders(ctx context.Context, actor models.AuthInfo, taskID string, req models.SetReminders) ([]models.Reminder, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := primitive.ObjectIDFromHex(actor.UserID)
+	if err != nil {
+		return nil, models.ErrUserNotFound
+	}
+
+	if len(req.Before) > MaxReminders {
+		return nil, fmt.Errorf("%w: a task has at most %d reminders", models.ErrInvalidInput, MaxReminders)
+	}
+	seen := map[int]bool{}
+	for _, before := range req.Before {
+		if before < 0 || before > MaxReminderBefore {
+			return nil, fmt.Errorf("%w: reminders fire 0 to %d minutes before the due date", models.ErrInvalidInput, MaxReminderBefore)
+		}
+		if seen[before] {
+			return nil, fmt.Errorf("%w: %d minutes is listed twice", models.ErrInvalidInput, before)
+		}
+		seen[before] = true
+	}
+
+	return ts.reminderRepo.SetReminders(ctx, userID, task.ID, req.Before, task.DueDate)
+}
+
+// rescheduleReminders moves the reminders of a task after its due date
+// changed.
+func (ts *taskService) rescheduleReminders(ctx context.Context, old, task models.Task) error {
+	if task.DueDate.Equal(old.DueDate) {
+		return nil
+	}
+	return ts.reminderRepo.RescheduleReminders(ctx, task.ID, task.DueDate)
+}
+
+// copyReminders gives a new occurrence of a recurring task the reminders of
+// the one before it.
+func (ts *taskService) copyReminders(ctx context.Context, from, to models.Task) error {
+	reminders, err := ts.reminderRepo.GetReminders(ctx, from.ID)
+	if err != nil || len(reminders) == 0 {
+		return err
+	}
+	before := make([]int, 0, len(reminders))
+	for _, reminder := range reminders {
+		before = append(before, reminder.Before)
+	}
+	_, err = ts.reminderRepo.SetReminders(ctx, reminders[0].UserID, to.ID, before, to.DueDate)
+	return err
+}
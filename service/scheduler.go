@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"todo/pkg/notify"
+	"todo/storage"
+)
+
+// Settings of the reminder scheduler.
+const (
+	// ReminderBatchSize bounds the reminders sent per poll; the rest wait for
+	// the next one.
+	ReminderBatchSize = 100
+	// MaxReminderAttempts is how often a reminder is tried, once per poll,
+	// before it is marked failed.
+	MaxReminderAttempts = 5
+	// ReminderGracePeriod is how long after the due date a late reminder is
+	// still sent, such as one that fell due while the server was down.
+	ReminderGracePeriod = time.Hour
+)
+
+// ReminderScheduler sends task reminders as they fall due. Delivery is
+// recorded on each reminder, so a restart does not send them again.
+type ReminderScheduler struct {
+	reminderRepo     storage.ReminderStorage
+	taskRepo         storage.TaskStorage
+	userRepo         storage.UserStorage
+	notificationRepo storage.NotificationStorage
+	notifier         notify.Notifier
+	interval         time.Duration
+	log              logger.ILogger
+}
+
+// NewReminderScheduler returns a scheduler polling store every interval and
+// sending through notifier over the channels each user enabled.
+func NewReminderScheduler(store *storage.Storage, notifier notify.Notifier, interval time.Duration, log logger.ILogger) *ReminderScheduler {
+	return &ReminderScheduler{
+		reminderRepo:     store.ReminderRepo,
+		taskRepo:         store.TaskRepo,
+		userRepo:         store.UserRepo,
+		notificationRepo: store.NotificationRepo,
+		notifier:         notifier,
+		interval:         interval,
+		log:              log,
+	}
+}
+
+// Run polls for due reminders until ctx is cancelled. A reminder being sent
+// when that happens is finished and recorded before Run returns.
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll sends the reminders that are due and returns how many were sent.
+func (s *ReminderScheduler) Poll(ctx context.Context) int {
+	reminders, err := s.reminderRepo.DueReminders(ctx, time.Now(), ReminderBatchSize)
+	if err != nil {
+		s.log.Error("Error polling reminders", logger.Error(err))
+		return 0
+	}
+
+	sent := 0
+	for _, reminder := range reminders {
+		if ctx.Err() != nil {
+			break
+		}
+		// Once sent, a reminder must be recorded even if we are shutting down
+		if s.deliver(context.WithoutCancel(ctx), reminder) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// deliver sends one reminder, or skips it when it is no longer wanted, and
+// records the outcome. It reports whether the reminder was sent.
+func (s *ReminderScheduler) deliver(ctx context.Context, reminder models.Reminder) bool {
+	task, err := s.taskRepo.GetTask(ctx, reminder.TaskID.Hex())
+	if errors.Is(err, models.ErrTaskNotFound) {
+		// Backends without foreign keys leave the reminders of deleted tasks
+		if err := s.reminderRepo.DeleteReminders(ctx, reminder.TaskID); err != nil {
+			s.log.Error("Error deleting reminders of a deleted task", logger.String("task_id", reminder.TaskID.Hex()), logger.Error(err))
+		}
+		return false
+	}
+	if err != nil {
+		s.log.Error("Error loading task of reminder", logger.String("reminder_id", reminder.ID.Hex()), logger.Error(err))
+		return false
+	}
+
+	delivery := models.ReminderDelivery{ID: reminder.ID, RemindAt: *reminder.RemindAt, Attempts: reminder.Attempts}
+	user, err := s.userRepo.GetUser(ctx, reminder.UserID.Hex())
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		s.log.Error("Error loading user of reminder", logger.String("reminder_id", reminder.ID.Hex()), logger.Error(err))
+		return false
+	}
+	userMissing := err != nil
+	settings, err := s.notificationRepo.GetNotificationSettings(ctx, reminder.UserID)
+	if err != nil {
+		s.log.Error("Error loading notification settings of reminder", logger.String("reminder_id", reminder.ID.Hex()), logger.Error(err))
+		return false
+	}
+	preferences, err := s.notificationRepo.GetNotificationPreferences(ctx, reminder.UserID)
+	if err != nil {
+		s.log.Error("Error loading notification preferences of reminder", logger.String("reminder_id", reminder.ID.Hex()), logger.Error(err))
+		return false
+	}
+	to := recipient(user, settings)
+	to.Channels = preferences.EventChannels(models.EventReminder, settings.Channels)
+	quietUntil, quiet := preferences.QuietUntil(time.Now())
+
+	switch {
+	case userMissing, user.Disabled, task.Completed, time.Since(task.DueDate) > ReminderGracePeriod, len(to.Channels) == 0:
+		delivery.Status = models.ReminderSkipped
+	case quiet && task.DueDate.After(quietUntil):
+		// The task can wait until the quiet hours are over; ones due before
+		// that are urgent and sent anyway
+		delivery.Status = models.ReminderPending
+		delivery.LastError = reminder.LastError
+		delivery.DeferredTo = &quietUntil
+	default:
+		delivery.Attempts++
+		err := s.send(ctx, to, task)
+		if err != nil {
+			s.log.Warning("Error sending reminder", logger.String("reminder_id", reminder.ID.Hex()), logger.Error(err))
+			delivery.LastError = err.Error()
+		}
+		// Reaching some of the channels counts as sent; trying again would
+		// repeat the reminder on them
+		if delivered(err) {
+			now := time.Now()
+			delivery.Status = models.ReminderSent
+			delivery.SentAt = &now
+		} else {
+			delivery.Status = models.ReminderPending
+			if delivery.Attempts >= MaxReminderAttempts {
+				delivery.Status = models.ReminderFailed
+			}
+		}
+	}
+
+	err = s.reminderRepo.RecordDelivery(ctx, delivery)
+	if err != nil && !errors.Is(err, models.ErrReminderNotFound) {
+		s.log.Error("Error recording reminder delivery", logger.String("reminder_id", reminder.ID.Hex()), logger.Error(err))
+	}
+	return delivery.Status == models.ReminderSent
+}
+
+// send renders the reminder of task and sends it to to.
+func (s *ReminderScheduler) send(ctx context.Context, to notify.Recipient, task models.Task) error {
+	msg, err := notify.Render("reminder", task)
+	if err != nil {
+		return err
+	}
+	return s.notifier.Notify(ctx, to, msg)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"todo/api/models"
+	"todo/storage"
+	"unicode/utf8"
+)
+
+// Bounds of the number of search results.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// snippetLength is roughly how many characters of text a snippet shows.
+const snippetLength = 160
+
+type SearchService interface {
+	// Search finds the actor's tasks, task lists and labels of the given
+	// types, all of them when types is empty, best matches first.
+	Search(ctx context.Context, actor models.AuthInfo, search string, types []string, limit int64) ([]models.SearchResult, error)
+}
+
+type searchService struct {
+	repo storage.SearchStorage
+}
+
+func NewSearchService(repo storage.SearchStorage) SearchService {
+	return &searchService{repo: repo}
+}
+
+func (ss *searchService) Search(ctx context.Context, actor models.AuthInfo, search string, types []string, limit int64) ([]models.SearchResult, error) {
+	terms := models.SearchTerms(search)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: search needs at least one word", models.ErrInvalidInput)
+	}
+
+	if len(types) == 0 {
+		types = models.SearchTypes
+	}
+	for _, typ := range types {
+		if typeRank(typ) < 0 {
+			return nil, fmt.Errorf("%w: cannot search for %q", models.ErrInvalidInput, typ)
+		}
+	}
+
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	results, err := ss.repo.Search(ctx, actor.UserID, models.SearchQuery{Terms: terms, Types: types, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return typeRank(results[i].Type) < typeRank(results[j].Type)
+	})
+	if int64(len(results)) > limit {
+		results = results[:limit]
+	}
+
+	highlight := termsRegexp(terms)
+	for i := range results {
+		results[i].Snippet = snippet(results[i], highlight)
+	}
+	return results, nil
+}
+
+func typeRank(typ string) int {
+	for i, known := range models.SearchTypes {
+		if typ == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// termsRegexp matches any of the terms, ignoring case. The terms are quoted,
+// so they are never read as patterns.
+func termsRegexp(terms []string) *regexp.Regexp {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// snippet cuts the part of a result's body around its first match, or shows
+// the title when the body does not match, and marks every match in it.
+func snippet(result models.SearchResult, highlight *regexp.Regexp) string {
+	text := result.Title
+	if match := highlight.FindStringIndex(result.Body); match != nil {
+		text = excerpt(result.Body, match[0])
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range highlight.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:match[0]]))
+		b.WriteString("<mark>" + html.EscapeString(text[match[0]:match[1]]) + "</mark>")
+		last = match[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// excerpt returns about snippetLength characters of text, starting a little
+// before the byte offset at and at a word boundary when possible.
+func excerpt(text string, at int) string {
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+
+	start := 0
+	if at > snippetLength/4 {
+		start = at - snippetLength/4
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		if space := strings.IndexByte(text[start:at], ' '); space >= 0 {
+			start += space + 1
+		}
+	}
+
+	end := len(text)
+	if rest := text[start:]; utf8.RuneCountInString(rest) > snippetLength {
+		end = start
+		for i := 0; i < snippetLength; i++ {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+		if space := strings.LastIndexByte(text[at:end], ' '); space >= 0 {
+			end = at + space
+		}
+	}
+
+	s := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) {
+		s += "…"
+	}
+	return s
+}
@@ -1,23 +1,41 @@
 package service
 
 import (
-	"todo/storage/mongodb"
+	"todo/pkg/logger"
+	"todo/pkg/notify"
+	"todo/pkg/smtp"
+	"todo/storage"
 )
 
 type Service struct {
-	AuthService     AuthService
-	UserService     UserService
-	TaskService     TaskService
-	TaskListService TaskListService
-	LabelService    LabelService
+	AuthService         AuthService
+	UserService         UserService
+	TaskService         TaskService
+	TaskListService     TaskListService
+	LabelService        LabelService
+	SmartListService    SmartListService
+	SearchService       SearchService
+	NotificationService NotificationService
+	DigestService       DigestService
+	WebhookService      WebhookService
 }
 
-func NewService(userRepo mongodb.UserRepo, taskRepo mongodb.TaskRepo, taskListRepo mongodb.TaskListRepo, labelRepo mongodb.LabelRepo) *Service {
+// NewService builds the services on top of store, sending notifications
+// through dispatcher and account emails through mailer. Task and task list
+// changes are queued for the users' webhooks.
+func NewService(store *storage.Storage, dispatcher *notify.Dispatcher, mailer *smtp.Mailer) *Service {
+	webhooks := NewWebhookPublisher(store.WebhookRepo, logger.New("webhooks"))
+	taskService := NewTaskService(store.TaskRepo, store.TaskListRepo, store.LabelRepo, store.ReminderRepo, webhooks)
 	return &Service{
-		AuthService:     NewAuthService(userRepo),
-		UserService:     NewUserService(userRepo),
-		TaskService:     NewTaskService(taskRepo),
-		TaskListService: NewTaskListService(taskListRepo),
-		LabelService:    NewLabelService(labelRepo),
+		AuthService:         NewAuthService(store.UserRepo, store.TokenRepo, store.RegistrationRepo, store.PasswordResetRepo, mailer),
+		UserService:         NewUserService(store.UserRepo, store.TokenRepo, store.RegistrationRepo, mailer),
+		TaskService:         taskService,
+		TaskListService:     NewTaskListService(store.TaskListRepo, webhooks),
+		LabelService:        NewLabelService(store.LabelRepo),
+		SmartListService:    NewSmartListService(store.SmartListRepo, taskService),
+		SearchService:       NewSearchService(store.SearchRepo),
+		NotificationService: NewNotificationService(store.NotificationRepo, store.UserRepo, dispatcher),
+		DigestService:       NewDigestService(store.DigestRepo, store.NotificationRepo, store.UserRepo, store.TaskRepo, store.TaskListRepo),
+		WebhookService:      NewWebhookService(store.WebhookRepo),
 	}
 }
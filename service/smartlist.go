@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"todo/api/models"
+	"todo/pkg/taskquery"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SmartListService interface {
+	CreateSmartList(ctx context.Context, actor models.AuthInfo, req models.CreateSmartList) (models.SmartList, error)
+	GetSmartListByID(ctx context.Context, actor models.AuthInfo, id string) (models.SmartList, error)
+	UpdateSmartList(ctx context.Context, actor models.AuthInfo, req models.UpdateSmartList) (models.SmartList, error)
+	DeleteSmartList(ctx context.Context, actor models.AuthInfo, id string) error
+	ListSmartLists(ctx context.Context, actor models.AuthInfo, page models.Pagination) ([]models.SmartList, models.PageInfo, error)
+	ListSmartListTasks(ctx context.Context, actor models.AuthInfo, id string, page models.Pagination) ([]models.Task, models.PageInfo, error)
+}
+
+type smartListService struct {
+	repo        storage.SmartListStorage
+	taskService TaskService
+}
+
+func NewSmartListService(repo storage.SmartListStorage, taskService TaskService) SmartListService {
+	return &smartListService{repo: repo, taskService: taskService}
+}
+
+func (sls *smartListService) CreateSmartList(ctx context.Context, actor models.AuthInfo, req models.CreateSmartList) (models.SmartList, error) {
+	userID, err := primitive.ObjectIDFromHex(actor.UserID)
+	if err != nil {
+		return models.SmartList{}, models.ErrUserNotFound
+	}
+	req.UserID = userID
+
+	if req.Sort, err = checkSmartList(req.Query, req.Sort); err != nil {
+		return models.SmartList{}, err
+	}
+	return sls.repo.CreateSmartList(ctx, req)
+}
+
+func (sls *smartListService) GetSmartListByID(ctx context.Context, actor models.AuthInfo, id string) (models.SmartList, error) {
+	return sls.ownedSmartList(ctx, actor, id)
+}
+
+func (sls *smartListService) UpdateSmartList(ctx context.Context, actor models.AuthInfo, req models.UpdateSmartList) (models.SmartList, error) {
+	if _, err := sls.ownedSmartList(ctx, actor, req.ID.Hex()); err != nil {
+		return models.SmartList{}, err
+	}
+
+	var err error
+	if req.Sort, err = checkSmartList(req.Query, req.Sort); err != nil {
+		return models.SmartList{}, err
+	}
+	return sls.repo.UpdateSmartList(ctx, req)
+}
+
+func (sls *smartListService) DeleteSmartList(ctx context.Context, actor models.AuthInfo, id string) error {
+	if _, err := sls.ownedSmartList(ctx, actor, id); err != nil {
+		return err
+	}
+	return sls.repo.DeleteSmartList(ctx, id)
+}
+
+func (sls *smartListService) ListSmartLists(ctx context.Context, actor models.AuthInfo, page models.Pagination) ([]models.SmartList, models.PageInfo, error) {
+	return listPage(page, func(page models.Pagination) ([]models.SmartList, int64, error) {
+		return sls.repo.GetAllSmartLists(ctx, actor.UserID, page)
+	}, models.SmartList.Cursor)
+}
+
+// ListSmartListTasks evaluates a smart list over all of the actor's task
+// lists, in the smart list's order.
+func (sls *smartListService) ListSmartListTasks(ctx context.Context, actor models.AuthInfo, id string, page models.Pagination) ([]models.Task, models.PageInfo, error) {
+	smartList, err := sls.ownedSmartList(ctx, actor, id)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	query, err := taskquery.Parse(smartList.Query)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	sort, err := models.ParseTaskSort(smartList.Sort)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	return sls.taskService.ListTasks(ctx, actor, models.TaskFilter{Query: query, Sort: sort}, page)
+}
+
+// ownedSmartList loads a smart list and checks that actor owns it. Smart
+// lists of other users are reported as not found.
+func (sls *smartListService) ownedSmartList(ctx context.Context, actor models.AuthInfo, id string) (models.SmartList, error) {
+	smartList, err := sls.repo.GetSmartList(ctx, id)
+	if err != nil {
+		return models.SmartList{}, err
+	}
+	if smartList.UserID.Hex() != actor.UserID {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+	return smartList, nil
+}
+
+// checkSmartList makes sure a smart list's query and sort parse, so a saved
+// list can always be evaluated, and returns the sort in its canonical form.
+func checkSmartList(query, sort string) (string, error) {
+	if _, err := taskquery.Parse(query); err != nil {
+		return "", fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	keys, err := models.ParseTaskSort(sort)
+	if err != nil {
+		return "", err
+	}
+	return models.FormatSort(keys), nil
+}
@@ -2,42 +2,437 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"todo/api/models"
-	"todo/storage/mongodb"
+	"todo/pkg/taskquery"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TaskService interface {
-	CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error)
-	GetTaskByID(ctx context.Context, id string) (models.Task, error)
-	UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error)
-	DeleteTask(ctx context.Context, id string) error
-	ListTasks(ctx context.Context, taskListID string, page, limit uint64) ([]models.Task, int64, error)
+	CreateTask(ctx context.Context, actor models.AuthInfo, req models.CreateTask) (models.Task, error)
+	GetTaskByID(ctx context.Context, actor models.AuthInfo, id string) (models.Task, error)
+	UpdateTask(ctx context.Context, actor models.AuthInfo, req models.UpdateTask) (models.Task, error)
+	DeleteTask(ctx context.Context, actor models.AuthInfo, id string) error
+	ListTasks(ctx context.Context, actor models.AuthInfo, filter models.TaskFilter, page models.Pagination) ([]models.Task, models.PageInfo, error)
+	AddTaskLabels(ctx context.Context, actor models.AuthInfo, taskID string, labelIDs []primitive.ObjectID) (models.Task, error)
+	RemoveTaskLabels(ctx context.Context, actor models.AuthInfo, taskID string, labelIDs []primitive.ObjectID) (models.Task, error)
+	ListSubtasks(ctx context.Context, actor models.AuthInfo, taskID string, page models.Pagination) ([]models.Task, models.PageInfo, error)
+	AddChecklistItem(ctx context.Context, actor models.AuthInfo, taskID string, req models.CreateChecklistItem) (models.Task, error)
+	UpdateChecklistItem(ctx context.Context, actor models.AuthInfo, taskID string, req models.UpdateChecklistItem) (models.Task, error)
+	DeleteChecklistItem(ctx context.Context, actor models.AuthInfo, taskID string, itemID primitive.ObjectID) (models.Task, error)
+	ReorderChecklist(ctx context.Context, actor models.AuthInfo, taskID string, itemIDs []primitive.ObjectID) (models.Task, error)
+	SetRecurrence(ctx context.Context, actor models.AuthInfo, taskID string, req models.SetRecurrence) (models.Task, error)
+	EndRecurrence(ctx context.Context, actor models.AuthInfo, taskID string) (models.Task, error)
+	SkipOccurrence(ctx context.Context, actor models.AuthInfo, taskID string) (models.Task, error)
+	PreviewOccurrences(ctx context.Context, actor models.AuthInfo, taskID string, count int) (models.Occurrences, error)
+	GetReminders(ctx context.Context, actor models.AuthInfo, taskID string) ([]models.Reminder, error)
+	SetReminders(ctx context.Context, actor models.AuthInfo, taskID string, req models.SetReminders) ([]models.Reminder, error)
 }
 
 type taskService struct {
-	repo mongodb.TaskRepo
+	repo         storage.TaskStorage
+	taskListRepo storage.TaskListStorage
+	labelRepo    storage.LabelStorage
+	reminderRepo storage.ReminderStorage
+	webhooks     *WebhookPublisher
+}
+
+func NewTaskService(repo storage.TaskStorage, taskListRepo storage.TaskListStorage, labelRepo storage.LabelStorage, reminderRepo storage.ReminderStorage, webhooks *WebhookPublisher) TaskService {
+	return &taskService{repo: repo, taskListRepo: taskListRepo, labelRepo: labelRepo, reminderRepo: reminderRepo, webhooks: webhooks}
+}
+
+func (ts *taskService) CreateTask(ctx context.Context, actor models.AuthInfo, req models.CreateTask) (models.Task, error) {
+	if req.ParentID != nil {
+		if err := ts.checkParent(ctx, actor, &req); err != nil {
+			return models.Task{}, err
+		}
+	}
+	if _, err := ownedTaskList(ctx, ts.taskListRepo, actor, req.TaskListID.Hex()); err != nil {
+		return models.Task{}, err
+	}
+	if err := ts.checkLabels(ctx, actor, req.LabelIDs); err != nil {
+		return models.Task{}, err
+	}
+	if req.Recurrence != nil {
+		recurrence, err := newRecurrence(req.Recurrence.Rule, req.Recurrence.Timezone, req.DueDate)
+		if err != nil {
+			return models.Task{}, err
+		}
+		req.Recurrence = recurrence
+	}
+	task, err := ts.repo.CreateTask(ctx, req)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskCreated, task)
+}
+
+func (ts *taskService) GetTaskByID(ctx context.Context, actor models.AuthInfo, id string) (models.Task, error) {
+	task, err := ts.ownedTask(ctx, actor, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.withProgress(ctx, task)
+}
+
+// UpdateTask updates a task. Completing it with CompleteSubtasks set also
+// completes its subtasks, and completing a recurring task creates its next
+// occurrence. Moving the due date moves the reminders with it. Webhooks get
+// task.completed when the task is completed, and task.updated otherwise.
+func (ts *taskService) UpdateTask(ctx context.Context, actor models.AuthInfo, req models.UpdateTask) (models.Task, error) {
+	old, err := ts.ownedTask(ctx, actor, req.ID.Hex())
+	if err != nil {
+		return models.Task{}, err
+	}
+	task, err := ts.repo.UpdateTask(ctx, req)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := ts.rescheduleReminders(ctx, old, task); err != nil {
+		return models.Task{}, err
+	}
+	if req.Completed && req.CompleteSubtasks {
+		if err := ts.repo.CompleteSubtasks(ctx, task.ID); err != nil {
+			return models.Task{}, err
+		}
+	}
+	completed := req.Completed && !old.Completed
+	if completed && task.Recurrence != nil {
+		if task, err = ts.createNextOccurrence(ctx, actor, task); err != nil {
+			return models.Task{}, err
+		}
+	}
+	if completed {
+		return ts.publish(ctx, actor, models.WebhookTaskCompleted, task)
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
+}
+
+// DeleteTask deletes a task with its subtasks. Webhooks get task.deleted with
+// the task as it was; its subtasks go without an event of their own.
+func (ts *taskService) DeleteTask(ctx context.Context, actor models.AuthInfo, id string) error {
+	task, err := ts.ownedTask(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if task, err = ts.withProgress(ctx, task); err != nil {
+		return err
+	}
+	if err := ts.repo.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+	ts.webhooks.Publish(ctx, actor.UserID, models.WebhookTaskDeleted, task)
+	return nil
+}
+
+// ListTasks lists the tasks matching filter. Without task lists in the filter
+// it covers every list of the actor; lists of other users are not found.
+func (ts *taskService) ListTasks(ctx context.Context, actor models.AuthInfo, filter models.TaskFilter, page models.Pagination) ([]models.Task, models.PageInfo, error) {
+	if filter.Labels.Match != "" && filter.Labels.Match != models.LabelMatchAny && filter.Labels.Match != models.LabelMatchAll {
+		return nil, models.PageInfo{}, models.ErrInvalidInput
+	}
+	// A cursor only makes sense in the order it was handed out for
+	if page.After != nil && page.After.Sort != models.FormatSort(filter.Sort) {
+		return nil, models.PageInfo{}, models.ErrInvalidCursor
+	}
+
+	if len(filter.TaskListIDs) == 0 {
+		taskLists, _, err := ts.taskListRepo.GetAllTaskLists(ctx, actor.UserID, models.Pagination{})
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		if len(taskLists) == 0 {
+			return []models.Task{}, models.PageInfo{}, nil
+		}
+		for _, taskList := range taskLists {
+			filter.TaskListIDs = append(filter.TaskListIDs, taskList.ID)
+		}
+	} else {
+		for _, id := range filter.TaskListIDs {
+			if _, err := ownedTaskList(ctx, ts.taskListRepo, actor, id.Hex()); err != nil {
+				return nil, models.PageInfo{}, err
+			}
+		}
+	}
+
+	if filter.Query != nil {
+		if err := ts.resolveQuery(ctx, actor, filter.Query); err != nil {
+			return nil, models.PageInfo{}, err
+		}
+	}
+
+	tasks, info, err := listPage(page, func(page models.Pagination) ([]models.Task, int64, error) {
+		return ts.repo.GetAllTasks(ctx, filter, page)
+	}, func(task models.Task) models.Cursor {
+		return task.SortCursor(filter.Sort)
+	})
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	if err := ts.fillProgress(ctx, tasks); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	return tasks, info, nil
+}
+
+// ListSubtasks lists the subtasks of a task, oldest first.
+func (ts *taskService) ListSubtasks(ctx context.Context, actor models.AuthInfo, taskID string, page models.Pagination) ([]models.Task, models.PageInfo, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	filter := models.TaskFilter{TaskListIDs: []primitive.ObjectID{task.TaskListID}, ParentID: &task.ID}
+	return ts.ListTasks(ctx, actor, filter, page)
+}
+
+func (ts *taskService) AddTaskLabels(ctx context.Context, actor models.AuthInfo, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	if _, err := ts.ownedTask(ctx, actor, taskID); err != nil {
+		return models.Task{}, err
+	}
+	if err := ts.checkLabels(ctx, actor, labelIDs); err != nil {
+		return models.Task{}, err
+	}
+	task, err := ts.repo.AddTaskLabels(ctx, taskID, labelIDs)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
+}
+
+func (ts *taskService) RemoveTaskLabels(ctx context.Context, actor models.AuthInfo, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	if _, err := ts.ownedTask(ctx, actor, taskID); err != nil {
+		return models.Task{}, err
+	}
+	task, err := ts.repo.RemoveTaskLabels(ctx, taskID, labelIDs)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
+}
+
+func (ts *taskService) AddChecklistItem(ctx context.Context, actor models.AuthInfo, taskID string, req models.CreateChecklistItem) (models.Task, error) {
+	task, err := ts.ownedTask(ctx, actor, taskID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return models.Task{}, fmt.Errorf("%w: a checklist item needs a title", models.ErrInvalidInput)
+	}
+	if len(task.Checklist) >= models.MaxChecklistItems {
+		return models.Task{}, fmt.Errorf("%w: a checklist holds at most %d items", models.ErrInvalidInput, models.MaxChecklistItems)
+	}
+	task, err = ts.repo.AddChecklistItem(ctx, taskID, req)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
 }
 
-func NewTaskService(repo mongodb.TaskRepo) TaskService {
-	return &taskService{repo: repo}
+// UpdateChecklistItem renames or toggles a checklist item.
+func (ts *taskService) UpdateChecklistItem(ctx context.Context, actor models.AuthInfo, taskID string, req models.UpdateChecklistItem) (models.Task, error) {
+	if _, err := ts.ownedTask(ctx, actor, taskID); err != nil {
+		return models.Task{}, err
+	}
+	if req.Title == nil && req.Done == nil {
+		return models.Task{}, fmt.Errorf("%w: nothing to update", models.ErrInvalidInput)
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return models.Task{}, fmt.Errorf("%w: a checklist item needs a title", models.ErrInvalidInput)
+		}
+		req.Title = &title
+	}
+	task, err := ts.repo.UpdateChecklistItem(ctx, taskID, req)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
 }
 
-func (ts *taskService) CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error) {
-	return ts.repo.CreateTask(ctx, req)
+func (ts *taskService) DeleteChecklistItem(ctx context.Context, actor models.AuthInfo, taskID string, itemID primitive.ObjectID) (models.Task, error) {
+	if _, err := ts.ownedTask(ctx, actor, taskID); err != nil {
+		return models.Task{}, err
+	}
+	task, err := ts.repo.DeleteChecklistItem(ctx, taskID, itemID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
 }
 
-func (ts *taskService) GetTaskByID(ctx context.Context, id string) (models.Task, error) {
-	return ts.repo.GetTask(ctx, id)
+// ReorderChecklist puts the checklist of a task in the order of itemIDs,
+// which must list every item exactly once.
+func (ts *taskService) ReorderChecklist(ctx context.Context, actor models.AuthInfo, taskID string, itemIDs []primitive.ObjectID) (models.Task, error) {
+	if _, err := ts.ownedTask(ctx, actor, taskID); err != nil {
+		return models.Task{}, err
+	}
+	task, err := ts.repo.ReorderChecklist(ctx, taskID, itemIDs)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return ts.publish(ctx, actor, models.WebhookTaskUpdated, task)
 }
 
-func (ts *taskService) UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error) {
-	return ts.repo.UpdateTask(ctx, req)
+// checkParent makes sure the parent of a new subtask belongs to actor and is
+// not a subtask itself, and puts the subtask in the list of its parent.
+func (ts *taskService) checkParent(ctx context.Context, actor models.AuthInfo, req *models.CreateTask) error {
+	parent, err := ts.ownedTask(ctx, actor, req.ParentID.Hex())
+	if errors.Is(err, models.ErrTaskNotFound) {
+		return fmt.Errorf("%w: parent task not found", models.ErrInvalidInput)
+	}
+	if err != nil {
+		return err
+	}
+
+	if parent.ParentID != nil {
+		return fmt.Errorf("%w: a subtask cannot have subtasks", models.ErrInvalidInput)
+	}
+	if req.TaskListID.IsZero() {
+		req.TaskListID = parent.TaskListID
+	}
+	if req.TaskListID != parent.TaskListID {
+		return fmt.Errorf("%w: a subtask must be in the task list of its parent", models.ErrInvalidInput)
+	}
+	return nil
+}
+
+// publish fills in the progress of a task and queues event about it for the
+// actor's webhooks.
+func (ts *taskService) publish(ctx context.Context, actor models.AuthInfo, event string, task models.Task) (models.Task, error) {
+	task, err := ts.withProgress(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	ts.webhooks.Publish(ctx, actor.UserID, event, task)
+	return task, nil
 }
 
-func (ts *taskService) DeleteTask(ctx context.Context, id string) error {
-	return ts.repo.DeleteTask(ctx, id)
+// withProgress returns a task with its progress filled in.
+func (ts *taskService) withProgress(ctx context.Context, task models.Task) (models.Task, error) {
+	tasks := []models.Task{task}
+	if err := ts.fillProgress(ctx, tasks); err != nil {
+		return models.Task{}, err
+	}
+	return tasks[0], nil
 }
 
-func (ts *taskService) ListTasks(ctx context.Context, taskListID string, page, limit uint64) ([]models.Task, int64, error) {
-	return ts.repo.GetAllTasks(ctx, taskListID, page, limit)
+// fillProgress fills in the progress of tasks from their checklists and
+// subtasks. Tasks read without labels or a checklist get empty ones.
+func (ts *taskService) fillProgress(ctx context.Context, tasks []models.Task) error {
+	parentIDs := []primitive.ObjectID{}
+	for _, task := range tasks {
+		if task.ParentID == nil {
+			parentIDs = append(parentIDs, task.ID)
+		}
+	}
+
+	counts := map[primitive.ObjectID]models.SubtaskCount{}
+	if len(parentIDs) > 0 {
+		var err error
+		if counts, err = ts.repo.CountSubtasks(ctx, parentIDs); err != nil {
+			return err
+		}
+	}
+
+	for i := range tasks {
+		if tasks[i].LabelIDs == nil {
+			tasks[i].LabelIDs = []primitive.ObjectID{}
+		}
+		if tasks[i].Checklist == nil {
+			tasks[i].Checklist = []models.ChecklistItem{}
+		}
+		tasks[i].Progress = models.NewTaskProgress(tasks[i].Checklist, counts[tasks[i].ID])
+	}
+	return nil
+}
+
+// resolveQuery fills in the IDs of the labels and task lists a query names,
+// among those of the actor. Names are matched ignoring case; a name matching
+// nothing leaves the term matching no task.
+func (ts *taskService) resolveQuery(ctx context.Context, actor models.AuthInfo, query taskquery.Expr) error {
+	needLabels, needLists := false, false
+	taskquery.Walk(query, func(expr taskquery.Expr) {
+		switch expr.(type) {
+		case *taskquery.Label:
+			needLabels = true
+		case *taskquery.List:
+			needLists = true
+		}
+	})
+
+	var labels []models.Label
+	var taskLists []models.TaskList
+	var err error
+	if needLabels {
+		if labels, _, err = ts.labelRepo.GetAllLabels(ctx, actor.UserID, "", models.Pagination{}); err != nil {
+			return err
+		}
+	}
+	if needLists {
+		if taskLists, _, err = ts.taskListRepo.GetAllTaskLists(ctx, actor.UserID, models.Pagination{}); err != nil {
+			return err
+		}
+	}
+
+	taskquery.Walk(query, func(expr taskquery.Expr) {
+		switch e := expr.(type) {
+		case *taskquery.Label:
+			e.IDs = nil
+			for _, label := range labels {
+				if strings.EqualFold(label.Name, e.Name) {
+					e.IDs = append(e.IDs, label.ID)
+				}
+			}
+		case *taskquery.List:
+			e.IDs = nil
+			for _, taskList := range taskLists {
+				if strings.EqualFold(taskList.Title, e.Name) {
+					e.IDs = append(e.IDs, taskList.ID)
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// checkLabels makes sure every label exists and belongs to actor, so a task
+// never ends up carrying somebody else's label.
+func (ts *taskService) checkLabels(ctx context.Context, actor models.AuthInfo, labelIDs []primitive.ObjectID) error {
+	for _, id := range labelIDs {
+		label, err := ts.labelRepo.GetLabel(ctx, id.Hex())
+		if errors.Is(err, models.ErrLabelNotFound) {
+			return models.ErrInvalidLabel
+		}
+		if err != nil {
+			return err
+		}
+		if label.UserID.Hex() != actor.UserID {
+			return models.ErrInvalidLabel
+		}
+	}
+	return nil
+}
+
+// ownedTask loads a task and checks that actor owns the task list it is in.
+// Tasks of other users are reported as not found.
+func (ts *taskService) ownedTask(ctx context.Context, actor models.AuthInfo, id string) (models.Task, error) {
+	task, err := ts.repo.GetTask(ctx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	_, err = ownedTaskList(ctx, ts.taskListRepo, actor, task.TaskListID.Hex())
+	if errors.Is(err, models.ErrTaskListNotFound) {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
 }
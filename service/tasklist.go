@@ -3,41 +3,92 @@ package service
 import (
 	"context"
 	"todo/api/models"
-	"todo/storage/mongodb"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TaskListService interface {
-	CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error)
-	GetTaskListByID(ctx context.Context, id string) (models.TaskList, error)
-	UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error)
-	DeleteTaskList(ctx context.Context, id string) error
-	ListTaskLists(ctx context.Context, userID string, page, limit uint64) ([]models.TaskList, int64, error)
+	CreateTaskList(ctx context.Context, actor models.AuthInfo, req models.CreateTaskList) (models.TaskList, error)
+	GetTaskListByID(ctx context.Context, actor models.AuthInfo, id string) (models.TaskList, error)
+	UpdateTaskList(ctx context.Context, actor models.AuthInfo, req models.UpdateTaskList) (models.TaskList, error)
+	DeleteTaskList(ctx context.Context, actor models.AuthInfo, id string, dryRun bool) (models.DeleteSummary, error)
+	ListTaskLists(ctx context.Context, actor models.AuthInfo, page models.Pagination) ([]models.TaskList, models.PageInfo, error)
 }
 
 type taskListService struct {
-	repo mongodb.TaskListRepo
+	repo     storage.TaskListStorage
+	webhooks *WebhookPublisher
+}
+
+func NewTaskListService(repo storage.TaskListStorage, webhooks *WebhookPublisher) TaskListService {
+	return &taskListService{repo: repo, webhooks: webhooks}
 }
 
-func NewTaskListService(repo mongodb.TaskListRepo) TaskListService {
-	return &taskListService{repo: repo}
+func (tls *taskListService) CreateTaskList(ctx context.Context, actor models.AuthInfo, req models.CreateTaskList) (models.TaskList, error) {
+	userID, err := primitive.ObjectIDFromHex(actor.UserID)
+	if err != nil {
+		return models.TaskList{}, models.ErrUserNotFound
+	}
+	req.UserID = userID
+
+	taskList, err := tls.repo.CreateTaskList(ctx, req)
+	if err != nil {
+		return models.TaskList{}, err
+	}
+	tls.webhooks.Publish(ctx, actor.UserID, models.WebhookTaskListCreated, taskList)
+	return taskList, nil
 }
 
-func (tls *taskListService) CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error) {
-	return tls.repo.CreateTaskList(ctx, req)
+func (tls *taskListService) GetTaskListByID(ctx context.Context, actor models.AuthInfo, id string) (models.TaskList, error) {
+	return ownedTaskList(ctx, tls.repo, actor, id)
 }
 
-func (tls *taskListService) GetTaskListByID(ctx context.Context, id string) (models.TaskList, error) {
-	return tls.repo.GetTaskList(ctx, id)
+func (tls *taskListService) UpdateTaskList(ctx context.Context, actor models.AuthInfo, req models.UpdateTaskList) (models.TaskList, error) {
+	if _, err := ownedTaskList(ctx, tls.repo, actor, req.ID.Hex()); err != nil {
+		return models.TaskList{}, err
+	}
+	taskList, err := tls.repo.UpdateTaskList(ctx, req)
+	if err != nil {
+		return models.TaskList{}, err
+	}
+	tls.webhooks.Publish(ctx, actor.UserID, models.WebhookTaskListUpdated, taskList)
+	return taskList, nil
 }
 
-func (tls *taskListService) UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error) {
-	return tls.repo.UpdateTaskList(ctx, req)
+// DeleteTaskList deletes a task list with its tasks. Webhooks get
+// task_list.deleted with the list as it was, unless it is a dry run; its
+// tasks go without events of their own.
+func (tls *taskListService) DeleteTaskList(ctx context.Context, actor models.AuthInfo, id string, dryRun bool) (models.DeleteSummary, error) {
+	taskList, err := ownedTaskList(ctx, tls.repo, actor, id)
+	if err != nil {
+		return models.DeleteSummary{}, err
+	}
+	summary, err := tls.repo.DeleteTaskList(ctx, id, dryRun)
+	if err != nil {
+		return models.DeleteSummary{}, err
+	}
+	if !dryRun {
+		tls.webhooks.Publish(ctx, actor.UserID, models.WebhookTaskListDeleted, taskList)
+	}
+	return summary, nil
 }
 
-func (tls *taskListService) DeleteTaskList(ctx context.Context, id string) error {
-	return tls.repo.DeleteTaskList(ctx, id)
+func (tls *taskListService) ListTaskLists(ctx context.Context, actor models.AuthInfo, page models.Pagination) ([]models.TaskList, models.PageInfo, error) {
+	return listPage(page, func(page models.Pagination) ([]models.TaskList, int64, error) {
+		return tls.repo.GetAllTaskLists(ctx, actor.UserID, page)
+	}, models.TaskList.Cursor)
 }
 
-func (tls *taskListService) ListTaskLists(ctx context.Context, userID string, page, limit uint64) ([]models.TaskList, int64, error) {
-	return tls.repo.GetAllTaskLists(ctx, userID, page, limit)
+// ownedTaskList loads a task list and checks that actor owns it. Lists of
+// other users are reported as not found so their existence doesn't leak.
+func ownedTaskList(ctx context.Context, repo storage.TaskListStorage, actor models.AuthInfo, id string) (models.TaskList, error) {
+	taskList, err := repo.GetTaskList(ctx, id)
+	if err != nil {
+		return models.TaskList{}, err
+	}
+	if taskList.UserID.Hex() != actor.UserID {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+	return taskList, nil
 }
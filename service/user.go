@@ -2,42 +2,235 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 	"todo/api/models"
-	"todo/storage/mongodb"
+	"todo/pkg"
+	"todo/pkg/check"
+	"todo/pkg/password"
+	"todo/pkg/rbac"
+	"todo/pkg/smtp"
+	"todo/storage"
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, req models.CreateUser) (models.User, error)
-	GetUserByID(ctx context.Context, id string) (models.User, error)
-	UpdateUser(ctx context.Context, req models.UpdateUser) (models.User, error)
-	DeleteUser(ctx context.Context, id string) error
-	ListUsers(ctx context.Context, page, limit uint64) ([]models.User, int64, error)
+	GetUserByID(ctx context.Context, actor models.AuthInfo, id string) (models.User, error)
+	UpdateUser(ctx context.Context, actor models.AuthInfo, req models.UpdateUser) (models.User, error)
+	ConfirmEmailChange(ctx context.Context, actor models.AuthInfo, id string, req models.ConfirmEmailChange) (models.User, error)
+	DeleteUser(ctx context.Context, actor models.AuthInfo, id string, dryRun bool) (models.DeleteSummary, error)
+	ListUsers(ctx context.Context, actor models.AuthInfo, page models.Pagination) ([]models.User, models.PageInfo, error)
+	SetUserDisabled(ctx context.Context, actor models.AuthInfo, id string, disabled bool) error
+	UpdateUserRole(ctx context.Context, actor models.AuthInfo, id string, role string) error
+	BootstrapAdmin(ctx context.Context, email string) error
 }
 
 type userService struct {
-	repo mongodb.UserRepo
+	repo             storage.UserStorage
+	tokenRepo        storage.TokenStorage
+	registrationRepo storage.RegistrationStorage
+	mailer           *smtp.Mailer
 }
 
-func NewUserService(repo mongodb.UserRepo) UserService {
-	return &userService{repo: repo}
+func NewUserService(repo storage.UserStorage, tokenRepo storage.TokenStorage, registrationRepo storage.RegistrationStorage, mailer *smtp.Mailer) UserService {
+	return &userService{repo: repo, tokenRepo: tokenRepo, registrationRepo: registrationRepo, mailer: mailer}
 }
 
 func (us *userService) CreateUser(ctx context.Context, req models.CreateUser) (models.User, error) {
+	if req.Role == "" {
+		req.Role = rbac.RoleMember
+	}
 	return us.repo.CreateUser(ctx, req)
 }
 
-func (us *userService) GetUserByID(ctx context.Context, id string) (models.User, error) {
+// GetUserByID returns the actor's own profile, or anyone's with users:read_all.
+// Other users look the same as missing ones.
+func (us *userService) GetUserByID(ctx context.Context, actor models.AuthInfo, id string) (models.User, error) {
+	if actor.UserID != id && !rbac.HasPermission(actor.Role, rbac.UsersReadAll) {
+		return models.User{}, models.ErrUserNotFound
+	}
 	return us.repo.GetUser(ctx, id)
 }
 
-func (us *userService) UpdateUser(ctx context.Context, req models.UpdateUser) (models.User, error) {
+// UpdateUser changes the actor's own profile. A new email is not taken over
+// right away: a one-time password is sent to it, and ConfirmEmailChange
+// switches to it once the user shows they own the address.
+func (us *userService) UpdateUser(ctx context.Context, actor models.AuthInfo, req models.UpdateUser) (models.User, error) {
+	if actor.UserID != req.ID.Hex() {
+		return models.User{}, models.ErrForbidden
+	}
+	if err := check.ValidateUsername(req.Username); err != nil {
+		return models.User{}, fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	if _, err := check.ValidateEmail(req.Email); err != nil {
+		return models.User{}, fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+
+	user, err := us.repo.GetUser(ctx, req.ID.Hex())
+	if err != nil {
+		return models.User{}, err
+	}
+	if req.Email != user.Email {
+		if err := us.requestEmailChange(ctx, user, req.Email); err != nil {
+			return models.User{}, err
+		}
+		req.Email = user.Email
+	}
 	return us.repo.UpdateUser(ctx, req)
 }
 
-func (us *userService) DeleteUser(ctx context.Context, id string) error {
-	return us.repo.DeleteUser(ctx, id)
+// requestEmailChange keeps the new email of a user as a pending registration
+// and emails it the one-time password that ConfirmEmailChange checks.
+func (us *userService) requestEmailChange(ctx context.Context, user models.User, email string) error {
+	if _, err := us.repo.GetUserByEmail(ctx, email); err == nil {
+		return models.ErrEmailTaken
+	}
+
+	otp, err := pkg.GenerateOTP()
+	if err != nil {
+		return err
+	}
+	otpHash, err := password.HashPassword(otp)
+	if err != nil {
+		return err
+	}
+
+	err = us.registrationRepo.UpsertPendingRegistration(ctx, models.PendingRegistration{
+		UserID:    &user.ID,
+		Username:  user.Username,
+		Email:     email,
+		OtpHash:   otpHash,
+		ExpiresAt: time.Now().Add(otpTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := smtp.Render("email_change", struct {
+		Code    string
+		Minutes int
+	}{otp, int(otpTTL.Minutes())}, email)
+	if err != nil {
+		return err
+	}
+	return us.mailer.Send(ctx, msg)
+}
+
+// ConfirmEmailChange checks the OTP sent to the new email of the actor and
+// switches them to it.
+func (us *userService) ConfirmEmailChange(ctx context.Context, actor models.AuthInfo, id string, req models.ConfirmEmailChange) (models.User, error) {
+	if actor.UserID != id {
+		return models.User{}, models.ErrForbidden
+	}
+
+	reg, err := us.registrationRepo.GetPendingRegistration(ctx, req.Email)
+	if err != nil || reg.UserID == nil || reg.UserID.Hex() != id {
+		return models.User{}, models.ErrInvalidOTP
+	}
+	if err := redeemOTP(ctx, us.registrationRepo, reg, req.Otp); err != nil {
+		return models.User{}, err
+	}
+
+	if _, err := us.repo.GetUserByEmail(ctx, reg.Email); err == nil {
+		return models.User{}, models.ErrEmailTaken
+	}
+	user, err := us.repo.GetUser(ctx, id)
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err = us.repo.UpdateUser(ctx, models.UpdateUser{ID: user.ID, Username: user.Username, Email: reg.Email})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err := us.registrationRepo.DeletePendingRegistration(ctx, reg.Email); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// DeleteUser lets users delete themselves and admins delete anyone. All of
+// the user's data goes with them.
+func (us *userService) DeleteUser(ctx context.Context, actor models.AuthInfo, id string, dryRun bool) (models.DeleteSummary, error) {
+	if actor.UserID != id && !rbac.HasPermission(actor.Role, rbac.UsersDeleteAny) {
+		return models.DeleteSummary{}, models.ErrForbidden
+	}
+	return us.repo.DeleteUser(ctx, id, dryRun)
+}
+
+func (us *userService) ListUsers(ctx context.Context, actor models.AuthInfo, page models.Pagination) ([]models.User, models.PageInfo, error) {
+	if !rbac.HasPermission(actor.Role, rbac.UsersReadAll) {
+		return nil, models.PageInfo{}, models.ErrForbidden
+	}
+	return listPage(page, func(page models.Pagination) ([]models.User, int64, error) {
+		return us.repo.GetAllUsers(ctx, page)
+	}, models.User.Cursor)
+}
+
+// SetUserDisabled disables or re-enables a user. Disabling also revokes the
+// user's refresh tokens and bumps their token version, so they stay logged
+// out if they are enabled again.
+func (us *userService) SetUserDisabled(ctx context.Context, actor models.AuthInfo, id string, disabled bool) error {
+	if !rbac.HasPermission(actor.Role, rbac.UsersDisable) {
+		return models.ErrForbidden
+	}
+	if actor.UserID == id {
+		return fmt.Errorf("%w: cannot disable yourself", models.ErrInvalidInput)
+	}
+
+	if err := us.repo.SetUserDisabled(ctx, id, disabled); err != nil {
+		return err
+	}
+	if !disabled {
+		return nil
+	}
+	if err := us.tokenRepo.RevokeUserTokens(ctx, id); err != nil {
+		return err
+	}
+	return us.repo.BumpTokenVersion(ctx, id)
+}
+
+func (us *userService) UpdateUserRole(ctx context.Context, actor models.AuthInfo, id string, role string) error {
+	if !rbac.HasPermission(actor.Role, rbac.UsersUpdateRole) {
+		return models.ErrForbidden
+	}
+	if !rbac.IsValidRole(role) {
+		return fmt.Errorf("%w: unknown role %q", models.ErrInvalidInput, role)
+	}
+	// Keeps the last admin from locking everyone out by demoting themselves
+	if actor.UserID == id {
+		return fmt.Errorf("%w: cannot change your own role", models.ErrInvalidInput)
+	}
+	return us.repo.UpdateUserRole(ctx, id, role)
 }
 
-func (us *userService) ListUsers(ctx context.Context, page, limit uint64) ([]models.User, int64, error) {
-	return us.repo.GetAllUsers(ctx, page, limit)
+// BootstrapAdmin promotes the configured admin account if it already exists
+// and nobody is an admin yet. Once there is an admin, roles are only changed
+// through the API, so a user who later takes over the email gains nothing.
+func (us *userService) BootstrapAdmin(ctx context.Context, email string) error {
+	if strings.TrimSpace(email) == "" {
+		return nil
+	}
+
+	admins, err := us.repo.CountUsersWithRole(ctx, rbac.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	user, err := us.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role == rbac.RoleAdmin {
+		return nil
+	}
+	return us.repo.UpdateUserRole(ctx, user.ID.Hex(), rbac.RoleAdmin)
 }
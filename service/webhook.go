@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"todo/pkg/webhook"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bounds of the webhooks a user can register.
+const (
+	MaxWebhooks                 = 20
+	MaxWebhookDescriptionLength = 200
+)
+
+type WebhookService interface {
+	// CreateWebhook registers an endpoint. The secret signing its
+	// deliveries is returned here only.
+	CreateWebhook(ctx context.Context, actor models.AuthInfo, userID string, req models.CreateWebhook) (models.Webhook, error)
+	GetWebhook(ctx context.Context, actor models.AuthInfo, userID, webhookID string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, actor models.AuthInfo, userID string, page models.Pagination) ([]models.Webhook, models.PageInfo, error)
+	UpdateWebhook(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateWebhook) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, actor models.AuthInfo, userID, webhookID string) error
+	// ListDeliveries returns the delivery log of a webhook, the latest first.
+	ListDeliveries(ctx context.Context, actor models.AuthInfo, userID, webhookID string, page models.Pagination) ([]models.WebhookDelivery, models.PageInfo, error)
+	// Redeliver queues a delivery of a webhook again, with the same event.
+	Redeliver(ctx context.Context, actor models.AuthInfo, userID, webhookID, deliveryID string) (models.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo storage.WebhookStorage
+}
+
+func NewWebhookService(repo storage.WebhookStorage) WebhookService {
+	return &webhookService{repo: repo}
+}
+
+func (ws *webhookService) CreateWebhook(ctx context.Context, actor models.AuthInfo, userID string, req models.CreateWebhook) (models.Webhook, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	req.UserID = id
+
+	events, err := checkWebhook(req.URL, req.Events, req.Description)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	req.Events = events
+
+	_, count, err := ws.repo.GetAllWebhooks(ctx, userID, models.Pagination{Limit: 1})
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if count >= MaxWebhooks {
+		return models.Webhook{}, fmt.Errorf("%w: a user can register at most %d webhooks", models.ErrInvalidInput, MaxWebhooks)
+	}
+
+	if req.Secret, err = webhook.NewSecret(); err != nil {
+		return models.Webhook{}, err
+	}
+	return ws.repo.CreateWebhook(ctx, req)
+}
+
+func (ws *webhookService) GetWebhook(ctx context.Context, actor models.AuthInfo, userID, webhookID string) (models.Webhook, error) {
+	hook, err := ws.ownedWebhook(ctx, actor, userID, webhookID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+func (ws *webhookService) ListWebhooks(ctx context.Context, actor models.AuthInfo, userID string, page models.Pagination) ([]models.Webhook, models.PageInfo, error) {
+	if _, err := ownUserID(actor, userID); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	webhooks, info, err := listPage(page, func(page models.Pagination) ([]models.Webhook, int64, error) {
+		return ws.repo.GetAllWebhooks(ctx, userID, page)
+	}, models.Webhook.Cursor)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, info, nil
+}
+
+// UpdateWebhook replaces a webhook. Its secret is kept.
+func (ws *webhookService) UpdateWebhook(ctx context.Context, actor models.AuthInfo, userID string, req models.UpdateWebhook) (models.Webhook, error) {
+	if _, err := ws.ownedWebhook(ctx, actor, userID, req.ID.Hex()); err != nil {
+		return models.Webhook{}, err
+	}
+	events, err := checkWebhook(req.URL, req.Events, req.Description)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	req.Events = events
+
+	hook, err := ws.repo.UpdateWebhook(ctx, req)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+func (ws *webhookService) DeleteWebhook(ctx context.Context, actor models.AuthInfo, userID, webhookID string) error {
+	if _, err := ws.ownedWebhook(ctx, actor, userID, webhookID); err != nil {
+		return err
+	}
+	return ws.repo.DeleteWebhook(ctx, webhookID)
+}
+
+func (ws *webhookService) ListDeliveries(ctx context.Context, actor models.AuthInfo, userID, webhookID string, page models.Pagination) ([]models.WebhookDelivery, models.PageInfo, error) {
+	if _, err := ws.ownedWebhook(ctx, actor, userID, webhookID); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	return listPage(page, func(page models.Pagination) ([]models.WebhookDelivery, int64, error) {
+		return ws.repo.GetDeliveries(ctx, webhookID, page)
+	}, models.WebhookDelivery.Cursor)
+}
+
+// Redeliver queues a new delivery of the event of an earlier one, to be
+// posted right away. The earlier delivery is left as it is, and the event
+// keeps its ID so receivers can tell they have seen it.
+func (ws *webhookService) Redeliver(ctx context.Context, actor models.AuthInfo, userID, webhookID, deliveryID string) (models.WebhookDelivery, error) {
+	hook, err := ws.ownedWebhook(ctx, actor, userID, webhookID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if !hook.Active {
+		return models.WebhookDelivery{}, fmt.Errorf("%w: resume the webhook to redeliver its events", models.ErrInvalidInput)
+	}
+	delivery, err := ws.repo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if delivery.WebhookID != hook.ID {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+
+	return ws.repo.EnqueueDelivery(ctx, models.EnqueueDelivery{
+		WebhookID:     hook.ID,
+		UserID:        hook.UserID,
+		EventID:       delivery.EventID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		RedeliveryOf:  &delivery.ID,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+// ownedWebhook loads a webhook of the actor. Webhooks of other users are
+// reported as not found.
+func (ws *webhookService) ownedWebhook(ctx context.Context, actor models.AuthInfo, userID, webhookID string) (models.Webhook, error) {
+	id, err := ownUserID(actor, userID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	hook, err := ws.repo.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if hook.UserID != id {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// checkWebhook validates the fields of a webhook and returns its events
+// without duplicates.
+func checkWebhook(rawURL string, events []string, description string) ([]string, error) {
+	if err := checkWebhookURL("url", rawURL); err != nil {
+		return nil, err
+	}
+	if len(description) > MaxWebhookDescriptionLength {
+		return nil, fmt.Errorf("%w: description is longer than %d characters", models.ErrInvalidInput, MaxWebhookDescriptionLength)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: subscribe to at least one event", models.ErrInvalidInput)
+	}
+
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, event := range events {
+		if !isWebhookEvent(event) {
+			return nil, fmt.Errorf("%w: unknown event %q, expected one of %s", models.ErrInvalidInput, event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	return unique, nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range models.WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPublisher queues the events of a user for the webhooks subscribed
+// to them. The scheduler posts them later, so a slow endpoint never holds up
+// the change that caused the event.
+type WebhookPublisher struct {
+	repo storage.WebhookStorage
+	log  logger.ILogger
+}
+
+func NewWebhookPublisher(repo storage.WebhookStorage, log logger.ILogger) *WebhookPublisher {
+	return &WebhookPublisher{repo: repo, log: log}
+}
+
+// Publish queues event about data for the webhooks of userID. Failures are
+// logged rather than returned, as the change the event is about has already
+// been made.
+func (p *WebhookPublisher) Publish(ctx context.Context, userID string, event string, data any) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return
+	}
+	// The change is made, so queue its event even if the request is gone
+	ctx = context.WithoutCancel(ctx)
+
+	webhooks, err := p.repo.SubscribedWebhooks(ctx, id, event)
+	if err != nil {
+		p.log.Error("Error finding webhooks", logger.String("event", event), logger.Error(err))
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	// Every webhook gets the same event, posted with the same body
+	eventID, now := primitive.NewObjectID(), time.Now()
+	payload, err := json.Marshal(models.WebhookEvent{ID: eventID, Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		p.log.Error("Error encoding webhook event", logger.String("event", event), logger.Error(err))
+		return
+	}
+
+	for _, hook := range webhooks {
+		_, err := p.repo.EnqueueDelivery(ctx, models.EnqueueDelivery{
+			WebhookID:     hook.ID,
+			UserID:        id,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       payload,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			p.log.Error("Error queuing webhook delivery", logger.String("webhook_id", hook.ID.Hex()), logger.Error(err))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"todo/pkg/webhook"
+	"todo/storage"
+)
+
+// Settings of the webhook scheduler.
+const (
+	// WebhookBatchSize bounds the deliveries posted per poll; the rest wait
+	// for the next one.
+	WebhookBatchSize = 100
+	// MaxWebhookAttempts is how often a delivery is posted before it is
+	// marked failed.
+	MaxWebhookAttempts = 10
+	// WebhookRetryDelay is the wait before the first retry. It doubles with
+	// every further attempt, up to WebhookMaxRetryDelay.
+	WebhookRetryDelay    = time.Minute
+	WebhookMaxRetryDelay = 6 * time.Hour
+)
+
+// WebhookScheduler posts queued webhook deliveries, retrying failed ones
+// with exponential backoff. Every attempt is recorded on the delivery, so a
+// restart picks up where the last one stopped.
+type WebhookScheduler struct {
+	repo     storage.WebhookStorage
+	client   *webhook.Client
+	interval time.Duration
+	log      logger.ILogger
+}
+
+// NewWebhookScheduler returns a scheduler polling store every interval and
+// posting through client.
+func NewWebhookScheduler(store *storage.Storage, client *webhook.Client, interval time.Duration, log logger.ILogger) *WebhookScheduler {
+	return &WebhookScheduler{
+		repo:     store.WebhookRepo,
+		client:   client,
+		interval: interval,
+		log:      log,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled. A delivery being
+// posted when that happens is finished and recorded before Run returns.
+func (s *WebhookScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll posts the deliveries that are due and returns how many succeeded.
+func (s *WebhookScheduler) Poll(ctx context.Context) int {
+	due, err := s.repo.DueDeliveries(ctx, time.Now(), WebhookBatchSize)
+	if err != nil {
+		s.log.Error("Error polling webhook deliveries", logger.Error(err))
+		return 0
+	}
+
+	succeeded := 0
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		// Once posted, a delivery must be recorded even if we are shutting down
+		if s.deliver(context.WithoutCancel(ctx), delivery) {
+			succeeded++
+		}
+	}
+	return succeeded
+}
+
+// deliver posts one delivery, or fails it when its webhook is gone or
+// paused, and records the outcome. It reports whether the post succeeded.
+func (s *WebhookScheduler) deliver(ctx context.Context, delivery models.WebhookDelivery) bool {
+	attempt := models.WebhookAttempt{ID: delivery.ID, ScheduledAt: *delivery.NextAttemptAt, Attempts: delivery.Attempts}
+
+	hook, err := s.repo.GetWebhook(ctx, delivery.WebhookID.Hex())
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound):
+		// Only a delivery queued while its webhook was being deleted is left
+		attempt.Status = models.DeliveryFailed
+		attempt.LastError = "webhook was deleted"
+	case err != nil:
+		s.log.Error("Error loading webhook of delivery", logger.String("delivery_id", delivery.ID.Hex()), logger.Error(err))
+		return false
+	case !hook.Active:
+		attempt.Status = models.DeliveryFailed
+		attempt.LastError = "webhook is paused"
+	default:
+		attempt.Attempts++
+		resp, err := s.client.Post(ctx, webhook.Delivery{
+			ID:     delivery.ID.Hex(),
+			Event:  delivery.Event,
+			URL:    hook.URL,
+			Secret: hook.Secret,
+			Body:   delivery.Payload,
+		})
+		attempt.ResponseStatus, attempt.ResponseBody = resp.Status, resp.Body
+		if err != nil {
+			s.log.Warning("Error posting webhook delivery", logger.String("delivery_id", delivery.ID.Hex()), logger.Error(err))
+			attempt.LastError = err.Error()
+		}
+
+		now := time.Now()
+		switch {
+		case err == nil:
+			attempt.Status = models.DeliverySucceeded
+			attempt.DeliveredAt = &now
+		case attempt.Attempts >= MaxWebhookAttempts:
+			attempt.Status = models.DeliveryFailed
+		default:
+			next := now.Add(retryDelay(attempt.Attempts))
+			attempt.Status = models.DeliveryPending
+			attempt.NextAttemptAt = &next
+		}
+	}
+
+	err = s.repo.RecordAttempt(ctx, attempt)
+	if err != nil && !errors.Is(err, models.ErrDeliveryNotFound) {
+		s.log.Error("Error recording webhook delivery", logger.String("delivery_id", delivery.ID.Hex()), logger.Error(err))
+	}
+	return attempt.Status == models.DeliverySucceeded
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := WebhookRetryDelay
+	for i := 1; i < attempts && delay < WebhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, WebhookMaxRetryDelay)
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DigestRepo struct {
+	db *DB
+}
+
+func NewDigestRepo(db *DB) *DigestRepo {
+	return &DigestRepo{db: db}
+}
+
+// GetDigestSettings returns the settings of a user, or the defaults.
+func (dr *DigestRepo) GetDigestSettings(ctx context.Context, userID primitive.ObjectID) (models.DigestSettings, error) {
+	dr.db.mu.RLock()
+	defer dr.db.mu.RUnlock()
+
+	settings, ok := dr.db.digestSettings[userID]
+	if !ok {
+		return models.DefaultDigestSettings(userID), nil
+	}
+	return cloneDigestSettings(settings), nil
+}
+
+// SaveDigestSettings creates or replaces the settings of a user, keeping
+// when the last digest was sent.
+func (dr *DigestRepo) SaveDigestSettings(ctx context.Context, req models.UpdateDigestSettings) (models.DigestSettings, error) {
+	dr.db.mu.Lock()
+	defer dr.db.mu.Unlock()
+
+	settings := cloneDigestSettings(models.DigestSettings{
+		UserID:     req.UserID,
+		Frequency:  req.Frequency,
+		SendAt:     req.SendAt,
+		Weekday:    req.Weekday,
+		Timezone:   req.Timezone,
+		NextAt:     req.NextAt,
+		LastSentAt: dr.db.digestSettings[req.UserID].LastSentAt,
+		UpdatedAt:  time.Now(),
+	})
+	dr.db.digestSettings[req.UserID] = settings
+	return cloneDigestSettings(settings), nil
+}
+
+// DueDigests returns the settings whose next digest is due at or before now.
+func (dr *DigestRepo) DueDigests(ctx context.Context, now time.Time, limit int) ([]models.DigestSettings, error) {
+	dr.db.mu.RLock()
+	defer dr.db.mu.RUnlock()
+
+	due := []models.DigestSettings{}
+	for _, settings := range sortedValues(dr.db.digestSettings) {
+		if settings.NextAt != nil && !settings.NextAt.After(now) {
+			due = append(due, cloneDigestSettings(settings))
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAt.Before(*due[j].NextAt)
+	})
+	if limit > 0 && limit < len(due) {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// RecordDigest stores the outcome of a digest and when the next is sent.
+func (dr *DigestRepo) RecordDigest(ctx context.Context, delivery models.DigestDelivery) error {
+	dr.db.mu.Lock()
+	defer dr.db.mu.Unlock()
+
+	settings, ok := dr.db.digestSettings[delivery.UserID]
+	if !ok || settings.NextAt == nil || !settings.NextAt.Equal(delivery.ScheduledAt) {
+		return models.ErrDigestNotFound
+	}
+	if delivery.SentAt != nil {
+		sentAt := *delivery.SentAt
+		settings.LastSentAt = &sentAt
+	}
+	nextAt := delivery.NextAt
+	settings.NextAt = &nextAt
+	dr.db.digestSettings[delivery.UserID] = settings
+	return nil
+}
+
+func cloneDigestSettings(settings models.DigestSettings) models.DigestSettings {
+	if settings.NextAt != nil {
+		nextAt := *settings.NextAt
+		settings.NextAt = &nextAt
+	}
+	if settings.LastSentAt != nil {
+		lastSentAt := *settings.LastSentAt
+		settings.LastSentAt = &lastSentAt
+	}
+	return settings
+}
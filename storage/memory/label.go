@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LabelRepo struct {
+	db *DB
+}
+
+func NewLabelRepo(db *DB) *LabelRepo {
+	return &LabelRepo{db: db}
+}
+
+// CreateLabel stores a new label.
+func (lr *LabelRepo) CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error) {
+	now := time.Now()
+	label := models.Label{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	lr.db.mu.Lock()
+	defer lr.db.mu.Unlock()
+
+	lr.db.labels[label.ID] = label
+	return label, nil
+}
+
+// GetLabel retrieves a label by its ID.
+func (lr *LabelRepo) GetLabel(ctx context.Context, labelID string) (models.Label, error) {
+	objectID, err := primitive.ObjectIDFromHex(labelID)
+	if err != nil {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+
+	lr.db.mu.RLock()
+	defer lr.db.mu.RUnlock()
+
+	label, ok := lr.db.labels[objectID]
+	if !ok {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+	return label, nil
+}
+
+// UpdateLabel updates the label information.
+func (lr *LabelRepo) UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error) {
+	lr.db.mu.Lock()
+	defer lr.db.mu.Unlock()
+
+	label, ok := lr.db.labels[req.ID]
+	if !ok {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+	label.Name = req.Name
+	label.Color = req.Color
+	label.UpdatedAt = time.Now()
+	lr.db.labels[label.ID] = label
+
+	return label, nil
+}
+
+// DeleteLabel removes a label by its ID and pulls it from the tasks that
+// reference it.
+func (lr *LabelRepo) DeleteLabel(ctx context.Context, labelID string) error {
+	objectID, err := primitive.ObjectIDFromHex(labelID)
+	if err != nil {
+		return models.ErrLabelNotFound
+	}
+
+	lr.db.mu.Lock()
+	defer lr.db.mu.Unlock()
+
+	if _, ok := lr.db.labels[objectID]; !ok {
+		return models.ErrLabelNotFound
+	}
+	delete(lr.db.labels, objectID)
+
+	for id, task := range lr.db.tasks {
+		if !containsID(task.LabelIDs, objectID) {
+			continue
+		}
+		kept := []primitive.ObjectID{}
+		for _, labelID := range task.LabelIDs {
+			if labelID != objectID {
+				kept = append(kept, labelID)
+			}
+		}
+		task.LabelIDs = kept
+		lr.db.tasks[id] = task
+	}
+	return nil
+}
+
+// GetAllLabels retrieves all labels for a specific user with pagination support.
+func (lr *LabelRepo) GetAllLabels(ctx context.Context, userID string, search string, page models.Pagination) ([]models.Label, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+
+	lr.db.mu.RLock()
+	defer lr.db.mu.RUnlock()
+
+	labels := []models.Label{}
+	for _, label := range sortedValues(lr.db.labels) {
+		if label.UserID == objectID && containsFold(label.Name, search) {
+			labels = append(labels, label)
+		}
+	}
+
+	return paginate(labels, page), int64(len(labels)), nil
+}
@@ -0,0 +1,199 @@
+// Package memory is an in-memory implementation of the storage interfaces.
+// It keeps the pagination, search and not-found behaviour of the MongoDB
+// repositories so it can stand in for them in tests and local development.
+// Nothing is persisted.
+package memory
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"todo/api/models"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DB holds every collection behind a single lock, so cascading deletes see a
+// consistent view of the data the way a MongoDB transaction does.
+type DB struct {
+	mu                      sync.RWMutex
+	users                   map[primitive.ObjectID]models.User
+	labels                  map[primitive.ObjectID]models.Label
+	tasks                   map[primitive.ObjectID]models.Task
+	taskLists               map[primitive.ObjectID]models.TaskList
+	refreshTokens           map[primitive.ObjectID]models.RefreshToken
+	registrations           map[string]models.PendingRegistration
+	passwordResets          map[primitive.ObjectID]models.PasswordReset
+	smartLists              map[primitive.ObjectID]models.SmartList
+	reminders               map[primitive.ObjectID]models.Reminder
+	notificationSettings    map[primitive.ObjectID]models.NotificationSettings
+	notificationPreferences map[primitive.ObjectID]models.NotificationPreferences
+	digestSettings          map[primitive.ObjectID]models.DigestSettings
+	webhooks                map[primitive.ObjectID]models.Webhook
+	webhookDeliveries       map[primitive.ObjectID]models.WebhookDelivery
+}
+
+// NewDB returns an empty in-memory database.
+func NewDB() *DB {
+	return &DB{
+		users:                   map[primitive.ObjectID]models.User{},
+		labels:                  map[primitive.ObjectID]models.Label{},
+		tasks:                   map[primitive.ObjectID]models.Task{},
+		taskLists:               map[primitive.ObjectID]models.TaskList{},
+		refreshTokens:           map[primitive.ObjectID]models.RefreshToken{},
+		registrations:           map[string]models.PendingRegistration{},
+		passwordResets:          map[primitive.ObjectID]models.PasswordReset{},
+		smartLists:              map[primitive.ObjectID]models.SmartList{},
+		reminders:               map[primitive.ObjectID]models.Reminder{},
+		notificationSettings:    map[primitive.ObjectID]models.NotificationSettings{},
+		notificationPreferences: map[primitive.ObjectID]models.NotificationPreferences{},
+		digestSettings:          map[primitive.ObjectID]models.DigestSettings{},
+		webhooks:                map[primitive.ObjectID]models.Webhook{},
+		webhookDeliveries:       map[primitive.ObjectID]models.WebhookDelivery{},
+	}
+}
+
+// NewStorage initializes all repositories on top of a fresh in-memory database.
+func NewStorage() *storage.Storage {
+	db := NewDB()
+	return &storage.Storage{
+		UserRepo:          NewUserRepo(db),
+		LabelRepo:         NewLabelRepo(db),
+		TaskRepo:          NewTaskRepo(db),
+		TaskListRepo:      NewTaskListRepo(db),
+		TokenRepo:         NewTokenRepo(db),
+		RegistrationRepo:  NewRegistrationRepo(db),
+		PasswordResetRepo: NewPasswordResetRepo(db),
+		SmartListRepo:     NewSmartListRepo(db),
+		SearchRepo:        NewSearchRepo(db),
+		ReminderRepo:      NewReminderRepo(db),
+		NotificationRepo:  NewNotificationRepo(db),
+		DigestRepo:        NewDigestRepo(db),
+		WebhookRepo:       NewWebhookRepo(db),
+	}
+}
+
+// Interface for User, Label, Task, TaskList, Token, Registration, PasswordReset, SmartList, Search, Reminder, Notification, Digest and Webhook repositories.
+var (
+	_ storage.UserStorage          = &UserRepo{}
+	_ storage.LabelStorage         = &LabelRepo{}
+	_ storage.TaskStorage          = &TaskRepo{}
+	_ storage.TaskListStorage      = &TaskListRepo{}
+	_ storage.TokenStorage         = &TokenRepo{}
+	_ storage.RegistrationStorage  = &RegistrationRepo{}
+	_ storage.PasswordResetStorage = &PasswordResetRepo{}
+	_ storage.SmartListStorage     = &SmartListRepo{}
+	_ storage.SearchStorage        = &SearchRepo{}
+	_ storage.ReminderStorage      = &ReminderRepo{}
+	_ storage.NotificationStorage  = &NotificationRepo{}
+	_ storage.DigestStorage        = &DigestRepo{}
+	_ storage.WebhookStorage       = &WebhookRepo{}
+)
+
+// sortedValues returns the values of a collection in insertion order.
+// ObjectIDs start with their creation time, which matches the natural order
+// MongoDB returns documents in.
+func sortedValues[T any](collection map[primitive.ObjectID]T) []T {
+	ids := make([]primitive.ObjectID, 0, len(collection))
+	for id := range collection {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+
+	values := make([]T, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, collection[id])
+	}
+	return values
+}
+
+// paginate sorts items the way the MongoDB repositories do, by creation
+// time and then ID, and returns the page of them.
+func paginate[T interface{ Cursor() models.Cursor }](items []T, page models.Pagination) []T {
+	return paginateSorted(items, models.DefaultSort, page, func(item T) models.Cursor { return item.Cursor() })
+}
+
+// paginateSorted sorts items by keys, then ID, and returns the ones after the
+// page cursor, skipping the offset. cursor must fill in the values of keys. A
+// limit of 0 means no limit, like in MongoDB.
+func paginateSorted[T any](items []T, keys []models.SortKey, page models.Pagination, cursor func(T) models.Cursor) []T {
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareCursors(cursor(items[i]), cursor(items[j]), keys) < 0
+	})
+
+	if page.After != nil {
+		after := *page.After
+		items = items[sort.Search(len(items), func(i int) bool {
+			return compareCursors(after, cursor(items[i]), keys) < 0
+		}):]
+	}
+	if page.Offset >= uint64(len(items)) {
+		return []T{}
+	}
+	items = items[page.Offset:]
+	if page.Limit > 0 && page.Limit < uint64(len(items)) {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+// compareCursors orders two positions by keys, then ID.
+func compareCursors(a, b models.Cursor, keys []models.SortKey) int {
+	for _, key := range keys {
+		c := compareValues(a.Value(key.Field), b.Value(key.Field))
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		switch b := b.(bool); {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return 0
+}
+
+// containsFold reports whether search occurs in s, ignoring case. Searches
+// are plain text, never patterns.
+func containsFold(s, search string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(search))
+}
+
+// cloneIDs copies an ID slice so callers can't modify stored documents.
+func cloneIDs(ids []primitive.ObjectID) []primitive.ObjectID {
+	if ids == nil {
+		return nil
+	}
+	return append([]primitive.ObjectID{}, ids...)
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
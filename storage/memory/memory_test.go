@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"testing"
+	"todo/storage"
+	"todo/storage/memory"
+	"todo/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		return memory.NewStorage()
+	})
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationRepo struct {
+	db *DB
+}
+
+func NewNotificationRepo(db *DB) *NotificationRepo {
+	return &NotificationRepo{db: db}
+}
+
+// GetNotificationSettings returns the settings of a user, or the defaults.
+func (nr *NotificationRepo) GetNotificationSettings(ctx context.Context, userID primitive.ObjectID) (models.NotificationSettings, error) {
+	nr.db.mu.RLock()
+	defer nr.db.mu.RUnlock()
+
+	settings, ok := nr.db.notificationSettings[userID]
+	if !ok {
+		return models.DefaultNotificationSettings(userID), nil
+	}
+	return cloneNotificationSettings(settings), nil
+}
+
+// SaveNotificationSettings creates or replaces the settings of a user.
+func (nr *NotificationRepo) SaveNotificationSettings(ctx context.Context, req models.UpdateNotificationSettings) (models.NotificationSettings, error) {
+	nr.db.mu.Lock()
+	defer nr.db.mu.Unlock()
+
+	settings := models.NotificationSettings{
+		UserID:         req.UserID,
+		Channels:       append([]string{}, req.Channels...),
+		TelegramChatID: req.TelegramChatID,
+		WebhookURL:     req.WebhookURL,
+		UpdatedAt:      time.Now(),
+	}
+	nr.db.notificationSettings[req.UserID] = settings
+	return cloneNotificationSettings(settings), nil
+}
+
+// cloneNotificationSettings copies settings so callers can't modify stored
+// documents.
+func cloneNotificationSettings(settings models.NotificationSettings) models.NotificationSettings {
+	settings.Channels = append([]string{}, settings.Channels...)
+	return settings
+}
+
+// GetNotificationPreferences returns the preferences of a user, or the
+// defaults.
+func (nr *NotificationRepo) GetNotificationPreferences(ctx context.Context, userID primitive.ObjectID) (models.NotificationPreferences, error) {
+	nr.db.mu.RLock()
+	defer nr.db.mu.RUnlock()
+
+	preferences, ok := nr.db.notificationPreferences[userID]
+	if !ok {
+		return models.DefaultNotificationPreferences(userID), nil
+	}
+	return cloneNotificationPreferences(preferences), nil
+}
+
+// SaveNotificationPreferences creates or replaces the preferences of a user.
+func (nr *NotificationRepo) SaveNotificationPreferences(ctx context.Context, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error) {
+	nr.db.mu.Lock()
+	defer nr.db.mu.Unlock()
+
+	preferences := cloneNotificationPreferences(models.NotificationPreferences{
+		UserID:     req.UserID,
+		Events:     req.Events,
+		Timezone:   req.Timezone,
+		QuietHours: req.QuietHours,
+		UpdatedAt:  time.Now(),
+	})
+	nr.db.notificationPreferences[req.UserID] = preferences
+	return cloneNotificationPreferences(preferences), nil
+}
+
+// cloneNotificationPreferences copies preferences so callers can't modify
+// stored documents.
+func cloneNotificationPreferences(preferences models.NotificationPreferences) models.NotificationPreferences {
+	events := make(map[string][]string, len(preferences.Events))
+	for event, channels := range preferences.Events {
+		events[event] = append([]string{}, channels...)
+	}
+	preferences.Events = events
+	if preferences.QuietHours != nil {
+		quietHours := *preferences.QuietHours
+		preferences.QuietHours = &quietHours
+	}
+	return preferences
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PasswordResetRepo struct {
+	db *DB
+}
+
+func NewPasswordResetRepo(db *DB) *PasswordResetRepo {
+	return &PasswordResetRepo{db: db}
+}
+
+// CreatePasswordReset stores a hashed password reset token.
+func (pr *PasswordResetRepo) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error {
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	reset.CreatedAt = time.Now()
+
+	pr.db.mu.Lock()
+	defer pr.db.mu.Unlock()
+
+	pr.db.passwordResets[reset.ID] = reset
+	return nil
+}
+
+// UsePasswordReset marks an unused, unexpired reset token as used and
+// returns it, along with every other token of the same user. A token can
+// therefore only be redeemed once, and older reset links die with it.
+func (pr *PasswordResetRepo) UsePasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error) {
+	pr.db.mu.Lock()
+	defer pr.db.mu.Unlock()
+
+	now := time.Now()
+	for _, reset := range pr.db.passwordResets {
+		if reset.TokenHash != tokenHash || reset.Used || !reset.ExpiresAt.After(now) {
+			continue
+		}
+		for otherID, other := range pr.db.passwordResets {
+			if other.UserID == reset.UserID {
+				other.Used = true
+				pr.db.passwordResets[otherID] = other
+			}
+		}
+		reset.Used = true
+		return reset, nil
+	}
+	return models.PasswordReset{}, errors.New("password reset not found")
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"time"
+	"todo/api/models"
+	"todo/pkg/taskquery"
+)
+
+// matchQuery reports whether a task matches a smart list query. A nil query
+// matches every task.
+func matchQuery(expr taskquery.Expr, task models.Task, now time.Time) bool {
+	switch e := expr.(type) {
+	case nil:
+		return true
+	case *taskquery.And:
+		return matchQuery(e.Left, task, now) && matchQuery(e.Right, task, now)
+	case *taskquery.Or:
+		return matchQuery(e.Left, task, now) || matchQuery(e.Right, task, now)
+	case *taskquery.Not:
+		return !matchQuery(e.Expr, task, now)
+	case *taskquery.Completed:
+		return task.Completed == e.Value
+	case *taskquery.Overdue:
+		return !task.Completed && !task.DueDate.IsZero() && task.DueDate.Before(now)
+	case *taskquery.NoDueDate:
+		return task.DueDate.IsZero()
+	case *taskquery.Title:
+		return containsFold(task.Title, e.Text)
+	case *taskquery.Label:
+		for _, id := range e.IDs {
+			if containsID(task.LabelIDs, id) {
+				return true
+			}
+		}
+		return false
+	case *taskquery.List:
+		return containsID(e.IDs, task.TaskListID)
+	case *taskquery.Compare:
+		var value time.Time
+		switch e.Field {
+		case taskquery.FieldDueDate:
+			if task.DueDate.IsZero() {
+				return false
+			}
+			value = task.DueDate
+		case taskquery.FieldCreatedAt:
+			value = task.CreatedAt
+		case taskquery.FieldUpdatedAt:
+			value = task.UpdatedAt
+		}
+
+		c := value.Compare(e.Time.Resolve(now))
+		switch e.Op {
+		case taskquery.OpLess:
+			return c < 0
+		case taskquery.OpLessEqual:
+			return c <= 0
+		case taskquery.OpGreater:
+			return c > 0
+		case taskquery.OpGreaterEqual:
+			return c >= 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RegistrationRepo struct {
+	db *DB
+}
+
+func NewRegistrationRepo(db *DB) *RegistrationRepo {
+	return &RegistrationRepo{db: db}
+}
+
+// UpsertPendingRegistration stores a pending registration, replacing an
+// earlier one for the same email only once it expired.
+func (rr *RegistrationRepo) UpsertPendingRegistration(ctx context.Context, reg models.PendingRegistration) error {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	reg.CreatedAt = time.Now()
+	if existing, ok := rr.db.registrations[reg.Email]; ok {
+		if existing.ExpiresAt.After(reg.CreatedAt) {
+			return models.ErrRegistrationPending
+		}
+		reg.ID = existing.ID
+	} else {
+		reg.ID = primitive.NewObjectID()
+	}
+	rr.db.registrations[reg.Email] = reg
+
+	return nil
+}
+
+// GetPendingRegistration retrieves the pending registration for an email.
+// Expired registrations are treated as missing, as the TTL index would
+// have removed them in MongoDB.
+func (rr *RegistrationRepo) GetPendingRegistration(ctx context.Context, email string) (models.PendingRegistration, error) {
+	rr.db.mu.RLock()
+	defer rr.db.mu.RUnlock()
+
+	reg, ok := rr.db.registrations[email]
+	if !ok || time.Now().After(reg.ExpiresAt) {
+		return models.PendingRegistration{}, errors.New("pending registration not found")
+	}
+	return reg, nil
+}
+
+// IncrementRegistrationAttempts uses up one OTP attempt, unless max were
+// made already.
+func (rr *RegistrationRepo) IncrementRegistrationAttempts(ctx context.Context, email string, max int) (bool, error) {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	reg, ok := rr.db.registrations[email]
+	if !ok || reg.Attempts >= max {
+		return false, nil
+	}
+	reg.Attempts++
+	rr.db.registrations[email] = reg
+	return true, nil
+}
+
+// DeletePendingRegistration removes the pending registration for an email.
+func (rr *RegistrationRepo) DeletePendingRegistration(ctx context.Context, email string) error {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	delete(rr.db.registrations, email)
+	return nil
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReminderRepo struct {
+	db *DB
+}
+
+func NewReminderRepo(db *DB) *ReminderRepo {
+	return &ReminderRepo{db: db}
+}
+
+// SetReminders replaces the reminders of a task, keeping the ones whose
+// offset stays.
+func (rr *ReminderRepo) SetReminders(ctx context.Context, userID, taskID primitive.ObjectID, before []int, dueDate time.Time) ([]models.Reminder, error) {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	kept := map[int]bool{}
+	for id, reminder := range rr.db.reminders {
+		if reminder.TaskID != taskID {
+			continue
+		}
+		if containsInt(before, reminder.Before) {
+			kept[reminder.Before] = true
+		} else {
+			delete(rr.db.reminders, id)
+		}
+	}
+
+	now := time.Now()
+	for _, minutes := range before {
+		if kept[minutes] {
+			continue
+		}
+		kept[minutes] = true
+		reminder := models.Reminder{
+			ID:        primitive.NewObjectID(),
+			TaskID:    taskID,
+			UserID:    userID,
+			Before:    minutes,
+			RemindAt:  models.ReminderTime(dueDate, minutes),
+			Status:    models.ReminderPending,
+			CreatedAt: now,
+		}
+		rr.db.reminders[reminder.ID] = reminder
+	}
+	return rr.taskReminders(taskID), nil
+}
+
+// GetReminders returns the reminders of a task, the earliest first.
+func (rr *ReminderRepo) GetReminders(ctx context.Context, taskID primitive.ObjectID) ([]models.Reminder, error) {
+	rr.db.mu.RLock()
+	defer rr.db.mu.RUnlock()
+
+	return rr.taskReminders(taskID), nil
+}
+
+// RescheduleReminders moves the reminders of a task to a new due date.
+func (rr *ReminderRepo) RescheduleReminders(ctx context.Context, taskID primitive.ObjectID, dueDate time.Time) error {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	for id, reminder := range rr.db.reminders {
+		if reminder.TaskID != taskID {
+			continue
+		}
+		remindAt := models.ReminderTime(dueDate, reminder.Before)
+		if sameTimePtr(reminder.RemindAt, remindAt) {
+			continue
+		}
+		reminder.RemindAt = remindAt
+		reminder.Status = models.ReminderPending
+		reminder.Attempts = 0
+		reminder.LastError = ""
+		reminder.SentAt = nil
+		rr.db.reminders[id] = reminder
+	}
+	return nil
+}
+
+// DueReminders returns the pending reminders due at or before now.
+func (rr *ReminderRepo) DueReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	rr.db.mu.RLock()
+	defer rr.db.mu.RUnlock()
+
+	due := []models.Reminder{}
+	for _, reminder := range sortedValues(rr.db.reminders) {
+		if reminder.Status == models.ReminderPending && reminder.RemindAt != nil && !reminder.RemindAt.After(now) {
+			due = append(due, cloneReminder(reminder))
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].RemindAt.Before(*due[j].RemindAt)
+	})
+	if limit > 0 && limit < len(due) {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// RecordDelivery stores the outcome of sending a reminder.
+func (rr *ReminderRepo) RecordDelivery(ctx context.Context, delivery models.ReminderDelivery) error {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	reminder, ok := rr.db.reminders[delivery.ID]
+	if !ok || reminder.RemindAt == nil || !reminder.RemindAt.Equal(delivery.RemindAt) {
+		return models.ErrReminderNotFound
+	}
+	reminder.Status = delivery.Status
+	reminder.Attempts = delivery.Attempts
+	reminder.LastError = delivery.LastError
+	reminder.SentAt = nil
+	if delivery.SentAt != nil {
+		sentAt := *delivery.SentAt
+		reminder.SentAt = &sentAt
+	}
+	if delivery.DeferredTo != nil {
+		remindAt := *delivery.DeferredTo
+		reminder.RemindAt = &remindAt
+	}
+	rr.db.reminders[reminder.ID] = reminder
+	return nil
+}
+
+// DeleteReminders removes the reminders of a task.
+func (rr *ReminderRepo) DeleteReminders(ctx context.Context, taskID primitive.ObjectID) error {
+	rr.db.mu.Lock()
+	defer rr.db.mu.Unlock()
+
+	for id, reminder := range rr.db.reminders {
+		if reminder.TaskID == taskID {
+			delete(rr.db.reminders, id)
+		}
+	}
+	return nil
+}
+
+// taskReminders returns copies of the reminders of a task, the earliest
+// first. The caller holds the lock.
+func (rr *ReminderRepo) taskReminders(taskID primitive.ObjectID) []models.Reminder {
+	reminders := []models.Reminder{}
+	for _, reminder := range rr.db.reminders {
+		if reminder.TaskID == taskID {
+			reminders = append(reminders, cloneReminder(reminder))
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].Before > reminders[j].Before
+	})
+	return reminders
+}
+
+func cloneReminder(reminder models.Reminder) models.Reminder {
+	if reminder.RemindAt != nil {
+		remindAt := *reminder.RemindAt
+		reminder.RemindAt = &remindAt
+	}
+	if reminder.SentAt != nil {
+		sentAt := *reminder.SentAt
+		reminder.SentAt = &sentAt
+	}
+	return reminder
+}
+
+func sameTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func containsInt(values []int, value int) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
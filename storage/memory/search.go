@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Weights of the searched fields, as in the text indexes of the MongoDB
+// backend.
+const (
+	titleWeight = 10
+	bodyWeight  = 1
+)
+
+type SearchRepo struct {
+	db *DB
+}
+
+func NewSearchRepo(db *DB) *SearchRepo {
+	return &SearchRepo{db: db}
+}
+
+// Search scores items by how often the terms occur as words in their title
+// and description, like a MongoDB text search without stemming.
+func (sr *SearchRepo) Search(ctx context.Context, userID string, query models.SearchQuery) ([]models.SearchResult, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrUserNotFound
+	}
+
+	sr.db.mu.RLock()
+	defer sr.db.mu.RUnlock()
+
+	results := []models.SearchResult{}
+	for _, typ := range query.Types {
+		found := []models.SearchResult{}
+		add := func(result models.SearchResult) {
+			result.Type = typ
+			result.Score = float64(titleWeight*countTerms(result.Title, query.Terms) + bodyWeight*countTerms(result.Body, query.Terms))
+			if result.Score > 0 {
+				found = append(found, result)
+			}
+		}
+
+		switch typ {
+		case models.SearchTypeTask:
+			for _, task := range sr.db.tasks {
+				if taskList, ok := sr.db.taskLists[task.TaskListID]; ok && taskList.UserID == objectID {
+					taskListID := task.TaskListID
+					add(models.SearchResult{ID: task.ID, TaskListID: &taskListID, Title: task.Title, Body: task.Description})
+				}
+			}
+		case models.SearchTypeTaskList:
+			for _, taskList := range sr.db.taskLists {
+				if taskList.UserID == objectID {
+					add(models.SearchResult{ID: taskList.ID, Title: taskList.Title, Body: taskList.Description})
+				}
+			}
+		case models.SearchTypeLabel:
+			for _, label := range sr.db.labels {
+				if label.UserID == objectID {
+					add(models.SearchResult{ID: label.ID, Title: label.Name})
+				}
+			}
+		}
+
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].Score != found[j].Score {
+				return found[i].Score > found[j].Score
+			}
+			return found[i].ID.Hex() < found[j].ID.Hex()
+		})
+		if query.Limit > 0 && int64(len(found)) > query.Limit {
+			found = found[:query.Limit]
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
+
+// countTerms counts the words of text that are one of terms.
+func countTerms(text string, terms []string) int {
+	count := 0
+	for _, word := range models.SearchWords(text) {
+		for _, term := range terms {
+			if word == term {
+				count++
+			}
+		}
+	}
+	return count
+}
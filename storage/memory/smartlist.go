@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SmartListRepo struct {
+	db *DB
+}
+
+func NewSmartListRepo(db *DB) *SmartListRepo {
+	return &SmartListRepo{db: db}
+}
+
+// CreateSmartList stores a new smart list.
+func (slr *SmartListRepo) CreateSmartList(ctx context.Context, req models.CreateSmartList) (models.SmartList, error) {
+	now := time.Now()
+	smartList := models.SmartList{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Query:     req.Query,
+		Sort:      req.Sort,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	slr.db.mu.Lock()
+	defer slr.db.mu.Unlock()
+
+	slr.db.smartLists[smartList.ID] = smartList
+	return smartList, nil
+}
+
+// GetSmartList retrieves a smart list by ID.
+func (slr *SmartListRepo) GetSmartList(ctx context.Context, smartListID string) (models.SmartList, error) {
+	objectID, err := primitive.ObjectIDFromHex(smartListID)
+	if err != nil {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+
+	slr.db.mu.RLock()
+	defer slr.db.mu.RUnlock()
+
+	smartList, ok := slr.db.smartLists[objectID]
+	if !ok {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+	return smartList, nil
+}
+
+// UpdateSmartList updates an existing smart list.
+func (slr *SmartListRepo) UpdateSmartList(ctx context.Context, req models.UpdateSmartList) (models.SmartList, error) {
+	slr.db.mu.Lock()
+	defer slr.db.mu.Unlock()
+
+	smartList, ok := slr.db.smartLists[req.ID]
+	if !ok {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+	smartList.Name = req.Name
+	smartList.Query = req.Query
+	smartList.Sort = req.Sort
+	smartList.UpdatedAt = time.Now()
+	slr.db.smartLists[smartList.ID] = smartList
+
+	return smartList, nil
+}
+
+// DeleteSmartList removes a smart list. The tasks it shows are not touched.
+func (slr *SmartListRepo) DeleteSmartList(ctx context.Context, smartListID string) error {
+	objectID, err := primitive.ObjectIDFromHex(smartListID)
+	if err != nil {
+		return models.ErrSmartListNotFound
+	}
+
+	slr.db.mu.Lock()
+	defer slr.db.mu.Unlock()
+
+	if _, ok := slr.db.smartLists[objectID]; !ok {
+		return models.ErrSmartListNotFound
+	}
+	delete(slr.db.smartLists, objectID)
+	return nil
+}
+
+// GetAllSmartLists retrieves the smart lists of a user with pagination.
+func (slr *SmartListRepo) GetAllSmartLists(ctx context.Context, userID string, page models.Pagination) ([]models.SmartList, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+
+	slr.db.mu.RLock()
+	defer slr.db.mu.RUnlock()
+
+	smartLists := []models.SmartList{}
+	for _, smartList := range slr.db.smartLists {
+		if smartList.UserID == objectID {
+			smartLists = append(smartLists, smartList)
+		}
+	}
+
+	return paginate(smartLists, page), int64(len(smartLists)), nil
+}
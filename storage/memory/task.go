@@ -0,0 +1,372 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskRepo struct {
+	db *DB
+}
+
+func NewTaskRepo(db *DB) *TaskRepo {
+	return &TaskRepo{db: db}
+}
+
+// CreateTask stores a new task.
+func (tr *TaskRepo) CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error) {
+	now := time.Now()
+	task := models.Task{
+		ID:          primitive.NewObjectID(),
+		TaskListID:  req.TaskListID,
+		ParentID:    req.ParentID,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+		Recurrence:  cloneRecurrence(req.Recurrence),
+		LabelIDs:    cloneIDs(req.LabelIDs),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	tr.db.tasks[task.ID] = task
+	return cloneTask(task), nil
+}
+
+// GetTask retrieves a task by ID.
+func (tr *TaskRepo) GetTask(ctx context.Context, taskID string) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	tr.db.mu.RLock()
+	defer tr.db.mu.RUnlock()
+
+	task, ok := tr.db.tasks[objectID]
+	if !ok {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	return cloneTask(task), nil
+}
+
+// UpdateTask updates an existing task.
+func (tr *TaskRepo) UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error) {
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	task, ok := tr.db.tasks[req.ID]
+	if !ok {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	task.Title = req.Title
+	task.Description = req.Description
+	task.DueDate = req.DueDate
+	task.UpdatedAt = time.Now()
+	switch {
+	case !req.Completed:
+		task.CompletedAt = nil
+	case !task.Completed:
+		completedAt := task.UpdatedAt
+		task.CompletedAt = &completedAt
+	}
+	task.Completed = req.Completed
+	tr.db.tasks[task.ID] = task
+
+	return cloneTask(task), nil
+}
+
+// DeleteTask removes a task with its subtasks.
+func (tr *TaskRepo) DeleteTask(ctx context.Context, taskID string) error {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.ErrTaskNotFound
+	}
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	if _, ok := tr.db.tasks[objectID]; !ok {
+		return models.ErrTaskNotFound
+	}
+	delete(tr.db.tasks, objectID)
+	for id, task := range tr.db.tasks {
+		if task.ParentID != nil && *task.ParentID == objectID {
+			delete(tr.db.tasks, id)
+		}
+	}
+	return nil
+}
+
+// AddTaskLabels attaches labels to a task. Labels already on the task are
+// left as they are.
+func (tr *TaskRepo) AddTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(taskID, func(task *models.Task) {
+		for _, id := range labelIDs {
+			if !containsID(task.LabelIDs, id) {
+				task.LabelIDs = append(task.LabelIDs, id)
+			}
+		}
+	})
+}
+
+// RemoveTaskLabels detaches labels from a task.
+func (tr *TaskRepo) RemoveTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(taskID, func(task *models.Task) {
+		kept := []primitive.ObjectID{}
+		for _, id := range task.LabelIDs {
+			if !containsID(labelIDs, id) {
+				kept = append(kept, id)
+			}
+		}
+		task.LabelIDs = kept
+	})
+}
+
+func (tr *TaskRepo) updateTaskLabels(taskID string, change func(task *models.Task)) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	task, ok := tr.db.tasks[objectID]
+	if !ok {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	task.LabelIDs = cloneIDs(task.LabelIDs)
+	change(&task)
+	task.UpdatedAt = time.Now()
+	tr.db.tasks[objectID] = task
+
+	return cloneTask(task), nil
+}
+
+// SetTaskRecurrence makes a task repeat, or stop repeating with nil.
+func (tr *TaskRepo) SetTaskRecurrence(ctx context.Context, taskID string, recurrence *models.Recurrence) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	task, ok := tr.db.tasks[objectID]
+	if !ok {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	task.Recurrence = cloneRecurrence(recurrence)
+	task.UpdatedAt = time.Now()
+	tr.db.tasks[objectID] = task
+
+	return cloneTask(task), nil
+}
+
+// AddChecklistItem appends an open item to the checklist of a task.
+func (tr *TaskRepo) AddChecklistItem(ctx context.Context, taskID string, req models.CreateChecklistItem) (models.Task, error) {
+	return tr.updateChecklist(taskID, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		return append(checklist, models.ChecklistItem{ID: primitive.NewObjectID(), Title: req.Title}), nil
+	})
+}
+
+// UpdateChecklistItem changes the title or state of a checklist item.
+func (tr *TaskRepo) UpdateChecklistItem(ctx context.Context, taskID string, req models.UpdateChecklistItem) (models.Task, error) {
+	return tr.updateChecklist(taskID, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i := checklistIndex(checklist, req.ID)
+		if i < 0 {
+			return nil, models.ErrChecklistItemNotFound
+		}
+		if req.Title != nil {
+			checklist[i].Title = *req.Title
+		}
+		if req.Done != nil {
+			checklist[i].Done = *req.Done
+		}
+		return checklist, nil
+	})
+}
+
+// DeleteChecklistItem removes an item from the checklist of a task.
+func (tr *TaskRepo) DeleteChecklistItem(ctx context.Context, taskID string, itemID primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(taskID, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i := checklistIndex(checklist, itemID)
+		if i < 0 {
+			return nil, models.ErrChecklistItemNotFound
+		}
+		return append(checklist[:i], checklist[i+1:]...), nil
+	})
+}
+
+// ReorderChecklist puts the items of a checklist in the order of itemIDs.
+func (tr *TaskRepo) ReorderChecklist(ctx context.Context, taskID string, itemIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(taskID, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		return models.ReorderChecklistItems(checklist, itemIDs)
+	})
+}
+
+func (tr *TaskRepo) updateChecklist(taskID string, change func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error)) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	task, ok := tr.db.tasks[objectID]
+	if !ok {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	checklist, err := change(cloneChecklist(task.Checklist))
+	if err != nil {
+		return models.Task{}, err
+	}
+	task.Checklist = checklist
+	task.UpdatedAt = time.Now()
+	tr.db.tasks[objectID] = task
+
+	return cloneTask(task), nil
+}
+
+// CountSubtasks counts the subtasks of each of the parents.
+func (tr *TaskRepo) CountSubtasks(ctx context.Context, parentIDs []primitive.ObjectID) (map[primitive.ObjectID]models.SubtaskCount, error) {
+	tr.db.mu.RLock()
+	defer tr.db.mu.RUnlock()
+
+	counts := map[primitive.ObjectID]models.SubtaskCount{}
+	for _, task := range tr.db.tasks {
+		if task.ParentID == nil || !containsID(parentIDs, *task.ParentID) {
+			continue
+		}
+		count := counts[*task.ParentID]
+		count.Total++
+		if task.Completed {
+			count.Completed++
+		}
+		counts[*task.ParentID] = count
+	}
+	return counts, nil
+}
+
+// CompleteSubtasks completes the open subtasks of a task.
+func (tr *TaskRepo) CompleteSubtasks(ctx context.Context, parentID primitive.ObjectID) error {
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	now := time.Now()
+	for id, task := range tr.db.tasks {
+		if task.ParentID != nil && *task.ParentID == parentID && !task.Completed {
+			task.Completed = true
+			completedAt := now
+			task.CompletedAt = &completedAt
+			task.UpdatedAt = now
+			tr.db.tasks[id] = task
+		}
+	}
+	return nil
+}
+
+// GetAllTasks retrieves the tasks matching the filter with pagination.
+func (tr *TaskRepo) GetAllTasks(ctx context.Context, filter models.TaskFilter, page models.Pagination) ([]models.Task, int64, error) {
+	tr.db.mu.RLock()
+	defer tr.db.mu.RUnlock()
+
+	now := time.Now()
+	tasks := []models.Task{}
+	for _, task := range tr.db.tasks {
+		if !containsID(filter.TaskListIDs, task.TaskListID) || !containsFold(task.Title, filter.Search) || !matchLabels(task, filter.Labels) ||
+			!matchTask(task, filter, now) || !matchQuery(filter.Query, task, now) {
+			continue
+		}
+		tasks = append(tasks, cloneTask(task))
+	}
+
+	keys := filter.SortKeys()
+	return paginateSorted(tasks, keys, page, func(task models.Task) models.Cursor {
+		return task.SortCursor(keys)
+	}), int64(len(tasks)), nil
+}
+
+// matchTask reports whether a task passes the completion and time filters.
+func matchTask(task models.Task, filter models.TaskFilter, now time.Time) bool {
+	hasDueDate := !task.DueDate.IsZero()
+	switch {
+	case filter.ParentID != nil && (task.ParentID == nil || *task.ParentID != *filter.ParentID),
+		filter.Completed != nil && task.Completed != *filter.Completed,
+		filter.Overdue && (task.Completed || !hasDueDate || !task.DueDate.Before(now)),
+		filter.NoDueDate && hasDueDate,
+		filter.DueBefore != nil && !(hasDueDate && task.DueDate.Before(*filter.DueBefore)),
+		filter.DueAfter != nil && !task.DueDate.After(*filter.DueAfter),
+		filter.CreatedBefore != nil && !task.CreatedAt.Before(*filter.CreatedBefore),
+		filter.CreatedAfter != nil && !task.CreatedAt.After(*filter.CreatedAfter),
+		filter.UpdatedBefore != nil && !task.UpdatedAt.Before(*filter.UpdatedBefore),
+		filter.UpdatedAfter != nil && !task.UpdatedAt.After(*filter.UpdatedAfter),
+		filter.CompletedBefore != nil && !(task.CompletedAt != nil && task.CompletedAt.Before(*filter.CompletedBefore)),
+		filter.CompletedAfter != nil && !(task.CompletedAt != nil && task.CompletedAt.After(*filter.CompletedAfter)):
+		return false
+	}
+	return true
+}
+
+// matchLabels reports whether a task passes a label filter.
+func matchLabels(task models.Task, labels models.LabelFilter) bool {
+	if len(labels.LabelIDs) == 0 {
+		return true
+	}
+
+	for _, id := range labels.LabelIDs {
+		found := containsID(task.LabelIDs, id)
+		if labels.Match == models.LabelMatchAll && !found {
+			return false
+		}
+		if labels.Match != models.LabelMatchAll && found {
+			return true
+		}
+	}
+	return labels.Match == models.LabelMatchAll
+}
+
+func cloneTask(task models.Task) models.Task {
+	task.LabelIDs = cloneIDs(task.LabelIDs)
+	task.Checklist = cloneChecklist(task.Checklist)
+	task.Recurrence = cloneRecurrence(task.Recurrence)
+	if task.ParentID != nil {
+		parentID := *task.ParentID
+		task.ParentID = &parentID
+	}
+	if task.CompletedAt != nil {
+		completedAt := *task.CompletedAt
+		task.CompletedAt = &completedAt
+	}
+	return task
+}
+
+func cloneChecklist(checklist []models.ChecklistItem) []models.ChecklistItem {
+	return append([]models.ChecklistItem{}, checklist...)
+}
+
+func cloneRecurrence(recurrence *models.Recurrence) *models.Recurrence {
+	if recurrence == nil {
+		return nil
+	}
+	clone := *recurrence
+	return &clone
+}
+
+func checklistIndex(checklist []models.ChecklistItem, itemID primitive.ObjectID) int {
+	for i, item := range checklist {
+		if item.ID == itemID {
+			return i
+		}
+	}
+	return -1
+}
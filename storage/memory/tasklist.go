@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskListRepo struct {
+	db *DB
+}
+
+func NewTaskListRepo(db *DB) *TaskListRepo {
+	return &TaskListRepo{db: db}
+}
+
+// CreateTaskList stores a new task list.
+func (tlr *TaskListRepo) CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error) {
+	now := time.Now()
+	taskList := models.TaskList{
+		ID:          primitive.NewObjectID(),
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	tlr.db.mu.Lock()
+	defer tlr.db.mu.Unlock()
+
+	tlr.db.taskLists[taskList.ID] = taskList
+	return taskList, nil
+}
+
+// GetTaskList retrieves a task list by ID.
+func (tlr *TaskListRepo) GetTaskList(ctx context.Context, taskListID string) (models.TaskList, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskListID)
+	if err != nil {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+
+	tlr.db.mu.RLock()
+	defer tlr.db.mu.RUnlock()
+
+	taskList, ok := tlr.db.taskLists[objectID]
+	if !ok {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+	return taskList, nil
+}
+
+// UpdateTaskList updates an existing task list.
+func (tlr *TaskListRepo) UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error) {
+	tlr.db.mu.Lock()
+	defer tlr.db.mu.Unlock()
+
+	taskList, ok := tlr.db.taskLists[req.ID]
+	if !ok {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+	taskList.Title = req.Title
+	taskList.Description = req.Description
+	taskList.UpdatedAt = time.Now()
+	tlr.db.taskLists[taskList.ID] = taskList
+
+	return taskList, nil
+}
+
+// DeleteTaskList removes a task list together with its tasks. With dryRun
+// nothing is deleted and the summary holds what would have been removed.
+func (tlr *TaskListRepo) DeleteTaskList(ctx context.Context, taskListID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	objectID, err := primitive.ObjectIDFromHex(taskListID)
+	if err != nil {
+		return summary, models.ErrTaskListNotFound
+	}
+
+	tlr.db.mu.Lock()
+	defer tlr.db.mu.Unlock()
+
+	if _, ok := tlr.db.taskLists[objectID]; !ok {
+		return summary, models.ErrTaskListNotFound
+	}
+	summary.TaskLists = 1
+
+	taskIDs := map[primitive.ObjectID]bool{}
+	for id, task := range tlr.db.tasks {
+		if task.TaskListID == objectID {
+			taskIDs[id] = true
+			summary.Tasks++
+			if !dryRun {
+				delete(tlr.db.tasks, id)
+			}
+		}
+	}
+
+	for id, reminder := range tlr.db.reminders {
+		if taskIDs[reminder.TaskID] {
+			summary.Reminders++
+			if !dryRun {
+				delete(tlr.db.reminders, id)
+			}
+		}
+	}
+
+	if !dryRun {
+		delete(tlr.db.taskLists, objectID)
+	}
+	return summary, nil
+}
+
+// GetAllTaskLists retrieves all task lists for a user with pagination.
+func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, page models.Pagination) ([]models.TaskList, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+
+	tlr.db.mu.RLock()
+	defer tlr.db.mu.RUnlock()
+
+	taskLists := []models.TaskList{}
+	for _, taskList := range sortedValues(tlr.db.taskLists) {
+		if taskList.UserID == objectID {
+			taskLists = append(taskLists, taskList)
+		}
+	}
+
+	return paginate(taskLists, page), int64(len(taskLists)), nil
+}
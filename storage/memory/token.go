@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TokenRepo struct {
+	db *DB
+}
+
+func NewTokenRepo(db *DB) *TokenRepo {
+	return &TokenRepo{db: db}
+}
+
+// CreateRefreshToken stores a hashed refresh token.
+func (tr *TokenRepo) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	token.CreatedAt = time.Now()
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	for _, existing := range tr.db.refreshTokens {
+		if existing.TokenHash == token.TokenHash {
+			return errors.New("error while creating refresh token: duplicate token hash")
+		}
+	}
+	tr.db.refreshTokens[token.ID] = token
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by the hash of its value.
+func (tr *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	tr.db.mu.RLock()
+	defer tr.db.mu.RUnlock()
+
+	for _, token := range tr.db.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, errors.New("refresh token not found")
+}
+
+// MarkRefreshTokenUsed flags an unused refresh token as used. It reports false
+// when the token had already been used or revoked.
+func (tr *TokenRepo) MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid refresh token id: %w", err)
+	}
+
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	token, ok := tr.db.refreshTokens[objectID]
+	if !ok || token.Used || token.Revoked {
+		return false, nil
+	}
+	token.Used = true
+	tr.db.refreshTokens[objectID] = token
+	return true, nil
+}
+
+// RevokeTokenFamily revokes every refresh token descended from the same login.
+func (tr *TokenRepo) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	tr.revoke(func(token models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+// RevokeDeviceTokens revokes the refresh tokens a user holds on one device.
+func (tr *TokenRepo) RevokeDeviceTokens(ctx context.Context, userID, deviceID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	tr.revoke(func(token models.RefreshToken) bool {
+		return token.UserID == objectID && token.DeviceID == deviceID
+	})
+	return nil
+}
+
+// RevokeUserTokens revokes all refresh tokens of a user.
+func (tr *TokenRepo) RevokeUserTokens(ctx context.Context, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	tr.revoke(func(token models.RefreshToken) bool { return token.UserID == objectID })
+	return nil
+}
+
+func (tr *TokenRepo) revoke(match func(token models.RefreshToken) bool) {
+	tr.db.mu.Lock()
+	defer tr.db.mu.Unlock()
+
+	for id, token := range tr.db.refreshTokens {
+		if !token.Revoked && match(token) {
+			token.Revoked = true
+			tr.db.refreshTokens[id] = token
+		}
+	}
+}
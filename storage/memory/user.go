@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserRepo struct {
+	db *DB
+}
+
+func NewUserRepo(db *DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+// CreateUser stores a new user. req.Password must already be hashed. Emails
+// are unique, like the users.email index in MongoDB.
+func (ur *UserRepo) CreateUser(ctx context.Context, req models.CreateUser) (models.User, error) {
+	ur.db.mu.Lock()
+	defer ur.db.mu.Unlock()
+
+	for _, user := range ur.db.users {
+		if user.Email == req.Email {
+			return models.User{}, fmt.Errorf("duplicate email %q", req.Email)
+		}
+	}
+
+	now := time.Now()
+	user := models.User{
+		ID:        primitive.NewObjectID(),
+		Username:  req.Username,
+		Email:     req.Email,
+		Password:  req.Password,
+		Role:      req.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	ur.db.users[user.ID] = user
+
+	return user, nil
+}
+
+// GetUser retrieves a user by ID.
+func (ur *UserRepo) GetUser(ctx context.Context, userID string) (models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.User{}, models.ErrUserNotFound
+	}
+
+	ur.db.mu.RLock()
+	defer ur.db.mu.RUnlock()
+
+	user, ok := ur.db.users[objectID]
+	if !ok {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// GetUserByEmail retrieves a user by email.
+func (ur *UserRepo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	ur.db.mu.RLock()
+	defer ur.db.mu.RUnlock()
+
+	for _, user := range ur.db.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, models.ErrUserNotFound
+}
+
+// UpdateUser updates an existing user.
+func (ur *UserRepo) UpdateUser(ctx context.Context, req models.UpdateUser) (models.User, error) {
+	ur.db.mu.Lock()
+	defer ur.db.mu.Unlock()
+
+	user, ok := ur.db.users[req.ID]
+	if !ok {
+		return models.User{}, models.ErrUserNotFound
+	}
+	user.Username = req.Username
+	user.Email = req.Email
+	user.UpdatedAt = time.Now()
+	ur.db.users[user.ID] = user
+
+	return user, nil
+}
+
+// UpdatePassword replaces the password hash of a user.
+func (ur *UserRepo) UpdatePassword(ctx context.Context, userID string, passwordHash string) error {
+	return ur.set(userID, func(user *models.User) { user.Password = passwordHash })
+}
+
+// UpdateUserRole changes the role of a user.
+func (ur *UserRepo) UpdateUserRole(ctx context.Context, userID string, role string) error {
+	return ur.set(userID, func(user *models.User) { user.Role = role })
+}
+
+// SetUserDisabled disables or re-enables a user.
+func (ur *UserRepo) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	return ur.set(userID, func(user *models.User) { user.Disabled = disabled })
+}
+
+// BumpTokenVersion invalidates the access tokens issued to a user so far.
+func (ur *UserRepo) BumpTokenVersion(ctx context.Context, userID string) error {
+	return ur.set(userID, func(user *models.User) { user.TokenVersion++ })
+}
+
+// set applies change to a single user and bumps UpdatedAt.
+func (ur *UserRepo) set(userID string, change func(user *models.User)) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrUserNotFound
+	}
+
+	ur.db.mu.Lock()
+	defer ur.db.mu.Unlock()
+
+	user, ok := ur.db.users[objectID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	change(&user)
+	user.UpdatedAt = time.Now()
+	ur.db.users[objectID] = user
+
+	return nil
+}
+
+// DeleteUser removes a user and everything they own (task lists, tasks,
+// labels, refresh tokens and password resets). With dryRun nothing is
+// deleted and the summary holds what would have been removed.
+func (ur *UserRepo) DeleteUser(ctx context.Context, userID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return summary, models.ErrUserNotFound
+	}
+
+	ur.db.mu.Lock()
+	defer ur.db.mu.Unlock()
+
+	if _, ok := ur.db.users[objectID]; !ok {
+		return summary, models.ErrUserNotFound
+	}
+	summary.Users = 1
+
+	for id, taskList := range ur.db.taskLists {
+		if taskList.UserID != objectID {
+			continue
+		}
+		summary.TaskLists++
+		for taskID, task := range ur.db.tasks {
+			if task.TaskListID == id {
+				summary.Tasks++
+				if !dryRun {
+					delete(ur.db.tasks, taskID)
+				}
+			}
+		}
+		if !dryRun {
+			delete(ur.db.taskLists, id)
+		}
+	}
+
+	for id, label := range ur.db.labels {
+		if label.UserID == objectID {
+			summary.Labels++
+			if !dryRun {
+				delete(ur.db.labels, id)
+			}
+		}
+	}
+
+	for id, token := range ur.db.refreshTokens {
+		if token.UserID == objectID {
+			summary.RefreshTokens++
+			if !dryRun {
+				delete(ur.db.refreshTokens, id)
+			}
+		}
+	}
+
+	for id, reset := range ur.db.passwordResets {
+		if reset.UserID == objectID {
+			summary.PasswordResets++
+			if !dryRun {
+				delete(ur.db.passwordResets, id)
+			}
+		}
+	}
+
+	for id, smartList := range ur.db.smartLists {
+		if smartList.UserID == objectID {
+			summary.SmartLists++
+			if !dryRun {
+				delete(ur.db.smartLists, id)
+			}
+		}
+	}
+
+	for id, reminder := range ur.db.reminders {
+		if reminder.UserID == objectID {
+			summary.Reminders++
+			if !dryRun {
+				delete(ur.db.reminders, id)
+			}
+		}
+	}
+
+	if !dryRun {
+		for id, webhook := range ur.db.webhooks {
+			if webhook.UserID == objectID {
+				delete(ur.db.webhooks, id)
+			}
+		}
+		for id, delivery := range ur.db.webhookDeliveries {
+			if delivery.UserID == objectID {
+				delete(ur.db.webhookDeliveries, id)
+			}
+		}
+		delete(ur.db.notificationSettings, objectID)
+		delete(ur.db.notificationPreferences, objectID)
+		delete(ur.db.digestSettings, objectID)
+		delete(ur.db.users, objectID)
+	}
+	return summary, nil
+}
+
+// GetAllUsers retrieves all users with pagination.
+func (ur *UserRepo) GetAllUsers(ctx context.Context, page models.Pagination) ([]models.User, int64, error) {
+	ur.db.mu.RLock()
+	defer ur.db.mu.RUnlock()
+
+	users := sortedValues(ur.db.users)
+	return paginate(users, page), int64(len(users)), nil
+}
+
+// CountUsersWithRole counts the users that have role.
+func (ur *UserRepo) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	ur.db.mu.RLock()
+	defer ur.db.mu.RUnlock()
+
+	var count int64
+	for _, user := range ur.db.users {
+		if user.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
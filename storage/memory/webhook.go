@@ -0,0 +1,282 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepo struct {
+	db *DB
+}
+
+func NewWebhookRepo(db *DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+// CreateWebhook stores a new, active webhook.
+func (wr *WebhookRepo) CreateWebhook(ctx context.Context, req models.CreateWebhook) (models.Webhook, error) {
+	now := time.Now()
+	webhook := models.Webhook{
+		ID:          primitive.NewObjectID(),
+		UserID:      req.UserID,
+		URL:         req.URL,
+		Events:      append([]string{}, req.Events...),
+		Description: req.Description,
+		Secret:      req.Secret,
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	wr.db.mu.Lock()
+	defer wr.db.mu.Unlock()
+
+	wr.db.webhooks[webhook.ID] = webhook
+	return cloneWebhook(webhook), nil
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (wr *WebhookRepo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+
+	wr.db.mu.RLock()
+	defer wr.db.mu.RUnlock()
+
+	webhook, ok := wr.db.webhooks[objectID]
+	if !ok {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+	return cloneWebhook(webhook), nil
+}
+
+// GetAllWebhooks retrieves the webhooks of a user with pagination support.
+func (wr *WebhookRepo) GetAllWebhooks(ctx context.Context, userID string, page models.Pagination) ([]models.Webhook, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+
+	wr.db.mu.RLock()
+	defer wr.db.mu.RUnlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range sortedValues(wr.db.webhooks) {
+		if webhook.UserID == objectID {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	return paginate(webhooks, page), int64(len(webhooks)), nil
+}
+
+// UpdateWebhook replaces the endpoint, events and description of a webhook,
+// and pauses or resumes it.
+func (wr *WebhookRepo) UpdateWebhook(ctx context.Context, req models.UpdateWebhook) (models.Webhook, error) {
+	wr.db.mu.Lock()
+	defer wr.db.mu.Unlock()
+
+	webhook, ok := wr.db.webhooks[req.ID]
+	if !ok {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+	webhook.URL = req.URL
+	webhook.Events = append([]string{}, req.Events...)
+	webhook.Description = req.Description
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	webhook.UpdatedAt = time.Now()
+	wr.db.webhooks[webhook.ID] = webhook
+
+	return cloneWebhook(webhook), nil
+}
+
+// DeleteWebhook removes a webhook and its deliveries.
+func (wr *WebhookRepo) DeleteWebhook(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.ErrWebhookNotFound
+	}
+
+	wr.db.mu.Lock()
+	defer wr.db.mu.Unlock()
+
+	if _, ok := wr.db.webhooks[objectID]; !ok {
+		return models.ErrWebhookNotFound
+	}
+	delete(wr.db.webhooks, objectID)
+	for deliveryID, delivery := range wr.db.webhookDeliveries {
+		if delivery.WebhookID == objectID {
+			delete(wr.db.webhookDeliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// SubscribedWebhooks returns the active webhooks of a user subscribed to
+// event, the oldest first.
+func (wr *WebhookRepo) SubscribedWebhooks(ctx context.Context, userID primitive.ObjectID, event string) ([]models.Webhook, error) {
+	wr.db.mu.RLock()
+	defer wr.db.mu.RUnlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range sortedValues(wr.db.webhooks) {
+		if webhook.UserID == userID && webhook.Active && containsString(webhook.Events, event) {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	return webhooks, nil
+}
+
+// EnqueueDelivery queues an event for a webhook.
+func (wr *WebhookRepo) EnqueueDelivery(ctx context.Context, req models.EnqueueDelivery) (models.WebhookDelivery, error) {
+	nextAttemptAt := req.NextAttemptAt
+	delivery := models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     req.WebhookID,
+		UserID:        req.UserID,
+		EventID:       req.EventID,
+		Event:         req.Event,
+		Payload:       append(json.RawMessage{}, req.Payload...),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &nextAttemptAt,
+		CreatedAt:     time.Now(),
+	}
+	if req.RedeliveryOf != nil {
+		redeliveryOf := *req.RedeliveryOf
+		delivery.RedeliveryOf = &redeliveryOf
+	}
+
+	wr.db.mu.Lock()
+	defer wr.db.mu.Unlock()
+
+	wr.db.webhookDeliveries[delivery.ID] = delivery
+	return cloneDelivery(delivery), nil
+}
+
+// GetDelivery retrieves a delivery by its ID.
+func (wr *WebhookRepo) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+
+	wr.db.mu.RLock()
+	defer wr.db.mu.RUnlock()
+
+	delivery, ok := wr.db.webhookDeliveries[objectID]
+	if !ok {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+	return cloneDelivery(delivery), nil
+}
+
+// GetDeliveries returns the delivery log of a webhook, the latest first.
+func (wr *WebhookRepo) GetDeliveries(ctx context.Context, webhookID string, page models.Pagination) ([]models.WebhookDelivery, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, 0, models.ErrWebhookNotFound
+	}
+
+	wr.db.mu.RLock()
+	defer wr.db.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range wr.db.webhookDeliveries {
+		if delivery.WebhookID == objectID {
+			deliveries = append(deliveries, cloneDelivery(delivery))
+		}
+	}
+	count := int64(len(deliveries))
+	return paginateSorted(deliveries, models.DeliverySort, page, models.WebhookDelivery.Cursor), count, nil
+}
+
+// DueDeliveries returns the pending deliveries due at or before now.
+func (wr *WebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	wr.db.mu.RLock()
+	defer wr.db.mu.RUnlock()
+
+	due := []models.WebhookDelivery{}
+	for _, delivery := range wr.db.webhookDeliveries {
+		if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, cloneDelivery(delivery))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].ID.Hex() < due[j].ID.Hex()
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// RecordAttempt stores the outcome of posting a delivery, unless it is no
+// longer pending at the time it was read.
+func (wr *WebhookRepo) RecordAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	wr.db.mu.Lock()
+	defer wr.db.mu.Unlock()
+
+	delivery, ok := wr.db.webhookDeliveries[attempt.ID]
+	if !ok || delivery.Status != models.DeliveryPending || delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(attempt.ScheduledAt) {
+		return models.ErrDeliveryNotFound
+	}
+	delivery.Status = attempt.Status
+	delivery.Attempts = attempt.Attempts
+	delivery.LastError = attempt.LastError
+	delivery.ResponseStatus = attempt.ResponseStatus
+	delivery.ResponseBody = attempt.ResponseBody
+	delivery.NextAttemptAt = nil
+	if attempt.NextAttemptAt != nil {
+		nextAttemptAt := *attempt.NextAttemptAt
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	delivery.DeliveredAt = nil
+	if attempt.DeliveredAt != nil {
+		deliveredAt := *attempt.DeliveredAt
+		delivery.DeliveredAt = &deliveredAt
+	}
+	wr.db.webhookDeliveries[delivery.ID] = delivery
+	return nil
+}
+
+func cloneWebhook(webhook models.Webhook) models.Webhook {
+	webhook.Events = append([]string{}, webhook.Events...)
+	return webhook
+}
+
+func cloneDelivery(delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Payload = append(json.RawMessage{}, delivery.Payload...)
+	if delivery.NextAttemptAt != nil {
+		nextAttemptAt := *delivery.NextAttemptAt
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.RedeliveryOf != nil {
+		redeliveryOf := *delivery.RedeliveryOf
+		delivery.RedeliveryOf = &redeliveryOf
+	}
+	if delivery.DeliveredAt != nil {
+		deliveredAt := *delivery.DeliveredAt
+		delivery.DeliveredAt = &deliveredAt
+	}
+	return delivery
+}
+
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DigestRepo struct {
+	db  *mongo.Database
+	log logger.ILogger
+}
+
+func NewDigestRepo(db *mongo.Database, log logger.ILogger) *DigestRepo {
+	return &DigestRepo{db: db, log: log}
+}
+
+// GetDigestSettings returns the settings of a user, or the defaults.
+func (dr *DigestRepo) GetDigestSettings(ctx context.Context, userID primitive.ObjectID) (models.DigestSettings, error) {
+	var settings models.DigestSettings
+	err := dr.db.Collection("digest_settings").FindOne(ctx, bson.M{"_id": userID}).Decode(&settings)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.DefaultDigestSettings(userID), nil
+		}
+		dr.log.Error("Error retrieving digest settings", logger.Error(err))
+		return models.DigestSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveDigestSettings creates or replaces the settings of a user, keeping
+// when the last digest was sent.
+func (dr *DigestRepo) SaveDigestSettings(ctx context.Context, req models.UpdateDigestSettings) (models.DigestSettings, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"frequency":  req.Frequency,
+			"send_at":    req.SendAt,
+			"weekday":    req.Weekday,
+			"timezone":   req.Timezone,
+			"updated_at": time.Now(),
+		},
+	}
+	if req.NextAt != nil {
+		update["$set"].(bson.M)["next_at"] = *req.NextAt
+	} else {
+		update["$unset"] = bson.M{"next_at": ""}
+	}
+
+	var settings models.DigestSettings
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := dr.db.Collection("digest_settings").FindOneAndUpdate(ctx, bson.M{"_id": req.UserID}, update, opts).Decode(&settings)
+	if err != nil {
+		dr.log.Error("Error saving digest settings", logger.Error(err))
+		return models.DigestSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// DueDigests returns the settings whose next digest is due at or before now.
+func (dr *DigestRepo) DueDigests(ctx context.Context, now time.Time, limit int) ([]models.DigestSettings, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := dr.db.Collection("digest_settings").Find(ctx, bson.M{"next_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		dr.log.Error("Error retrieving due digests", logger.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	due := []models.DigestSettings{}
+	if err := cursor.All(ctx, &due); err != nil {
+		dr.log.Error("Error decoding due digests", logger.Error(err))
+		return nil, err
+	}
+	return due, nil
+}
+
+// RecordDigest stores the outcome of a digest and when the next is sent,
+// unless the settings have been saved since it fell due.
+func (dr *DigestRepo) RecordDigest(ctx context.Context, delivery models.DigestDelivery) error {
+	update := bson.M{"$set": bson.M{"next_at": delivery.NextAt}}
+	if delivery.SentAt != nil {
+		update["$set"].(bson.M)["last_sent_at"] = *delivery.SentAt
+	}
+
+	res, err := dr.db.Collection("digest_settings").UpdateOne(ctx, bson.M{"_id": delivery.UserID, "next_at": delivery.ScheduledAt}, update)
+	if err != nil {
+		dr.log.Error("Error recording digest", logger.Error(err))
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrDigestNotFound
+	}
+	return nil
+}
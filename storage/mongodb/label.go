@@ -3,17 +3,19 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"time"
 	"todo/api/models"
-	"todo/pkg"
 	"todo/pkg/logger"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type LabelRepo struct {
 	collection *mongo.Collection
+	tasks      *mongo.Collection
 	logger     logger.ILogger
 }
 
@@ -21,16 +23,21 @@ type LabelRepo struct {
 func NewLabelRepo(db *mongo.Database, log logger.ILogger) *LabelRepo {
 	return &LabelRepo{
 		collection: db.Collection("labels"),
+		tasks:      db.Collection("tasks"),
 		logger:     log,
 	}
 }
 
 // CreateLabel creates a new label in the database.
 func (lr *LabelRepo) CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error) {
+	now := time.Now()
 	label := models.Label{
-		Id:     pkg.GenerateUUID(),
-		Name:   req.Name,
-		UserID: req.UserID,
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
 	_, err := lr.collection.InsertOne(ctx, label)
@@ -43,11 +50,16 @@ func (lr *LabelRepo) CreateLabel(ctx context.Context, req models.CreateLabel) (m
 
 // GetLabel retrieves a label by its ID.
 func (lr *LabelRepo) GetLabel(ctx context.Context, labelID string) (models.Label, error) {
+	objectID, err := primitive.ObjectIDFromHex(labelID)
+	if err != nil {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+
 	var label models.Label
-	err := lr.collection.FindOne(ctx, bson.M{"id": labelID}).Decode(&label)
+	err = lr.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&label)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return models.Label{}, fmt.Errorf("label not found: %w", err)
+			return models.Label{}, models.ErrLabelNotFound
 		}
 		lr.logger.Error("error while getting label from db", logger.Error(err))
 		return models.Label{}, fmt.Errorf("error while getting label: %w", err)
@@ -57,42 +69,71 @@ func (lr *LabelRepo) GetLabel(ctx context.Context, labelID string) (models.Label
 
 // UpdateLabel updates the label information.
 func (lr *LabelRepo) UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error) {
-	filter := bson.M{"id": req.Id}
-	update := bson.M{"$set": bson.M{"name": req.Name}}
+	filter := bson.M{"_id": req.ID}
+	update := bson.M{"$set": bson.M{
+		"name":       req.Name,
+		"color":      req.Color,
+		"updated_at": time.Now(),
+	}}
 
-	_, err := lr.collection.UpdateOne(ctx, filter, update)
+	res, err := lr.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		lr.logger.Error("error while updating label in db", logger.Error(err))
 		return models.Label{}, fmt.Errorf("error while updating label: %w", err)
 	}
+	if res.MatchedCount == 0 {
+		return models.Label{}, models.ErrLabelNotFound
+	}
 
-	return models.Label{
-		Id:     req.Id,
-		Name:   req.Name,
-		UserID: req.UserID,
-	}, nil
+	return lr.GetLabel(ctx, req.ID.Hex())
 }
 
-// DeleteLabel removes a label by its ID.
+// DeleteLabel removes a label by its ID and pulls it from the tasks that
+// reference it, in a single transaction.
 func (lr *LabelRepo) DeleteLabel(ctx context.Context, labelID string) error {
-	_, err := lr.collection.DeleteOne(ctx, bson.M{"id": labelID})
+	objectID, err := primitive.ObjectIDFromHex(labelID)
 	if err != nil {
+		return models.ErrLabelNotFound
+	}
+
+	err = withTransaction(ctx, lr.collection.Database(), func(sc mongo.SessionContext) error {
+		res, err := lr.collection.DeleteOne(sc, bson.M{"_id": objectID})
+		if err != nil {
+			return fmt.Errorf("error while deleting label: %w", err)
+		}
+		if res.DeletedCount == 0 {
+			return models.ErrLabelNotFound
+		}
+
+		_, err = lr.tasks.UpdateMany(sc, bson.M{"label_ids": objectID}, bson.M{"$pull": bson.M{"label_ids": objectID}})
+		if err != nil {
+			return fmt.Errorf("error while removing label from tasks: %w", err)
+		}
+		return nil
+	})
+	if err != nil && err != models.ErrLabelNotFound {
 		lr.logger.Error("error while deleting label from db", logger.Error(err))
-		return fmt.Errorf("error while deleting label: %w", err)
 	}
-	return nil
+	return err
 }
 
 // GetAllLabels retrieves all labels for a specific user with pagination support.
-func (lr *LabelRepo) GetAllLabels(ctx context.Context, userID string, search string, page, limit uint64) ([]models.Label, int64, error) {
-	var labels []models.Label
-	count, err := lr.collection.CountDocuments(ctx, bson.M{"user_id": userID, "name": bson.M{"$regex": search, "$options": "i"}})
+func (lr *LabelRepo) GetAllLabels(ctx context.Context, userID string, search string, page models.Pagination) ([]models.Label, int64, error) {
+	labels := []models.Label{}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	filter := bson.M{"user_id": objectID, "name": bson.M{"$regex": regexp.QuoteMeta(search), "$options": "i"}}
+
+	count, err := lr.collection.CountDocuments(ctx, filter)
 	if err != nil {
 		lr.logger.Error("error while counting labels in db", logger.Error(err))
 		return nil, 0, fmt.Errorf("error while counting labels: %w", err)
 	}
 
-	cursor, err := lr.collection.Find(ctx, bson.M{"user_id": userID, "name": bson.M{"$regex": search, "$options": "i"}}, options.Find().SetSkip((page-1)*limit).SetLimit(limit))
+	cursor, err := lr.collection.Find(ctx, pageFilter(filter, page), pageOptions(page))
 	if err != nil {
 		lr.logger.Error("error while getting labels from db", logger.Error(err))
 		return nil, 0, fmt.Errorf("error while getting labels: %w", err)
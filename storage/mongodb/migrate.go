@@ -0,0 +1,365 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration is one step of the schema. Versions are applied in ascending
+// order and recorded in the schema_migrations collection. MongoDB cannot run
+// collection and index changes in a transaction, so Up and Down must be safe
+// to run again after a partial failure.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+}
+
+// ErrMigrationLocked is returned when another migrator held the lock for
+// longer than the migrator was willing to wait.
+var ErrMigrationLocked = errors.New("migrations are locked by another process")
+
+// ErrMigrationLockLost is returned when the lock could not be kept while
+// migrating, so another migrator may have taken it over.
+var ErrMigrationLockLost = errors.New("lost the migration lock")
+
+const (
+	migrationsCollection    = "schema_migrations"
+	migrationLockCollection = "schema_migrations_lock"
+	migrationLockID         = "lock"
+
+	// A lock older than this was left behind by a migrator that died and
+	// may be taken over. The holder refreshes it every
+	// migrationLockRefresh, so a running migration keeps it however long
+	// it takes.
+	migrationLockTimeout = 2 * time.Minute
+	migrationLockRefresh = 20 * time.Second
+	// How long to wait for another migrator before giving up, and how often
+	// to check on it.
+	migrationLockWait = 2 * time.Minute
+	migrationLockPoll = time.Second
+)
+
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrator applies and rolls back the migrations of the todo database.
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+}
+
+// NewMigrator returns a migrator for the migrations defined in this package.
+func NewMigrator(db *mongo.Database) *Migrator {
+	hostname, _ := os.Hostname()
+
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}
+}
+
+// Up applies every migration that has not been applied yet and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := []Migration{}
+
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := migration.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+			}
+
+			_, err := m.db.Collection(migrationsCollection).InsertOne(ctx, appliedMigration{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("error while recording migration %d: %w", migration.Version, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last n applied migrations, newest first, and returns them.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	done := []Migration{}
+
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		versions := []int{}
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		if n < len(versions) {
+			versions = versions[:n]
+		}
+
+		for _, version := range versions {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not defined, refusing to roll back past it", version)
+			}
+			if err := migration.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("rolling back migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+			}
+
+			_, err := m.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": version})
+			if err != nil {
+				return fmt.Errorf("error while removing migration %d: %w", version, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every defined migration and whether it has been applied.
+// Applied versions this binary does not know about are listed too.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Version:     record.Version,
+			Description: record.Description + " (unknown to this version)",
+			Applied:     true,
+			AppliedAt:   record.AppliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// applied returns the recorded migrations by version.
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("error while reading applied migrations: %w", err)
+	}
+
+	records := []appliedMigration{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("error while decoding applied migrations: %w", err)
+	}
+
+	applied := map[int]appliedMigration{}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// withLock runs fn while holding the migration lock, waiting for another
+// migrator to finish first. The lock is a single document, so only one
+// insert of it can succeed. While fn runs the lock is kept fresh, and fn's
+// context is cancelled if that fails.
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	deadline := time.Now().Add(migrationLockWait)
+	for {
+		locked, err := m.lock(ctx)
+		if err != nil {
+			return err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationLockPoll):
+		}
+	}
+	defer m.unlock()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go m.keepLock(ctx, cancel)
+
+	if err := fn(ctx); err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, ErrMigrationLockLost) {
+			return cause
+		}
+		return err
+	}
+	return nil
+}
+
+// keepLock refreshes the lock until ctx is done, so other migrators do not
+// take it over as abandoned. If the lock is gone or cannot be refreshed it
+// cancels ctx with ErrMigrationLockLost.
+func (m *Migrator) keepLock(ctx context.Context, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(migrationLockRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		res, err := m.db.Collection(migrationLockCollection).UpdateOne(ctx,
+			bson.M{"_id": migrationLockID, "owner": m.owner},
+			bson.M{"$set": bson.M{"locked_at": time.Now()}},
+		)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			cancel(fmt.Errorf("%w: %v", ErrMigrationLockLost, err))
+			return
+		}
+		if res.MatchedCount == 0 {
+			cancel(ErrMigrationLockLost)
+			return
+		}
+	}
+}
+
+func (m *Migrator) lock(ctx context.Context) (bool, error) {
+	collection := m.db.Collection(migrationLockCollection)
+	now := time.Now()
+
+	_, err := collection.InsertOne(ctx, bson.M{"_id": migrationLockID, "owner": m.owner, "locked_at": now})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("error while taking migration lock: %w", err)
+	}
+
+	// Take the lock over if its holder has been gone for too long
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"_id": migrationLockID, "locked_at": bson.M{"$lt": now.Add(-migrationLockTimeout)}},
+		bson.M{"$set": bson.M{"owner": m.owner, "locked_at": now}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("error while taking migration lock: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// unlock releases the lock if it is still ours. It runs on a fresh context so
+// the lock is released even when the migration was cancelled.
+func (m *Migrator) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m.db.Collection(migrationLockCollection).DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": m.owner})
+}
+
+// createCollection creates a collection unless it already exists.
+func createCollection(ctx context.Context, db *mongo.Database, name string) error {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return nil
+	}
+	return db.CreateCollection(ctx, name)
+}
+
+// setValidator replaces the $jsonSchema validator of a collection. A nil
+// schema removes validation. Validation is moderate, so documents written
+// before the validator existed can still be updated.
+func setValidator(ctx context.Context, db *mongo.Database, name string, schema bson.M) error {
+	validator := bson.M{}
+	if schema != nil {
+		validator = bson.M{"$jsonSchema": schema}
+	}
+
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: name},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}).Err()
+}
+
+// dropIndexes drops indexes by their keys, ignoring ones that do not exist.
+func dropIndexes(ctx context.Context, collection *mongo.Collection, indexes []mongo.IndexModel) error {
+	for _, index := range indexes {
+		var err error
+		// Text indexes are stored under other keys than they are declared
+		// with, so they are named and dropped by name
+		if index.Options != nil && index.Options.Name != nil {
+			_, err = collection.Indexes().DropOne(ctx, *index.Options.Name)
+		} else {
+			_, err = collection.Indexes().DropOneWithKey(ctx, index.Keys)
+		}
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound") {
+			return err
+		}
+	}
+	return nil
+}
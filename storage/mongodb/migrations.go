@@ -0,0 +1,553 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations is the schema of the todo database, oldest first. Append new
+// migrations with the next version; never change one that has shipped.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create collections with validators",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for name, schema := range collectionSchemas {
+				if err := createCollection(ctx, db, name); err != nil {
+					return err
+				}
+				if err := setValidator(ctx, db, name, schema); err != nil {
+					return err
+				}
+			}
+
+			// The old mongo-shell script created this instead of task_lists
+			// and nothing ever wrote to it
+			count, err := db.Collection("taskLists").EstimatedDocumentCount(ctx)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return db.Collection("taskLists").Drop(ctx)
+			}
+			return nil
+		},
+		// Collections are kept so rolling back never loses data
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for name := range collectionSchemas {
+				if err := setValidator(ctx, db, name, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	indexMigration(2, "create indexes", collectionIndexes),
+	indexMigration(3, "create pagination indexes", paginationIndexes),
+	indexMigration(4, "create task filter indexes", taskFilterIndexes),
+	{
+		Version:     5,
+		Description: "create smart lists",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "smart_lists"); err != nil {
+				return err
+			}
+			if err := setValidator(ctx, db, "smart_lists", smartListSchema); err != nil {
+				return err
+			}
+			_, err := db.Collection("smart_lists").Indexes().CreateMany(ctx, smartListIndexes)
+			return err
+		},
+		// Like migration 1, the collection is kept so no data is lost
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("smart_lists"), smartListIndexes); err != nil {
+				return err
+			}
+			return setValidator(ctx, db, "smart_lists", nil)
+		},
+	},
+	indexMigration(6, "create text search indexes", searchIndexes),
+	indexMigration(7, "create subtask index", subtaskIndexes),
+	{
+		Version:     8,
+		Description: "create reminders",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "reminders"); err != nil {
+				return err
+			}
+			if err := setValidator(ctx, db, "reminders", reminderSchema); err != nil {
+				return err
+			}
+			_, err := db.Collection("reminders").Indexes().CreateMany(ctx, reminderIndexes)
+			return err
+		},
+		// Like migration 5, the collection is kept so no data is lost
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("reminders"), reminderIndexes); err != nil {
+				return err
+			}
+			return setValidator(ctx, db, "reminders", nil)
+		},
+	},
+	{
+		Version:     9,
+		Description: "create notification settings",
+		// Settings are looked up by user ID, which is their _id, so there are
+		// no indexes to create
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "notification_settings"); err != nil {
+				return err
+			}
+			return setValidator(ctx, db, "notification_settings", notificationSettingsSchema)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return setValidator(ctx, db, "notification_settings", nil)
+		},
+	},
+	{
+		Version:     10,
+		Description: "create digest settings",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Tasks completed before completed_at existed count as completed
+			// when last updated
+			_, err := db.Collection("tasks").UpdateMany(ctx,
+				bson.M{"completed": true, "completed_at": bson.M{"$exists": false}},
+				bson.A{bson.M{"$set": bson.M{"completed_at": "$updated_at"}}},
+			)
+			if err != nil {
+				return err
+			}
+			if err := createCollection(ctx, db, "digest_settings"); err != nil {
+				return err
+			}
+			if err := setValidator(ctx, db, "digest_settings", digestSettingsSchema); err != nil {
+				return err
+			}
+			_, err = db.Collection("digest_settings").Indexes().CreateMany(ctx, digestSettingsIndexes)
+			return err
+		},
+		// Like migration 8, the collection is kept so no data is lost
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("digest_settings"), digestSettingsIndexes); err != nil {
+				return err
+			}
+			return setValidator(ctx, db, "digest_settings", nil)
+		},
+	},
+	{
+		Version:     11,
+		Description: "create webhooks",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for name, schema := range map[string]bson.M{"webhooks": webhookSchema, "webhook_deliveries": webhookDeliverySchema} {
+				if err := createCollection(ctx, db, name); err != nil {
+					return err
+				}
+				if err := setValidator(ctx, db, name, schema); err != nil {
+					return err
+				}
+			}
+			for name, list := range webhookIndexes {
+				if _, err := db.Collection(name).Indexes().CreateMany(ctx, list); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// Like migration 8, the collections are kept so no data is lost
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for name, list := range webhookIndexes {
+				if err := dropIndexes(ctx, db.Collection(name), list); err != nil {
+					return err
+				}
+				if err := setValidator(ctx, db, name, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// indexMigration creates indexes on the way up and drops them on the way down.
+func indexMigration(version int, description string, indexes map[string][]mongo.IndexModel) Migration {
+	return Migration{
+		Version:     version,
+		Description: description,
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for name, list := range indexes {
+				if _, err := db.Collection(name).Indexes().CreateMany(ctx, list); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for name, list := range indexes {
+				if err := dropIndexes(ctx, db.Collection(name), list); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+var (
+	objectIDField = bson.M{"bsonType": "objectId"}
+	stringField   = bson.M{"bsonType": "string"}
+	boolField     = bson.M{"bsonType": "bool"}
+	dateField     = bson.M{"bsonType": "date"}
+	intField      = bson.M{"bsonType": bson.A{"int", "long"}}
+)
+
+// jsonSchema describes the documents of a collection. Fields that are not
+// listed are allowed, so adding a field to a model does not need a migration.
+func jsonSchema(required []string, properties bson.M) bson.M {
+	properties["_id"] = objectIDField
+	return bson.M{
+		"bsonType":   "object",
+		"required":   required,
+		"properties": properties,
+	}
+}
+
+// collectionSchemas matches the bson tags of the models in api/models.
+var collectionSchemas = map[string]bson.M{
+	"users": jsonSchema(
+		[]string{"username", "email", "password", "created_at", "updated_at"},
+		bson.M{
+			"username":      stringField,
+			"email":         stringField,
+			"password":      stringField,
+			"role":          stringField,
+			"disabled":      boolField,
+			"token_version": intField,
+			"created_at":    dateField,
+			"updated_at":    dateField,
+		},
+	),
+	"task_lists": jsonSchema(
+		[]string{"user_id", "title", "created_at"},
+		bson.M{
+			"user_id":     objectIDField,
+			"title":       stringField,
+			"description": stringField,
+			"created_at":  dateField,
+			"updated_at":  dateField,
+		},
+	),
+	"tasks": jsonSchema(
+		[]string{"task_list_id", "title", "created_at"},
+		bson.M{
+			"task_list_id": objectIDField,
+			"title":        stringField,
+			"description":  stringField,
+			"due_date":     dateField,
+			"completed":    boolField,
+			"label_ids": bson.M{
+				"bsonType": "array",
+				"items":    objectIDField,
+			},
+			"created_at": dateField,
+			"updated_at": dateField,
+		},
+	),
+	"labels": jsonSchema(
+		[]string{"user_id", "name", "created_at"},
+		bson.M{
+			"user_id":    objectIDField,
+			"name":       stringField,
+			"color":      stringField,
+			"created_at": dateField,
+			"updated_at": dateField,
+		},
+	),
+	"refresh_tokens": jsonSchema(
+		[]string{"user_id", "family_id", "token_hash", "expires_at"},
+		bson.M{
+			"user_id":    objectIDField,
+			"family_id":  stringField,
+			"device_id":  stringField,
+			"token_hash": stringField,
+			"used":       boolField,
+			"revoked":    boolField,
+			"expires_at": dateField,
+			"created_at": dateField,
+		},
+	),
+	"pending_registrations": jsonSchema(
+		[]string{"email", "password_hash", "otp_hash", "expires_at"},
+		bson.M{
+			"user_id":       objectIDField,
+			"email":         stringField,
+			"username":      stringField,
+			"password_hash": stringField,
+			"otp_hash":      stringField,
+			"attempts":      intField,
+			"expires_at":    dateField,
+			"created_at":    dateField,
+		},
+	),
+	"password_resets": jsonSchema(
+		[]string{"user_id", "token_hash", "expires_at"},
+		bson.M{
+			"user_id":    objectIDField,
+			"token_hash": stringField,
+			"used":       boolField,
+			"expires_at": dateField,
+			"created_at": dateField,
+		},
+	),
+}
+
+// collectionIndexes backs the queries of the repositories in this package.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+	"task_lists": {
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	},
+	"tasks": {
+		{
+			Keys: bson.D{{Key: "task_list_id", Value: 1}},
+		},
+		{
+			// Multikey index backing label filters and label cleanup
+			Keys: bson.D{{Key: "label_ids", Value: 1}},
+		},
+	},
+	"labels": {
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	},
+	"refresh_tokens": {
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "device_id", Value: 1}},
+		},
+		{
+			// Let MongoDB drop refresh tokens once they expire
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"pending_registrations": {
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"password_resets": {
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	},
+}
+
+// paginationIndexes back the sort and cursor filter of pageOptions and
+// pageFilter for each listing.
+var paginationIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	"task_lists": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	"tasks": {
+		{Keys: bson.D{{Key: "task_list_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	"labels": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+}
+
+// taskFilterIndexes back the task listing filters and sorts that are common
+// enough to matter: open tasks by due date, and recently changed tasks.
+var taskFilterIndexes = map[string][]mongo.IndexModel{
+	"tasks": {
+		{Keys: bson.D{{Key: "task_list_id", Value: 1}, {Key: "completed", Value: 1}, {Key: "due_date", Value: 1}}},
+		{Keys: bson.D{{Key: "task_list_id", Value: 1}, {Key: "due_date", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "task_list_id", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+}
+
+var smartListSchema = jsonSchema(
+	[]string{"user_id", "name", "query", "created_at"},
+	bson.M{
+		"user_id":    objectIDField,
+		"name":       stringField,
+		"query":      stringField,
+		"sort":       stringField,
+		"created_at": dateField,
+		"updated_at": dateField,
+	},
+)
+
+var smartListIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+}
+
+// searchIndexes back the search endpoint. A collection has at most one text
+// index; titles weigh ten times as much as descriptions. No language is set,
+// so words are matched as written, without stemming or stop words.
+var searchIndexes = map[string][]mongo.IndexModel{
+	"tasks":      {textIndex("tasks_text", "title", "description")},
+	"task_lists": {textIndex("task_lists_text", "title", "description")},
+	"labels":     {textIndex("labels_text", "name")},
+}
+
+// subtaskIndexes back the listing and counting of subtasks. Top level tasks
+// have no parent_id, so the index is sparse.
+var subtaskIndexes = map[string][]mongo.IndexModel{
+	"tasks": {
+		{
+			Keys:    bson.D{{Key: "parent_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+}
+
+var reminderSchema = jsonSchema(
+	[]string{"task_id", "user_id", "before_minutes", "status", "attempts", "created_at"},
+	bson.M{
+		"task_id":        objectIDField,
+		"user_id":        objectIDField,
+		"before_minutes": intField,
+		"remind_at":      bson.M{"bsonType": bson.A{"date", "null"}},
+		"status":         stringField,
+		"attempts":       intField,
+		"last_error":     stringField,
+		"sent_at":        bson.M{"bsonType": bson.A{"date", "null"}},
+		"created_at":     dateField,
+	},
+)
+
+// reminderIndexes keep one reminder per offset of a task and back the
+// scheduler's poll for pending reminders that are due.
+var reminderIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "task_id", Value: 1}, {Key: "before_minutes", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "remind_at", Value: 1}, {Key: "_id", Value: 1}}},
+}
+
+var notificationSettingsSchema = jsonSchema(
+	[]string{"channels"},
+	bson.M{
+		"channels":         bson.M{"bsonType": "array", "items": stringField},
+		"telegram_chat_id": stringField,
+		"webhook_url":      stringField,
+		"updated_at":       dateField,
+	},
+)
+
+var digestSettingsSchema = jsonSchema(
+	[]string{"frequency", "send_at", "timezone"},
+	bson.M{
+		"frequency":    stringField,
+		"send_at":      stringField,
+		"weekday":      stringField,
+		"timezone":     stringField,
+		"next_at":      dateField,
+		"last_sent_at": dateField,
+		"updated_at":   dateField,
+	},
+)
+
+// digestSettingsIndexes back the scheduler's poll for digests that are due.
+// Digests that are off have no next_at, so the index is sparse.
+var digestSettingsIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "next_at", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	},
+}
+
+var webhookSchema = jsonSchema(
+	[]string{"user_id", "url", "events", "secret", "active", "created_at"},
+	bson.M{
+		"user_id":     objectIDField,
+		"url":         stringField,
+		"events":      bson.M{"bsonType": "array", "items": stringField},
+		"description": stringField,
+		"secret":      stringField,
+		"active":      boolField,
+		"created_at":  dateField,
+		"updated_at":  dateField,
+	},
+)
+
+var webhookDeliverySchema = jsonSchema(
+	[]string{"webhook_id", "user_id", "event_id", "event", "payload", "status", "attempts", "created_at"},
+	bson.M{
+		"webhook_id":      objectIDField,
+		"user_id":         objectIDField,
+		"event_id":        objectIDField,
+		"event":           stringField,
+		"payload":         bson.M{"bsonType": "binData"},
+		"status":          stringField,
+		"attempts":        intField,
+		"next_attempt_at": bson.M{"bsonType": bson.A{"date", "null"}},
+		"last_error":      stringField,
+		"response_status": intField,
+		"response_body":   stringField,
+		"redelivery_of":   objectIDField,
+		"delivered_at":    bson.M{"bsonType": bson.A{"date", "null"}},
+		"created_at":      dateField,
+	},
+)
+
+// webhookIndexes back the listing of a user's webhooks and the lookup of the
+// ones subscribed to an event, the delivery log of a webhook, newest first,
+// and the scheduler's poll for pending deliveries that are due.
+var webhookIndexes = map[string][]mongo.IndexModel{
+	"webhooks": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	"webhook_deliveries": {
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+}
+
+func textIndex(name, title string, body ...string) mongo.IndexModel {
+	keys := bson.D{{Key: title, Value: "text"}}
+	weights := bson.D{{Key: title, Value: 10}}
+	for _, field := range body {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+		weights = append(weights, bson.E{Key: field, Value: 1})
+	}
+	return mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(name).SetWeights(weights).SetDefaultLanguage("none"),
+	}
+}
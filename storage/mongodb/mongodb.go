@@ -1,34 +1,62 @@
 package mongodb
 
 import (
+	"context"
 	"todo/pkg/logger"
 	"todo/storage"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// Storage struct contains all repositories for MongoDB.
-type Storage struct {
-	UserRepo     UserRepo
-	LabelRepo    LabelRepo
-	TaskRepo     TaskRepo
-	TaskListRepo TaskListRepo
+// Connect connects to MongoDB and returns the todoapp database.
+func Connect(dbURI string) (*mongo.Database, error) {
+	clientOptions := options.Client().ApplyURI(dbURI)
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure MongoDB connection is established
+	if err := client.Ping(context.TODO(), nil); err != nil {
+		return nil, err
+	}
+
+	return client.Database("todoapp"), nil
 }
 
-// NewStorage initializes a new Storage struct with the provided MongoDB database and logger.
-func NewStorage(db *mongo.Database, log logger.ILogger) *Storage {
-	return &Storage{
-		UserRepo:     *NewUserRepo(db, log),
-		LabelRepo:    *NewLabelRepo(db, log),
-		TaskRepo:     *NewTaskRepo(db, log),
-		TaskListRepo: *NewTaskListRepo(db, log),
+// NewStorage initializes the MongoDB repositories with the provided database and logger.
+func NewStorage(db *mongo.Database, log logger.ILogger) *storage.Storage {
+	return &storage.Storage{
+		UserRepo:          NewUserRepo(db, log),
+		LabelRepo:         NewLabelRepo(db, log),
+		TaskRepo:          NewTaskRepo(db, log),
+		TaskListRepo:      NewTaskListRepo(db, log),
+		TokenRepo:         NewTokenRepo(db, log),
+		RegistrationRepo:  NewRegistrationRepo(db, log),
+		PasswordResetRepo: NewPasswordResetRepo(db, log),
+		SmartListRepo:     NewSmartListRepo(db, log),
+		SearchRepo:        NewSearchRepo(db, log),
+		ReminderRepo:      NewReminderRepo(db, log),
+		NotificationRepo:  NewNotificationRepo(db, log),
+		DigestRepo:        NewDigestRepo(db, log),
+		WebhookRepo:       NewWebhookRepo(db, log),
 	}
 }
 
-// Interface for User, Label, Task, and TaskList repositories.
+// Interface for User, Label, Task, TaskList, Token, Registration, PasswordReset, SmartList, Search, Reminder, Notification, Digest and Webhook repositories.
 var (
-	_ storage.UserStorage     = &UserRepo{}
-	_ storage.LabelStorage    = &LabelRepo{}
-	_ storage.TaskStorage     = &TaskRepo{}
-	_ storage.TaskListStorage = &TaskListRepo{}
+	_ storage.UserStorage          = &UserRepo{}
+	_ storage.LabelStorage         = &LabelRepo{}
+	_ storage.TaskStorage          = &TaskRepo{}
+	_ storage.TaskListStorage      = &TaskListRepo{}
+	_ storage.TokenStorage         = &TokenRepo{}
+	_ storage.RegistrationStorage  = &RegistrationRepo{}
+	_ storage.PasswordResetStorage = &PasswordResetRepo{}
+	_ storage.SmartListStorage     = &SmartListRepo{}
+	_ storage.SearchStorage        = &SearchRepo{}
+	_ storage.ReminderStorage      = &ReminderRepo{}
+	_ storage.NotificationStorage  = &NotificationRepo{}
+	_ storage.DigestStorage        = &DigestRepo{}
+	_ storage.WebhookStorage       = &WebhookRepo{}
 )
@@ -0,0 +1,46 @@
+package mongodb_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"todo/pkg/logger"
+	"todo/storage"
+	"todo/storage/mongodb"
+	"todo/storage/storagetest"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestConformance runs against the server at TEST_MONGODB_URI and is skipped
+// without it. The server must be a replica set, as deletes run in
+// transactions. Every subtest migrates a database of its own, dropped
+// afterwards.
+func TestConformance(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI is not set")
+	}
+	ctx := context.Background()
+
+	conn, err := mongodb.Connect(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := conn.Client()
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		db := client.Database("test_" + primitive.NewObjectID().Hex())
+		t.Cleanup(func() {
+			if err := db.Drop(ctx); err != nil {
+				t.Error(err)
+			}
+		})
+
+		if _, err := mongodb.NewMigrator(db).Up(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return mongodb.NewStorage(db, logger.New("test"))
+	})
+}
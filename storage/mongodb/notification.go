@@ -0,0 +1,125 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationRepo struct {
+	db  *mongo.Database
+	log logger.ILogger
+}
+
+func NewNotificationRepo(db *mongo.Database, log logger.ILogger) *NotificationRepo {
+	return &NotificationRepo{db: db, log: log}
+}
+
+// GetNotificationSettings returns the settings of a user, or the defaults.
+func (nr *NotificationRepo) GetNotificationSettings(ctx context.Context, userID primitive.ObjectID) (models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := nr.db.Collection("notification_settings").FindOne(ctx, bson.M{"_id": userID}).Decode(&settings)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.DefaultNotificationSettings(userID), nil
+		}
+		nr.log.Error("Error retrieving notification settings", logger.Error(err))
+		return models.NotificationSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveNotificationSettings creates or replaces the settings of a user.
+func (nr *NotificationRepo) SaveNotificationSettings(ctx context.Context, req models.UpdateNotificationSettings) (models.NotificationSettings, error) {
+	settings := models.NotificationSettings{
+		UserID:         req.UserID,
+		Channels:       append([]string{}, req.Channels...),
+		TelegramChatID: req.TelegramChatID,
+		WebhookURL:     req.WebhookURL,
+		UpdatedAt:      time.Now(),
+	}
+
+	// The preferences share the document, so only the settings are set
+	update := bson.M{
+		"$set": bson.M{
+			"channels":         settings.Channels,
+			"telegram_chat_id": settings.TelegramChatID,
+			"webhook_url":      settings.WebhookURL,
+			"updated_at":       settings.UpdatedAt,
+		},
+	}
+	_, err := nr.db.Collection("notification_settings").UpdateOne(ctx, bson.M{"_id": req.UserID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		nr.log.Error("Error saving notification settings", logger.Error(err))
+		return models.NotificationSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// GetNotificationPreferences returns the preferences of a user, or the
+// defaults.
+func (nr *NotificationRepo) GetNotificationPreferences(ctx context.Context, userID primitive.ObjectID) (models.NotificationPreferences, error) {
+	var preferences models.NotificationPreferences
+	err := nr.db.Collection("notification_settings").FindOne(ctx, bson.M{"_id": userID}).Decode(&preferences)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.DefaultNotificationPreferences(userID), nil
+		}
+		nr.log.Error("Error retrieving notification preferences", logger.Error(err))
+		return models.NotificationPreferences{}, err
+	}
+
+	// Users who only saved their settings have none of the fields
+	if preferences.Events == nil {
+		preferences.Events = map[string][]string{}
+	}
+	if preferences.Timezone == "" {
+		preferences.Timezone = "UTC"
+	}
+	return preferences, nil
+}
+
+// SaveNotificationPreferences creates or replaces the preferences of a user.
+// A user without settings gets the default ones.
+func (nr *NotificationRepo) SaveNotificationPreferences(ctx context.Context, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error) {
+	preferences := models.NotificationPreferences{
+		UserID:     req.UserID,
+		Events:     req.Events,
+		Timezone:   req.Timezone,
+		QuietHours: req.QuietHours,
+		UpdatedAt:  time.Now(),
+	}
+	if preferences.Events == nil {
+		preferences.Events = map[string][]string{}
+	}
+
+	defaults := models.DefaultNotificationSettings(req.UserID)
+	update := bson.M{
+		"$set": bson.M{
+			"events":      preferences.Events,
+			"timezone":    preferences.Timezone,
+			"quiet_hours": preferences.QuietHours,
+			"updated_at":  preferences.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"channels":         defaults.Channels,
+			"telegram_chat_id": defaults.TelegramChatID,
+			"webhook_url":      defaults.WebhookURL,
+		},
+	}
+	_, err := nr.db.Collection("notification_settings").UpdateOne(ctx, bson.M{"_id": req.UserID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		nr.log.Error("Error saving notification preferences", logger.Error(err))
+		return models.NotificationPreferences{}, err
+	}
+
+	return preferences, nil
+}
@@ -0,0 +1,71 @@
+package mongodb
+
+import (
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pageFilter narrows filter down to the documents after the page cursor. The
+// filter without it still counts the whole listing.
+func pageFilter(filter bson.M, page models.Pagination) bson.M {
+	return sortedPageFilter(filter, models.DefaultSort, page)
+}
+
+// pageOptions sorts by creation time, then ID, so pages are stable, and
+// applies the offset and limit of the page.
+func pageOptions(page models.Pagination) *options.FindOptions {
+	return sortedPageOptions(models.DefaultSort, page)
+}
+
+// sortedPageFilter is pageFilter for a listing sorted by keys. A document
+// comes after the cursor if it ties on the first keys and sorts after it on
+// the next one, or ties on all of them and has a greater ID.
+func sortedPageFilter(filter bson.M, keys []models.SortKey, page models.Pagination) bson.M {
+	if page.After == nil {
+		return filter
+	}
+
+	after := bson.A{}
+	equal := bson.M{}
+	for _, key := range keys {
+		op := "$gt"
+		if key.Desc {
+			op = "$lt"
+		}
+		value := page.After.Value(key.Field)
+
+		branch := bson.M{key.Field: bson.M{op: value}}
+		for field, eq := range equal {
+			branch[field] = eq
+		}
+		after = append(after, branch)
+		equal[key.Field] = value
+	}
+	last := bson.M{"_id": bson.M{"$gt": page.After.ID}}
+	for field, eq := range equal {
+		last[field] = eq
+	}
+	after = append(after, last)
+
+	return bson.M{"$and": bson.A{filter, bson.M{"$or": after}}}
+}
+
+// sortedPageOptions is pageOptions for a listing sorted by keys.
+func sortedPageOptions(keys []models.SortKey, page models.Pagination) *options.FindOptions {
+	sort := bson.D{}
+	for _, key := range keys {
+		direction := 1
+		if key.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: key.Field, Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+
+	return options.Find().
+		SetSort(sort).
+		SetSkip(int64(page.Offset)).
+		SetLimit(int64(page.Limit))
+}
@@ -0,0 +1,70 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PasswordResetRepo struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	logger     logger.ILogger
+}
+
+// NewPasswordResetRepo initializes a new PasswordResetRepo with a MongoDB collection and logger.
+func NewPasswordResetRepo(db *mongo.Database, log logger.ILogger) *PasswordResetRepo {
+	return &PasswordResetRepo{
+		db:         db,
+		collection: db.Collection("password_resets"),
+		logger:     log,
+	}
+}
+
+// CreatePasswordReset stores a hashed password reset token.
+func (pr *PasswordResetRepo) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error {
+	reset.CreatedAt = time.Now()
+
+	_, err := pr.collection.InsertOne(ctx, reset)
+	if err != nil {
+		pr.logger.Error("error while creating password reset in db", logger.Error(err))
+		return fmt.Errorf("error while creating password reset: %w", err)
+	}
+	return nil
+}
+
+// UsePasswordReset marks an unused, unexpired reset token as used and returns
+// it, along with every other token of the same user, in one transaction. A
+// token can therefore only be redeemed once, and older reset links die with it.
+func (pr *PasswordResetRepo) UsePasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used":       false,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"used": true}}
+
+	var reset models.PasswordReset
+	err := withTransaction(ctx, pr.db, func(sc mongo.SessionContext) error {
+		err := pr.collection.FindOneAndUpdate(sc, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&reset)
+		if err != nil {
+			return err
+		}
+		_, err = pr.collection.UpdateMany(sc, bson.M{"user_id": reset.UserID, "used": false}, update)
+		return err
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.PasswordReset{}, fmt.Errorf("password reset not found: %w", err)
+		}
+		pr.logger.Error("error while using password reset in db", logger.Error(err))
+		return models.PasswordReset{}, fmt.Errorf("error while using password reset: %w", err)
+	}
+	return reset, nil
+}
@@ -0,0 +1,60 @@
+package mongodb
+
+import (
+	"regexp"
+	"time"
+	"todo/pkg/taskquery"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var compareOperators = map[string]string{
+	taskquery.OpLess:         "$lt",
+	taskquery.OpLessEqual:    "$lte",
+	taskquery.OpGreater:      "$gt",
+	taskquery.OpGreaterEqual: "$gte",
+}
+
+// queryFilter translates a smart list query into a filter on tasks. Tasks
+// without a due date store the zero time.
+func queryFilter(expr taskquery.Expr, now time.Time) bson.M {
+	switch e := expr.(type) {
+	case *taskquery.And:
+		return bson.M{"$and": bson.A{queryFilter(e.Left, now), queryFilter(e.Right, now)}}
+	case *taskquery.Or:
+		return bson.M{"$or": bson.A{queryFilter(e.Left, now), queryFilter(e.Right, now)}}
+	case *taskquery.Not:
+		return bson.M{"$nor": bson.A{queryFilter(e.Expr, now)}}
+	case *taskquery.Completed:
+		return bson.M{"completed": e.Value}
+	case *taskquery.Overdue:
+		return bson.M{"completed": false, "due_date": bson.M{"$gt": time.Time{}, "$lt": now}}
+	case *taskquery.NoDueDate:
+		return bson.M{"due_date": bson.M{"$in": bson.A{time.Time{}, nil}}}
+	case *taskquery.Title:
+		return bson.M{"title": bson.M{"$regex": regexp.QuoteMeta(e.Text), "$options": "i"}}
+	case *taskquery.Label:
+		return bson.M{"label_ids": bson.M{"$in": idArray(e.IDs)}}
+	case *taskquery.List:
+		return bson.M{"task_list_id": bson.M{"$in": idArray(e.IDs)}}
+	case *taskquery.Compare:
+		condition := bson.M{compareOperators[e.Op]: e.Time.Resolve(now)}
+		if e.Field == taskquery.FieldDueDate {
+			condition["$ne"] = time.Time{}
+		}
+		return bson.M{e.Field: condition}
+	}
+	// Unknown nodes match nothing rather than everything
+	return bson.M{"_id": bson.M{"$exists": false}}
+}
+
+// idArray keeps an empty ID list from being encoded as null, which $in
+// rejects.
+func idArray(ids []primitive.ObjectID) bson.A {
+	array := bson.A{}
+	for _, id := range ids {
+		array = append(array, id)
+	}
+	return array
+}
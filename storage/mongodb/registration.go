@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RegistrationRepo struct {
+	collection *mongo.Collection
+	logger     logger.ILogger
+}
+
+// NewRegistrationRepo initializes a new RegistrationRepo with a MongoDB collection and logger.
+func NewRegistrationRepo(db *mongo.Database, log logger.ILogger) *RegistrationRepo {
+	return &RegistrationRepo{
+		collection: db.Collection("pending_registrations"),
+		logger:     log,
+	}
+}
+
+// UpsertPendingRegistration stores a pending registration, replacing an
+// earlier one for the same email only once it expired. An unexpired one
+// keeps the upsert from matching, and the unique email index then refuses
+// to insert a second one.
+func (rr *RegistrationRepo) UpsertPendingRegistration(ctx context.Context, reg models.PendingRegistration) error {
+	reg.ID = primitive.NilObjectID
+	reg.CreatedAt = time.Now()
+
+	filter := bson.M{"email": reg.Email, "expires_at": bson.M{"$lte": reg.CreatedAt}}
+	_, err := rr.collection.ReplaceOne(ctx, filter, reg, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ErrRegistrationPending
+	}
+	if err != nil {
+		rr.logger.Error("error while saving pending registration in db", logger.Error(err))
+		return fmt.Errorf("error while saving pending registration: %w", err)
+	}
+	return nil
+}
+
+// GetPendingRegistration retrieves the pending registration for an email.
+func (rr *RegistrationRepo) GetPendingRegistration(ctx context.Context, email string) (models.PendingRegistration, error) {
+	var reg models.PendingRegistration
+	err := rr.collection.FindOne(ctx, bson.M{"email": email}).Decode(&reg)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.PendingRegistration{}, fmt.Errorf("pending registration not found: %w", err)
+		}
+		rr.logger.Error("error while getting pending registration from db", logger.Error(err))
+		return models.PendingRegistration{}, fmt.Errorf("error while getting pending registration: %w", err)
+	}
+	return reg, nil
+}
+
+// IncrementRegistrationAttempts uses up one OTP attempt, unless max were
+// made already.
+func (rr *RegistrationRepo) IncrementRegistrationAttempts(ctx context.Context, email string, max int) (bool, error) {
+	filter := bson.M{"email": email, "attempts": bson.M{"$lt": max}}
+	res, err := rr.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"attempts": 1}})
+	if err != nil {
+		rr.logger.Error("error while updating pending registration in db", logger.Error(err))
+		return false, fmt.Errorf("error while updating pending registration: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// DeletePendingRegistration removes the pending registration for an email.
+func (rr *RegistrationRepo) DeletePendingRegistration(ctx context.Context, email string) error {
+	_, err := rr.collection.DeleteOne(ctx, bson.M{"email": email})
+	if err != nil {
+		rr.logger.Error("error while deleting pending registration from db", logger.Error(err))
+		return fmt.Errorf("error while deleting pending registration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReminderRepo struct {
+	db  *mongo.Database
+	log logger.ILogger
+}
+
+func NewReminderRepo(db *mongo.Database, log logger.ILogger) *ReminderRepo {
+	return &ReminderRepo{db: db, log: log}
+}
+
+// SetReminders replaces the reminders of a task. Kept offsets are matched by
+// the unique index on task_id and before_minutes, so their state is left
+// untouched.
+func (rr *ReminderRepo) SetReminders(ctx context.Context, userID, taskID primitive.ObjectID, before []int, dueDate time.Time) ([]models.Reminder, error) {
+	collection := rr.db.Collection("reminders")
+
+	_, err := collection.DeleteMany(ctx, bson.M{"task_id": taskID, "before_minutes": bson.M{"$nin": before}})
+	if err != nil {
+		rr.log.Error("Error removing reminders", logger.Error(err))
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, minutes := range before {
+		reminder := models.Reminder{
+			ID:        primitive.NewObjectID(),
+			TaskID:    taskID,
+			UserID:    userID,
+			Before:    minutes,
+			RemindAt:  models.ReminderTime(dueDate, minutes),
+			Status:    models.ReminderPending,
+			CreatedAt: now,
+		}
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"task_id": taskID, "before_minutes": minutes},
+			bson.M{"$setOnInsert": reminder},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			rr.log.Error("Error creating reminder", logger.Error(err))
+			return nil, err
+		}
+	}
+
+	return rr.GetReminders(ctx, taskID)
+}
+
+// GetReminders returns the reminders of a task, the earliest first.
+func (rr *ReminderRepo) GetReminders(ctx context.Context, taskID primitive.ObjectID) ([]models.Reminder, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "before_minutes", Value: -1}})
+	return rr.find(ctx, bson.M{"task_id": taskID}, opts)
+}
+
+// RescheduleReminders moves the reminders of a task to a new due date. Each
+// one is only reset when its time changes, so saving a task without touching
+// the due date does not send its reminders again.
+func (rr *ReminderRepo) RescheduleReminders(ctx context.Context, taskID primitive.ObjectID, dueDate time.Time) error {
+	reminders, err := rr.find(ctx, bson.M{"task_id": taskID}, options.Find())
+	if err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		remindAt := models.ReminderTime(dueDate, reminder.Before)
+		if sameTimePtr(reminder.RemindAt, remindAt) {
+			continue
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"remind_at":  remindAt,
+				"status":     models.ReminderPending,
+				"attempts":   0,
+				"last_error": "",
+				"sent_at":    nil,
+			},
+		}
+		if _, err := rr.db.Collection("reminders").UpdateOne(ctx, bson.M{"_id": reminder.ID}, update); err != nil {
+			rr.log.Error("Error rescheduling reminder", logger.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// DueReminders returns the pending reminders due at or before now.
+func (rr *ReminderRepo) DueReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	filter := bson.M{"status": models.ReminderPending, "remind_at": bson.M{"$lte": now}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "remind_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	return rr.find(ctx, filter, opts)
+}
+
+// RecordDelivery stores the outcome of sending a reminder, unless the
+// reminder has been moved since it was read.
+func (rr *ReminderRepo) RecordDelivery(ctx context.Context, delivery models.ReminderDelivery) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     delivery.Status,
+			"attempts":   delivery.Attempts,
+			"last_error": delivery.LastError,
+			"sent_at":    delivery.SentAt,
+		},
+	}
+	if delivery.DeferredTo != nil {
+		update["$set"].(bson.M)["remind_at"] = *delivery.DeferredTo
+	}
+
+	res, err := rr.db.Collection("reminders").UpdateOne(ctx, bson.M{"_id": delivery.ID, "remind_at": delivery.RemindAt}, update)
+	if err != nil {
+		rr.log.Error("Error recording reminder delivery", logger.Error(err))
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrReminderNotFound
+	}
+	return nil
+}
+
+// DeleteReminders removes the reminders of a task.
+func (rr *ReminderRepo) DeleteReminders(ctx context.Context, taskID primitive.ObjectID) error {
+	_, err := rr.db.Collection("reminders").DeleteMany(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		rr.log.Error("Error deleting reminders", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (rr *ReminderRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Reminder, error) {
+	cursor, err := rr.db.Collection("reminders").Find(ctx, filter, opts)
+	if err != nil {
+		rr.log.Error("Error retrieving reminders", logger.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reminders := []models.Reminder{}
+	if err := cursor.All(ctx, &reminders); err != nil {
+		rr.log.Error("Error decoding reminders", logger.Error(err))
+		return nil, err
+	}
+	return reminders, nil
+}
+
+func sameTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
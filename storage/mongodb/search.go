@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"context"
+	"strings"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SearchRepo struct {
+	db  *mongo.Database
+	log logger.ILogger
+}
+
+func NewSearchRepo(db *mongo.Database, log logger.ILogger) *SearchRepo {
+	return &SearchRepo{db: db, log: log}
+}
+
+// textHit holds the fields of a task, task list or label found by a text
+// search, with its score.
+type textHit struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	TaskListID  primitive.ObjectID `bson:"task_list_id"`
+	Title       string             `bson:"title"`
+	Name        string             `bson:"name"`
+	Description string             `bson:"description"`
+	Score       float64            `bson:"score"`
+}
+
+// Search runs a text search on each requested collection. The terms are
+// plain words, so $search sees neither phrases nor negations and matches
+// documents containing any of them.
+func (sr *SearchRepo) Search(ctx context.Context, userID string, query models.SearchQuery) ([]models.SearchResult, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrUserNotFound
+	}
+	search := strings.Join(query.Terms, " ")
+
+	results := []models.SearchResult{}
+	for _, typ := range query.Types {
+		var collection string
+		filter := bson.M{"$text": bson.M{"$search": search}}
+		switch typ {
+		case models.SearchTypeTask:
+			taskListIDs, err := sr.taskListIDs(ctx, objectID)
+			if err != nil {
+				return nil, err
+			}
+			collection, filter["task_list_id"] = "tasks", bson.M{"$in": taskListIDs}
+		case models.SearchTypeTaskList:
+			collection, filter["user_id"] = "task_lists", objectID
+		case models.SearchTypeLabel:
+			collection, filter["user_id"] = "labels", objectID
+		default:
+			continue
+		}
+
+		score := bson.M{"$meta": "textScore"}
+		opts := options.Find().
+			SetProjection(bson.M{"score": score}).
+			SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+			SetLimit(query.Limit)
+
+		cursor, err := sr.db.Collection(collection).Find(ctx, filter, opts)
+		if err != nil {
+			sr.log.Error("Error searching "+collection, logger.Error(err))
+			return nil, err
+		}
+		var hits []textHit
+		if err := cursor.All(ctx, &hits); err != nil {
+			sr.log.Error("Error decoding search results", logger.Error(err))
+			return nil, err
+		}
+
+		for _, hit := range hits {
+			result := models.SearchResult{Type: typ, ID: hit.ID, Title: hit.Title, Body: hit.Description, Score: hit.Score}
+			switch typ {
+			case models.SearchTypeTask:
+				taskListID := hit.TaskListID
+				result.TaskListID = &taskListID
+			case models.SearchTypeLabel:
+				result.Title = hit.Name
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// taskListIDs returns the IDs of the task lists of a user, as an array $in
+// accepts even when there are none.
+func (sr *SearchRepo) taskListIDs(ctx context.Context, userID primitive.ObjectID) (bson.A, error) {
+	cursor, err := sr.db.Collection("task_lists").Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		sr.log.Error("Error retrieving task lists", logger.Error(err))
+		return nil, err
+	}
+	var taskLists []models.TaskList
+	if err := cursor.All(ctx, &taskLists); err != nil {
+		sr.log.Error("Error decoding task lists", logger.Error(err))
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, taskList := range taskLists {
+		ids = append(ids, taskList.ID)
+	}
+	return idArray(ids), nil
+}
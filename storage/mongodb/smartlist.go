@@ -0,0 +1,140 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SmartListRepo struct {
+	db  *mongo.Database
+	log logger.ILogger
+}
+
+func NewSmartListRepo(db *mongo.Database, log logger.ILogger) *SmartListRepo {
+	return &SmartListRepo{db: db, log: log}
+}
+
+// CreateSmartList creates a new smart list in the database.
+func (slr *SmartListRepo) CreateSmartList(ctx context.Context, req models.CreateSmartList) (models.SmartList, error) {
+	now := time.Now()
+	smartList := models.SmartList{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Query:     req.Query,
+		Sort:      req.Sort,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := slr.db.Collection("smart_lists").InsertOne(ctx, smartList)
+	if err != nil {
+		slr.log.Error("Error creating smart list", logger.Error(err))
+		return models.SmartList{}, err
+	}
+
+	return smartList, nil
+}
+
+// GetSmartList retrieves a smart list by ID.
+func (slr *SmartListRepo) GetSmartList(ctx context.Context, smartListID string) (models.SmartList, error) {
+	objectID, err := primitive.ObjectIDFromHex(smartListID)
+	if err != nil {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+
+	var smartList models.SmartList
+	err = slr.db.Collection("smart_lists").FindOne(ctx, bson.M{"_id": objectID}).Decode(&smartList)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.SmartList{}, models.ErrSmartListNotFound
+		}
+		slr.log.Error("Error retrieving smart list", logger.Error(err))
+		return models.SmartList{}, err
+	}
+
+	return smartList, nil
+}
+
+// UpdateSmartList updates an existing smart list.
+func (slr *SmartListRepo) UpdateSmartList(ctx context.Context, req models.UpdateSmartList) (models.SmartList, error) {
+	filter := bson.M{"_id": req.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"name":       req.Name,
+			"query":      req.Query,
+			"sort":       req.Sort,
+			"updated_at": time.Now(),
+		},
+	}
+
+	res, err := slr.db.Collection("smart_lists").UpdateOne(ctx, filter, update)
+	if err != nil {
+		slr.log.Error("Error updating smart list", logger.Error(err))
+		return models.SmartList{}, err
+	}
+	if res.MatchedCount == 0 {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+
+	return slr.GetSmartList(ctx, req.ID.Hex())
+}
+
+// DeleteSmartList removes a smart list. The tasks it shows are not touched.
+func (slr *SmartListRepo) DeleteSmartList(ctx context.Context, smartListID string) error {
+	objectID, err := primitive.ObjectIDFromHex(smartListID)
+	if err != nil {
+		return models.ErrSmartListNotFound
+	}
+
+	res, err := slr.db.Collection("smart_lists").DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		slr.log.Error("Error deleting smart list", logger.Error(err))
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return models.ErrSmartListNotFound
+	}
+	return nil
+}
+
+// GetAllSmartLists retrieves the smart lists of a user with pagination.
+func (slr *SmartListRepo) GetAllSmartLists(ctx context.Context, userID string, page models.Pagination) ([]models.SmartList, int64, error) {
+	smartLists := []models.SmartList{}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	filter := bson.M{"user_id": objectID}
+
+	cursor, err := slr.db.Collection("smart_lists").Find(ctx, pageFilter(filter, page), pageOptions(page))
+	if err != nil {
+		slr.log.Error("Error retrieving smart lists", logger.Error(err))
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var smartList models.SmartList
+		if err := cursor.Decode(&smartList); err != nil {
+			slr.log.Error("Error decoding smart list", logger.Error(err))
+			continue
+		}
+		smartLists = append(smartLists, smartList)
+	}
+
+	count, err := slr.db.Collection("smart_lists").CountDocuments(ctx, filter)
+	if err != nil {
+		slr.log.Error("Error counting smart lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return smartLists, count, nil
+}
@@ -2,10 +2,13 @@ package mongodb
 
 import (
 	"context"
+	"regexp"
+	"time"
 	"todo/api/models"
 	"todo/pkg/logger"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -21,17 +24,26 @@ func NewTaskRepo(db *mongo.Database, log logger.ILogger) *TaskRepo {
 
 // CreateTask creates a new task in the database.
 func (tr *TaskRepo) CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error) {
+	now := time.Now()
+	// Arrays are stored empty rather than null, which $addToSet and $push
+	// refuse to update
 	task := models.Task{
-		ID:          req.ID,
+		ID:          primitive.NewObjectID(),
+		TaskListID:  req.TaskListID,
+		ParentID:    req.ParentID,
 		Title:       req.Title,
 		Description: req.Description,
-		UserID:      req.UserID,
-		Status:      req.Status,
+		DueDate:     req.DueDate,
+		Recurrence:  req.Recurrence,
+		LabelIDs:    append([]primitive.ObjectID{}, req.LabelIDs...),
+		Checklist:   []models.ChecklistItem{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
 	_, err := tr.db.Collection("tasks").InsertOne(ctx, task)
 	if err != nil {
-		tr.log.Error("Error creating task: ", err)
+		tr.log.Error("Error creating task", logger.Error(err))
 		return models.Task{}, err
 	}
 
@@ -40,13 +52,18 @@ func (tr *TaskRepo) CreateTask(ctx context.Context, req models.CreateTask) (mode
 
 // GetTask retrieves a task by ID.
 func (tr *TaskRepo) GetTask(ctx context.Context, taskID string) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
 	var task models.Task
-	err := tr.db.Collection("tasks").FindOne(ctx, bson.M{"id": taskID}).Decode(&task)
+	err = tr.db.Collection("tasks").FindOne(ctx, bson.M{"_id": objectID}).Decode(&task)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return models.Task{}, nil // Return an empty task if not found
+			return models.Task{}, models.ErrTaskNotFound
 		}
-		tr.log.Error("Error retrieving task: ", err)
+		tr.log.Error("Error retrieving task", logger.Error(err))
 		return models.Task{}, err
 	}
 
@@ -55,51 +72,256 @@ func (tr *TaskRepo) GetTask(ctx context.Context, taskID string) (models.Task, er
 
 // UpdateTask updates an existing task.
 func (tr *TaskRepo) UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error) {
-	filter := bson.M{"id": req.ID}
+	now := time.Now()
+	filter := bson.M{"_id": req.ID}
 	update := bson.M{
 		"$set": bson.M{
 			"title":       req.Title,
 			"description": req.Description,
-			"user_id":     req.UserID,
-			"status":      req.Status,
+			"due_date":    req.DueDate,
+			"completed":   req.Completed,
+			"updated_at":  now,
 		},
 	}
+	if !req.Completed {
+		update["$unset"] = bson.M{"completed_at": ""}
+	}
 
-	_, err := tr.db.Collection("tasks").UpdateOne(ctx, filter, update)
+	res, err := tr.db.Collection("tasks").UpdateOne(ctx, filter, update)
 	if err != nil {
-		tr.log.Error("Error updating task: ", err)
+		tr.log.Error("Error updating task", logger.Error(err))
 		return models.Task{}, err
 	}
+	if res.MatchedCount == 0 {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	if req.Completed {
+		// Completing a completed task keeps the time it was completed at
+		_, err = tr.db.Collection("tasks").UpdateOne(ctx,
+			bson.M{"_id": req.ID, "completed_at": nil},
+			bson.M{"$set": bson.M{"completed_at": now}},
+		)
+		if err != nil {
+			tr.log.Error("Error updating task", logger.Error(err))
+			return models.Task{}, err
+		}
+	}
 
-	return tr.GetTask(ctx, req.ID)
+	return tr.GetTask(ctx, req.ID.Hex())
 }
 
-// DeleteTask removes a task from the database.
+// DeleteTask removes a task with its subtasks from the database.
 func (tr *TaskRepo) DeleteTask(ctx context.Context, taskID string) error {
-	_, err := tr.db.Collection("tasks").DeleteOne(ctx, bson.M{"id": taskID})
+	objectID, err := primitive.ObjectIDFromHex(taskID)
 	if err != nil {
-		tr.log.Error("Error deleting task: ", err)
+		return models.ErrTaskNotFound
+	}
+
+	res, err := tr.db.Collection("tasks").DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		tr.log.Error("Error deleting task", logger.Error(err))
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return models.ErrTaskNotFound
+	}
+
+	// Subtasks cannot have subtasks of their own, so one pass is enough
+	if _, err := tr.db.Collection("tasks").DeleteMany(ctx, bson.M{"parent_id": objectID}); err != nil {
+		tr.log.Error("Error deleting subtasks", logger.Error(err))
 		return err
 	}
 	return nil
 }
 
-// GetAllTasks retrieves all tasks for a user with pagination.
-func (tr *TaskRepo) GetAllTasks(ctx context.Context, userID string, search string, page, limit uint64) ([]models.Task, int64, error) {
-	tasks := []models.Task{}
-	filter := bson.M{"user_id": userID}
+// AddTaskLabels attaches labels to a task. Labels already on the task are
+// left as they are.
+func (tr *TaskRepo) AddTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(ctx, taskID, bson.M{"$addToSet": bson.M{"label_ids": bson.M{"$each": labelIDs}}})
+}
+
+// RemoveTaskLabels detaches labels from a task.
+func (tr *TaskRepo) RemoveTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(ctx, taskID, bson.M{"$pullAll": bson.M{"label_ids": labelIDs}})
+}
+
+func (tr *TaskRepo) updateTaskLabels(ctx context.Context, taskID string, update bson.M) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	update["$set"] = bson.M{"updated_at": time.Now()}
+
+	var task models.Task
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = tr.db.Collection("tasks").FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Task{}, models.ErrTaskNotFound
+		}
+		tr.log.Error("Error updating task labels", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// SetTaskRecurrence makes a task repeat, or stop repeating with nil.
+func (tr *TaskRepo) SetTaskRecurrence(ctx context.Context, taskID string, recurrence *models.Recurrence) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	update := bson.M{"$set": bson.M{"recurrence": recurrence, "updated_at": time.Now()}}
+	if recurrence == nil {
+		update = bson.M{"$unset": bson.M{"recurrence": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+
+	var task models.Task
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = tr.db.Collection("tasks").FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Task{}, models.ErrTaskNotFound
+		}
+		tr.log.Error("Error updating task recurrence", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// AddChecklistItem appends an open item to the checklist of a task.
+func (tr *TaskRepo) AddChecklistItem(ctx context.Context, taskID string, req models.CreateChecklistItem) (models.Task, error) {
+	item := models.ChecklistItem{ID: primitive.NewObjectID(), Title: req.Title}
+	return tr.updateChecklist(ctx, taskID, bson.M{}, bson.M{
+		"$push": bson.M{"checklist": item},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+// UpdateChecklistItem changes the title or state of a checklist item.
+func (tr *TaskRepo) UpdateChecklistItem(ctx context.Context, taskID string, req models.UpdateChecklistItem) (models.Task, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if req.Title != nil {
+		set["checklist.$.title"] = *req.Title
+	}
+	if req.Done != nil {
+		set["checklist.$.done"] = *req.Done
+	}
+	return tr.updateChecklist(ctx, taskID, bson.M{"checklist._id": req.ID}, bson.M{"$set": set})
+}
+
+// DeleteChecklistItem removes an item from the checklist of a task.
+func (tr *TaskRepo) DeleteChecklistItem(ctx context.Context, taskID string, itemID primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, bson.M{"checklist._id": itemID}, bson.M{
+		"$pull": bson.M{"checklist": bson.M{"_id": itemID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+// ReorderChecklist puts the items of a checklist in the order of itemIDs.
+// The update only applies to the checklist it was computed from, so items
+// changed in the meantime are not lost.
+func (tr *TaskRepo) ReorderChecklist(ctx context.Context, taskID string, itemIDs []primitive.ObjectID) (models.Task, error) {
+	task, err := tr.GetTask(ctx, taskID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	checklist, err := models.ReorderChecklistItems(task.Checklist, itemIDs)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return tr.updateChecklist(ctx, taskID, bson.M{"checklist": task.Checklist}, bson.M{
+		"$set": bson.M{"checklist": checklist, "updated_at": time.Now()},
+	})
+}
+
+// updateChecklist applies an update to a task matching the filter. When the
+// task exists but does not match, the checklist item is reported missing.
+func (tr *TaskRepo) updateChecklist(ctx context.Context, taskID string, filter bson.M, update bson.M) (models.Task, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	filter["_id"] = objectID
+
+	var task models.Task
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = tr.db.Collection("tasks").FindOneAndUpdate(ctx, filter, update, opts).Decode(&task)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			tr.log.Error("Error updating task checklist", logger.Error(err))
+			return models.Task{}, err
+		}
+		if _, err := tr.GetTask(ctx, taskID); err != nil {
+			return models.Task{}, err
+		}
+		return models.Task{}, models.ErrChecklistItemNotFound
+	}
+
+	return task, nil
+}
+
+// CountSubtasks counts the subtasks of each of the parents.
+func (tr *TaskRepo) CountSubtasks(ctx context.Context, parentIDs []primitive.ObjectID) (map[primitive.ObjectID]models.SubtaskCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"parent_id": bson.M{"$in": parentIDs}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$parent_id",
+			"total":     bson.M{"$sum": 1},
+			"completed": bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", 1, 0}}},
+		}}},
+	}
+	cursor, err := tr.db.Collection("tasks").Aggregate(ctx, pipeline)
+	if err != nil {
+		tr.log.Error("Error counting subtasks", logger.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[primitive.ObjectID]models.SubtaskCount{}
+	for cursor.Next(ctx) {
+		var group struct {
+			ParentID  primitive.ObjectID `bson:"_id"`
+			Total     int                `bson:"total"`
+			Completed int                `bson:"completed"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			tr.log.Error("Error decoding subtask count", logger.Error(err))
+			continue
+		}
+		counts[group.ParentID] = models.SubtaskCount{Total: group.Total, Completed: group.Completed}
+	}
+
+	return counts, nil
+}
 
-	if search != "" {
-		filter["title"] = bson.M{"$regex": search, "$options": "i"}
+// CompleteSubtasks completes the open subtasks of a task.
+func (tr *TaskRepo) CompleteSubtasks(ctx context.Context, parentID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := tr.db.Collection("tasks").UpdateMany(ctx,
+		bson.M{"parent_id": parentID, "completed": false},
+		bson.M{"$set": bson.M{"completed": true, "completed_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		tr.log.Error("Error completing subtasks", logger.Error(err))
+		return err
 	}
+	return nil
+}
 
-	opts := options.Find().
-		SetSkip((page - 1) * limit).
-		SetLimit(limit)
+// GetAllTasks retrieves the tasks matching the filter with pagination.
+func (tr *TaskRepo) GetAllTasks(ctx context.Context, taskFilter models.TaskFilter, page models.Pagination) ([]models.Task, int64, error) {
+	tasks := []models.Task{}
+	filter := taskFilterQuery(taskFilter, time.Now())
+	keys := taskFilter.SortKeys()
 
-	cursor, err := tr.db.Collection("tasks").Find(ctx, filter, opts)
+	cursor, err := tr.db.Collection("tasks").Find(ctx, sortedPageFilter(filter, keys, page), sortedPageOptions(keys, page))
 	if err != nil {
-		tr.log.Error("Error retrieving tasks: ", err)
+		tr.log.Error("Error retrieving tasks", logger.Error(err))
 		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
@@ -107,7 +329,7 @@ func (tr *TaskRepo) GetAllTasks(ctx context.Context, userID string, search strin
 	for cursor.Next(ctx) {
 		var task models.Task
 		if err := cursor.Decode(&task); err != nil {
-			tr.log.Error("Error decoding task: ", err)
+			tr.log.Error("Error decoding task", logger.Error(err))
 			continue
 		}
 		tasks = append(tasks, task)
@@ -115,9 +337,70 @@ func (tr *TaskRepo) GetAllTasks(ctx context.Context, userID string, search strin
 
 	count, err := tr.db.Collection("tasks").CountDocuments(ctx, filter)
 	if err != nil {
-		tr.log.Error("Error counting tasks: ", err)
+		tr.log.Error("Error counting tasks", logger.Error(err))
 		return nil, 0, err
 	}
 
 	return tasks, count, nil
 }
+
+// taskFilterQuery translates a task filter into a query. Tasks without a due
+// date store the zero time.
+func taskFilterQuery(taskFilter models.TaskFilter, now time.Time) bson.M {
+	conditions := bson.A{bson.M{"task_list_id": bson.M{"$in": taskFilter.TaskListIDs}}}
+
+	if taskFilter.ParentID != nil {
+		conditions = append(conditions, bson.M{"parent_id": *taskFilter.ParentID})
+	}
+
+	if taskFilter.Search != "" {
+		conditions = append(conditions, bson.M{"title": bson.M{"$regex": regexp.QuoteMeta(taskFilter.Search), "$options": "i"}})
+	}
+
+	if len(taskFilter.Labels.LabelIDs) > 0 {
+		if taskFilter.Labels.Match == models.LabelMatchAll {
+			conditions = append(conditions, bson.M{"label_ids": bson.M{"$all": taskFilter.Labels.LabelIDs}})
+		} else {
+			conditions = append(conditions, bson.M{"label_ids": bson.M{"$in": taskFilter.Labels.LabelIDs}})
+		}
+	}
+
+	if taskFilter.Completed != nil {
+		conditions = append(conditions, bson.M{"completed": *taskFilter.Completed})
+	}
+	if taskFilter.Overdue {
+		conditions = append(conditions, bson.M{"completed": false, "due_date": bson.M{"$gt": time.Time{}, "$lt": now}})
+	}
+	if taskFilter.NoDueDate {
+		conditions = append(conditions, bson.M{"due_date": bson.M{"$in": bson.A{time.Time{}, nil}}})
+	}
+	if taskFilter.DueBefore != nil {
+		conditions = append(conditions, bson.M{"due_date": bson.M{"$gt": time.Time{}, "$lt": *taskFilter.DueBefore}})
+	}
+	if taskFilter.DueAfter != nil {
+		conditions = append(conditions, bson.M{"due_date": bson.M{"$gt": *taskFilter.DueAfter}})
+	}
+	if taskFilter.CreatedBefore != nil {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$lt": *taskFilter.CreatedBefore}})
+	}
+	if taskFilter.CreatedAfter != nil {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$gt": *taskFilter.CreatedAfter}})
+	}
+	if taskFilter.UpdatedBefore != nil {
+		conditions = append(conditions, bson.M{"updated_at": bson.M{"$lt": *taskFilter.UpdatedBefore}})
+	}
+	if taskFilter.UpdatedAfter != nil {
+		conditions = append(conditions, bson.M{"updated_at": bson.M{"$gt": *taskFilter.UpdatedAfter}})
+	}
+	if taskFilter.CompletedBefore != nil {
+		conditions = append(conditions, bson.M{"completed_at": bson.M{"$lt": *taskFilter.CompletedBefore}})
+	}
+	if taskFilter.CompletedAfter != nil {
+		conditions = append(conditions, bson.M{"completed_at": bson.M{"$gt": *taskFilter.CompletedAfter}})
+	}
+	if taskFilter.Query != nil {
+		conditions = append(conditions, queryFilter(taskFilter.Query, now))
+	}
+
+	return bson.M{"$and": conditions}
+}
@@ -2,12 +2,13 @@ package mongodb
 
 import (
 	"context"
+	"time"
 	"todo/api/models"
 	"todo/pkg/logger"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TaskListRepo struct {
@@ -21,15 +22,19 @@ func NewTaskListRepo(db *mongo.Database, log logger.ILogger) *TaskListRepo {
 
 // CreateTaskList creates a new task list in the database.
 func (tlr *TaskListRepo) CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error) {
+	now := time.Now()
 	taskList := models.TaskList{
-		ID:     req.ID,
-		Title:  req.Title,
-		UserID: req.UserID,
+		ID:          primitive.NewObjectID(),
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
 	_, err := tlr.db.Collection("task_lists").InsertOne(ctx, taskList)
 	if err != nil {
-		tlr.log.Error("Error creating task list: ", err)
+		tlr.log.Error("Error creating task list", logger.Error(err))
 		return models.TaskList{}, err
 	}
 
@@ -38,13 +43,18 @@ func (tlr *TaskListRepo) CreateTaskList(ctx context.Context, req models.CreateTa
 
 // GetTaskList retrieves a task list by ID.
 func (tlr *TaskListRepo) GetTaskList(ctx context.Context, taskListID string) (models.TaskList, error) {
+	objectID, err := primitive.ObjectIDFromHex(taskListID)
+	if err != nil {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+
 	var taskList models.TaskList
-	err := tlr.db.Collection("task_lists").FindOne(ctx, bson.M{"id": taskListID}).Decode(&taskList)
+	err = tlr.db.Collection("task_lists").FindOne(ctx, bson.M{"_id": objectID}).Decode(&taskList)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return models.TaskList{}, nil // Return an empty task list
+			return models.TaskList{}, models.ErrTaskListNotFound
 		}
-		tlr.log.Error("Error retrieving task list: ", err)
+		tlr.log.Error("Error retrieving task list", logger.Error(err))
 		return models.TaskList{}, err
 	}
 
@@ -53,44 +63,86 @@ func (tlr *TaskListRepo) GetTaskList(ctx context.Context, taskListID string) (mo
 
 // UpdateTaskList updates an existing task list.
 func (tlr *TaskListRepo) UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error) {
-	filter := bson.M{"id": req.ID}
+	filter := bson.M{"_id": req.ID}
 	update := bson.M{
 		"$set": bson.M{
-			"title": req.Title,
+			"title":       req.Title,
+			"description": req.Description,
+			"updated_at":  time.Now(),
 		},
 	}
 
-	_, err := tlr.db.Collection("task_lists").UpdateOne(ctx, filter, update)
+	res, err := tlr.db.Collection("task_lists").UpdateOne(ctx, filter, update)
 	if err != nil {
-		tlr.log.Error("Error updating task list: ", err)
+		tlr.log.Error("Error updating task list", logger.Error(err))
 		return models.TaskList{}, err
 	}
+	if res.MatchedCount == 0 {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
 
-	return tlr.GetTaskList(ctx, req.ID)
+	return tlr.GetTaskList(ctx, req.ID.Hex())
 }
 
-// DeleteTaskList removes a task list from the database.
-func (tlr *TaskListRepo) DeleteTaskList(ctx context.Context, taskListID string) error {
-	_, err := tlr.db.Collection("task_lists").DeleteOne(ctx, bson.M{"id": taskListID})
+// DeleteTaskList removes a task list together with its tasks and their
+// reminders in a single transaction. With dryRun nothing is deleted and the summary holds what
+// would have been removed.
+func (tlr *TaskListRepo) DeleteTaskList(ctx context.Context, taskListID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	objectID, err := primitive.ObjectIDFromHex(taskListID)
 	if err != nil {
-		tlr.log.Error("Error deleting task list: ", err)
+		return summary, models.ErrTaskListNotFound
+	}
+
+	err = withTransaction(ctx, tlr.db, func(sc mongo.SessionContext) error {
+		summary = models.DeleteSummary{DryRun: dryRun}
+
+		taskLists, err := removeMany(sc, tlr.db.Collection("task_lists"), bson.M{"_id": objectID}, dryRun)
+		if err != nil {
+			return err
+		}
+		if taskLists == 0 {
+			return models.ErrTaskListNotFound
+		}
+		summary.TaskLists = taskLists
+
+		taskIDs, err := tlr.db.Collection("tasks").Distinct(sc, "_id", bson.M{"task_list_id": objectID})
+		if err != nil {
+			return err
+		}
+		if len(taskIDs) > 0 {
+			summary.Reminders, err = removeMany(sc, tlr.db.Collection("reminders"), bson.M{"task_id": bson.M{"$in": taskIDs}}, dryRun)
+			if err != nil {
+				return err
+			}
+		}
+
+		summary.Tasks, err = removeMany(sc, tlr.db.Collection("tasks"), bson.M{"task_list_id": objectID}, dryRun)
 		return err
+	})
+	if err != nil {
+		if err != models.ErrTaskListNotFound {
+			tlr.log.Error("Error deleting task list", logger.Error(err))
+		}
+		return models.DeleteSummary{DryRun: dryRun}, err
 	}
-	return nil
+	return summary, nil
 }
 
 // GetAllTaskLists retrieves all task lists for a user with pagination.
-func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, page, limit uint64) ([]models.TaskList, int64, error) {
+func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, page models.Pagination) ([]models.TaskList, int64, error) {
 	taskLists := []models.TaskList{}
-	filter := bson.M{"user_id": userID}
 
-	opts := options.Find().
-		SetSkip((page - 1) * limit).
-		SetLimit(limit)
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	filter := bson.M{"user_id": objectID}
 
-	cursor, err := tlr.db.Collection("task_lists").Find(ctx, filter, opts)
+	cursor, err := tlr.db.Collection("task_lists").Find(ctx, pageFilter(filter, page), pageOptions(page))
 	if err != nil {
-		tlr.log.Error("Error retrieving task lists: ", err)
+		tlr.log.Error("Error retrieving task lists", logger.Error(err))
 		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
@@ -98,7 +150,7 @@ func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, pag
 	for cursor.Next(ctx) {
 		var taskList models.TaskList
 		if err := cursor.Decode(&taskList); err != nil {
-			tlr.log.Error("Error decoding task list: ", err)
+			tlr.log.Error("Error decoding task list", logger.Error(err))
 			continue
 		}
 		taskLists = append(taskLists, taskList)
@@ -106,7 +158,7 @@ func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, pag
 
 	count, err := tlr.db.Collection("task_lists").CountDocuments(ctx, filter)
 	if err != nil {
-		tlr.log.Error("Error counting task lists: ", err)
+		tlr.log.Error("Error counting task lists", logger.Error(err))
 		return nil, 0, err
 	}
 
@@ -0,0 +1,105 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TokenRepo struct {
+	collection *mongo.Collection
+	logger     logger.ILogger
+}
+
+// NewTokenRepo initializes a new TokenRepo with a MongoDB collection and logger.
+func NewTokenRepo(db *mongo.Database, log logger.ILogger) *TokenRepo {
+	return &TokenRepo{
+		collection: db.Collection("refresh_tokens"),
+		logger:     log,
+	}
+}
+
+// CreateRefreshToken stores a hashed refresh token.
+func (tr *TokenRepo) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	token.CreatedAt = time.Now()
+
+	_, err := tr.collection.InsertOne(ctx, token)
+	if err != nil {
+		tr.logger.Error("error while creating refresh token in db", logger.Error(err))
+		return fmt.Errorf("error while creating refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by the hash of its value.
+func (tr *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := tr.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.RefreshToken{}, fmt.Errorf("refresh token not found: %w", err)
+		}
+		tr.logger.Error("error while getting refresh token from db", logger.Error(err))
+		return models.RefreshToken{}, fmt.Errorf("error while getting refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenUsed flags an unused refresh token as used. It reports false
+// when the token had already been used or revoked, so that two concurrent
+// refreshes with the same token cannot both succeed.
+func (tr *TokenRepo) MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid refresh token id: %w", err)
+	}
+
+	filter := bson.M{"_id": objectID, "used": false, "revoked": false}
+	update := bson.M{"$set": bson.M{"used": true}}
+
+	res, err := tr.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		tr.logger.Error("error while marking refresh token used in db", logger.Error(err))
+		return false, fmt.Errorf("error while marking refresh token used: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// RevokeTokenFamily revokes every refresh token descended from the same login.
+func (tr *TokenRepo) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	return tr.revoke(ctx, bson.M{"family_id": familyID})
+}
+
+// RevokeDeviceTokens revokes the refresh tokens a user holds on one device.
+func (tr *TokenRepo) RevokeDeviceTokens(ctx context.Context, userID, deviceID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	return tr.revoke(ctx, bson.M{"user_id": objectID, "device_id": deviceID})
+}
+
+// RevokeUserTokens revokes all refresh tokens of a user.
+func (tr *TokenRepo) RevokeUserTokens(ctx context.Context, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	return tr.revoke(ctx, bson.M{"user_id": objectID})
+}
+
+func (tr *TokenRepo) revoke(ctx context.Context, filter bson.M) error {
+	filter["revoked"] = false
+	_, err := tr.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		tr.logger.Error("error while revoking refresh tokens in db", logger.Error(err))
+		return fmt.Errorf("error while revoking refresh tokens: %w", err)
+	}
+	return nil
+}
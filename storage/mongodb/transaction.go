@@ -0,0 +1,39 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// withTransaction runs fn in a multi-document transaction, retrying it on
+// transient errors. MongoDB only supports transactions on replica sets and
+// sharded clusters, so a standalone server has to run as a single-node
+// replica set.
+func withTransaction(ctx context.Context, db *mongo.Database, fn func(sc mongo.SessionContext) error) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// removeMany deletes the documents matching filter and returns how many were
+// removed. In dry-run mode it only counts them.
+func removeMany(ctx context.Context, collection *mongo.Collection, filter bson.M, dryRun bool) (int64, error) {
+	if dryRun {
+		return collection.CountDocuments(ctx, filter)
+	}
+
+	res, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -2,12 +2,14 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
+	"time"
 	"todo/api/models"
 	"todo/pkg/logger"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepo struct {
@@ -19,17 +21,22 @@ func NewUserRepo(db *mongo.Database, log logger.ILogger) *UserRepo {
 	return &UserRepo{db: db, log: log}
 }
 
-// CreateUser creates a new user in the database.
+// CreateUser creates a new user in the database. req.Password must already be hashed.
 func (ur *UserRepo) CreateUser(ctx context.Context, req models.CreateUser) (models.User, error) {
+	now := time.Now()
 	user := models.User{
-		ID:    req.ID,
+		ID:        primitive.NewObjectID(),
 		Username:  req.Username,
-		Email: req.Email,
+		Email:     req.Email,
+		Password:  req.Password,
+		Role:      req.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
 	_, err := ur.db.Collection("users").InsertOne(ctx, user)
 	if err != nil {
-		ur.log.Error("Error creating user: ", err)
+		ur.log.Error("Error creating user", logger.Error(err))
 		return models.User{}, err
 	}
 
@@ -38,13 +45,33 @@ func (ur *UserRepo) CreateUser(ctx context.Context, req models.CreateUser) (mode
 
 // GetUser retrieves a user by ID.
 func (ur *UserRepo) GetUser(ctx context.Context, userID string) (models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.User{}, models.ErrUserNotFound
+	}
+
 	var user models.User
-	err := ur.db.Collection("users").FindOne(ctx, bson.M{"id": userID}).Decode(&user)
+	err = ur.db.Collection("users").FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return models.User{}, nil // Return an empty user if not found
+			return models.User{}, models.ErrUserNotFound
 		}
-		ur.log.Error("Error retrieving user: ", err)
+		ur.log.Error("Error retrieving user", logger.Error(err))
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// GetUserByEmail retrieves a user by email.
+func (ur *UserRepo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := ur.db.Collection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.User{}, models.ErrUserNotFound
+		}
+		ur.log.Error("Error retrieving user by email", logger.Error(err))
 		return models.User{}, err
 	}
 
@@ -53,43 +80,162 @@ func (ur *UserRepo) GetUser(ctx context.Context, userID string) (models.User, er
 
 // UpdateUser updates an existing user.
 func (ur *UserRepo) UpdateUser(ctx context.Context, req models.UpdateUser) (models.User, error) {
-	filter := bson.M{"id": req.ID}
+	filter := bson.M{"_id": req.ID}
 	update := bson.M{
 		"$set": bson.M{
-			"name":  req.Name,
-			"email": req.Email,
+			"username":   req.Username,
+			"email":      req.Email,
+			"updated_at": time.Now(),
 		},
 	}
 
 	_, err := ur.db.Collection("users").UpdateOne(ctx, filter, update)
 	if err != nil {
-		ur.log.Error("Error updating user: ", err)
+		ur.log.Error("Error updating user", logger.Error(err))
 		return models.User{}, err
 	}
 
-	return ur.GetUser(ctx, req.ID)
+	return ur.GetUser(ctx, req.ID.Hex())
+}
+
+// UpdatePassword replaces the password hash of a user.
+func (ur *UserRepo) UpdatePassword(ctx context.Context, userID string, passwordHash string) error {
+	return ur.set(ctx, userID, bson.M{"password": passwordHash})
+}
+
+// UpdateUserRole changes the role of a user.
+func (ur *UserRepo) UpdateUserRole(ctx context.Context, userID string, role string) error {
+	return ur.set(ctx, userID, bson.M{"role": role})
+}
+
+// SetUserDisabled disables or re-enables a user.
+func (ur *UserRepo) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	return ur.set(ctx, userID, bson.M{"disabled": disabled})
+}
+
+// BumpTokenVersion invalidates the access tokens issued to a user so far.
+func (ur *UserRepo) BumpTokenVersion(ctx context.Context, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrUserNotFound
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"token_version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	res, err := ur.db.Collection("users").UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		ur.log.Error("Error bumping token version", logger.Error(err))
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
 }
 
-// DeleteUser removes a user from the database.
-func (ur *UserRepo) DeleteUser(ctx context.Context, userID string) error {
-	_, err := ur.db.Collection("users").DeleteOne(ctx, bson.M{"id": userID})
+// set applies fields to a single user and bumps updated_at.
+func (ur *UserRepo) set(ctx context.Context, userID string, fields bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrUserNotFound
+	}
+
+	fields["updated_at"] = time.Now()
+
+	res, err := ur.db.Collection("users").UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": fields})
 	if err != nil {
-		ur.log.Error("Error deleting user: ", err)
+		ur.log.Error("Error updating user", logger.Error(err))
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
 	return nil
 }
 
+// DeleteUser removes a user and everything they own (task lists, tasks,
+// labels, reminders, refresh tokens and password resets) in a single
+// transaction. With
+// dryRun nothing is deleted and the summary holds what would have been removed.
+func (ur *UserRepo) DeleteUser(ctx context.Context, userID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return summary, models.ErrUserNotFound
+	}
+
+	err = withTransaction(ctx, ur.db, func(sc mongo.SessionContext) error {
+		summary = models.DeleteSummary{DryRun: dryRun}
+
+		users, err := removeMany(sc, ur.db.Collection("users"), bson.M{"_id": objectID}, dryRun)
+		if err != nil {
+			return err
+		}
+		if users == 0 {
+			return models.ErrUserNotFound
+		}
+		summary.Users = users
+
+		taskListIDs, err := ur.db.Collection("task_lists").Distinct(sc, "_id", bson.M{"user_id": objectID})
+		if err != nil {
+			return err
+		}
+		if len(taskListIDs) > 0 {
+			summary.Tasks, err = removeMany(sc, ur.db.Collection("tasks"), bson.M{"task_list_id": bson.M{"$in": taskListIDs}}, dryRun)
+			if err != nil {
+				return err
+			}
+		}
+
+		owned := bson.M{"user_id": objectID}
+		if summary.TaskLists, err = removeMany(sc, ur.db.Collection("task_lists"), owned, dryRun); err != nil {
+			return err
+		}
+		if summary.Labels, err = removeMany(sc, ur.db.Collection("labels"), owned, dryRun); err != nil {
+			return err
+		}
+		if summary.RefreshTokens, err = removeMany(sc, ur.db.Collection("refresh_tokens"), owned, dryRun); err != nil {
+			return err
+		}
+		if summary.PasswordResets, err = removeMany(sc, ur.db.Collection("password_resets"), owned, dryRun); err != nil {
+			return err
+		}
+		if summary.SmartLists, err = removeMany(sc, ur.db.Collection("smart_lists"), owned, dryRun); err != nil {
+			return err
+		}
+		if summary.Reminders, err = removeMany(sc, ur.db.Collection("reminders"), owned, dryRun); err != nil {
+			return err
+		}
+		if _, err = removeMany(sc, ur.db.Collection("webhooks"), owned, dryRun); err != nil {
+			return err
+		}
+		if _, err = removeMany(sc, ur.db.Collection("webhook_deliveries"), owned, dryRun); err != nil {
+			return err
+		}
+		if _, err = removeMany(sc, ur.db.Collection("notification_settings"), bson.M{"_id": objectID}, dryRun); err != nil {
+			return err
+		}
+		_, err = removeMany(sc, ur.db.Collection("digest_settings"), bson.M{"_id": objectID}, dryRun)
+		return err
+	})
+	if err != nil {
+		if err != models.ErrUserNotFound {
+			ur.log.Error("Error deleting user", logger.Error(err))
+		}
+		return models.DeleteSummary{DryRun: dryRun}, err
+	}
+	return summary, nil
+}
+
 // GetAllUsers retrieves all users with pagination.
-func (ur *UserRepo) GetAllUsers(ctx context.Context, page, limit uint64) ([]models.User, int64, error) {
+func (ur *UserRepo) GetAllUsers(ctx context.Context, page models.Pagination) ([]models.User, int64, error) {
 	users := []models.User{}
-	opts := options.Find().
-		SetSkip((page - 1) * limit).
-		SetLimit(limit)
-
-	cursor, err := ur.db.Collection("users").Find(ctx, bson.M{}, opts)
+	cursor, err := ur.db.Collection("users").Find(ctx, pageFilter(bson.M{}, page), pageOptions(page))
 	if err != nil {
-		ur.log.Error("Error retrieving users: ", err)
+		ur.log.Error("Error retrieving users", logger.Error(err))
 		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
@@ -97,7 +243,7 @@ func (ur *UserRepo) GetAllUsers(ctx context.Context, page, limit uint64) ([]mode
 	for cursor.Next(ctx) {
 		var user models.User
 		if err := cursor.Decode(&user); err != nil {
-			ur.log.Error("Error decoding user: ", err)
+			ur.log.Error("Error decoding user", logger.Error(err))
 			continue
 		}
 		users = append(users, user)
@@ -105,9 +251,19 @@ func (ur *UserRepo) GetAllUsers(ctx context.Context, page, limit uint64) ([]mode
 
 	count, err := ur.db.Collection("users").CountDocuments(ctx, bson.M{})
 	if err != nil {
-		ur.log.Error("Error counting users: ", err)
-		return nil, 0, err
+		ur.log.Error("Error counting users", logger.Error(err))
+		return nil, 0, fmt.Errorf("error while counting users: %w", err)
 	}
 
 	return users, count, nil
 }
+
+// CountUsersWithRole counts the users that have role.
+func (ur *UserRepo) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	count, err := ur.db.Collection("users").CountDocuments(ctx, bson.M{"role": role})
+	if err != nil {
+		ur.log.Error("Error counting users", logger.Error(err))
+		return 0, fmt.Errorf("error while counting users: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,274 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookRepo struct {
+	db  *mongo.Database
+	log logger.ILogger
+}
+
+func NewWebhookRepo(db *mongo.Database, log logger.ILogger) *WebhookRepo {
+	return &WebhookRepo{db: db, log: log}
+}
+
+// CreateWebhook stores a new, active webhook.
+func (wr *WebhookRepo) CreateWebhook(ctx context.Context, req models.CreateWebhook) (models.Webhook, error) {
+	now := time.Now()
+	webhook := models.Webhook{
+		ID:          primitive.NewObjectID(),
+		UserID:      req.UserID,
+		URL:         req.URL,
+		Events:      append([]string{}, req.Events...),
+		Description: req.Description,
+		Secret:      req.Secret,
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := wr.db.Collection("webhooks").InsertOne(ctx, webhook); err != nil {
+		wr.log.Error("Error creating webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (wr *WebhookRepo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+
+	var webhook models.Webhook
+	err = wr.db.Collection("webhooks").FindOne(ctx, bson.M{"_id": objectID}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Webhook{}, models.ErrWebhookNotFound
+		}
+		wr.log.Error("Error retrieving webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// GetAllWebhooks retrieves the webhooks of a user with pagination support.
+func (wr *WebhookRepo) GetAllWebhooks(ctx context.Context, userID string, page models.Pagination) ([]models.Webhook, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	filter := bson.M{"user_id": objectID}
+
+	count, err := wr.db.Collection("webhooks").CountDocuments(ctx, filter)
+	if err != nil {
+		wr.log.Error("Error counting webhooks", logger.Error(err))
+		return nil, 0, err
+	}
+	webhooks, err := wr.find(ctx, pageFilter(filter, page), pageOptions(page))
+	if err != nil {
+		return nil, 0, err
+	}
+	return webhooks, count, nil
+}
+
+// UpdateWebhook replaces the endpoint, events and description of a webhook,
+// and pauses or resumes it.
+func (wr *WebhookRepo) UpdateWebhook(ctx context.Context, req models.UpdateWebhook) (models.Webhook, error) {
+	set := bson.M{
+		"url":         req.URL,
+		"events":      append([]string{}, req.Events...),
+		"description": req.Description,
+		"updated_at":  time.Now(),
+	}
+	if req.Active != nil {
+		set["active"] = *req.Active
+	}
+
+	var webhook models.Webhook
+	err := wr.db.Collection("webhooks").FindOneAndUpdate(ctx,
+		bson.M{"_id": req.ID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Webhook{}, models.ErrWebhookNotFound
+		}
+		wr.log.Error("Error updating webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its deliveries in one transaction.
+func (wr *WebhookRepo) DeleteWebhook(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.ErrWebhookNotFound
+	}
+
+	err = withTransaction(ctx, wr.db, func(sc mongo.SessionContext) error {
+		res, err := wr.db.Collection("webhooks").DeleteOne(sc, bson.M{"_id": objectID})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			return models.ErrWebhookNotFound
+		}
+		_, err = wr.db.Collection("webhook_deliveries").DeleteMany(sc, bson.M{"webhook_id": objectID})
+		return err
+	})
+	if err != nil && err != models.ErrWebhookNotFound {
+		wr.log.Error("Error deleting webhook", logger.Error(err))
+	}
+	return err
+}
+
+// SubscribedWebhooks returns the active webhooks of a user subscribed to
+// event, the oldest first.
+func (wr *WebhookRepo) SubscribedWebhooks(ctx context.Context, userID primitive.ObjectID, event string) ([]models.Webhook, error) {
+	filter := bson.M{"user_id": userID, "active": true, "events": event}
+	return wr.find(ctx, filter, pageOptions(models.Pagination{}))
+}
+
+// EnqueueDelivery queues an event for a webhook.
+func (wr *WebhookRepo) EnqueueDelivery(ctx context.Context, req models.EnqueueDelivery) (models.WebhookDelivery, error) {
+	nextAttemptAt := req.NextAttemptAt
+	delivery := models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     req.WebhookID,
+		UserID:        req.UserID,
+		EventID:       req.EventID,
+		Event:         req.Event,
+		Payload:       req.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &nextAttemptAt,
+		RedeliveryOf:  req.RedeliveryOf,
+		CreatedAt:     time.Now(),
+	}
+
+	if _, err := wr.db.Collection("webhook_deliveries").InsertOne(ctx, delivery); err != nil {
+		wr.log.Error("Error enqueuing webhook delivery", logger.Error(err))
+		return models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+// GetDelivery retrieves a delivery by its ID.
+func (wr *WebhookRepo) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+
+	var delivery models.WebhookDelivery
+	err = wr.db.Collection("webhook_deliveries").FindOne(ctx, bson.M{"_id": objectID}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+		}
+		wr.log.Error("Error retrieving webhook delivery", logger.Error(err))
+		return models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+// GetDeliveries returns the delivery log of a webhook, the latest first.
+func (wr *WebhookRepo) GetDeliveries(ctx context.Context, webhookID string, page models.Pagination) ([]models.WebhookDelivery, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, 0, models.ErrWebhookNotFound
+	}
+	filter := bson.M{"webhook_id": objectID}
+
+	count, err := wr.db.Collection("webhook_deliveries").CountDocuments(ctx, filter)
+	if err != nil {
+		wr.log.Error("Error counting webhook deliveries", logger.Error(err))
+		return nil, 0, err
+	}
+	deliveries, err := wr.findDeliveries(ctx,
+		sortedPageFilter(filter, models.DeliverySort, page), sortedPageOptions(models.DeliverySort, page))
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, count, nil
+}
+
+// DueDeliveries returns the pending deliveries due at or before now.
+func (wr *WebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	filter := bson.M{"status": models.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	return wr.findDeliveries(ctx, filter, opts)
+}
+
+// RecordAttempt stores the outcome of posting a delivery, unless it is no
+// longer pending at the time it was read.
+func (wr *WebhookRepo) RecordAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":          attempt.Status,
+			"attempts":        attempt.Attempts,
+			"last_error":      attempt.LastError,
+			"response_status": attempt.ResponseStatus,
+			"response_body":   attempt.ResponseBody,
+			"next_attempt_at": attempt.NextAttemptAt,
+			"delivered_at":    attempt.DeliveredAt,
+		},
+	}
+
+	res, err := wr.db.Collection("webhook_deliveries").UpdateOne(ctx,
+		bson.M{"_id": attempt.ID, "status": models.DeliveryPending, "next_attempt_at": attempt.ScheduledAt}, update)
+	if err != nil {
+		wr.log.Error("Error recording webhook attempt", logger.Error(err))
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (wr *WebhookRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Webhook, error) {
+	cursor, err := wr.db.Collection("webhooks").Find(ctx, filter, opts)
+	if err != nil {
+		wr.log.Error("Error retrieving webhooks", logger.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		wr.log.Error("Error decoding webhooks", logger.Error(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (wr *WebhookRepo) findDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.WebhookDelivery, error) {
+	cursor, err := wr.db.Collection("webhook_deliveries").Find(ctx, filter, opts)
+	if err != nil {
+		wr.log.Error("Error retrieving webhook deliveries", logger.Error(err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		wr.log.Error("Error decoding webhook deliveries", logger.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
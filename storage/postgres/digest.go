@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DigestRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewDigestRepo(db *pgxpool.Pool, log logger.ILogger) *DigestRepo {
+	return &DigestRepo{db: db, log: log}
+}
+
+const digestSettingsColumns = "user_id, frequency, send_at, weekday, timezone, next_at, last_sent_at, updated_at"
+
+func scanDigestSettings(row scanner) (models.DigestSettings, error) {
+	var settings models.DigestSettings
+	var userID string
+	err := row.Scan(&userID, &settings.Frequency, &settings.SendAt, &settings.Weekday, &settings.Timezone,
+		&settings.NextAt, &settings.LastSentAt, &settings.UpdatedAt)
+	settings.UserID = objectID(userID)
+	return settings, err
+}
+
+// GetDigestSettings returns the settings of a user, or the defaults.
+func (dr *DigestRepo) GetDigestSettings(ctx context.Context, userID primitive.ObjectID) (models.DigestSettings, error) {
+	settings, err := scanDigestSettings(dr.db.QueryRow(ctx,
+		`SELECT `+digestSettingsColumns+` FROM digest_settings WHERE user_id = $1`, userID.Hex()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DefaultDigestSettings(userID), nil
+		}
+		dr.log.Error("Error retrieving digest settings", logger.Error(err))
+		return models.DigestSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveDigestSettings creates or replaces the settings of a user, keeping
+// when the last digest was sent.
+func (dr *DigestRepo) SaveDigestSettings(ctx context.Context, req models.UpdateDigestSettings) (models.DigestSettings, error) {
+	settings, err := scanDigestSettings(dr.db.QueryRow(ctx,
+		`INSERT INTO digest_settings (user_id, frequency, send_at, weekday, timezone, next_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, send_at = EXCLUDED.send_at,
+			weekday = EXCLUDED.weekday, timezone = EXCLUDED.timezone, next_at = EXCLUDED.next_at, updated_at = EXCLUDED.updated_at
+		RETURNING `+digestSettingsColumns,
+		req.UserID.Hex(), req.Frequency, req.SendAt, req.Weekday, req.Timezone, req.NextAt, time.Now()))
+	if err != nil {
+		dr.log.Error("Error saving digest settings", logger.Error(err))
+		return models.DigestSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// DueDigests returns the settings whose next digest is due at or before now.
+func (dr *DigestRepo) DueDigests(ctx context.Context, now time.Time, limit int) ([]models.DigestSettings, error) {
+	rows, err := dr.db.Query(ctx, `SELECT `+digestSettingsColumns+` FROM digest_settings
+		WHERE next_at <= $1 ORDER BY next_at, user_id LIMIT $2`, now, limit)
+	if err != nil {
+		dr.log.Error("Error retrieving due digests", logger.Error(err))
+		return nil, err
+	}
+	due, err := pgx.AppendRows([]models.DigestSettings{}, rows, func(row pgx.CollectableRow) (models.DigestSettings, error) {
+		return scanDigestSettings(row)
+	})
+	if err != nil {
+		dr.log.Error("Error decoding due digests", logger.Error(err))
+		return nil, err
+	}
+	return due, nil
+}
+
+// RecordDigest stores the outcome of a digest and when the next is sent,
+// unless the settings have been saved since it fell due.
+func (dr *DigestRepo) RecordDigest(ctx context.Context, delivery models.DigestDelivery) error {
+	res, err := dr.db.Exec(ctx, `UPDATE digest_settings SET next_at = $3, last_sent_at = COALESCE($4, last_sent_at)
+		WHERE user_id = $1 AND next_at = $2`,
+		delivery.UserID.Hex(), delivery.ScheduledAt, delivery.NextAt, delivery.SentAt)
+	if err != nil {
+		dr.log.Error("Error recording digest", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrDigestNotFound
+	}
+	return nil
+}
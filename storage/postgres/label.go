@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LabelRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewLabelRepo(db *pgxpool.Pool, log logger.ILogger) *LabelRepo {
+	return &LabelRepo{db: db, log: log}
+}
+
+const labelColumns = "id, user_id, name, color, created_at, updated_at"
+
+func scanLabel(row scanner) (models.Label, error) {
+	var label models.Label
+	var id, userID string
+	err := row.Scan(&id, &userID, &label.Name, &label.Color, &label.CreatedAt, &label.UpdatedAt)
+	label.ID = objectID(id)
+	label.UserID = objectID(userID)
+	return label, err
+}
+
+// CreateLabel creates a new label in the database.
+func (lr *LabelRepo) CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error) {
+	now := time.Now()
+	label := models.Label{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := lr.db.Exec(ctx, `INSERT INTO labels (`+labelColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		label.ID.Hex(), label.UserID.Hex(), label.Name, label.Color, label.CreatedAt, label.UpdatedAt)
+	if err != nil {
+		lr.log.Error("Error creating label", logger.Error(err))
+		return models.Label{}, err
+	}
+
+	return label, nil
+}
+
+// GetLabel retrieves a label by its ID.
+func (lr *LabelRepo) GetLabel(ctx context.Context, labelID string) (models.Label, error) {
+	if _, err := primitive.ObjectIDFromHex(labelID); err != nil {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+
+	label, err := scanLabel(lr.db.QueryRow(ctx, `SELECT `+labelColumns+` FROM labels WHERE id = $1`, labelID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Label{}, models.ErrLabelNotFound
+		}
+		lr.log.Error("Error retrieving label", logger.Error(err))
+		return models.Label{}, err
+	}
+
+	return label, nil
+}
+
+// UpdateLabel updates the label information.
+func (lr *LabelRepo) UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error) {
+	label, err := scanLabel(lr.db.QueryRow(ctx,
+		`UPDATE labels SET name = $2, color = $3, updated_at = $4 WHERE id = $1 RETURNING `+labelColumns,
+		req.ID.Hex(), req.Name, req.Color, time.Now()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Label{}, models.ErrLabelNotFound
+		}
+		lr.log.Error("Error updating label", logger.Error(err))
+		return models.Label{}, err
+	}
+
+	return label, nil
+}
+
+// DeleteLabel removes a label by its ID. The task_labels foreign key drops it
+// from the tasks that carry it.
+func (lr *LabelRepo) DeleteLabel(ctx context.Context, labelID string) error {
+	if _, err := primitive.ObjectIDFromHex(labelID); err != nil {
+		return models.ErrLabelNotFound
+	}
+
+	res, err := lr.db.Exec(ctx, `DELETE FROM labels WHERE id = $1`, labelID)
+	if err != nil {
+		lr.log.Error("Error deleting label", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrLabelNotFound
+	}
+	return nil
+}
+
+// GetAllLabels retrieves all labels for a specific user with pagination support.
+func (lr *LabelRepo) GetAllLabels(ctx context.Context, userID string, search string, page models.Pagination) ([]models.Label, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	clause, args := pageClause("", models.DefaultSort, page, []any{userID, search})
+
+	rows, err := lr.db.Query(ctx, `SELECT `+labelColumns+` FROM labels WHERE user_id = $1 AND strpos(lower(name), lower($2)) > 0`+clause, args...)
+	if err != nil {
+		lr.log.Error("Error retrieving labels", logger.Error(err))
+		return nil, 0, err
+	}
+	labels, err := pgx.AppendRows([]models.Label{}, rows, func(row pgx.CollectableRow) (models.Label, error) {
+		return scanLabel(row)
+	})
+	if err != nil {
+		lr.log.Error("Error decoding labels", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	err = lr.db.QueryRow(ctx, `SELECT count(*) FROM labels WHERE user_id = $1 AND strpos(lower(name), lower($2)) > 0`, userID, search).Scan(&count)
+	if err != nil {
+		lr.log.Error("Error counting labels", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return labels, count, nil
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies the up migrations that have not run yet, in file name
+// order. Each migration runs in its own transaction together with its
+// schema_migrations row, and the table lock keeps concurrent app instances
+// from applying the same migration twice.
+func Migrate(ctx context.Context, db *pgxpool.Pool) error {
+	_, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT        PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	for _, file := range files {
+		version, ok := strings.CutSuffix(file.Name(), ".up.sql")
+		if !ok {
+			continue
+		}
+		if err := applyMigration(ctx, db, version, "migrations/"+file.Name()); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *pgxpool.Pool, version, path string) error {
+	script, err := migrations.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "LOCK TABLE schema_migrations IN EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+
+	var applied bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, string(script)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
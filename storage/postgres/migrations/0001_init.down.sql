@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS pending_registrations;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS task_labels;
+DROP TABLE IF EXISTS labels;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS task_lists;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,98 @@
+-- IDs are MongoDB ObjectIDs in hex so both backends share the same models.
+-- They start with their creation time, so ordering by id is insertion order.
+
+CREATE TABLE users (
+    id            CHAR(24)    PRIMARY KEY,
+    username      TEXT        NOT NULL,
+    email         TEXT        NOT NULL UNIQUE,
+    password      TEXT        NOT NULL DEFAULT '',
+    role          TEXT        NOT NULL DEFAULT '',
+    disabled      BOOLEAN     NOT NULL DEFAULT FALSE,
+    -- Access tokens carry the token version of their user and stop working
+    -- once it is bumped.
+    token_version INTEGER     NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE task_lists (
+    id          CHAR(24)    PRIMARY KEY,
+    user_id     CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title       TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX task_lists_user_id_idx ON task_lists (user_id);
+
+CREATE TABLE tasks (
+    id           CHAR(24)    PRIMARY KEY,
+    task_list_id CHAR(24)    NOT NULL REFERENCES task_lists (id) ON DELETE CASCADE,
+    title        TEXT        NOT NULL,
+    description  TEXT        NOT NULL DEFAULT '',
+    due_date     TIMESTAMPTZ NOT NULL,
+    completed    BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX tasks_task_list_id_idx ON tasks (task_list_id);
+
+CREATE TABLE labels (
+    id          CHAR(24)    PRIMARY KEY,
+    user_id     CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        TEXT        NOT NULL,
+    color       TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX labels_user_id_idx ON labels (user_id);
+
+-- Deleting a task or a label drops the link, so labels never dangle.
+CREATE TABLE task_labels (
+    task_id     CHAR(24)    NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    label_id    CHAR(24)    NOT NULL REFERENCES labels (id) ON DELETE CASCADE,
+    position    BIGINT      GENERATED ALWAYS AS IDENTITY,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX task_labels_label_id_idx ON task_labels (label_id);
+
+CREATE TABLE refresh_tokens (
+    id          CHAR(24)    PRIMARY KEY,
+    user_id     CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id   TEXT        NOT NULL,
+    device_id   TEXT        NOT NULL DEFAULT '',
+    token_hash  TEXT        NOT NULL UNIQUE,
+    used        BOOLEAN     NOT NULL DEFAULT FALSE,
+    revoked     BOOLEAN     NOT NULL DEFAULT FALSE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_device_id_idx ON refresh_tokens (user_id, device_id);
+
+-- user_id is only set when the registration changes the email of a user.
+CREATE TABLE pending_registrations (
+    id            CHAR(24)    PRIMARY KEY,
+    user_id       CHAR(24)    REFERENCES users (id) ON DELETE CASCADE,
+    email         TEXT        NOT NULL UNIQUE,
+    username      TEXT        NOT NULL,
+    password_hash TEXT        NOT NULL,
+    otp_hash      TEXT        NOT NULL,
+    attempts      INTEGER     NOT NULL DEFAULT 0,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE password_resets (
+    id          CHAR(24)    PRIMARY KEY,
+    user_id     CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash  TEXT        NOT NULL UNIQUE,
+    used        BOOLEAN     NOT NULL DEFAULT FALSE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,5 @@
+-- Listings are sorted and paged by (created_at, id) within their owner
+CREATE INDEX users_created_at_id_idx ON users (created_at, id);
+CREATE INDEX task_lists_user_id_created_at_id_idx ON task_lists (user_id, created_at, id);
+CREATE INDEX tasks_task_list_id_created_at_id_idx ON tasks (task_list_id, created_at, id);
+CREATE INDEX labels_user_id_created_at_id_idx ON labels (user_id, created_at, id);
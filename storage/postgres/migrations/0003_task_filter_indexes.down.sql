@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS tasks_task_list_id_updated_at_id_idx;
+DROP INDEX IF EXISTS tasks_task_list_id_due_date_id_idx;
+DROP INDEX IF EXISTS tasks_task_list_id_completed_due_date_idx;
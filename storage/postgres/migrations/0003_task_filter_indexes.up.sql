@@ -0,0 +1,4 @@
+-- Open tasks by due date, and recently changed tasks, within a list
+CREATE INDEX tasks_task_list_id_completed_due_date_idx ON tasks (task_list_id, completed, due_date);
+CREATE INDEX tasks_task_list_id_due_date_id_idx ON tasks (task_list_id, due_date, id);
+CREATE INDEX tasks_task_list_id_updated_at_id_idx ON tasks (task_list_id, updated_at, id);
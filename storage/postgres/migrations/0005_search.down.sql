@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS labels_search_idx;
+DROP INDEX IF EXISTS task_lists_search_idx;
+DROP INDEX IF EXISTS tasks_search_idx;
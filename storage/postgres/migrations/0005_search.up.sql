@@ -0,0 +1,9 @@
+-- Full-text search of tasks, task lists and labels. Titles weigh A and
+-- descriptions B. The simple configuration matches words as written,
+-- without stemming or stop words, like the text indexes of MongoDB.
+-- Queries must repeat these expressions exactly to use the indexes.
+CREATE INDEX tasks_search_idx ON tasks USING GIN
+    ((setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', description), 'B')));
+CREATE INDEX task_lists_search_idx ON task_lists USING GIN
+    ((setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', description), 'B')));
+CREATE INDEX labels_search_idx ON labels USING GIN ((setweight(to_tsvector('simple', name), 'A')));
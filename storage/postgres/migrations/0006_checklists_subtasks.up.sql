@@ -0,0 +1,17 @@
+-- Subtasks point at their parent and go when it goes. Only subtasks have a
+-- parent, so the index leaves the other tasks out.
+ALTER TABLE tasks ADD COLUMN parent_id CHAR(24) REFERENCES tasks (id) ON DELETE CASCADE;
+
+CREATE INDEX tasks_parent_id_idx ON tasks (parent_id) WHERE parent_id IS NOT NULL;
+
+-- Checklist items are read in the order of position, which reordering
+-- rewrites.
+CREATE TABLE checklist_items (
+    id          CHAR(24)    PRIMARY KEY,
+    task_id     CHAR(24)    NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    title       TEXT        NOT NULL,
+    done        BOOLEAN     NOT NULL DEFAULT FALSE,
+    position    INTEGER     NOT NULL
+);
+
+CREATE INDEX checklist_items_task_id_position_idx ON checklist_items (task_id, position);
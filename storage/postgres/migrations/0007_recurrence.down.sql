@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS recurrence_start,
+    DROP COLUMN IF EXISTS recurrence_timezone,
+    DROP COLUMN IF EXISTS recurrence_rule;
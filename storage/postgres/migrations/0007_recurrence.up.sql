@@ -0,0 +1,5 @@
+-- A task repeats when it has a rule. The start anchors COUNT and INTERVAL.
+ALTER TABLE tasks
+    ADD COLUMN recurrence_rule     TEXT,
+    ADD COLUMN recurrence_timezone TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN recurrence_start    TIMESTAMPTZ;
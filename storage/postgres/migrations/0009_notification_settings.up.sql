@@ -0,0 +1,8 @@
+-- Where a user's notifications go. Users without a row get the defaults.
+CREATE TABLE notification_settings (
+    user_id           CHAR(24)    PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    channels          TEXT[]      NOT NULL,
+    telegram_chat_id  TEXT        NOT NULL DEFAULT '',
+    webhook_url       TEXT        NOT NULL DEFAULT '',
+    updated_at        TIMESTAMPTZ NOT NULL
+);
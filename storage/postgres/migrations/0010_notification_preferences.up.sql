@@ -0,0 +1,6 @@
+-- Preferences are kept with the settings. events maps an event to its
+-- channels; quiet hours are off while quiet_start is empty.
+ALTER TABLE notification_settings ADD COLUMN events JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE notification_settings ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE notification_settings ADD COLUMN quiet_start TEXT NOT NULL DEFAULT '';
+ALTER TABLE notification_settings ADD COLUMN quiet_end TEXT NOT NULL DEFAULT '';
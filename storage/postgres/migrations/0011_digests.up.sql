@@ -0,0 +1,19 @@
+-- completed_at backs the tasks completed in a period of the digest. Tasks
+-- completed before it existed count as completed when last updated.
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMPTZ;
+UPDATE tasks SET completed_at = updated_at WHERE completed;
+
+-- When a user is emailed the digest of their tasks. Users without a row get
+-- none; next_at is NULL while digests are off, and the scheduler polls by it.
+CREATE TABLE digest_settings (
+    user_id       CHAR(24)    PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    frequency     TEXT        NOT NULL,
+    send_at       TEXT        NOT NULL,
+    weekday       TEXT        NOT NULL,
+    timezone      TEXT        NOT NULL,
+    next_at       TIMESTAMPTZ,
+    last_sent_at  TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX digest_settings_next_at_idx ON digest_settings (next_at, user_id);
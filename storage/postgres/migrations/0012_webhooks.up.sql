@@ -0,0 +1,39 @@
+-- Endpoints users post their events to. events lists the event types the
+-- webhook subscribed to.
+CREATE TABLE webhooks (
+    id           CHAR(24)    PRIMARY KEY,
+    user_id      CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url          TEXT        NOT NULL,
+    events       TEXT[]      NOT NULL,
+    description  TEXT        NOT NULL DEFAULT '',
+    secret       TEXT        NOT NULL,
+    active       BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX webhooks_user_id_created_at_id_idx ON webhooks (user_id, created_at, id);
+
+-- One row per event queued for a webhook, kept as the delivery log. payload
+-- is the JSON posted. next_attempt_at is NULL once the delivery succeeded or
+-- failed; the scheduler polls the pending ones by it.
+CREATE TABLE webhook_deliveries (
+    id               CHAR(24)    PRIMARY KEY,
+    webhook_id       CHAR(24)    NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    user_id          CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    event_id         CHAR(24)    NOT NULL,
+    event            TEXT        NOT NULL,
+    payload          JSON        NOT NULL,
+    status           TEXT        NOT NULL,
+    attempts         INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ,
+    last_error       TEXT        NOT NULL DEFAULT '',
+    response_status  INTEGER     NOT NULL DEFAULT 0,
+    response_body    TEXT        NOT NULL DEFAULT '',
+    redelivery_of    CHAR(24),
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_created_at_id_idx ON webhook_deliveries (webhook_id, created_at DESC, id);
+CREATE INDEX webhook_deliveries_status_next_attempt_at_id_idx ON webhook_deliveries (status, next_attempt_at, id);
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewNotificationRepo(db *pgxpool.Pool, log logger.ILogger) *NotificationRepo {
+	return &NotificationRepo{db: db, log: log}
+}
+
+const notificationSettingsColumns = "user_id, channels, telegram_chat_id, webhook_url, updated_at"
+
+func scanNotificationSettings(row scanner) (models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	var userID string
+	err := row.Scan(&userID, &settings.Channels, &settings.TelegramChatID, &settings.WebhookURL, &settings.UpdatedAt)
+	settings.UserID = objectID(userID)
+	return settings, err
+}
+
+// GetNotificationSettings returns the settings of a user, or the defaults.
+func (nr *NotificationRepo) GetNotificationSettings(ctx context.Context, userID primitive.ObjectID) (models.NotificationSettings, error) {
+	settings, err := scanNotificationSettings(nr.db.QueryRow(ctx,
+		`SELECT `+notificationSettingsColumns+` FROM notification_settings WHERE user_id = $1`, userID.Hex()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DefaultNotificationSettings(userID), nil
+		}
+		nr.log.Error("Error retrieving notification settings", logger.Error(err))
+		return models.NotificationSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveNotificationSettings creates or replaces the settings of a user.
+func (nr *NotificationRepo) SaveNotificationSettings(ctx context.Context, req models.UpdateNotificationSettings) (models.NotificationSettings, error) {
+	channels := append([]string{}, req.Channels...)
+	settings, err := scanNotificationSettings(nr.db.QueryRow(ctx,
+		`INSERT INTO notification_settings (`+notificationSettingsColumns+`) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET channels = EXCLUDED.channels, telegram_chat_id = EXCLUDED.telegram_chat_id,
+			webhook_url = EXCLUDED.webhook_url, updated_at = EXCLUDED.updated_at
+		RETURNING `+notificationSettingsColumns,
+		req.UserID.Hex(), channels, req.TelegramChatID, req.WebhookURL, time.Now()))
+	if err != nil {
+		nr.log.Error("Error saving notification settings", logger.Error(err))
+		return models.NotificationSettings{}, err
+	}
+
+	return settings, nil
+}
+
+const notificationPreferencesColumns = "user_id, events, timezone, quiet_start, quiet_end, updated_at"
+
+func scanNotificationPreferences(row scanner) (models.NotificationPreferences, error) {
+	var preferences models.NotificationPreferences
+	var userID, quietStart, quietEnd string
+	err := row.Scan(&userID, &preferences.Events, &preferences.Timezone, &quietStart, &quietEnd, &preferences.UpdatedAt)
+	preferences.UserID = objectID(userID)
+	if preferences.Events == nil {
+		preferences.Events = map[string][]string{}
+	}
+	if quietStart != "" {
+		preferences.QuietHours = &models.QuietHours{Start: quietStart, End: quietEnd}
+	}
+	return preferences, err
+}
+
+// GetNotificationPreferences returns the preferences of a user, or the
+// defaults.
+func (nr *NotificationRepo) GetNotificationPreferences(ctx context.Context, userID primitive.ObjectID) (models.NotificationPreferences, error) {
+	preferences, err := scanNotificationPreferences(nr.db.QueryRow(ctx,
+		`SELECT `+notificationPreferencesColumns+` FROM notification_settings WHERE user_id = $1`, userID.Hex()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DefaultNotificationPreferences(userID), nil
+		}
+		nr.log.Error("Error retrieving notification preferences", logger.Error(err))
+		return models.NotificationPreferences{}, err
+	}
+
+	return preferences, nil
+}
+
+// SaveNotificationPreferences creates or replaces the preferences of a user.
+// A user without settings gets the default ones.
+func (nr *NotificationRepo) SaveNotificationPreferences(ctx context.Context, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error) {
+	events := req.Events
+	if events == nil {
+		events = map[string][]string{}
+	}
+	var quietStart, quietEnd string
+	if req.QuietHours != nil {
+		quietStart, quietEnd = req.QuietHours.Start, req.QuietHours.End
+	}
+
+	preferences, err := scanNotificationPreferences(nr.db.QueryRow(ctx,
+		`INSERT INTO notification_settings (channels, `+notificationPreferencesColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET events = EXCLUDED.events, timezone = EXCLUDED.timezone,
+			quiet_start = EXCLUDED.quiet_start, quiet_end = EXCLUDED.quiet_end, updated_at = EXCLUDED.updated_at
+		RETURNING `+notificationPreferencesColumns,
+		models.DefaultNotificationSettings(req.UserID).Channels, req.UserID.Hex(), events, req.Timezone, quietStart, quietEnd, time.Now()))
+	if err != nil {
+		nr.log.Error("Error saving notification preferences", logger.Error(err))
+		return models.NotificationPreferences{}, err
+	}
+
+	return preferences, nil
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PasswordResetRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewPasswordResetRepo(db *pgxpool.Pool, log logger.ILogger) *PasswordResetRepo {
+	return &PasswordResetRepo{db: db, log: log}
+}
+
+// CreatePasswordReset stores a hashed password reset token.
+func (pr *PasswordResetRepo) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error {
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	reset.CreatedAt = time.Now()
+
+	_, err := pr.db.Exec(ctx, `INSERT INTO password_resets (id, user_id, token_hash, used, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		reset.ID.Hex(), reset.UserID.Hex(), reset.TokenHash, reset.Used, reset.ExpiresAt, reset.CreatedAt)
+	if err != nil {
+		pr.log.Error("Error creating password reset", logger.Error(err))
+		return fmt.Errorf("error while creating password reset: %w", err)
+	}
+	return nil
+}
+
+// UsePasswordReset atomically marks an unused, unexpired reset token as used
+// and returns it, along with every other token of the same user. A token can
+// therefore only be redeemed once, and older reset links die with it.
+func (pr *PasswordResetRepo) UsePasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error) {
+	var reset models.PasswordReset
+	var id, userID string
+	err := pr.db.QueryRow(ctx, `WITH used_reset AS (
+			UPDATE password_resets SET used = TRUE
+			WHERE token_hash = $1 AND NOT used AND expires_at > now()
+			RETURNING id, user_id, token_hash, used, expires_at, created_at
+		), others AS (
+			UPDATE password_resets SET used = TRUE
+			WHERE user_id IN (SELECT user_id FROM used_reset) AND token_hash <> $1 AND NOT used
+		)
+		SELECT id, user_id, token_hash, used, expires_at, created_at FROM used_reset`, tokenHash).
+		Scan(&id, &userID, &reset.TokenHash, &reset.Used, &reset.ExpiresAt, &reset.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PasswordReset{}, fmt.Errorf("password reset not found: %w", err)
+		}
+		pr.log.Error("Error using password reset", logger.Error(err))
+		return models.PasswordReset{}, fmt.Errorf("error while using password reset: %w", err)
+	}
+	reset.ID = objectID(id)
+	reset.UserID = objectID(userID)
+
+	return reset, nil
+}
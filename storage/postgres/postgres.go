@@ -0,0 +1,165 @@
+// Package postgres implements the storage interfaces on PostgreSQL. The
+// schema lives in migrations/ and is applied with Migrate.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"todo/storage"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Connect opens a connection pool to PostgreSQL and checks the server is reachable.
+func Connect(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	db, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewStorage initializes the PostgreSQL repositories with the provided pool and logger.
+func NewStorage(db *pgxpool.Pool, log logger.ILogger) *storage.Storage {
+	return &storage.Storage{
+		UserRepo:          NewUserRepo(db, log),
+		LabelRepo:         NewLabelRepo(db, log),
+		TaskRepo:          NewTaskRepo(db, log),
+		TaskListRepo:      NewTaskListRepo(db, log),
+		TokenRepo:         NewTokenRepo(db, log),
+		RegistrationRepo:  NewRegistrationRepo(db, log),
+		PasswordResetRepo: NewPasswordResetRepo(db, log),
+		SmartListRepo:     NewSmartListRepo(db, log),
+		SearchRepo:        NewSearchRepo(db, log),
+		ReminderRepo:      NewReminderRepo(db, log),
+		NotificationRepo:  NewNotificationRepo(db, log),
+		DigestRepo:        NewDigestRepo(db, log),
+		WebhookRepo:       NewWebhookRepo(db, log),
+	}
+}
+
+// Interface for User, Label, Task, TaskList, Token, Registration, PasswordReset, SmartList, Search, Reminder, Notification, Digest and Webhook repositories.
+var (
+	_ storage.UserStorage          = &UserRepo{}
+	_ storage.LabelStorage         = &LabelRepo{}
+	_ storage.TaskStorage          = &TaskRepo{}
+	_ storage.TaskListStorage      = &TaskListRepo{}
+	_ storage.TokenStorage         = &TokenRepo{}
+	_ storage.RegistrationStorage  = &RegistrationRepo{}
+	_ storage.PasswordResetStorage = &PasswordResetRepo{}
+	_ storage.SmartListStorage     = &SmartListRepo{}
+	_ storage.SearchStorage        = &SearchRepo{}
+	_ storage.ReminderStorage      = &ReminderRepo{}
+	_ storage.NotificationStorage  = &NotificationRepo{}
+	_ storage.DigestStorage        = &DigestRepo{}
+	_ storage.WebhookStorage       = &WebhookRepo{}
+)
+
+// scanner is satisfied by pgx.Row and pgx.CollectableRow.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// objectID converts a stored id back to an ObjectID. Ids are only ever
+// written from ObjectIDs, so a malformed one yields the zero ID.
+func objectID(hex string) primitive.ObjectID {
+	id, _ := primitive.ObjectIDFromHex(hex)
+	return id
+}
+
+// objectIDs converts stored ids back to ObjectIDs. An empty list becomes nil,
+// like a task without labels read from MongoDB.
+func objectIDs(hexes []string) []primitive.ObjectID {
+	if len(hexes) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(hexes))
+	for _, hex := range hexes {
+		ids = append(ids, objectID(hex))
+	}
+	return ids
+}
+
+// hexIDs converts ObjectIDs to their stored form, dropping duplicates.
+func hexIDs(ids []primitive.ObjectID) []string {
+	seen := map[primitive.ObjectID]bool{}
+	hexes := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			hexes = append(hexes, id.Hex())
+		}
+	}
+	return hexes
+}
+
+// pageClause continues a WHERE clause with the cursor of the page and adds
+// the ordering by keys, then ID, with LIMIT and OFFSET. The placeholders are
+// numbered after args, which are returned with the page values appended.
+// LIMIT NULLIF($n, 0) makes a limit of 0 mean no limit, like in MongoDB.
+// prefix qualifies the columns, for queries that alias their table.
+func pageClause(prefix string, keys []models.SortKey, page models.Pagination, args []any) (string, []any) {
+	clause := ""
+	if page.After != nil {
+		clause, args = afterCondition(prefix, keys, *page.After, args)
+	}
+
+	order := []string{}
+	for _, key := range keys {
+		if key.Desc {
+			order = append(order, prefix+key.Field+" DESC")
+		} else {
+			order = append(order, prefix+key.Field)
+		}
+	}
+	order = append(order, prefix+"id")
+
+	args = append(args, int64(page.Limit), int64(page.Offset))
+	clause += fmt.Sprintf(" ORDER BY %s LIMIT NULLIF($%d, 0) OFFSET $%d", strings.Join(order, ", "), len(args)-1, len(args))
+	return clause, args
+}
+
+// afterCondition matches the rows sorting after the cursor. Ascending orders
+// compare rows, which an index on the sort columns can answer; mixed orders
+// spell out each key.
+func afterCondition(prefix string, keys []models.SortKey, after models.Cursor, args []any) (string, []any) {
+	columns, params, branches := []string{}, []string{}, []string{}
+	mixed := false
+	for _, key := range keys {
+		args = append(args, after.Value(key.Field))
+		column, param := prefix+key.Field, fmt.Sprintf("$%d", len(args))
+
+		op := ">"
+		if key.Desc {
+			op, mixed = "<", true
+		}
+		branches = append(branches, equalPrefix(columns, params)+column+" "+op+" "+param)
+		columns, params = append(columns, column), append(params, param)
+	}
+	args = append(args, after.ID.Hex())
+	column, param := prefix+"id", fmt.Sprintf("$%d", len(args))
+	branches = append(branches, equalPrefix(columns, params)+column+" > "+param)
+
+	if !mixed {
+		columns, params = append(columns, column), append(params, param)
+		return fmt.Sprintf(" AND (%s) > (%s)", strings.Join(columns, ", "), strings.Join(params, ", ")), args
+	}
+	return " AND ((" + strings.Join(branches, ") OR (") + "))", args
+}
+
+func equalPrefix(columns, params []string) string {
+	prefix := ""
+	for i := range columns {
+		prefix += columns[i] + " = " + params[i] + " AND "
+	}
+	return prefix
+}
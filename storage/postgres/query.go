@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"time"
+	"todo/pkg/taskquery"
+)
+
+// queryCondition translates a smart list query into a condition on "tasks t".
+// param adds a value to the query's arguments and returns its placeholder.
+// Tasks without a due date store the zero time.
+func queryCondition(expr taskquery.Expr, now time.Time, param func(value any) string) string {
+	switch e := expr.(type) {
+	case *taskquery.And:
+		return "(" + queryCondition(e.Left, now, param) + " AND " + queryCondition(e.Right, now, param) + ")"
+	case *taskquery.Or:
+		return "(" + queryCondition(e.Left, now, param) + " OR " + queryCondition(e.Right, now, param) + ")"
+	case *taskquery.Not:
+		return "NOT " + queryCondition(e.Expr, now, param)
+	case *taskquery.Completed:
+		return "t.completed = " + param(e.Value)
+	case *taskquery.Overdue:
+		return "(NOT t.completed AND t.due_date > " + param(time.Time{}) + " AND t.due_date < " + param(now) + ")"
+	case *taskquery.NoDueDate:
+		return "t.due_date = " + param(time.Time{})
+	case *taskquery.Title:
+		return "strpos(lower(t.title), lower(" + param(e.Text) + ")) > 0"
+	case *taskquery.Label:
+		return "EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = t.id AND tl.label_id = ANY(" + param(hexIDs(e.IDs)) + "))"
+	case *taskquery.List:
+		return "t.task_list_id = ANY(" + param(hexIDs(e.IDs)) + ")"
+	case *taskquery.Compare:
+		op, ok := compareOperators[e.Op]
+		if !ok {
+			break
+		}
+		condition := "t." + e.Field + " " + op + " " + param(e.Time.Resolve(now))
+		if e.Field == taskquery.FieldDueDate {
+			condition = "(" + condition + " AND t.due_date > " + param(time.Time{}) + ")"
+		}
+		return condition
+	}
+	// Unknown nodes match nothing rather than everything
+	return "FALSE"
+}
+
+// compareOperators also keeps anything but a known operator out of the SQL.
+var compareOperators = map[string]string{
+	taskquery.OpLess:         "<",
+	taskquery.OpLessEqual:    "<=",
+	taskquery.OpGreater:      ">",
+	taskquery.OpGreaterEqual: ">=",
+}
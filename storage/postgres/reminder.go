@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReminderRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewReminderRepo(db *pgxpool.Pool, log logger.ILogger) *ReminderRepo {
+	return &ReminderRepo{db: db, log: log}
+}
+
+const reminderColumns = "id, task_id, user_id, before_minutes, remind_at, status, attempts, last_error, sent_at, created_at"
+
+func scanReminder(row scanner) (models.Reminder, error) {
+	var reminder models.Reminder
+	var id, taskID, userID string
+	err := row.Scan(&id, &taskID, &userID, &reminder.Before, &reminder.RemindAt, &reminder.Status,
+		&reminder.Attempts, &reminder.LastError, &reminder.SentAt, &reminder.CreatedAt)
+	reminder.ID = objectID(id)
+	reminder.TaskID = objectID(taskID)
+	reminder.UserID = objectID(userID)
+	return reminder, err
+}
+
+// SetReminders replaces the reminders of a task. Kept offsets conflict with
+// the unique (task_id, before_minutes) key, so their state is left untouched.
+func (rr *ReminderRepo) SetReminders(ctx context.Context, userID, taskID primitive.ObjectID, before []int, dueDate time.Time) ([]models.Reminder, error) {
+	// A nil slice would be sent as NULL and delete nothing
+	if before == nil {
+		before = []int{}
+	}
+
+	now := time.Now()
+	err := pgx.BeginFunc(ctx, rr.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM reminders WHERE task_id = $1 AND NOT before_minutes = ANY($2)`, taskID.Hex(), before)
+		if err != nil {
+			return err
+		}
+		for _, minutes := range before {
+			_, err := tx.Exec(ctx, `INSERT INTO reminders (id, task_id, user_id, before_minutes, remind_at, status, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (task_id, before_minutes) DO NOTHING`,
+				primitive.NewObjectID().Hex(), taskID.Hex(), userID.Hex(), minutes, models.ReminderTime(dueDate, minutes), models.ReminderPending, now)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rr.log.Error("Error setting reminders", logger.Error(err))
+		return nil, err
+	}
+
+	return rr.GetReminders(ctx, taskID)
+}
+
+// GetReminders returns the reminders of a task, the earliest first.
+func (rr *ReminderRepo) GetReminders(ctx context.Context, taskID primitive.ObjectID) ([]models.Reminder, error) {
+	return rr.query(ctx, `SELECT `+reminderColumns+` FROM reminders WHERE task_id = $1 ORDER BY before_minutes DESC`, taskID.Hex())
+}
+
+// RescheduleReminders moves the reminders of a task to a new due date. Only
+// the reminders whose time changes are reset, so saving a task without
+// touching the due date does not send its reminders again.
+func (rr *ReminderRepo) RescheduleReminders(ctx context.Context, taskID primitive.ObjectID, dueDate time.Time) error {
+	_, err := rr.db.Exec(ctx, `UPDATE reminders
+		SET remind_at = $2::TIMESTAMPTZ - make_interval(mins => before_minutes),
+			status = $3, attempts = 0, last_error = '', sent_at = NULL
+		WHERE task_id = $1 AND remind_at IS DISTINCT FROM $2::TIMESTAMPTZ - make_interval(mins => before_minutes)`,
+		taskID.Hex(), models.ReminderTime(dueDate, 0), models.ReminderPending)
+	if err != nil {
+		rr.log.Error("Error rescheduling reminders", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DueReminders returns the pending reminders due at or before now.
+func (rr *ReminderRepo) DueReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	return rr.query(ctx, `SELECT `+reminderColumns+` FROM reminders
+		WHERE status = $1 AND remind_at <= $2 ORDER BY remind_at, id LIMIT $3`,
+		models.ReminderPending, now, limit)
+}
+
+// RecordDelivery stores the outcome of sending a reminder, unless the
+// reminder has been moved since it was read.
+func (rr *ReminderRepo) RecordDelivery(ctx context.Context, delivery models.ReminderDelivery) error {
+	res, err := rr.db.Exec(ctx, `UPDATE reminders SET status = $3, attempts = $4, last_error = $5, sent_at = $6,
+			remind_at = COALESCE($7, remind_at)
+		WHERE id = $1 AND remind_at = $2`,
+		delivery.ID.Hex(), delivery.RemindAt, delivery.Status, delivery.Attempts, delivery.LastError, delivery.SentAt, delivery.DeferredTo)
+	if err != nil {
+		rr.log.Error("Error recording reminder delivery", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrReminderNotFound
+	}
+	return nil
+}
+
+// DeleteReminders removes the reminders of a task.
+func (rr *ReminderRepo) DeleteReminders(ctx context.Context, taskID primitive.ObjectID) error {
+	if _, err := rr.db.Exec(ctx, `DELETE FROM reminders WHERE task_id = $1`, taskID.Hex()); err != nil {
+		rr.log.Error("Error deleting reminders", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (rr *ReminderRepo) query(ctx context.Context, sql string, args ...any) ([]models.Reminder, error) {
+	rows, err := rr.db.Query(ctx, sql, args...)
+	if err != nil {
+		rr.log.Error("Error retrieving reminders", logger.Error(err))
+		return nil, err
+	}
+	reminders, err := pgx.AppendRows([]models.Reminder{}, rows, func(row pgx.CollectableRow) (models.Reminder, error) {
+		return scanReminder(row)
+	})
+	if err != nil {
+		rr.log.Error("Error decoding reminders", logger.Error(err))
+		return nil, err
+	}
+	return reminders, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewSearchRepo(db *pgxpool.Pool, log logger.ILogger) *SearchRepo {
+	return &SearchRepo{db: db, log: log}
+}
+
+// The documents searched, as indexed by the 0005_search migration.
+const (
+	titleDescriptionVector = `(setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', description), 'B'))`
+	nameVector             = `(setweight(to_tsvector('simple', name), 'A'))`
+)
+
+// searchQueries select id, task list id, title, body and score of the
+// matches of each type. $1 is the user, $2 the text query and $3 the limit.
+var searchQueries = map[string]string{
+	models.SearchTypeTask: `SELECT id, task_list_id, title, description, ` + rank(titleDescriptionVector) + ` AS score
+		FROM tasks, to_tsquery('simple', $2) q
+		WHERE task_list_id IN (SELECT id FROM task_lists WHERE user_id = $1) AND ` + titleDescriptionVector + ` @@ q`,
+	models.SearchTypeTaskList: `SELECT id, '', title, description, ` + rank(titleDescriptionVector) + ` AS score
+		FROM task_lists, to_tsquery('simple', $2) q
+		WHERE user_id = $1 AND ` + titleDescriptionVector + ` @@ q`,
+	models.SearchTypeLabel: `SELECT id, '', name, '', ` + rank(nameVector) + ` AS score
+		FROM labels, to_tsquery('simple', $2) q
+		WHERE user_id = $1 AND ` + nameVector + ` @@ q`,
+}
+
+// rank scores a match, weighing titles (A) ten times as much as
+// descriptions (B).
+func rank(vector string) string {
+	return `ts_rank('{0, 0, 0.1, 1}', ` + vector + `, q)`
+}
+
+// Search matches documents containing any of the terms. The terms are plain
+// words, so joining them with | always makes a valid tsquery.
+func (sr *SearchRepo) Search(ctx context.Context, userID string, query models.SearchQuery) ([]models.SearchResult, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, models.ErrUserNotFound
+	}
+	tsquery := strings.Join(query.Terms, " | ")
+
+	results := []models.SearchResult{}
+	for _, typ := range query.Types {
+		sql, ok := searchQueries[typ]
+		if !ok {
+			continue
+		}
+
+		rows, err := sr.db.Query(ctx, sql+` ORDER BY score DESC, id LIMIT NULLIF($3, 0)`, userID, tsquery, query.Limit)
+		if err != nil {
+			sr.log.Error("Error searching", logger.Error(err))
+			return nil, err
+		}
+		results, err = pgx.AppendRows(results, rows, func(row pgx.CollectableRow) (models.SearchResult, error) {
+			result := models.SearchResult{Type: typ}
+			var id, taskListID string
+			var score float32
+			err := row.Scan(&id, &taskListID, &result.Title, &result.Body, &score)
+			result.ID = objectID(id)
+			result.Score = float64(score)
+			if taskListID != "" {
+				taskListObjectID := objectID(taskListID)
+				result.TaskListID = &taskListObjectID
+			}
+			return result, err
+		})
+		if err != nil {
+			sr.log.Error("Error decoding search results", logger.Error(err))
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,466 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewTaskRepo(db *pgxpool.Pool, log logger.ILogger) *TaskRepo {
+	return &TaskRepo{db: db, log: log}
+}
+
+// taskColumns selects a task from "tasks t" with its label IDs in the order
+// they were added and its checklist as a JSON array.
+const taskColumns = `t.id, t.task_list_id, t.parent_id, t.title, t.description, t.due_date,
+	t.recurrence_rule, t.recurrence_timezone, t.recurrence_start, t.completed, t.completed_at,
+	ARRAY(SELECT tl.label_id::TEXT FROM task_labels tl WHERE tl.task_id = t.id ORDER BY tl.position),
+	COALESCE((SELECT json_agg(json_build_object('id', ci.id, 'title', ci.title, 'done', ci.done) ORDER BY ci.position)
+		FROM checklist_items ci WHERE ci.task_id = t.id), '[]'),
+	t.created_at, t.updated_at`
+
+func scanTask(row scanner) (models.Task, error) {
+	var task models.Task
+	var id, taskListID string
+	var parentID, rule *string
+	var timezone string
+	var start *time.Time
+	var labelIDs []string
+	err := row.Scan(&id, &taskListID, &parentID, &task.Title, &task.Description, &task.DueDate,
+		&rule, &timezone, &start, &task.Completed, &task.CompletedAt, &labelIDs, &task.Checklist, &task.CreatedAt, &task.UpdatedAt)
+	task.ID = objectID(id)
+	task.TaskListID = objectID(taskListID)
+	if parentID != nil {
+		parent := objectID(*parentID)
+		task.ParentID = &parent
+	}
+	if rule != nil && start != nil {
+		task.Recurrence = &models.Recurrence{Rule: *rule, Timezone: timezone, Start: *start}
+	}
+	task.LabelIDs = objectIDs(labelIDs)
+	return task, err
+}
+
+// CreateTask creates a new task in the database.
+func (tr *TaskRepo) CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error) {
+	now := time.Now()
+	task := models.Task{
+		ID:          primitive.NewObjectID(),
+		TaskListID:  req.TaskListID,
+		ParentID:    req.ParentID,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+		Recurrence:  req.Recurrence,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	labelIDs := hexIDs(req.LabelIDs)
+	task.LabelIDs = objectIDs(labelIDs)
+
+	err := pgx.BeginFunc(ctx, tr.db, func(tx pgx.Tx) error {
+		rule, timezone, start := recurrenceValues(task.Recurrence)
+		_, err := tx.Exec(ctx, `INSERT INTO tasks (id, task_list_id, parent_id, title, description, due_date,
+				recurrence_rule, recurrence_timezone, recurrence_start, completed, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			task.ID.Hex(), task.TaskListID.Hex(), parentHex(task.ParentID), task.Title, task.Description, task.DueDate,
+			rule, timezone, start, task.Completed, task.CreatedAt, task.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return insertTaskLabels(ctx, tx, task.ID.Hex(), labelIDs)
+	})
+	if err != nil {
+		tr.log.Error("Error creating task", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// parentHex returns the parent_id column value of a task.
+func parentHex(parentID *primitive.ObjectID) *string {
+	if parentID == nil {
+		return nil
+	}
+	hex := parentID.Hex()
+	return &hex
+}
+
+// insertTaskLabels links labels to a task one by one so their position
+// follows the order they were given in. Existing links are kept as they are.
+func insertTaskLabels(ctx context.Context, tx pgx.Tx, taskID string, labelIDs []string) error {
+	for _, labelID := range labelIDs {
+		_, err := tx.Exec(ctx, `INSERT INTO task_labels (task_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, taskID, labelID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTask retrieves a task by ID.
+func (tr *TaskRepo) GetTask(ctx context.Context, taskID string) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	task, err := scanTask(tr.db.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks t WHERE t.id = $1`, taskID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Task{}, models.ErrTaskNotFound
+		}
+		tr.log.Error("Error retrieving task", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// UpdateTask updates an existing task.
+func (tr *TaskRepo) UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error) {
+	// Completing a completed task keeps the time it was completed at
+	res, err := tr.db.Exec(ctx, `UPDATE tasks SET title = $2, description = $3, due_date = $4, completed = $5, updated_at = $6,
+			completed_at = CASE WHEN $5 THEN COALESCE(completed_at, $6) END
+		WHERE id = $1`,
+		req.ID.Hex(), req.Title, req.Description, req.DueDate, req.Completed, time.Now())
+	if err != nil {
+		tr.log.Error("Error updating task", logger.Error(err))
+		return models.Task{}, err
+	}
+	if res.RowsAffected() == 0 {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	return tr.GetTask(ctx, req.ID.Hex())
+}
+
+// DeleteTask removes a task from the database. The parent_id foreign key
+// removes its subtasks.
+func (tr *TaskRepo) DeleteTask(ctx context.Context, taskID string) error {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.ErrTaskNotFound
+	}
+
+	res, err := tr.db.Exec(ctx, `DELETE FROM tasks WHERE id = $1`, taskID)
+	if err != nil {
+		tr.log.Error("Error deleting task", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrTaskNotFound
+	}
+	return nil
+}
+
+// AddTaskLabels attaches labels to a task. Labels already on the task are
+// left as they are.
+func (tr *TaskRepo) AddTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(ctx, taskID, func(tx pgx.Tx) error {
+		return insertTaskLabels(ctx, tx, taskID, hexIDs(labelIDs))
+	})
+}
+
+// RemoveTaskLabels detaches labels from a task.
+func (tr *TaskRepo) RemoveTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(ctx, taskID, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM task_labels WHERE task_id = $1 AND label_id = ANY($2)`, taskID, hexIDs(labelIDs))
+		return err
+	})
+}
+
+func (tr *TaskRepo) updateTaskLabels(ctx context.Context, taskID string, change func(tx pgx.Tx) error) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	err := pgx.BeginFunc(ctx, tr.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, `UPDATE tasks SET updated_at = $2 WHERE id = $1`, taskID, time.Now())
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return models.ErrTaskNotFound
+		}
+		return change(tx)
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrTaskNotFound) {
+			tr.log.Error("Error updating task labels", logger.Error(err))
+		}
+		return models.Task{}, err
+	}
+
+	return tr.GetTask(ctx, taskID)
+}
+
+// SetTaskRecurrence makes a task repeat, or stop repeating with nil.
+func (tr *TaskRepo) SetTaskRecurrence(ctx context.Context, taskID string, recurrence *models.Recurrence) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	rule, timezone, start := recurrenceValues(recurrence)
+	res, err := tr.db.Exec(ctx, `UPDATE tasks SET recurrence_rule = $2, recurrence_timezone = $3, recurrence_start = $4, updated_at = $5 WHERE id = $1`,
+		taskID, rule, timezone, start, time.Now())
+	if err != nil {
+		tr.log.Error("Error updating task recurrence", logger.Error(err))
+		return models.Task{}, err
+	}
+	if res.RowsAffected() == 0 {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	return tr.GetTask(ctx, taskID)
+}
+
+// recurrenceValues returns the recurrence columns of a task. Tasks that do
+// not repeat have no rule and no start.
+func recurrenceValues(recurrence *models.Recurrence) (rule *string, timezone string, start *time.Time) {
+	if recurrence == nil {
+		return nil, "", nil
+	}
+	return &recurrence.Rule, recurrence.Timezone, &recurrence.Start
+}
+
+// AddChecklistItem appends an open item to the checklist of a task.
+func (tr *TaskRepo) AddChecklistItem(ctx context.Context, taskID string, req models.CreateChecklistItem) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO checklist_items (id, task_id, title, position)
+			SELECT $1, $2, $3, COALESCE(max(position), 0) + 1 FROM checklist_items WHERE task_id = $2`,
+			primitive.NewObjectID().Hex(), taskID, req.Title)
+		return err
+	})
+}
+
+// UpdateChecklistItem changes the title or state of a checklist item.
+func (tr *TaskRepo) UpdateChecklistItem(ctx context.Context, taskID string, req models.UpdateChecklistItem) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, `UPDATE checklist_items SET title = COALESCE($3::TEXT, title), done = COALESCE($4::BOOLEAN, done)
+			WHERE id = $1 AND task_id = $2`, req.ID.Hex(), taskID, req.Title, req.Done)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return models.ErrChecklistItemNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteChecklistItem removes an item from the checklist of a task.
+func (tr *TaskRepo) DeleteChecklistItem(ctx context.Context, taskID string, itemID primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, `DELETE FROM checklist_items WHERE id = $1 AND task_id = $2`, itemID.Hex(), taskID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return models.ErrChecklistItemNotFound
+		}
+		return nil
+	})
+}
+
+// ReorderChecklist puts the items of a checklist in the order of itemIDs.
+func (tr *TaskRepo) ReorderChecklist(ctx context.Context, taskID string, itemIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT id FROM checklist_items WHERE task_id = $1 FOR UPDATE`, taskID)
+		if err != nil {
+			return err
+		}
+		checklist, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ChecklistItem, error) {
+			var id string
+			err := row.Scan(&id)
+			return models.ChecklistItem{ID: objectID(id)}, err
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := models.ReorderChecklistItems(checklist, itemIDs); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE checklist_items ci SET position = o.position
+			FROM unnest($2::TEXT[]) WITH ORDINALITY AS o (id, position)
+			WHERE ci.task_id = $1 AND ci.id = o.id`, taskID, hexIDs(itemIDs))
+		return err
+	})
+}
+
+// updateChecklist changes the checklist of a task in a transaction that also
+// bumps its updated_at.
+func (tr *TaskRepo) updateChecklist(ctx context.Context, taskID string, change func(tx pgx.Tx) error) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	err := pgx.BeginFunc(ctx, tr.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, `UPDATE tasks SET updated_at = $2 WHERE id = $1`, taskID, time.Now())
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return models.ErrTaskNotFound
+		}
+		return change(tx)
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrTaskNotFound) && !errors.Is(err, models.ErrChecklistItemNotFound) && !errors.Is(err, models.ErrInvalidInput) {
+			tr.log.Error("Error updating task checklist", logger.Error(err))
+		}
+		return models.Task{}, err
+	}
+
+	return tr.GetTask(ctx, taskID)
+}
+
+// CountSubtasks counts the subtasks of each of the parents.
+func (tr *TaskRepo) CountSubtasks(ctx context.Context, parentIDs []primitive.ObjectID) (map[primitive.ObjectID]models.SubtaskCount, error) {
+	rows, err := tr.db.Query(ctx, `SELECT parent_id, count(*), count(*) FILTER (WHERE completed)
+		FROM tasks WHERE parent_id = ANY($1) GROUP BY parent_id`, hexIDs(parentIDs))
+	if err != nil {
+		tr.log.Error("Error counting subtasks", logger.Error(err))
+		return nil, err
+	}
+
+	counts := map[primitive.ObjectID]models.SubtaskCount{}
+	var parentID string
+	var count models.SubtaskCount
+	_, err = pgx.ForEachRow(rows, []any{&parentID, &count.Total, &count.Completed}, func() error {
+		counts[objectID(parentID)] = count
+		return nil
+	})
+	if err != nil {
+		tr.log.Error("Error decoding subtask counts", logger.Error(err))
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// CompleteSubtasks completes the open subtasks of a task.
+func (tr *TaskRepo) CompleteSubtasks(ctx context.Context, parentID primitive.ObjectID) error {
+	_, err := tr.db.Exec(ctx, `UPDATE tasks SET completed = TRUE, completed_at = $2, updated_at = $2 WHERE parent_id = $1 AND NOT completed`, parentID.Hex(), time.Now())
+	if err != nil {
+		tr.log.Error("Error completing subtasks", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetAllTasks retrieves the tasks matching the filter with pagination.
+func (tr *TaskRepo) GetAllTasks(ctx context.Context, taskFilter models.TaskFilter, page models.Pagination) ([]models.Task, int64, error) {
+	filter, args := taskFilterWhere(taskFilter, time.Now())
+	clause, pageArgs := pageClause("t.", taskFilter.SortKeys(), page, args)
+
+	rows, err := tr.db.Query(ctx, `SELECT `+taskColumns+` FROM tasks t WHERE `+filter+clause, pageArgs...)
+	if err != nil {
+		tr.log.Error("Error retrieving tasks", logger.Error(err))
+		return nil, 0, err
+	}
+	tasks, err := pgx.AppendRows([]models.Task{}, rows, func(row pgx.CollectableRow) (models.Task, error) {
+		return scanTask(row)
+	})
+	if err != nil {
+		tr.log.Error("Error decoding tasks", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := tr.db.QueryRow(ctx, `SELECT count(*) FROM tasks t WHERE `+filter, args...).Scan(&count); err != nil {
+		tr.log.Error("Error counting tasks", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return tasks, count, nil
+}
+
+// taskFilterWhere translates a task filter into a condition on "tasks t".
+// Tasks without a due date store the zero time.
+func taskFilterWhere(filter models.TaskFilter, now time.Time) (string, []any) {
+	where := []string{}
+	args := []any{}
+	add := func(condition string, values ...any) {
+		params := make([]any, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			params[i] = len(args)
+		}
+		where = append(where, fmt.Sprintf(condition, params...))
+	}
+
+	add("t.task_list_id = ANY($%d)", hexIDs(filter.TaskListIDs))
+
+	if filter.ParentID != nil {
+		add("t.parent_id = $%d", filter.ParentID.Hex())
+	}
+
+	if filter.Search != "" {
+		add("strpos(lower(t.title), lower($%d)) > 0", filter.Search)
+	}
+
+	if len(filter.Labels.LabelIDs) > 0 {
+		labelIDs := hexIDs(filter.Labels.LabelIDs)
+		if filter.Labels.Match == models.LabelMatchAll {
+			add("(SELECT count(*) FROM task_labels tl WHERE tl.task_id = t.id AND tl.label_id = ANY($%d)) = $%d", labelIDs, len(labelIDs))
+		} else {
+			add("EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = t.id AND tl.label_id = ANY($%d))", labelIDs)
+		}
+	}
+
+	if filter.Completed != nil {
+		add("t.completed = $%d", *filter.Completed)
+	}
+	if filter.Overdue {
+		add("NOT t.completed AND t.due_date > $%d AND t.due_date < $%d", time.Time{}, now)
+	}
+	if filter.NoDueDate {
+		add("t.due_date = $%d", time.Time{})
+	}
+	if filter.DueBefore != nil {
+		add("t.due_date > $%d AND t.due_date < $%d", time.Time{}, *filter.DueBefore)
+	}
+	if filter.DueAfter != nil {
+		add("t.due_date > $%d", *filter.DueAfter)
+	}
+	if filter.CreatedBefore != nil {
+		add("t.created_at < $%d", *filter.CreatedBefore)
+	}
+	if filter.CreatedAfter != nil {
+		add("t.created_at > $%d", *filter.CreatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		add("t.updated_at < $%d", *filter.UpdatedBefore)
+	}
+	if filter.UpdatedAfter != nil {
+		add("t.updated_at > $%d", *filter.UpdatedAfter)
+	}
+	if filter.CompletedBefore != nil {
+		add("t.completed_at < $%d", *filter.CompletedBefore)
+	}
+	if filter.CompletedAfter != nil {
+		add("t.completed_at > $%d", *filter.CompletedAfter)
+	}
+	if filter.Query != nil {
+		where = append(where, queryCondition(filter.Query, now, func(value any) string {
+			args = append(args, value)
+			return fmt.Sprintf("$%d", len(args))
+		}))
+	}
+
+	return strings.Join(where, " AND "), args
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskListRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewTaskListRepo(db *pgxpool.Pool, log logger.ILogger) *TaskListRepo {
+	return &TaskListRepo{db: db, log: log}
+}
+
+const taskListColumns = "id, user_id, title, description, created_at, updated_at"
+
+func scanTaskList(row scanner) (models.TaskList, error) {
+	var taskList models.TaskList
+	var id, userID string
+	err := row.Scan(&id, &userID, &taskList.Title, &taskList.Description, &taskList.CreatedAt, &taskList.UpdatedAt)
+	taskList.ID = objectID(id)
+	taskList.UserID = objectID(userID)
+	return taskList, err
+}
+
+// CreateTaskList creates a new task list in the database.
+func (tlr *TaskListRepo) CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error) {
+	now := time.Now()
+	taskList := models.TaskList{
+		ID:          primitive.NewObjectID(),
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	_, err := tlr.db.Exec(ctx, `INSERT INTO task_lists (`+taskListColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		taskList.ID.Hex(), taskList.UserID.Hex(), taskList.Title, taskList.Description, taskList.CreatedAt, taskList.UpdatedAt)
+	if err != nil {
+		tlr.log.Error("Error creating task list", logger.Error(err))
+		return models.TaskList{}, err
+	}
+
+	return taskList, nil
+}
+
+// GetTaskList retrieves a task list by ID.
+func (tlr *TaskListRepo) GetTaskList(ctx context.Context, taskListID string) (models.TaskList, error) {
+	if _, err := primitive.ObjectIDFromHex(taskListID); err != nil {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+
+	taskList, err := scanTaskList(tlr.db.QueryRow(ctx, `SELECT `+taskListColumns+` FROM task_lists WHERE id = $1`, taskListID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TaskList{}, models.ErrTaskListNotFound
+		}
+		tlr.log.Error("Error retrieving task list", logger.Error(err))
+		return models.TaskList{}, err
+	}
+
+	return taskList, nil
+}
+
+// UpdateTaskList updates an existing task list.
+func (tlr *TaskListRepo) UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error) {
+	taskList, err := scanTaskList(tlr.db.QueryRow(ctx,
+		`UPDATE task_lists SET title = $2, description = $3, updated_at = $4 WHERE id = $1 RETURNING `+taskListColumns,
+		req.ID.Hex(), req.Title, req.Description, time.Now()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TaskList{}, models.ErrTaskListNotFound
+		}
+		tlr.log.Error("Error updating task list", logger.Error(err))
+		return models.TaskList{}, err
+	}
+
+	return taskList, nil
+}
+
+// DeleteTaskList removes a task list in a single transaction; the foreign key
+// cascades to its tasks. With dryRun nothing is deleted and the summary holds
+// what would have been removed.
+func (tlr *TaskListRepo) DeleteTaskList(ctx context.Context, taskListID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	if _, err := primitive.ObjectIDFromHex(taskListID); err != nil {
+		return summary, models.ErrTaskListNotFound
+	}
+
+	err := pgx.BeginFunc(ctx, tlr.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `SELECT count(*) FROM (SELECT 1 FROM task_lists WHERE id = $1 FOR UPDATE) tl`, taskListID).Scan(&summary.TaskLists)
+		if err != nil {
+			return err
+		}
+		if summary.TaskLists == 0 {
+			return models.ErrTaskListNotFound
+		}
+
+		err = tx.QueryRow(ctx, `SELECT
+			(SELECT count(*) FROM tasks WHERE task_list_id = $1),
+			(SELECT count(*) FROM reminders WHERE task_id IN (SELECT id FROM tasks WHERE task_list_id = $1))`, taskListID).
+			Scan(&summary.Tasks, &summary.Reminders)
+		if err != nil || dryRun {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM task_lists WHERE id = $1`, taskListID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrTaskListNotFound) {
+			tlr.log.Error("Error deleting task list", logger.Error(err))
+		}
+		return models.DeleteSummary{DryRun: dryRun}, err
+	}
+	return summary, nil
+}
+
+// GetAllTaskLists retrieves all task lists for a user with pagination.
+func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, page models.Pagination) ([]models.TaskList, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	clause, args := pageClause("", models.DefaultSort, page, []any{userID})
+
+	rows, err := tlr.db.Query(ctx, `SELECT `+taskListColumns+` FROM task_lists WHERE user_id = $1`+clause, args...)
+	if err != nil {
+		tlr.log.Error("Error retrieving task lists", logger.Error(err))
+		return nil, 0, err
+	}
+	taskLists, err := pgx.AppendRows([]models.TaskList{}, rows, func(row pgx.CollectableRow) (models.TaskList, error) {
+		return scanTaskList(row)
+	})
+	if err != nil {
+		tlr.log.Error("Error decoding task lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := tlr.db.QueryRow(ctx, `SELECT count(*) FROM task_lists WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		tlr.log.Error("Error counting task lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return taskLists, count, nil
+}
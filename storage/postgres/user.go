@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewUserRepo(db *pgxpool.Pool, log logger.ILogger) *UserRepo {
+	return &UserRepo{db: db, log: log}
+}
+
+const userColumns = "id, username, email, password, role, disabled, token_version, created_at, updated_at"
+
+func scanUser(row scanner) (models.User, error) {
+	var user models.User
+	var id string
+	err := row.Scan(&id, &user.Username, &user.Email, &user.Password, &user.Role, &user.Disabled, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt)
+	user.ID = objectID(id)
+	return user, err
+}
+
+// CreateUser creates a new user in the database. req.Password must already be hashed.
+func (ur *UserRepo) CreateUser(ctx context.Context, req models.CreateUser) (models.User, error) {
+	now := time.Now()
+	user := models.User{
+		ID:        primitive.NewObjectID(),
+		Username:  req.Username,
+		Email:     req.Email,
+		Password:  req.Password,
+		Role:      req.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := ur.db.Exec(ctx, `INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		user.ID.Hex(), user.Username, user.Email, user.Password, user.Role, user.Disabled, user.TokenVersion, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		ur.log.Error("Error creating user", logger.Error(err))
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// GetUser retrieves a user by ID.
+func (ur *UserRepo) GetUser(ctx context.Context, userID string) (models.User, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return ur.getUser(ctx, "id", userID)
+}
+
+// GetUserByEmail retrieves a user by email.
+func (ur *UserRepo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return ur.getUser(ctx, "email", email)
+}
+
+func (ur *UserRepo) getUser(ctx context.Context, column, value string) (models.User, error) {
+	user, err := scanUser(ur.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE `+column+` = $1`, value))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, models.ErrUserNotFound
+		}
+		ur.log.Error("Error retrieving user", logger.Error(err))
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// UpdateUser updates an existing user.
+func (ur *UserRepo) UpdateUser(ctx context.Context, req models.UpdateUser) (models.User, error) {
+	user, err := scanUser(ur.db.QueryRow(ctx,
+		`UPDATE users SET username = $2, email = $3, updated_at = $4 WHERE id = $1 RETURNING `+userColumns,
+		req.ID.Hex(), req.Username, req.Email, time.Now()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, models.ErrUserNotFound
+		}
+		ur.log.Error("Error updating user", logger.Error(err))
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// UpdatePassword replaces the password hash of a user.
+func (ur *UserRepo) UpdatePassword(ctx context.Context, userID string, passwordHash string) error {
+	return ur.set(ctx, userID, "password", passwordHash)
+}
+
+// UpdateUserRole changes the role of a user.
+func (ur *UserRepo) UpdateUserRole(ctx context.Context, userID string, role string) error {
+	return ur.set(ctx, userID, "role", role)
+}
+
+// SetUserDisabled disables or re-enables a user.
+func (ur *UserRepo) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	return ur.set(ctx, userID, "disabled", disabled)
+}
+
+// BumpTokenVersion invalidates the access tokens issued to a user so far.
+func (ur *UserRepo) BumpTokenVersion(ctx context.Context, userID string) error {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return models.ErrUserNotFound
+	}
+
+	res, err := ur.db.Exec(ctx, `UPDATE users SET token_version = token_version + 1, updated_at = $2 WHERE id = $1`, userID, time.Now())
+	if err != nil {
+		ur.log.Error("Error bumping token version", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// set updates a single column of a user and bumps updated_at.
+func (ur *UserRepo) set(ctx context.Context, userID string, column string, value any) error {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return models.ErrUserNotFound
+	}
+
+	res, err := ur.db.Exec(ctx, `UPDATE users SET `+column+` = $2, updated_at = $3 WHERE id = $1`, userID, value, time.Now())
+	if err != nil {
+		ur.log.Error("Error updating user", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser removes a user in a single transaction. The foreign keys cascade
+// to their task lists, tasks, labels, refresh tokens and password resets.
+// With dryRun nothing is deleted and the summary holds what would have been
+// removed.
+func (ur *UserRepo) DeleteUser(ctx context.Context, userID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return summary, models.ErrUserNotFound
+	}
+
+	err := pgx.BeginFunc(ctx, ur.db, func(tx pgx.Tx) error {
+		// Lock the user so nothing is added to them while counting
+		err := tx.QueryRow(ctx, `SELECT count(*) FROM (SELECT 1 FROM users WHERE id = $1 FOR UPDATE) u`, userID).Scan(&summary.Users)
+		if err != nil {
+			return err
+		}
+		if summary.Users == 0 {
+			return models.ErrUserNotFound
+		}
+
+		err = tx.QueryRow(ctx, `SELECT
+			(SELECT count(*) FROM task_lists WHERE user_id = $1),
+			(SELECT count(*) FROM tasks WHERE task_list_id IN (SELECT id FROM task_lists WHERE user_id = $1)),
+			(SELECT count(*) FROM labels WHERE user_id = $1),
+			(SELECT count(*) FROM refresh_tokens WHERE user_id = $1),
+			(SELECT count(*) FROM password_resets WHERE user_id = $1),
+			(SELECT count(*) FROM smart_lists WHERE user_id = $1),
+			(SELECT count(*) FROM reminders WHERE user_id = $1)`, userID).
+			Scan(&summary.TaskLists, &summary.Tasks, &summary.Labels, &summary.RefreshTokens, &summary.PasswordResets, &summary.SmartLists, &summary.Reminders)
+		if err != nil || dryRun {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrUserNotFound) {
+			ur.log.Error("Error deleting user", logger.Error(err))
+		}
+		return models.DeleteSummary{DryRun: dryRun}, err
+	}
+	return summary, nil
+}
+
+// GetAllUsers retrieves all users with pagination.
+func (ur *UserRepo) GetAllUsers(ctx context.Context, page models.Pagination) ([]models.User, int64, error) {
+	clause, args := pageClause("", models.DefaultSort, page, nil)
+
+	rows, err := ur.db.Query(ctx, `SELECT `+userColumns+` FROM users WHERE true`+clause, args...)
+	if err != nil {
+		ur.log.Error("Error retrieving users", logger.Error(err))
+		return nil, 0, err
+	}
+	users, err := pgx.AppendRows([]models.User{}, rows, func(row pgx.CollectableRow) (models.User, error) {
+		return scanUser(row)
+	})
+	if err != nil {
+		ur.log.Error("Error decoding users", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := ur.db.QueryRow(ctx, `SELECT count(*) FROM users`).Scan(&count); err != nil {
+		ur.log.Error("Error counting users", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return users, count, nil
+}
+
+// CountUsersWithRole counts the users that have role.
+func (ur *UserRepo) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+	if err := ur.db.QueryRow(ctx, `SELECT count(*) FROM users WHERE role = $1`, role).Scan(&count); err != nil {
+		ur.log.Error("Error counting users", logger.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
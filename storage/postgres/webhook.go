@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepo struct {
+	db  *pgxpool.Pool
+	log logger.ILogger
+}
+
+func NewWebhookRepo(db *pgxpool.Pool, log logger.ILogger) *WebhookRepo {
+	return &WebhookRepo{db: db, log: log}
+}
+
+const webhookColumns = "id, user_id, url, events, description, secret, active, created_at, updated_at"
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var id, userID string
+	err := row.Scan(&id, &userID, &webhook.URL, &webhook.Events, &webhook.Description, &webhook.Secret,
+		&webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	webhook.ID = objectID(id)
+	webhook.UserID = objectID(userID)
+	return webhook, err
+}
+
+const deliveryColumns = "id, webhook_id, user_id, event_id, event, payload, status, attempts, next_attempt_at, " +
+	"last_error, response_status, response_body, redelivery_of, delivered_at, created_at"
+
+func scanDelivery(row scanner) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var id, webhookID, userID, eventID string
+	var redeliveryOf *string
+	var payload []byte
+	err := row.Scan(&id, &webhookID, &userID, &eventID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.ResponseStatus,
+		&delivery.ResponseBody, &redeliveryOf, &delivery.DeliveredAt, &delivery.CreatedAt)
+	delivery.ID = objectID(id)
+	delivery.WebhookID = objectID(webhookID)
+	delivery.UserID = objectID(userID)
+	delivery.EventID = objectID(eventID)
+	delivery.Payload = payload
+	if redeliveryOf != nil {
+		id := objectID(*redeliveryOf)
+		delivery.RedeliveryOf = &id
+	}
+	return delivery, err
+}
+
+// CreateWebhook stores a new, active webhook.
+func (wr *WebhookRepo) CreateWebhook(ctx context.Context, req models.CreateWebhook) (models.Webhook, error) {
+	webhook, err := scanWebhook(wr.db.QueryRow(ctx,
+		`INSERT INTO webhooks (`+webhookColumns+`) VALUES ($1, $2, $3, $4, $5, $6, TRUE, $7, $7) RETURNING `+webhookColumns,
+		primitive.NewObjectID().Hex(), req.UserID.Hex(), req.URL, req.Events, req.Description, req.Secret, time.Now()))
+	if err != nil {
+		wr.log.Error("Error creating webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (wr *WebhookRepo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+
+	webhook, err := scanWebhook(wr.db.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Webhook{}, models.ErrWebhookNotFound
+		}
+		wr.log.Error("Error retrieving webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// GetAllWebhooks retrieves the webhooks of a user with pagination support.
+func (wr *WebhookRepo) GetAllWebhooks(ctx context.Context, userID string, page models.Pagination) ([]models.Webhook, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	clause, args := pageClause("", models.DefaultSort, page, []any{userID})
+
+	webhooks, err := wr.query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1`+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := wr.db.QueryRow(ctx, `SELECT count(*) FROM webhooks WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		wr.log.Error("Error counting webhooks", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return webhooks, count, nil
+}
+
+// UpdateWebhook replaces the endpoint, events and description of a webhook,
+// and pauses or resumes it.
+func (wr *WebhookRepo) UpdateWebhook(ctx context.Context, req models.UpdateWebhook) (models.Webhook, error) {
+	webhook, err := scanWebhook(wr.db.QueryRow(ctx,
+		`UPDATE webhooks SET url = $2, events = $3, description = $4, active = COALESCE($5::BOOLEAN, active), updated_at = $6
+		WHERE id = $1 RETURNING `+webhookColumns,
+		req.ID.Hex(), req.URL, req.Events, req.Description, req.Active, time.Now()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Webhook{}, models.ErrWebhookNotFound
+		}
+		wr.log.Error("Error updating webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook. The webhook_deliveries foreign key removes
+// its deliveries.
+func (wr *WebhookRepo) DeleteWebhook(ctx context.Context, id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return models.ErrWebhookNotFound
+	}
+
+	res, err := wr.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		wr.log.Error("Error deleting webhook", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SubscribedWebhooks returns the active webhooks of a user subscribed to
+// event, the oldest first.
+func (wr *WebhookRepo) SubscribedWebhooks(ctx context.Context, userID primitive.ObjectID, event string) ([]models.Webhook, error) {
+	return wr.query(ctx, `SELECT `+webhookColumns+` FROM webhooks
+		WHERE user_id = $1 AND active AND $2 = ANY(events) ORDER BY created_at, id`, userID.Hex(), event)
+}
+
+// EnqueueDelivery queues an event for a webhook.
+func (wr *WebhookRepo) EnqueueDelivery(ctx context.Context, req models.EnqueueDelivery) (models.WebhookDelivery, error) {
+	var redeliveryOf *string
+	if req.RedeliveryOf != nil {
+		id := req.RedeliveryOf.Hex()
+		redeliveryOf = &id
+	}
+
+	delivery, err := scanDelivery(wr.db.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (id, webhook_id, user_id, event_id, event, payload, status, next_attempt_at, redelivery_of, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING `+deliveryColumns,
+		primitive.NewObjectID().Hex(), req.WebhookID.Hex(), req.UserID.Hex(), req.EventID.Hex(), req.Event,
+		string(req.Payload), models.DeliveryPending, req.NextAttemptAt, redeliveryOf, time.Now()))
+	if err != nil {
+		wr.log.Error("Error enqueuing webhook delivery", logger.Error(err))
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// GetDelivery retrieves a delivery by its ID.
+func (wr *WebhookRepo) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+
+	delivery, err := scanDelivery(wr.db.QueryRow(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+		}
+		wr.log.Error("Error retrieving webhook delivery", logger.Error(err))
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries returns the delivery log of a webhook, the latest first.
+func (wr *WebhookRepo) GetDeliveries(ctx context.Context, webhookID string, page models.Pagination) ([]models.WebhookDelivery, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(webhookID); err != nil {
+		return nil, 0, models.ErrWebhookNotFound
+	}
+	clause, args := pageClause("", models.DeliverySort, page, []any{webhookID})
+
+	deliveries, err := wr.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id = $1`+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := wr.db.QueryRow(ctx, `SELECT count(*) FROM webhook_deliveries WHERE webhook_id = $1`, webhookID).Scan(&count); err != nil {
+		wr.log.Error("Error counting webhook deliveries", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return deliveries, count, nil
+}
+
+// DueDeliveries returns the pending deliveries due at or before now.
+func (wr *WebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return wr.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, id LIMIT $3`,
+		models.DeliveryPending, now, limit)
+}
+
+// RecordAttempt stores the outcome of posting a delivery, unless it is no
+// longer pending at the time it was read.
+func (wr *WebhookRepo) RecordAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	res, err := wr.db.Exec(ctx, `UPDATE webhook_deliveries SET status = $4, attempts = $5, last_error = $6,
+			response_status = $7, response_body = $8, next_attempt_at = $9, delivered_at = $10
+		WHERE id = $1 AND status = $2 AND next_attempt_at = $3`,
+		attempt.ID.Hex(), models.DeliveryPending, attempt.ScheduledAt, attempt.Status, attempt.Attempts, attempt.LastError,
+		attempt.ResponseStatus, attempt.ResponseBody, attempt.NextAttemptAt, attempt.DeliveredAt)
+	if err != nil {
+		wr.log.Error("Error recording webhook attempt", logger.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return models.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (wr *WebhookRepo) query(ctx context.Context, sql string, args ...any) ([]models.Webhook, error) {
+	rows, err := wr.db.Query(ctx, sql, args...)
+	if err != nil {
+		wr.log.Error("Error retrieving webhooks", logger.Error(err))
+		return nil, err
+	}
+	webhooks, err := pgx.AppendRows([]models.Webhook{}, rows, func(row pgx.CollectableRow) (models.Webhook, error) {
+		return scanWebhook(row)
+	})
+	if err != nil {
+		wr.log.Error("Error decoding webhooks", logger.Error(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (wr *WebhookRepo) queryDeliveries(ctx context.Context, sql string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := wr.db.Query(ctx, sql, args...)
+	if err != nil {
+		wr.log.Error("Error retrieving webhook deliveries", logger.Error(err))
+		return nil, err
+	}
+	deliveries, err := pgx.AppendRows([]models.WebhookDelivery{}, rows, func(row pgx.CollectableRow) (models.WebhookDelivery, error) {
+		return scanDelivery(row)
+	})
+	if err != nil {
+		wr.log.Error("Error decoding webhook deliveries", logger.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
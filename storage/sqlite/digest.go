@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DigestRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewDigestRepo(db *sql.DB, log logger.ILogger) *DigestRepo {
+	return &DigestRepo{db: db, log: log}
+}
+
+const digestSettingsColumns = "user_id, frequency, send_at, weekday, timezone, next_at, last_sent_at, updated_at"
+
+func scanDigestSettings(row scanner) (models.DigestSettings, error) {
+	var settings models.DigestSettings
+	var userID string
+	var nextAt, lastSentAt sql.NullTime
+	err := row.Scan(&userID, &settings.Frequency, &settings.SendAt, &settings.Weekday, &settings.Timezone,
+		&nextAt, &lastSentAt, &settings.UpdatedAt)
+	settings.UserID = objectID(userID)
+	if nextAt.Valid {
+		settings.NextAt = &nextAt.Time
+	}
+	if lastSentAt.Valid {
+		settings.LastSentAt = &lastSentAt.Time
+	}
+	return settings, err
+}
+
+// GetDigestSettings returns the settings of a user, or the defaults.
+func (dr *DigestRepo) GetDigestSettings(ctx context.Context, userID primitive.ObjectID) (models.DigestSettings, error) {
+	settings, err := scanDigestSettings(dr.db.QueryRowContext(ctx,
+		`SELECT `+digestSettingsColumns+` FROM digest_settings WHERE user_id = ?`, userID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DefaultDigestSettings(userID), nil
+		}
+		dr.log.Error("Error retrieving digest settings", logger.Error(err))
+		return models.DigestSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveDigestSettings creates or replaces the settings of a user, keeping
+// when the last digest was sent.
+func (dr *DigestRepo) SaveDigestSettings(ctx context.Context, req models.UpdateDigestSettings) (models.DigestSettings, error) {
+	settings, err := scanDigestSettings(dr.db.QueryRowContext(ctx,
+		`INSERT INTO digest_settings (user_id, frequency, send_at, weekday, timezone, next_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET frequency = excluded.frequency, send_at = excluded.send_at,
+			weekday = excluded.weekday, timezone = excluded.timezone, next_at = excluded.next_at, updated_at = excluded.updated_at
+		RETURNING `+digestSettingsColumns,
+		req.UserID.Hex(), req.Frequency, req.SendAt, req.Weekday, req.Timezone, nullTime(req.NextAt), time.Now().UTC()))
+	if err != nil {
+		dr.log.Error("Error saving digest settings", logger.Error(err))
+		return models.DigestSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// DueDigests returns the settings whose next digest is due at or before now.
+func (dr *DigestRepo) DueDigests(ctx context.Context, now time.Time, limit int) ([]models.DigestSettings, error) {
+	rows, err := dr.db.QueryContext(ctx, `SELECT `+digestSettingsColumns+` FROM digest_settings
+		WHERE next_at <= ? ORDER BY next_at, user_id LIMIT ?`, now.UTC(), limit)
+	if err != nil {
+		dr.log.Error("Error retrieving due digests", logger.Error(err))
+		return nil, err
+	}
+	due, err := collectRows(rows, scanDigestSettings)
+	if err != nil {
+		dr.log.Error("Error decoding due digests", logger.Error(err))
+		return nil, err
+	}
+	return due, nil
+}
+
+// RecordDigest stores the outcome of a digest and when the next is sent,
+// unless the settings have been saved since it fell due.
+func (dr *DigestRepo) RecordDigest(ctx context.Context, delivery models.DigestDelivery) error {
+	res, err := dr.db.ExecContext(ctx, `UPDATE digest_settings SET next_at = ?, last_sent_at = COALESCE(?, last_sent_at)
+		WHERE user_id = ? AND next_at = ?`,
+		delivery.NextAt.UTC(), nullTime(delivery.SentAt), delivery.UserID.Hex(), delivery.ScheduledAt.UTC())
+	if err != nil {
+		dr.log.Error("Error recording digest", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrDigestNotFound
+	}
+	return nil
+}
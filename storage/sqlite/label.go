@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LabelRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewLabelRepo(db *sql.DB, log logger.ILogger) *LabelRepo {
+	return &LabelRepo{db: db, log: log}
+}
+
+const labelColumns = "id, user_id, name, color, created_at, updated_at"
+
+func scanLabel(row scanner) (models.Label, error) {
+	var label models.Label
+	var id, userID string
+	err := row.Scan(&id, &userID, &label.Name, &label.Color, &label.CreatedAt, &label.UpdatedAt)
+	label.ID = objectID(id)
+	label.UserID = objectID(userID)
+	return label, err
+}
+
+// CreateLabel creates a new label in the database.
+func (lr *LabelRepo) CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error) {
+	now := time.Now().UTC()
+	label := models.Label{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := lr.db.ExecContext(ctx, `INSERT INTO labels (`+labelColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		label.ID.Hex(), label.UserID.Hex(), label.Name, label.Color, label.CreatedAt, label.UpdatedAt)
+	if err != nil {
+		lr.log.Error("Error creating label", logger.Error(err))
+		return models.Label{}, err
+	}
+
+	return label, nil
+}
+
+// GetLabel retrieves a label by its ID.
+func (lr *LabelRepo) GetLabel(ctx context.Context, labelID string) (models.Label, error) {
+	if _, err := primitive.ObjectIDFromHex(labelID); err != nil {
+		return models.Label{}, models.ErrLabelNotFound
+	}
+
+	label, err := scanLabel(lr.db.QueryRowContext(ctx, `SELECT `+labelColumns+` FROM labels WHERE id = ?`, labelID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Label{}, models.ErrLabelNotFound
+		}
+		lr.log.Error("Error retrieving label", logger.Error(err))
+		return models.Label{}, err
+	}
+
+	return label, nil
+}
+
+// UpdateLabel updates the label information.
+func (lr *LabelRepo) UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error) {
+	label, err := scanLabel(lr.db.QueryRowContext(ctx,
+		`UPDATE labels SET name = ?, color = ?, updated_at = ? WHERE id = ? RETURNING `+labelColumns,
+		req.Name, req.Color, time.Now().UTC(), req.ID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Label{}, models.ErrLabelNotFound
+		}
+		lr.log.Error("Error updating label", logger.Error(err))
+		return models.Label{}, err
+	}
+
+	return label, nil
+}
+
+// DeleteLabel removes a label by its ID. The task_labels foreign key drops it
+// from the tasks that carry it.
+func (lr *LabelRepo) DeleteLabel(ctx context.Context, labelID string) error {
+	if _, err := primitive.ObjectIDFromHex(labelID); err != nil {
+		return models.ErrLabelNotFound
+	}
+
+	res, err := lr.db.ExecContext(ctx, `DELETE FROM labels WHERE id = ?`, labelID)
+	if err != nil {
+		lr.log.Error("Error deleting label", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrLabelNotFound
+	}
+	return nil
+}
+
+// GetAllLabels retrieves all labels for a specific user with pagination
+// support, optionally narrowed down by a full-text search over the name.
+func (lr *LabelRepo) GetAllLabels(ctx context.Context, userID string, search string, page models.Pagination) ([]models.Label, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+
+	filter := "l.user_id = ?"
+	args := []any{userID}
+	if search != "" {
+		condition, searchArgs := searchCondition("labels", "l", []string{"name"}, search)
+		filter += " AND " + condition
+		args = append(args, searchArgs...)
+	}
+	clause, pageArgs := pageClause("l.", models.DefaultSort, page, args)
+
+	rows, err := lr.db.QueryContext(ctx, `SELECT `+labelColumns+` FROM labels l WHERE `+filter+clause, pageArgs...)
+	if err != nil {
+		lr.log.Error("Error retrieving labels", logger.Error(err))
+		return nil, 0, err
+	}
+	labels, err := collectRows(rows, scanLabel)
+	if err != nil {
+		lr.log.Error("Error decoding labels", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := lr.db.QueryRowContext(ctx, `SELECT count(*) FROM labels l WHERE `+filter, args...).Scan(&count); err != nil {
+		lr.log.Error("Error counting labels", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return labels, count, nil
+}
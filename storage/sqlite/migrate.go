@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies the up migrations that have not run yet, in file name
+// order. Each migration runs in its own transaction together with its
+// schema_migrations row.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT     PRIMARY KEY,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	for _, file := range files {
+		version, ok := strings.CutSuffix(file.Name(), ".up.sql")
+		if !ok {
+			continue
+		}
+		if err := applyMigration(ctx, db, version, "migrations/"+file.Name()); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, version, path string) error {
+	script, err := migrations.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		var applied bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)", version).Scan(&applied)
+		if err != nil || applied {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", version)
+		return err
+	})
+}
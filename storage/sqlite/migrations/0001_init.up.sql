@@ -0,0 +1,140 @@
+-- IDs are MongoDB ObjectIDs in hex so all backends share the same models.
+-- They start with their creation time, so ordering by id is insertion order.
+-- Timestamps are stored in UTC so they compare correctly as text.
+
+CREATE TABLE users (
+    id            TEXT     PRIMARY KEY,
+    username      TEXT     NOT NULL,
+    email         TEXT     NOT NULL UNIQUE,
+    password      TEXT     NOT NULL DEFAULT '',
+    role          TEXT     NOT NULL DEFAULT '',
+    disabled      BOOLEAN  NOT NULL DEFAULT FALSE,
+    -- Access tokens carry the token version of their user and stop working
+    -- once it is bumped.
+    token_version INTEGER  NOT NULL DEFAULT 0,
+    created_at    DATETIME NOT NULL,
+    updated_at    DATETIME NOT NULL
+);
+
+CREATE TABLE task_lists (
+    id          TEXT     PRIMARY KEY,
+    user_id     TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title       TEXT     NOT NULL,
+    description TEXT     NOT NULL DEFAULT '',
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+
+CREATE INDEX task_lists_user_id_idx ON task_lists (user_id);
+
+CREATE TABLE tasks (
+    id           TEXT     PRIMARY KEY,
+    task_list_id TEXT     NOT NULL REFERENCES task_lists (id) ON DELETE CASCADE,
+    title        TEXT     NOT NULL,
+    description  TEXT     NOT NULL DEFAULT '',
+    due_date     DATETIME NOT NULL,
+    completed    BOOLEAN  NOT NULL DEFAULT FALSE,
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL
+);
+
+CREATE INDEX tasks_task_list_id_idx ON tasks (task_list_id);
+
+CREATE TABLE labels (
+    id          TEXT     PRIMARY KEY,
+    user_id     TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        TEXT     NOT NULL,
+    color       TEXT     NOT NULL DEFAULT '',
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+
+CREATE INDEX labels_user_id_idx ON labels (user_id);
+
+-- Deleting a task or a label drops the link, so labels never dangle. The
+-- rowid keeps the order labels were added in.
+CREATE TABLE task_labels (
+    task_id     TEXT     NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    label_id    TEXT     NOT NULL REFERENCES labels (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX task_labels_label_id_idx ON task_labels (label_id);
+
+CREATE TABLE refresh_tokens (
+    id          TEXT     PRIMARY KEY,
+    user_id     TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id   TEXT     NOT NULL,
+    device_id   TEXT     NOT NULL DEFAULT '',
+    token_hash  TEXT     NOT NULL UNIQUE,
+    used        BOOLEAN  NOT NULL DEFAULT FALSE,
+    revoked     BOOLEAN  NOT NULL DEFAULT FALSE,
+    expires_at  DATETIME NOT NULL,
+    created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_device_id_idx ON refresh_tokens (user_id, device_id);
+
+-- user_id is only set when the registration changes the email of a user.
+CREATE TABLE pending_registrations (
+    id            TEXT     PRIMARY KEY,
+    user_id       TEXT     REFERENCES users (id) ON DELETE CASCADE,
+    email         TEXT     NOT NULL UNIQUE,
+    username      TEXT     NOT NULL,
+    password_hash TEXT     NOT NULL,
+    otp_hash      TEXT     NOT NULL,
+    attempts      INTEGER  NOT NULL DEFAULT 0,
+    expires_at    DATETIME NOT NULL,
+    created_at    DATETIME NOT NULL
+);
+
+CREATE TABLE password_resets (
+    id          TEXT     PRIMARY KEY,
+    user_id     TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash  TEXT     NOT NULL UNIQUE,
+    used        BOOLEAN  NOT NULL DEFAULT FALSE,
+    expires_at  DATETIME NOT NULL,
+    created_at  DATETIME NOT NULL
+);
+
+-- Full-text indexes backing the search parameter. The trigram tokenizer
+-- matches any substring of three or more characters, case-insensitively,
+-- like the regex search of the MongoDB backend. Triggers keep them in step
+-- with their tables, including rows removed by cascading deletes.
+
+CREATE VIRTUAL TABLE tasks_fts USING fts5 (
+    title, description,
+    content = 'tasks', content_rowid = 'rowid', tokenize = 'trigram'
+);
+
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+END;
+
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+    INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+    INSERT INTO tasks_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE VIRTUAL TABLE labels_fts USING fts5 (
+    name,
+    content = 'labels', content_rowid = 'rowid', tokenize = 'trigram'
+);
+
+CREATE TRIGGER labels_fts_insert AFTER INSERT ON labels BEGIN
+    INSERT INTO labels_fts (rowid, name) VALUES (new.rowid, new.name);
+END;
+
+CREATE TRIGGER labels_fts_delete AFTER DELETE ON labels BEGIN
+    INSERT INTO labels_fts (labels_fts, rowid, name) VALUES ('delete', old.rowid, old.name);
+END;
+
+CREATE TRIGGER labels_fts_update AFTER UPDATE OF name ON labels BEGIN
+    INSERT INTO labels_fts (labels_fts, rowid, name) VALUES ('delete', old.rowid, old.name);
+    INSERT INTO labels_fts (rowid, name) VALUES (new.rowid, new.name);
+END;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS labels_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS tasks_task_list_id_created_at_id_idx;
+DROP INDEX IF EXISTS task_lists_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS users_created_at_id_idx;
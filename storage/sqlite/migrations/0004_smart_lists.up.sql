@@ -0,0 +1,12 @@
+-- Saved task queries, listed and paged by (created_at, id) within their owner
+CREATE TABLE smart_lists (
+    id          TEXT     PRIMARY KEY,
+    user_id     TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        TEXT     NOT NULL,
+    query       TEXT     NOT NULL,
+    sort        TEXT     NOT NULL DEFAULT '',
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+
+CREATE INDEX smart_lists_user_id_created_at_id_idx ON smart_lists (user_id, created_at, id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS task_lists_fts_update;
+DROP TRIGGER IF EXISTS task_lists_fts_delete;
+DROP TRIGGER IF EXISTS task_lists_fts_insert;
+DROP TABLE IF EXISTS task_lists_fts;
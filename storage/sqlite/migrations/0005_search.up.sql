@@ -0,0 +1,22 @@
+-- Task lists get a full-text index like tasks and labels, for the search
+-- endpoint, and it is filled with the lists that already exist.
+
+CREATE VIRTUAL TABLE task_lists_fts USING fts5 (
+    title, description,
+    content = 'task_lists', content_rowid = 'rowid', tokenize = 'trigram'
+);
+
+CREATE TRIGGER task_lists_fts_insert AFTER INSERT ON task_lists BEGIN
+    INSERT INTO task_lists_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE TRIGGER task_lists_fts_delete AFTER DELETE ON task_lists BEGIN
+    INSERT INTO task_lists_fts (task_lists_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+END;
+
+CREATE TRIGGER task_lists_fts_update AFTER UPDATE OF title, description ON task_lists BEGIN
+    INSERT INTO task_lists_fts (task_lists_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+    INSERT INTO task_lists_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+INSERT INTO task_lists_fts (task_lists_fts) VALUES ('rebuild');
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS checklist_items;
+DROP INDEX IF EXISTS tasks_parent_id_idx;
+ALTER TABLE tasks DROP COLUMN parent_id;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN recurrence_start;
+ALTER TABLE tasks DROP COLUMN recurrence_timezone;
+ALTER TABLE tasks DROP COLUMN recurrence_rule;
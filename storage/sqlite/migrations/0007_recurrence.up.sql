@@ -0,0 +1,4 @@
+-- A task repeats when it has a rule. The start anchors COUNT and INTERVAL.
+ALTER TABLE tasks ADD COLUMN recurrence_rule TEXT;
+ALTER TABLE tasks ADD COLUMN recurrence_timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN recurrence_start DATETIME;
@@ -0,0 +1,17 @@
+-- One reminder per offset of a task. remind_at is NULL while the task has no
+-- due date; the scheduler polls the pending ones by it.
+CREATE TABLE reminders (
+    id              TEXT     PRIMARY KEY,
+    task_id         TEXT     NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    user_id         TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    before_minutes  INTEGER  NOT NULL,
+    remind_at       DATETIME,
+    status          TEXT     NOT NULL,
+    attempts        INTEGER  NOT NULL DEFAULT 0,
+    last_error      TEXT     NOT NULL DEFAULT '',
+    sent_at         DATETIME,
+    created_at      DATETIME NOT NULL,
+    UNIQUE (task_id, before_minutes)
+);
+
+CREATE INDEX reminders_status_remind_at_id_idx ON reminders (status, remind_at, id);
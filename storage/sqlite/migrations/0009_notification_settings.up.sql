@@ -0,0 +1,9 @@
+-- Where a user's notifications go. Users without a row get the defaults.
+-- channels is a comma separated list.
+CREATE TABLE notification_settings (
+    user_id           TEXT     PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    channels          TEXT     NOT NULL,
+    telegram_chat_id  TEXT     NOT NULL DEFAULT '',
+    webhook_url       TEXT     NOT NULL DEFAULT '',
+    updated_at        DATETIME NOT NULL
+);
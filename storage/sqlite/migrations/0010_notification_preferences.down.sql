@@ -0,0 +1,4 @@
+ALTER TABLE notification_settings DROP COLUMN quiet_end;
+ALTER TABLE notification_settings DROP COLUMN quiet_start;
+ALTER TABLE notification_settings DROP COLUMN timezone;
+ALTER TABLE notification_settings DROP COLUMN events;
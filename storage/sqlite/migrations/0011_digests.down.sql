@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS digest_settings;
+ALTER TABLE tasks DROP COLUMN completed_at;
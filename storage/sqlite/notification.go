@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewNotificationRepo(db *sql.DB, log logger.ILogger) *NotificationRepo {
+	return &NotificationRepo{db: db, log: log}
+}
+
+const notificationSettingsColumns = "user_id, channels, telegram_chat_id, webhook_url, updated_at"
+
+func scanNotificationSettings(row scanner) (models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	var userID, channels string
+	err := row.Scan(&userID, &channels, &settings.TelegramChatID, &settings.WebhookURL, &settings.UpdatedAt)
+	settings.UserID = objectID(userID)
+	settings.Channels = []string{}
+	if channels != "" {
+		settings.Channels = strings.Split(channels, ",")
+	}
+	return settings, err
+}
+
+// GetNotificationSettings returns the settings of a user, or the defaults.
+func (nr *NotificationRepo) GetNotificationSettings(ctx context.Context, userID primitive.ObjectID) (models.NotificationSettings, error) {
+	settings, err := scanNotificationSettings(nr.db.QueryRowContext(ctx,
+		`SELECT `+notificationSettingsColumns+` FROM notification_settings WHERE user_id = ?`, userID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DefaultNotificationSettings(userID), nil
+		}
+		nr.log.Error("Error retrieving notification settings", logger.Error(err))
+		return models.NotificationSettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveNotificationSettings creates or replaces the settings of a user.
+func (nr *NotificationRepo) SaveNotificationSettings(ctx context.Context, req models.UpdateNotificationSettings) (models.NotificationSettings, error) {
+	settings, err := scanNotificationSettings(nr.db.QueryRowContext(ctx,
+		`INSERT INTO notification_settings (`+notificationSettingsColumns+`) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET channels = excluded.channels, telegram_chat_id = excluded.telegram_chat_id,
+			webhook_url = excluded.webhook_url, updated_at = excluded.updated_at
+		RETURNING `+notificationSettingsColumns,
+		req.UserID.Hex(), strings.Join(req.Channels, ","), req.TelegramChatID, req.WebhookURL, time.Now().UTC()))
+	if err != nil {
+		nr.log.Error("Error saving notification settings", logger.Error(err))
+		return models.NotificationSettings{}, err
+	}
+
+	return settings, nil
+}
+
+const notificationPreferencesColumns = "user_id, events, timezone, quiet_start, quiet_end, updated_at"
+
+func scanNotificationPreferences(row scanner) (models.NotificationPreferences, error) {
+	var preferences models.NotificationPreferences
+	var userID, events, quietStart, quietEnd string
+	err := row.Scan(&userID, &events, &preferences.Timezone, &quietStart, &quietEnd, &preferences.UpdatedAt)
+	if err != nil {
+		return preferences, err
+	}
+	preferences.UserID = objectID(userID)
+	if quietStart != "" {
+		preferences.QuietHours = &models.QuietHours{Start: quietStart, End: quietEnd}
+	}
+	preferences.Events = map[string][]string{}
+	err = json.Unmarshal([]byte(events), &preferences.Events)
+	return preferences, err
+}
+
+// GetNotificationPreferences returns the preferences of a user, or the
+// defaults.
+func (nr *NotificationRepo) GetNotificationPreferences(ctx context.Context, userID primitive.ObjectID) (models.NotificationPreferences, error) {
+	preferences, err := scanNotificationPreferences(nr.db.QueryRowContext(ctx,
+		`SELECT `+notificationPreferencesColumns+` FROM notification_settings WHERE user_id = ?`, userID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DefaultNotificationPreferences(userID), nil
+		}
+		nr.log.Error("Error retrieving notification preferences", logger.Error(err))
+		return models.NotificationPreferences{}, err
+	}
+
+	return preferences, nil
+}
+
+// SaveNotificationPreferences creates or replaces the preferences of a user.
+// A user without settings gets the default ones.
+func (nr *NotificationRepo) SaveNotificationPreferences(ctx context.Context, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error) {
+	events := req.Events
+	if events == nil {
+		events = map[string][]string{}
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	var quietStart, quietEnd string
+	if req.QuietHours != nil {
+		quietStart, quietEnd = req.QuietHours.Start, req.QuietHours.End
+	}
+
+	preferences, err := scanNotificationPreferences(nr.db.QueryRowContext(ctx,
+		`INSERT INTO notification_settings (channels, `+notificationPreferencesColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET events = excluded.events, timezone = excluded.timezone,
+			quiet_start = excluded.quiet_start, quiet_end = excluded.quiet_end, updated_at = excluded.updated_at
+		RETURNING `+notificationPreferencesColumns,
+		strings.Join(models.DefaultNotificationSettings(req.UserID).Channels, ","), req.UserID.Hex(), string(data),
+		req.Timezone, quietStart, quietEnd, time.Now().UTC()))
+	if err != nil {
+		nr.log.Error("Error saving notification preferences", logger.Error(err))
+		return models.NotificationPreferences{}, err
+	}
+
+	return preferences, nil
+}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PasswordResetRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewPasswordResetRepo(db *sql.DB, log logger.ILogger) *PasswordResetRepo {
+	return &PasswordResetRepo{db: db, log: log}
+}
+
+// CreatePasswordReset stores a hashed password reset token.
+func (pr *PasswordResetRepo) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error {
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	reset.CreatedAt = time.Now().UTC()
+
+	_, err := pr.db.ExecContext(ctx, `INSERT INTO password_resets (id, user_id, token_hash, used, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		reset.ID.Hex(), reset.UserID.Hex(), reset.TokenHash, reset.Used, reset.ExpiresAt.UTC(), reset.CreatedAt)
+	if err != nil {
+		pr.log.Error("Error creating password reset", logger.Error(err))
+		return fmt.Errorf("error while creating password reset: %w", err)
+	}
+	return nil
+}
+
+// UsePasswordReset atomically marks an unused, unexpired reset token as used
+// and returns it, along with every other token of the same user. A token can
+// therefore only be redeemed once, and older reset links die with it.
+func (pr *PasswordResetRepo) UsePasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error) {
+	var reset models.PasswordReset
+	var id, userID string
+	err := withTx(ctx, pr.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `UPDATE password_resets SET used = TRUE
+			WHERE token_hash = ? AND NOT used AND expires_at > ?
+			RETURNING id, user_id, token_hash, used, expires_at, created_at`, tokenHash, time.Now().UTC()).
+			Scan(&id, &userID, &reset.TokenHash, &reset.Used, &reset.ExpiresAt, &reset.CreatedAt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE password_resets SET used = TRUE WHERE user_id = ? AND NOT used`, userID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PasswordReset{}, fmt.Errorf("password reset not found: %w", err)
+		}
+		pr.log.Error("Error using password reset", logger.Error(err))
+		return models.PasswordReset{}, fmt.Errorf("error while using password reset: %w", err)
+	}
+	reset.ID = objectID(id)
+	reset.UserID = objectID(userID)
+
+	return reset, nil
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RegistrationRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewRegistrationRepo(db *sql.DB, log logger.ILogger) *RegistrationRepo {
+	return &RegistrationRepo{db: db, log: log}
+}
+
+// UpsertPendingRegistration stores a pending registration, replacing an
+// earlier one for the same email only once it expired.
+func (rr *RegistrationRepo) UpsertPendingRegistration(ctx context.Context, reg models.PendingRegistration) error {
+	reg.CreatedAt = time.Now().UTC()
+
+	var userID sql.NullString
+	if reg.UserID != nil {
+		userID = sql.NullString{String: reg.UserID.Hex(), Valid: true}
+	}
+
+	res, err := rr.db.ExecContext(ctx, `INSERT INTO pending_registrations (id, user_id, email, username, password_hash, otp_hash, attempts, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (email) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			username = EXCLUDED.username,
+			password_hash = EXCLUDED.password_hash,
+			otp_hash = EXCLUDED.otp_hash,
+			attempts = EXCLUDED.attempts,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at
+		WHERE pending_registrations.expires_at <= EXCLUDED.created_at`,
+		primitive.NewObjectID().Hex(), userID, reg.Email, reg.Username, reg.PasswordHash, reg.OtpHash, reg.Attempts, reg.ExpiresAt.UTC(), reg.CreatedAt)
+	if err != nil {
+		rr.log.Error("Error saving pending registration", logger.Error(err))
+		return fmt.Errorf("error while saving pending registration: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrRegistrationPending
+	}
+	return nil
+}
+
+// GetPendingRegistration retrieves the pending registration for an email.
+// Expired registrations are treated as missing, as the TTL index would
+// have removed them in MongoDB.
+func (rr *RegistrationRepo) GetPendingRegistration(ctx context.Context, email string) (models.PendingRegistration, error) {
+	var reg models.PendingRegistration
+	var id string
+	var userID sql.NullString
+	err := rr.db.QueryRowContext(ctx, `SELECT id, user_id, email, username, password_hash, otp_hash, attempts, expires_at, created_at
+		FROM pending_registrations WHERE email = ? AND expires_at > ?`, email, time.Now().UTC()).
+		Scan(&id, &userID, &reg.Email, &reg.Username, &reg.PasswordHash, &reg.OtpHash, &reg.Attempts, &reg.ExpiresAt, &reg.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PendingRegistration{}, fmt.Errorf("pending registration not found: %w", err)
+		}
+		rr.log.Error("Error retrieving pending registration", logger.Error(err))
+		return models.PendingRegistration{}, fmt.Errorf("error while getting pending registration: %w", err)
+	}
+	reg.ID = objectID(id)
+	if userID.Valid {
+		id := objectID(userID.String)
+		reg.UserID = &id
+	}
+
+	return reg, nil
+}
+
+// IncrementRegistrationAttempts uses up one OTP attempt, unless max were
+// made already.
+func (rr *RegistrationRepo) IncrementRegistrationAttempts(ctx context.Context, email string, max int) (bool, error) {
+	res, err := rr.db.ExecContext(ctx, `UPDATE pending_registrations SET attempts = attempts + 1 WHERE email = ? AND attempts < ?`, email, max)
+	if err != nil {
+		rr.log.Error("Error updating pending registration", logger.Error(err))
+		return false, fmt.Errorf("error while updating pending registration: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// DeletePendingRegistration removes the pending registration for an email.
+func (rr *RegistrationRepo) DeletePendingRegistration(ctx context.Context, email string) error {
+	_, err := rr.db.ExecContext(ctx, `DELETE FROM pending_registrations WHERE email = ?`, email)
+	if err != nil {
+		rr.log.Error("Error deleting pending registration", logger.Error(err))
+		return fmt.Errorf("error while deleting pending registration: %w", err)
+	}
+	return nil
+}
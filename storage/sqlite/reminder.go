@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReminderRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewReminderRepo(db *sql.DB, log logger.ILogger) *ReminderRepo {
+	return &ReminderRepo{db: db, log: log}
+}
+
+const reminderColumns = "id, task_id, user_id, before_minutes, remind_at, status, attempts, last_error, sent_at, created_at"
+
+func scanReminder(row scanner) (models.Reminder, error) {
+	var reminder models.Reminder
+	var id, taskID, userID string
+	var remindAt, sentAt sql.NullTime
+	err := row.Scan(&id, &taskID, &userID, &reminder.Before, &remindAt, &reminder.Status,
+		&reminder.Attempts, &reminder.LastError, &sentAt, &reminder.CreatedAt)
+	reminder.ID = objectID(id)
+	reminder.TaskID = objectID(taskID)
+	reminder.UserID = objectID(userID)
+	if remindAt.Valid {
+		reminder.RemindAt = &remindAt.Time
+	}
+	if sentAt.Valid {
+		reminder.SentAt = &sentAt.Time
+	}
+	return reminder, err
+}
+
+// nullTime stores an optional time in UTC, like every other time.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// SetReminders replaces the reminders of a task. Kept offsets conflict with
+// the unique (task_id, before_minutes) key, so their state is left untouched.
+func (rr *ReminderRepo) SetReminders(ctx context.Context, userID, taskID primitive.ObjectID, before []int, dueDate time.Time) ([]models.Reminder, error) {
+	now := time.Now().UTC()
+	err := withTx(ctx, rr.db, func(tx *sql.Tx) error {
+		reminders, err := taskReminders(ctx, tx, taskID)
+		if err != nil {
+			return err
+		}
+		for _, reminder := range reminders {
+			if containsInt(before, reminder.Before) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM reminders WHERE id = ?`, reminder.ID.Hex()); err != nil {
+				return err
+			}
+		}
+
+		for _, minutes := range before {
+			_, err := tx.ExecContext(ctx, `INSERT INTO reminders (id, task_id, user_id, before_minutes, remind_at, status, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (task_id, before_minutes) DO NOTHING`,
+				primitive.NewObjectID().Hex(), taskID.Hex(), userID.Hex(), minutes, nullTime(models.ReminderTime(dueDate, minutes)), models.ReminderPending, now)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rr.log.Error("Error setting reminders", logger.Error(err))
+		return nil, err
+	}
+
+	return rr.GetReminders(ctx, taskID)
+}
+
+// GetReminders returns the reminders of a task, the earliest first.
+func (rr *ReminderRepo) GetReminders(ctx context.Context, taskID primitive.ObjectID) ([]models.Reminder, error) {
+	return rr.query(ctx, `SELECT `+reminderColumns+` FROM reminders WHERE task_id = ? ORDER BY before_minutes DESC`, taskID.Hex())
+}
+
+// RescheduleReminders moves the reminders of a task to a new due date. Only
+// the reminders whose time changes are reset, so saving a task without
+// touching the due date does not send its reminders again. The times are
+// worked out here, as SQLite has no arithmetic on the stored format.
+func (rr *ReminderRepo) RescheduleReminders(ctx context.Context, taskID primitive.ObjectID, dueDate time.Time) error {
+	err := withTx(ctx, rr.db, func(tx *sql.Tx) error {
+		reminders, err := taskReminders(ctx, tx, taskID)
+		if err != nil {
+			return err
+		}
+		for _, reminder := range reminders {
+			remindAt := models.ReminderTime(dueDate, reminder.Before)
+			if sameTimePtr(reminder.RemindAt, remindAt) {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, `UPDATE reminders SET remind_at = ?, status = ?, attempts = 0, last_error = '', sent_at = NULL WHERE id = ?`,
+				nullTime(remindAt), models.ReminderPending, reminder.ID.Hex())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rr.log.Error("Error rescheduling reminders", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DueReminders returns the pending reminders due at or before now.
+func (rr *ReminderRepo) DueReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	return rr.query(ctx, `SELECT `+reminderColumns+` FROM reminders
+		WHERE status = ? AND remind_at <= ? ORDER BY remind_at, id LIMIT ?`,
+		models.ReminderPending, now.UTC(), limit)
+}
+
+// RecordDelivery stores the outcome of sending a reminder, unless the
+// reminder has been moved since it was read.
+func (rr *ReminderRepo) RecordDelivery(ctx context.Context, delivery models.ReminderDelivery) error {
+	res, err := rr.db.ExecContext(ctx, `UPDATE reminders SET status = ?, attempts = ?, last_error = ?, sent_at = ?,
+			remind_at = COALESCE(?, remind_at)
+		WHERE id = ? AND remind_at = ?`,
+		delivery.Status, delivery.Attempts, delivery.LastError, nullTime(delivery.SentAt), nullTime(delivery.DeferredTo),
+		delivery.ID.Hex(), delivery.RemindAt.UTC())
+	if err != nil {
+		rr.log.Error("Error recording reminder delivery", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrReminderNotFound
+	}
+	return nil
+}
+
+// DeleteReminders removes the reminders of a task.
+func (rr *ReminderRepo) DeleteReminders(ctx context.Context, taskID primitive.ObjectID) error {
+	if _, err := rr.db.ExecContext(ctx, `DELETE FROM reminders WHERE task_id = ?`, taskID.Hex()); err != nil {
+		rr.log.Error("Error deleting reminders", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (rr *ReminderRepo) query(ctx context.Context, query string, args ...any) ([]models.Reminder, error) {
+	rows, err := rr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		rr.log.Error("Error retrieving reminders", logger.Error(err))
+		return nil, err
+	}
+	reminders, err := collectRows(rows, scanReminder)
+	if err != nil {
+		rr.log.Error("Error decoding reminders", logger.Error(err))
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// taskReminders reads the reminders of a task inside a transaction.
+func taskReminders(ctx context.Context, tx *sql.Tx, taskID primitive.ObjectID) ([]models.Reminder, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT `+reminderColumns+` FROM reminders WHERE task_id = ?`, taskID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return collectRows(rows, scanReminder)
+}
+
+func sameTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func containsInt(values []int, value int) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
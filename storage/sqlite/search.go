@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewSearchRepo(db *sql.DB, log logger.ILogger) *SearchRepo {
+	return &SearchRepo{db: db, log: log}
+}
+
+// searchTables describes, for each type, how to select id, task list id,
+// title and body of the rows of a table and which rows belong to the user.
+var searchTables = map[string]struct {
+	table, columns, owner string
+	// weights of the indexed columns for bm25, titles first
+	weights string
+}{
+	models.SearchTypeTask: {
+		table:   "tasks",
+		columns: "t.id, t.task_list_id, t.title, t.description",
+		owner:   "t.task_list_id IN (SELECT id FROM task_lists WHERE user_id = ?)",
+		weights: "10.0, 1.0",
+	},
+	models.SearchTypeTaskList: {
+		table:   "task_lists",
+		columns: "t.id, '', t.title, t.description",
+		owner:   "t.user_id = ?",
+		weights: "10.0, 1.0",
+	},
+	models.SearchTypeLabel: {
+		table:   "labels",
+		columns: "t.id, '', t.name, ''",
+		owner:   "t.user_id = ?",
+		weights: "1.0",
+	},
+}
+
+// Search ranks the rows matching any of the terms with bm25. The trigram
+// indexes cannot look up words shorter than three characters, so those are
+// left out of the ranking; a search made only of them falls back to LIKE,
+// with every match scoring the same.
+func (sr *SearchRepo) Search(ctx context.Context, userID string, query models.SearchQuery) ([]models.SearchResult, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, models.ErrUserNotFound
+	}
+
+	phrases := []string{}
+	for _, term := range query.Terms {
+		if utf8.RuneCountInString(term) >= 3 {
+			phrases = append(phrases, `"`+term+`"`)
+		}
+	}
+
+	results := []models.SearchResult{}
+	for _, typ := range query.Types {
+		table, ok := searchTables[typ]
+		if !ok {
+			continue
+		}
+		fts := table.table + "_fts"
+
+		var where, score string
+		var args []any
+		join := ""
+		if len(phrases) > 0 {
+			join = " JOIN " + fts + " ON " + fts + ".rowid = t.rowid"
+			where = fts + " MATCH ?"
+			score = "-bm25(" + fts + ", " + table.weights + ")"
+			args = []any{strings.Join(phrases, " OR ")}
+		} else {
+			likes := []string{}
+			for _, term := range query.Terms {
+				condition, termArgs := searchCondition(table.table, "t", ftsColumns(typ), term)
+				likes = append(likes, condition)
+				args = append(args, termArgs...)
+			}
+			where = "(" + strings.Join(likes, " OR ") + ")"
+			score = "1.0"
+		}
+
+		limit := query.Limit
+		if limit == 0 {
+			limit = -1
+		}
+		rows, err := sr.db.QueryContext(ctx, `SELECT `+table.columns+`, `+score+` AS score FROM `+table.table+` t`+join+
+			` WHERE `+where+` AND `+table.owner+` ORDER BY score DESC, t.id LIMIT ?`, append(args, userID, limit)...)
+		if err != nil {
+			sr.log.Error("Error searching", logger.Error(err))
+			return nil, err
+		}
+		found, err := collectRows(rows, func(row scanner) (models.SearchResult, error) {
+			result := models.SearchResult{Type: typ}
+			var id, taskListID string
+			err := row.Scan(&id, &taskListID, &result.Title, &result.Body, &result.Score)
+			result.ID = objectID(id)
+			if taskListID != "" {
+				taskListObjectID := objectID(taskListID)
+				result.TaskListID = &taskListObjectID
+			}
+			return result, err
+		})
+		if err != nil {
+			sr.log.Error("Error decoding search results", logger.Error(err))
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
+
+// ftsColumns returns the indexed columns of the table searched for typ.
+func ftsColumns(typ string) []string {
+	if typ == models.SearchTypeLabel {
+		return []string{"name"}
+	}
+	return []string{"title", "description"}
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SmartListRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewSmartListRepo(db *sql.DB, log logger.ILogger) *SmartListRepo {
+	return &SmartListRepo{db: db, log: log}
+}
+
+const smartListColumns = "id, user_id, name, query, sort, created_at, updated_at"
+
+func scanSmartList(row scanner) (models.SmartList, error) {
+	var smartList models.SmartList
+	var id, userID string
+	err := row.Scan(&id, &userID, &smartList.Name, &smartList.Query, &smartList.Sort, &smartList.CreatedAt, &smartList.UpdatedAt)
+	smartList.ID = objectID(id)
+	smartList.UserID = objectID(userID)
+	return smartList, err
+}
+
+// CreateSmartList creates a new smart list in the database.
+func (slr *SmartListRepo) CreateSmartList(ctx context.Context, req models.CreateSmartList) (models.SmartList, error) {
+	now := time.Now().UTC()
+	smartList := models.SmartList{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Query:     req.Query,
+		Sort:      req.Sort,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := slr.db.ExecContext(ctx, `INSERT INTO smart_lists (`+smartListColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		smartList.ID.Hex(), smartList.UserID.Hex(), smartList.Name, smartList.Query, smartList.Sort, smartList.CreatedAt, smartList.UpdatedAt)
+	if err != nil {
+		slr.log.Error("Error creating smart list", logger.Error(err))
+		return models.SmartList{}, err
+	}
+
+	return smartList, nil
+}
+
+// GetSmartList retrieves a smart list by ID.
+func (slr *SmartListRepo) GetSmartList(ctx context.Context, smartListID string) (models.SmartList, error) {
+	if _, err := primitive.ObjectIDFromHex(smartListID); err != nil {
+		return models.SmartList{}, models.ErrSmartListNotFound
+	}
+
+	smartList, err := scanSmartList(slr.db.QueryRowContext(ctx, `SELECT `+smartListColumns+` FROM smart_lists WHERE id = ?`, smartListID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SmartList{}, models.ErrSmartListNotFound
+		}
+		slr.log.Error("Error retrieving smart list", logger.Error(err))
+		return models.SmartList{}, err
+	}
+
+	return smartList, nil
+}
+
+// UpdateSmartList updates an existing smart list.
+func (slr *SmartListRepo) UpdateSmartList(ctx context.Context, req models.UpdateSmartList) (models.SmartList, error) {
+	smartList, err := scanSmartList(slr.db.QueryRowContext(ctx,
+		`UPDATE smart_lists SET name = ?, query = ?, sort = ?, updated_at = ? WHERE id = ? RETURNING `+smartListColumns,
+		req.Name, req.Query, req.Sort, time.Now().UTC(), req.ID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SmartList{}, models.ErrSmartListNotFound
+		}
+		slr.log.Error("Error updating smart list", logger.Error(err))
+		return models.SmartList{}, err
+	}
+
+	return smartList, nil
+}
+
+// DeleteSmartList removes a smart list. The tasks it shows are not touched.
+func (slr *SmartListRepo) DeleteSmartList(ctx context.Context, smartListID string) error {
+	if _, err := primitive.ObjectIDFromHex(smartListID); err != nil {
+		return models.ErrSmartListNotFound
+	}
+
+	res, err := slr.db.ExecContext(ctx, `DELETE FROM smart_lists WHERE id = ?`, smartListID)
+	if err != nil {
+		slr.log.Error("Error deleting smart list", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrSmartListNotFound
+	}
+	return nil
+}
+
+// GetAllSmartLists retrieves the smart lists of a user with pagination.
+func (slr *SmartListRepo) GetAllSmartLists(ctx context.Context, userID string, page models.Pagination) ([]models.SmartList, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	clause, args := pageClause("", models.DefaultSort, page, []any{userID})
+
+	rows, err := slr.db.QueryContext(ctx, `SELECT `+smartListColumns+` FROM smart_lists WHERE user_id = ?`+clause, args...)
+	if err != nil {
+		slr.log.Error("Error retrieving smart lists", logger.Error(err))
+		return nil, 0, err
+	}
+	smartLists, err := collectRows(rows, scanSmartList)
+	if err != nil {
+		slr.log.Error("Error decoding smart lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := slr.db.QueryRowContext(ctx, `SELECT count(*) FROM smart_lists WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		slr.log.Error("Error counting smart lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return smartLists, count, nil
+}
@@ -0,0 +1,232 @@
+// Package sqlite implements the storage interfaces on an embedded SQLite
+// database kept in a single file, for deployments without a database server.
+// It uses a pure-Go driver, so the binary still builds without cgo. The
+// schema lives in migrations/ and is applied with Migrate.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+	"todo/storage"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite"
+)
+
+// Open opens the database file at path, creating it if needed. ":memory:"
+// gives a private in-memory database.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer anyway, and one connection keeps an
+	// in-memory database from being opened once per connection.
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewStorage initializes the SQLite repositories with the provided database and logger.
+func NewStorage(db *sql.DB, log logger.ILogger) *storage.Storage {
+	return &storage.Storage{
+		UserRepo:          NewUserRepo(db, log),
+		LabelRepo:         NewLabelRepo(db, log),
+		TaskRepo:          NewTaskRepo(db, log),
+		TaskListRepo:      NewTaskListRepo(db, log),
+		TokenRepo:         NewTokenRepo(db, log),
+		RegistrationRepo:  NewRegistrationRepo(db, log),
+		PasswordResetRepo: NewPasswordResetRepo(db, log),
+		SmartListRepo:     NewSmartListRepo(db, log),
+		SearchRepo:        NewSearchRepo(db, log),
+		ReminderRepo:      NewReminderRepo(db, log),
+		NotificationRepo:  NewNotificationRepo(db, log),
+		DigestRepo:        NewDigestRepo(db, log),
+		WebhookRepo:       NewWebhookRepo(db, log),
+	}
+}
+
+// Interface for User, Label, Task, TaskList, Token, Registration, PasswordReset, SmartList, Search, Reminder, Notification, Digest and Webhook repositories.
+var (
+	_ storage.UserStorage          = &UserRepo{}
+	_ storage.LabelStorage         = &LabelRepo{}
+	_ storage.TaskStorage          = &TaskRepo{}
+	_ storage.TaskListStorage      = &TaskListRepo{}
+	_ storage.TokenStorage         = &TokenRepo{}
+	_ storage.RegistrationStorage  = &RegistrationRepo{}
+	_ storage.PasswordResetStorage = &PasswordResetRepo{}
+	_ storage.SmartListStorage     = &SmartListRepo{}
+	_ storage.SearchStorage        = &SearchRepo{}
+	_ storage.ReminderStorage      = &ReminderRepo{}
+	_ storage.NotificationStorage  = &NotificationRepo{}
+	_ storage.DigestStorage        = &DigestRepo{}
+	_ storage.WebhookStorage       = &WebhookRepo{}
+)
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// collectRows scans every row and closes rows. No rows gives an empty slice.
+func collectRows[T any](rows *sql.Rows, scan func(scanner) (T, error)) ([]T, error) {
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// withTx runs fn in a transaction, committing when it returns nil.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// objectID converts a stored id back to an ObjectID. Ids are only ever
+// written from ObjectIDs, so a malformed one yields the zero ID.
+func objectID(hex string) primitive.ObjectID {
+	id, _ := primitive.ObjectIDFromHex(hex)
+	return id
+}
+
+// hexIDs converts ObjectIDs to their stored form, dropping duplicates.
+func hexIDs(ids []primitive.ObjectID) []string {
+	seen := map[primitive.ObjectID]bool{}
+	hexes := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			hexes = append(hexes, id.Hex())
+		}
+	}
+	return hexes
+}
+
+// inList returns "(?, ?, ...)" and its arguments for an IN condition.
+func inList(values []string) (string, []any) {
+	args := make([]any, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
+
+// pageClause continues a WHERE clause with the cursor of the page and adds
+// the ordering by keys, then ID, with LIMIT and OFFSET, returning args with
+// their values appended. A limit of 0 means no limit, like in MongoDB, which
+// SQLite spells -1. prefix qualifies the columns, for queries that alias
+// their table.
+func pageClause(prefix string, keys []models.SortKey, page models.Pagination, args []any) (string, []any) {
+	clause := ""
+	if page.After != nil {
+		clause, args = afterCondition(prefix, keys, *page.After, args)
+	}
+
+	order := []string{}
+	for _, key := range keys {
+		if key.Desc {
+			order = append(order, prefix+key.Field+" DESC")
+		} else {
+			order = append(order, prefix+key.Field)
+		}
+	}
+	order = append(order, prefix+"id")
+
+	limit := int64(page.Limit)
+	if limit == 0 {
+		limit = -1
+	}
+	clause += " ORDER BY " + strings.Join(order, ", ") + " LIMIT ? OFFSET ?"
+	return clause, append(args, limit, int64(page.Offset))
+}
+
+// afterCondition matches the rows sorting after the cursor. Ascending orders
+// compare rows, which an index on the sort columns can answer; mixed orders
+// spell out each key.
+func afterCondition(prefix string, keys []models.SortKey, after models.Cursor, args []any) (string, []any) {
+	columns, values := []string{}, []any{}
+	mixed := false
+	for _, key := range keys {
+		columns = append(columns, prefix+key.Field)
+		values = append(values, sqlValue(after.Value(key.Field)))
+		mixed = mixed || key.Desc
+	}
+	columns = append(columns, prefix+"id")
+	values = append(values, after.ID.Hex())
+
+	if !mixed {
+		params := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		return " AND (" + strings.Join(columns, ", ") + ") > (" + params + ")", append(args, values...)
+	}
+
+	branches := []string{}
+	for i, column := range columns {
+		branch := ""
+		for j := 0; j < i; j++ {
+			branch += columns[j] + " = ? AND "
+			args = append(args, values[j])
+		}
+		op := ">"
+		if i < len(keys) && keys[i].Desc {
+			op = "<"
+		}
+		branches = append(branches, branch+column+" "+op+" ?")
+		args = append(args, values[i])
+	}
+	return " AND ((" + strings.Join(branches, ") OR (") + "))", args
+}
+
+// sqlValue stores times in UTC, so their text compares in time order.
+func sqlValue(value any) any {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC()
+	}
+	return value
+}
+
+// searchCondition returns a condition matching the rows of table (aliased as
+// alias) whose full-text index, table_fts, contains search in any of columns.
+// The trigram index cannot answer MATCH for fewer than three characters, so
+// short searches fall back to LIKE on the same index.
+func searchCondition(table, alias string, columns []string, search string) (string, []any) {
+	fts := table + "_fts"
+	if utf8.RuneCountInString(search) >= 3 {
+		phrase := `"` + strings.ReplaceAll(search, `"`, `""`) + `"`
+		return fmt.Sprintf("%s.rowid IN (SELECT rowid FROM %s WHERE %s MATCH ?)", alias, fts, fts), []any{phrase}
+	}
+
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search) + "%"
+	likes := []string{}
+	args := []any{}
+	for _, column := range columns {
+		likes = append(likes, column+` LIKE ? ESCAPE '\'`)
+		args = append(args, pattern)
+	}
+	return fmt.Sprintf("%s.rowid IN (SELECT rowid FROM %s WHERE %s)", alias, fts, strings.Join(likes, " OR ")), args
+}
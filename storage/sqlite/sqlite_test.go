@@ -0,0 +1,26 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"todo/pkg/logger"
+	"todo/storage"
+	"todo/storage/sqlite"
+	"todo/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "todo.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if err := sqlite.Migrate(context.Background(), db); err != nil {
+			t.Fatal(err)
+		}
+		return sqlite.NewStorage(db, logger.New("test"))
+	})
+}
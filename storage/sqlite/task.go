@@ -0,0 +1,483 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewTaskRepo(db *sql.DB, log logger.ILogger) *TaskRepo {
+	return &TaskRepo{db: db, log: log}
+}
+
+// taskColumns selects a task from "tasks t" with its label IDs, comma
+// separated in the order they were added, and its checklist as a JSON array.
+const taskColumns = `t.id, t.task_list_id, t.parent_id, t.title, t.description, t.due_date,
+	t.recurrence_rule, t.recurrence_timezone, t.recurrence_start, t.completed, t.completed_at,
+	(SELECT group_concat(tl.label_id, ',' ORDER BY tl.rowid) FROM task_labels tl WHERE tl.task_id = t.id),
+	(SELECT json_group_array(json_object('id', ci.id, 'title', ci.title, 'done', json(CASE WHEN ci.done THEN 'true' ELSE 'false' END)))
+		FROM (SELECT * FROM checklist_items WHERE task_id = t.id ORDER BY position) ci),
+	t.created_at, t.updated_at`
+
+func scanTask(row scanner) (models.Task, error) {
+	var task models.Task
+	var id, taskListID, timezone, checklist string
+	var parentID, rule, labelIDs sql.NullString
+	var start, completedAt sql.NullTime
+	err := row.Scan(&id, &taskListID, &parentID, &task.Title, &task.Description, &task.DueDate,
+		&rule, &timezone, &start, &task.Completed, &completedAt, &labelIDs, &checklist, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		return task, err
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+	task.ID = objectID(id)
+	task.TaskListID = objectID(taskListID)
+	if parentID.Valid {
+		parent := objectID(parentID.String)
+		task.ParentID = &parent
+	}
+	if rule.Valid && start.Valid {
+		task.Recurrence = &models.Recurrence{Rule: rule.String, Timezone: timezone, Start: start.Time}
+	}
+	if labelIDs.String != "" {
+		for _, labelID := range strings.Split(labelIDs.String, ",") {
+			task.LabelIDs = append(task.LabelIDs, objectID(labelID))
+		}
+	}
+	err = json.Unmarshal([]byte(checklist), &task.Checklist)
+	return task, err
+}
+
+// CreateTask creates a new task in the database.
+func (tr *TaskRepo) CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error) {
+	now := time.Now().UTC()
+	task := models.Task{
+		ID:          primitive.NewObjectID(),
+		TaskListID:  req.TaskListID,
+		ParentID:    req.ParentID,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     req.DueDate.UTC(),
+		Recurrence:  req.Recurrence,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	labelIDs := hexIDs(req.LabelIDs)
+	for _, labelID := range labelIDs {
+		task.LabelIDs = append(task.LabelIDs, objectID(labelID))
+	}
+
+	err := withTx(ctx, tr.db, func(tx *sql.Tx) error {
+		rule, timezone, start := recurrenceValues(task.Recurrence)
+		_, err := tx.ExecContext(ctx, `INSERT INTO tasks (id, task_list_id, parent_id, title, description, due_date,
+				recurrence_rule, recurrence_timezone, recurrence_start, completed, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			task.ID.Hex(), task.TaskListID.Hex(), parentHex(task.ParentID), task.Title, task.Description, task.DueDate,
+			rule, timezone, start, task.Completed, task.CreatedAt, task.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return insertTaskLabels(ctx, tx, task.ID.Hex(), labelIDs)
+	})
+	if err != nil {
+		tr.log.Error("Error creating task", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// parentHex returns the parent_id column value of a task.
+func parentHex(parentID *primitive.ObjectID) sql.NullString {
+	if parentID == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: parentID.Hex(), Valid: true}
+}
+
+// insertTaskLabels links labels to a task one by one so their order follows
+// the order they were given in. Existing links are kept as they are.
+func insertTaskLabels(ctx context.Context, tx *sql.Tx, taskID string, labelIDs []string) error {
+	for _, labelID := range labelIDs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO task_labels (task_id, label_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, taskID, labelID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTask retrieves a task by ID.
+func (tr *TaskRepo) GetTask(ctx context.Context, taskID string) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	task, err := scanTask(tr.db.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks t WHERE t.id = ?`, taskID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Task{}, models.ErrTaskNotFound
+		}
+		tr.log.Error("Error retrieving task", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// UpdateTask updates an existing task.
+func (tr *TaskRepo) UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error) {
+	// Completing a completed task keeps the time it was completed at
+	now := time.Now().UTC()
+	res, err := tr.db.ExecContext(ctx, `UPDATE tasks SET title = ?, description = ?, due_date = ?, completed = ?, updated_at = ?,
+			completed_at = CASE WHEN ? THEN COALESCE(completed_at, ?) END
+		WHERE id = ?`,
+		req.Title, req.Description, req.DueDate.UTC(), req.Completed, now, req.Completed, now, req.ID.Hex())
+	if err != nil {
+		tr.log.Error("Error updating task", logger.Error(err))
+		return models.Task{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	return tr.GetTask(ctx, req.ID.Hex())
+}
+
+// DeleteTask removes a task from the database. The parent_id foreign key
+// removes its subtasks.
+func (tr *TaskRepo) DeleteTask(ctx context.Context, taskID string) error {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.ErrTaskNotFound
+	}
+
+	res, err := tr.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, taskID)
+	if err != nil {
+		tr.log.Error("Error deleting task", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrTaskNotFound
+	}
+	return nil
+}
+
+// AddTaskLabels attaches labels to a task. Labels already on the task are
+// left as they are.
+func (tr *TaskRepo) AddTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(ctx, taskID, func(tx *sql.Tx) error {
+		return insertTaskLabels(ctx, tx, taskID, hexIDs(labelIDs))
+	})
+}
+
+// RemoveTaskLabels detaches labels from a task.
+func (tr *TaskRepo) RemoveTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateTaskLabels(ctx, taskID, func(tx *sql.Tx) error {
+		in, args := inList(hexIDs(labelIDs))
+		_, err := tx.ExecContext(ctx, `DELETE FROM task_labels WHERE task_id = ? AND label_id IN `+in, append([]any{taskID}, args...)...)
+		return err
+	})
+}
+
+func (tr *TaskRepo) updateTaskLabels(ctx context.Context, taskID string, change func(tx *sql.Tx) error) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	err := withTx(ctx, tr.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE tasks SET updated_at = ? WHERE id = ?`, time.Now().UTC(), taskID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return models.ErrTaskNotFound
+		}
+		return change(tx)
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrTaskNotFound) {
+			tr.log.Error("Error updating task labels", logger.Error(err))
+		}
+		return models.Task{}, err
+	}
+
+	return tr.GetTask(ctx, taskID)
+}
+
+// SetTaskRecurrence makes a task repeat, or stop repeating with nil.
+func (tr *TaskRepo) SetTaskRecurrence(ctx context.Context, taskID string, recurrence *models.Recurrence) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	rule, timezone, start := recurrenceValues(recurrence)
+	res, err := tr.db.ExecContext(ctx, `UPDATE tasks SET recurrence_rule = ?, recurrence_timezone = ?, recurrence_start = ?, updated_at = ? WHERE id = ?`,
+		rule, timezone, start, time.Now().UTC(), taskID)
+	if err != nil {
+		tr.log.Error("Error updating task recurrence", logger.Error(err))
+		return models.Task{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	return tr.GetTask(ctx, taskID)
+}
+
+// recurrenceValues returns the recurrence columns of a task. Tasks that do
+// not repeat have no rule and no start.
+func recurrenceValues(recurrence *models.Recurrence) (rule sql.NullString, timezone string, start sql.NullTime) {
+	if recurrence == nil {
+		return rule, "", start
+	}
+	return sql.NullString{String: recurrence.Rule, Valid: true}, recurrence.Timezone, sql.NullTime{Time: recurrence.Start.UTC(), Valid: true}
+}
+
+// AddChecklistItem appends an open item to the checklist of a task.
+func (tr *TaskRepo) AddChecklistItem(ctx context.Context, taskID string, req models.CreateChecklistItem) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO checklist_items (id, task_id, title, position)
+			SELECT ?, ?, ?, COALESCE(max(position), 0) + 1 FROM checklist_items WHERE task_id = ?`,
+			primitive.NewObjectID().Hex(), taskID, req.Title, taskID)
+		return err
+	})
+}
+
+// UpdateChecklistItem changes the title or state of a checklist item.
+func (tr *TaskRepo) UpdateChecklistItem(ctx context.Context, taskID string, req models.UpdateChecklistItem) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE checklist_items SET title = COALESCE(?, title), done = COALESCE(?, done)
+			WHERE id = ? AND task_id = ?`, req.Title, req.Done, req.ID.Hex(), taskID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return models.ErrChecklistItemNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteChecklistItem removes an item from the checklist of a task.
+func (tr *TaskRepo) DeleteChecklistItem(ctx context.Context, taskID string, itemID primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM checklist_items WHERE id = ? AND task_id = ?`, itemID.Hex(), taskID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return models.ErrChecklistItemNotFound
+		}
+		return nil
+	})
+}
+
+// ReorderChecklist puts the items of a checklist in the order of itemIDs.
+func (tr *TaskRepo) ReorderChecklist(ctx context.Context, taskID string, itemIDs []primitive.ObjectID) (models.Task, error) {
+	return tr.updateChecklist(ctx, taskID, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM checklist_items WHERE task_id = ?`, taskID)
+		if err != nil {
+			return err
+		}
+		checklist, err := collectRows(rows, func(row scanner) (models.ChecklistItem, error) {
+			var id string
+			err := row.Scan(&id)
+			return models.ChecklistItem{ID: objectID(id)}, err
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := models.ReorderChecklistItems(checklist, itemIDs); err != nil {
+			return err
+		}
+
+		for i, id := range itemIDs {
+			_, err := tx.ExecContext(ctx, `UPDATE checklist_items SET position = ? WHERE id = ? AND task_id = ?`, i+1, id.Hex(), taskID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateChecklist changes the checklist of a task in a transaction that also
+// bumps its updated_at.
+func (tr *TaskRepo) updateChecklist(ctx context.Context, taskID string, change func(tx *sql.Tx) error) (models.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskID); err != nil {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+
+	err := withTx(ctx, tr.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE tasks SET updated_at = ? WHERE id = ?`, time.Now().UTC(), taskID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return models.ErrTaskNotFound
+		}
+		return change(tx)
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrTaskNotFound) && !errors.Is(err, models.ErrChecklistItemNotFound) && !errors.Is(err, models.ErrInvalidInput) {
+			tr.log.Error("Error updating task checklist", logger.Error(err))
+		}
+		return models.Task{}, err
+	}
+
+	return tr.GetTask(ctx, taskID)
+}
+
+// CountSubtasks counts the subtasks of each of the parents.
+func (tr *TaskRepo) CountSubtasks(ctx context.Context, parentIDs []primitive.ObjectID) (map[primitive.ObjectID]models.SubtaskCount, error) {
+	in, args := inList(hexIDs(parentIDs))
+	rows, err := tr.db.QueryContext(ctx, `SELECT parent_id, count(*), sum(completed) FROM tasks WHERE parent_id IN `+in+` GROUP BY parent_id`, args...)
+	if err != nil {
+		tr.log.Error("Error counting subtasks", logger.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[primitive.ObjectID]models.SubtaskCount{}
+	for rows.Next() {
+		var parentID string
+		var count models.SubtaskCount
+		if err := rows.Scan(&parentID, &count.Total, &count.Completed); err != nil {
+			tr.log.Error("Error decoding subtask counts", logger.Error(err))
+			return nil, err
+		}
+		counts[objectID(parentID)] = count
+	}
+	if err := rows.Err(); err != nil {
+		tr.log.Error("Error decoding subtask counts", logger.Error(err))
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// CompleteSubtasks completes the open subtasks of a task.
+func (tr *TaskRepo) CompleteSubtasks(ctx context.Context, parentID primitive.ObjectID) error {
+	now := time.Now().UTC()
+	_, err := tr.db.ExecContext(ctx, `UPDATE tasks SET completed = TRUE, completed_at = ?, updated_at = ? WHERE parent_id = ? AND NOT completed`,
+		now, now, parentID.Hex())
+	if err != nil {
+		tr.log.Error("Error completing subtasks", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetAllTasks retrieves the tasks matching the filter with pagination. The
+// search is a full-text search over title and description.
+func (tr *TaskRepo) GetAllTasks(ctx context.Context, taskFilter models.TaskFilter, page models.Pagination) ([]models.Task, int64, error) {
+	filter, args := taskFilterWhere(taskFilter, time.Now())
+	clause, pageArgs := pageClause("t.", taskFilter.SortKeys(), page, args)
+
+	rows, err := tr.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks t WHERE `+filter+clause, pageArgs...)
+	if err != nil {
+		tr.log.Error("Error retrieving tasks", logger.Error(err))
+		return nil, 0, err
+	}
+	tasks, err := collectRows(rows, scanTask)
+	if err != nil {
+		tr.log.Error("Error decoding tasks", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := tr.db.QueryRowContext(ctx, `SELECT count(*) FROM tasks t WHERE `+filter, args...).Scan(&count); err != nil {
+		tr.log.Error("Error counting tasks", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return tasks, count, nil
+}
+
+// taskFilterWhere translates a task filter into a condition on "tasks t".
+// Tasks without a due date store the zero time.
+func taskFilterWhere(filter models.TaskFilter, now time.Time) (string, []any) {
+	in, args := inList(hexIDs(filter.TaskListIDs))
+	where := []string{"t.task_list_id IN " + in}
+	add := func(condition string, values ...any) {
+		where = append(where, condition)
+		for _, value := range values {
+			args = append(args, sqlValue(value))
+		}
+	}
+
+	if filter.ParentID != nil {
+		add("t.parent_id = ?", filter.ParentID.Hex())
+	}
+
+	if filter.Search != "" {
+		condition, searchArgs := searchCondition("tasks", "t", []string{"title", "description"}, filter.Search)
+		add(condition, searchArgs...)
+	}
+
+	if len(filter.Labels.LabelIDs) > 0 {
+		labelIDs := hexIDs(filter.Labels.LabelIDs)
+		in, labelArgs := inList(labelIDs)
+		if filter.Labels.Match == models.LabelMatchAll {
+			add("(SELECT count(*) FROM task_labels tl WHERE tl.task_id = t.id AND tl.label_id IN "+in+") = ?", append(labelArgs, len(labelIDs))...)
+		} else {
+			add("EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = t.id AND tl.label_id IN "+in+")", labelArgs...)
+		}
+	}
+
+	if filter.Completed != nil {
+		add("t.completed = ?", *filter.Completed)
+	}
+	if filter.Overdue {
+		add("NOT t.completed AND t.due_date > ? AND t.due_date < ?", time.Time{}, now)
+	}
+	if filter.NoDueDate {
+		add("t.due_date = ?", time.Time{})
+	}
+	if filter.DueBefore != nil {
+		add("t.due_date > ? AND t.due_date < ?", time.Time{}, *filter.DueBefore)
+	}
+	if filter.DueAfter != nil {
+		add("t.due_date > ?", *filter.DueAfter)
+	}
+	if filter.CreatedBefore != nil {
+		add("t.created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.CreatedAfter != nil {
+		add("t.created_at > ?", *filter.CreatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		add("t.updated_at < ?", *filter.UpdatedBefore)
+	}
+	if filter.UpdatedAfter != nil {
+		add("t.updated_at > ?", *filter.UpdatedAfter)
+	}
+	if filter.CompletedBefore != nil {
+		add("t.completed_at < ?", *filter.CompletedBefore)
+	}
+	if filter.CompletedAfter != nil {
+		add("t.completed_at > ?", *filter.CompletedAfter)
+	}
+	if filter.Query != nil {
+		where = append(where, queryCondition(filter.Query, now, func(value any) string {
+			args = append(args, sqlValue(value))
+			return "?"
+		}))
+	}
+
+	return strings.Join(where, " AND "), args
+}
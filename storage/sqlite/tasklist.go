@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskListRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewTaskListRepo(db *sql.DB, log logger.ILogger) *TaskListRepo {
+	return &TaskListRepo{db: db, log: log}
+}
+
+const taskListColumns = "id, user_id, title, description, created_at, updated_at"
+
+func scanTaskList(row scanner) (models.TaskList, error) {
+	var taskList models.TaskList
+	var id, userID string
+	err := row.Scan(&id, &userID, &taskList.Title, &taskList.Description, &taskList.CreatedAt, &taskList.UpdatedAt)
+	taskList.ID = objectID(id)
+	taskList.UserID = objectID(userID)
+	return taskList, err
+}
+
+// CreateTaskList creates a new task list in the database.
+func (tlr *TaskListRepo) CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error) {
+	now := time.Now().UTC()
+	taskList := models.TaskList{
+		ID:          primitive.NewObjectID(),
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	_, err := tlr.db.ExecContext(ctx, `INSERT INTO task_lists (`+taskListColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		taskList.ID.Hex(), taskList.UserID.Hex(), taskList.Title, taskList.Description, taskList.CreatedAt, taskList.UpdatedAt)
+	if err != nil {
+		tlr.log.Error("Error creating task list", logger.Error(err))
+		return models.TaskList{}, err
+	}
+
+	return taskList, nil
+}
+
+// GetTaskList retrieves a task list by ID.
+func (tlr *TaskListRepo) GetTaskList(ctx context.Context, taskListID string) (models.TaskList, error) {
+	if _, err := primitive.ObjectIDFromHex(taskListID); err != nil {
+		return models.TaskList{}, models.ErrTaskListNotFound
+	}
+
+	taskList, err := scanTaskList(tlr.db.QueryRowContext(ctx, `SELECT `+taskListColumns+` FROM task_lists WHERE id = ?`, taskListID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TaskList{}, models.ErrTaskListNotFound
+		}
+		tlr.log.Error("Error retrieving task list", logger.Error(err))
+		return models.TaskList{}, err
+	}
+
+	return taskList, nil
+}
+
+// UpdateTaskList updates an existing task list.
+func (tlr *TaskListRepo) UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error) {
+	taskList, err := scanTaskList(tlr.db.QueryRowContext(ctx,
+		`UPDATE task_lists SET title = ?, description = ?, updated_at = ? WHERE id = ? RETURNING `+taskListColumns,
+		req.Title, req.Description, time.Now().UTC(), req.ID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TaskList{}, models.ErrTaskListNotFound
+		}
+		tlr.log.Error("Error updating task list", logger.Error(err))
+		return models.TaskList{}, err
+	}
+
+	return taskList, nil
+}
+
+// DeleteTaskList removes a task list in a single transaction; the foreign key
+// cascades to its tasks. With dryRun nothing is deleted and the summary holds
+// what would have been removed.
+func (tlr *TaskListRepo) DeleteTaskList(ctx context.Context, taskListID string, dryRun bool) (models.DeleteSummary, error) {
+	summary := models.DeleteSummary{DryRun: dryRun}
+
+	if _, err := primitive.ObjectIDFromHex(taskListID); err != nil {
+		return summary, models.ErrTaskListNotFound
+	}
+
+	err := withTx(ctx, tlr.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT
+			(SELECT count(*) FROM task_lists WHERE id = ?1),
+			(SELECT count(*) FROM tasks WHERE task_list_id = ?1),
+			(SELECT count(*) FROM reminders WHERE task_id IN (SELECT id FROM tasks WHERE task_list_id = ?1))`, taskListID).
+			Scan(&summary.TaskLists, &summary.Tasks, &summary.Reminders)
+		if err != nil {
+			return err
+		}
+		if summary.TaskLists == 0 {
+			return models.ErrTaskListNotFound
+		}
+		if dryRun {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM task_lists WHERE id = ?`, taskListID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrTaskListNotFound) {
+			tlr.log.Error("Error deleting task list", logger.Error(err))
+		}
+		return models.DeleteSummary{DryRun: dryRun}, err
+	}
+	return summary, nil
+}
+
+// GetAllTaskLists retrieves all task lists for a user with pagination.
+func (tlr *TaskListRepo) GetAllTaskLists(ctx context.Context, userID string, page models.Pagination) ([]models.TaskList, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	clause, args := pageClause("", models.DefaultSort, page, []any{userID})
+
+	rows, err := tlr.db.QueryContext(ctx, `SELECT `+taskListColumns+` FROM task_lists WHERE user_id = ?`+clause, args...)
+	if err != nil {
+		tlr.log.Error("Error retrieving task lists", logger.Error(err))
+		return nil, 0, err
+	}
+	taskLists, err := collectRows(rows, scanTaskList)
+	if err != nil {
+		tlr.log.Error("Error decoding task lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := tlr.db.QueryRowContext(ctx, `SELECT count(*) FROM task_lists WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		tlr.log.Error("Error counting task lists", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return taskLists, count, nil
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TokenRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewTokenRepo(db *sql.DB, log logger.ILogger) *TokenRepo {
+	return &TokenRepo{db: db, log: log}
+}
+
+// CreateRefreshToken stores a hashed refresh token.
+func (tr *TokenRepo) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	token.CreatedAt = time.Now().UTC()
+
+	_, err := tr.db.ExecContext(ctx, `INSERT INTO refresh_tokens (id, user_id, family_id, device_id, token_hash, used, revoked, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID.Hex(), token.UserID.Hex(), token.FamilyID, token.DeviceID, token.TokenHash, token.Used, token.Revoked, token.ExpiresAt.UTC(), token.CreatedAt)
+	if err != nil {
+		tr.log.Error("Error creating refresh token", logger.Error(err))
+		return fmt.Errorf("error while creating refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by the hash of its value.
+func (tr *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	var id, userID string
+	err := tr.db.QueryRowContext(ctx, `SELECT id, user_id, family_id, device_id, token_hash, used, revoked, expires_at, created_at
+		FROM refresh_tokens WHERE token_hash = ?`, tokenHash).
+		Scan(&id, &userID, &token.FamilyID, &token.DeviceID, &token.TokenHash, &token.Used, &token.Revoked, &token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, fmt.Errorf("refresh token not found: %w", err)
+		}
+		tr.log.Error("Error retrieving refresh token", logger.Error(err))
+		return models.RefreshToken{}, fmt.Errorf("error while getting refresh token: %w", err)
+	}
+	token.ID = objectID(id)
+	token.UserID = objectID(userID)
+
+	return token, nil
+}
+
+// MarkRefreshTokenUsed flags an unused refresh token as used. It reports false
+// when the token had already been used or revoked, so that two concurrent
+// refreshes with the same token cannot both succeed.
+func (tr *TokenRepo) MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return false, fmt.Errorf("invalid refresh token id: %w", err)
+	}
+
+	res, err := tr.db.ExecContext(ctx, `UPDATE refresh_tokens SET used = TRUE WHERE id = ? AND NOT used AND NOT revoked`, id)
+	if err != nil {
+		tr.log.Error("Error marking refresh token used", logger.Error(err))
+		return false, fmt.Errorf("error while marking refresh token used: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RevokeTokenFamily revokes every refresh token descended from the same login.
+func (tr *TokenRepo) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	return tr.revoke(ctx, "family_id = ?", familyID)
+}
+
+// RevokeDeviceTokens revokes the refresh tokens a user holds on one device.
+func (tr *TokenRepo) RevokeDeviceTokens(ctx context.Context, userID, deviceID string) error {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	return tr.revoke(ctx, "user_id = ? AND device_id = ?", userID, deviceID)
+}
+
+// RevokeUserTokens revokes all refresh tokens of a user.
+func (tr *TokenRepo) RevokeUserTokens(ctx context.Context, userID string) error {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+	return tr.revoke(ctx, "user_id = ?", userID)
+}
+
+func (tr *TokenRepo) revoke(ctx context.Context, filter string, args ...any) error {
+	_, err := tr.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked = TRUE WHERE NOT revoked AND `+filter, args...)
+	if err != nil {
+		tr.log.Error("Error revoking refresh tokens", logger.Error(err))
+		return fmt.Errorf("error while revoking refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"todo/api/models"
+	"todo/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepo struct {
+	db  *sql.DB
+	log logger.ILogger
+}
+
+func NewWebhookRepo(db *sql.DB, log logger.ILogger) *WebhookRepo {
+	return &WebhookRepo{db: db, log: log}
+}
+
+const webhookColumns = "id, user_id, url, events, description, secret, active, created_at, updated_at"
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var id, userID, events string
+	err := row.Scan(&id, &userID, &webhook.URL, &events, &webhook.Description, &webhook.Secret,
+		&webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	webhook.ID = objectID(id)
+	webhook.UserID = objectID(userID)
+	webhook.Events = []string{}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+	return webhook, err
+}
+
+const deliveryColumns = "id, webhook_id, user_id, event_id, event, payload, status, attempts, next_attempt_at, " +
+	"last_error, response_status, response_body, redelivery_of, delivered_at, created_at"
+
+func scanDelivery(row scanner) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var id, webhookID, userID, eventID, payload string
+	var redeliveryOf sql.NullString
+	var nextAttemptAt, deliveredAt sql.NullTime
+	err := row.Scan(&id, &webhookID, &userID, &eventID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &nextAttemptAt, &delivery.LastError, &delivery.ResponseStatus,
+		&delivery.ResponseBody, &redeliveryOf, &deliveredAt, &delivery.CreatedAt)
+	delivery.ID = objectID(id)
+	delivery.WebhookID = objectID(webhookID)
+	delivery.UserID = objectID(userID)
+	delivery.EventID = objectID(eventID)
+	delivery.Payload = []byte(payload)
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if redeliveryOf.Valid {
+		id := objectID(redeliveryOf.String)
+		delivery.RedeliveryOf = &id
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, err
+}
+
+// CreateWebhook stores a new, active webhook.
+func (wr *WebhookRepo) CreateWebhook(ctx context.Context, req models.CreateWebhook) (models.Webhook, error) {
+	now := time.Now().UTC()
+	webhook, err := scanWebhook(wr.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?, ?, TRUE, ?, ?) RETURNING `+webhookColumns,
+		primitive.NewObjectID().Hex(), req.UserID.Hex(), req.URL, strings.Join(req.Events, ","), req.Description, req.Secret, now, now))
+	if err != nil {
+		wr.log.Error("Error creating webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (wr *WebhookRepo) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return models.Webhook{}, models.ErrWebhookNotFound
+	}
+
+	webhook, err := scanWebhook(wr.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, models.ErrWebhookNotFound
+		}
+		wr.log.Error("Error retrieving webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// GetAllWebhooks retrieves the webhooks of a user with pagination support.
+func (wr *WebhookRepo) GetAllWebhooks(ctx context.Context, userID string, page models.Pagination) ([]models.Webhook, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return nil, 0, models.ErrUserNotFound
+	}
+	clause, args := pageClause("", models.DefaultSort, page, []any{userID})
+
+	webhooks, err := wr.query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ?`+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := wr.db.QueryRowContext(ctx, `SELECT count(*) FROM webhooks WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		wr.log.Error("Error counting webhooks", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return webhooks, count, nil
+}
+
+// UpdateWebhook replaces the endpoint, events and description of a webhook,
+// and pauses or resumes it.
+func (wr *WebhookRepo) UpdateWebhook(ctx context.Context, req models.UpdateWebhook) (models.Webhook, error) {
+	var active sql.NullBool
+	if req.Active != nil {
+		active = sql.NullBool{Bool: *req.Active, Valid: true}
+	}
+
+	webhook, err := scanWebhook(wr.db.QueryRowContext(ctx,
+		`UPDATE webhooks SET url = ?, events = ?, description = ?, active = COALESCE(?, active), updated_at = ?
+		WHERE id = ? RETURNING `+webhookColumns,
+		req.URL, strings.Join(req.Events, ","), req.Description, active, time.Now().UTC(), req.ID.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, models.ErrWebhookNotFound
+		}
+		wr.log.Error("Error updating webhook", logger.Error(err))
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook. The webhook_deliveries foreign key removes
+// its deliveries.
+func (wr *WebhookRepo) DeleteWebhook(ctx context.Context, id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return models.ErrWebhookNotFound
+	}
+
+	res, err := wr.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		wr.log.Error("Error deleting webhook", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SubscribedWebhooks returns the active webhooks of a user subscribed to
+// event, the oldest first.
+func (wr *WebhookRepo) SubscribedWebhooks(ctx context.Context, userID primitive.ObjectID, event string) ([]models.Webhook, error) {
+	return wr.query(ctx, `SELECT `+webhookColumns+` FROM webhooks
+		WHERE user_id = ? AND active AND instr(',' || events || ',', ',' || ? || ',') > 0 ORDER BY created_at, id`,
+		userID.Hex(), event)
+}
+
+// EnqueueDelivery queues an event for a webhook.
+func (wr *WebhookRepo) EnqueueDelivery(ctx context.Context, req models.EnqueueDelivery) (models.WebhookDelivery, error) {
+	var redeliveryOf sql.NullString
+	if req.RedeliveryOf != nil {
+		redeliveryOf = sql.NullString{String: req.RedeliveryOf.Hex(), Valid: true}
+	}
+
+	delivery, err := scanDelivery(wr.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (id, webhook_id, user_id, event_id, event, payload, status, next_attempt_at, redelivery_of, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING `+deliveryColumns,
+		primitive.NewObjectID().Hex(), req.WebhookID.Hex(), req.UserID.Hex(), req.EventID.Hex(), req.Event,
+		string(req.Payload), models.DeliveryPending, req.NextAttemptAt.UTC(), redeliveryOf, time.Now().UTC()))
+	if err != nil {
+		wr.log.Error("Error enqueuing webhook delivery", logger.Error(err))
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// GetDelivery retrieves a delivery by its ID.
+func (wr *WebhookRepo) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+	}
+
+	delivery, err := scanDelivery(wr.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookDelivery{}, models.ErrDeliveryNotFound
+		}
+		wr.log.Error("Error retrieving webhook delivery", logger.Error(err))
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries returns the delivery log of a webhook, the latest first.
+func (wr *WebhookRepo) GetDeliveries(ctx context.Context, webhookID string, page models.Pagination) ([]models.WebhookDelivery, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(webhookID); err != nil {
+		return nil, 0, models.ErrWebhookNotFound
+	}
+	clause, args := pageClause("", models.DeliverySort, page, []any{webhookID})
+
+	deliveries, err := wr.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id = ?`+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	if err := wr.db.QueryRowContext(ctx, `SELECT count(*) FROM webhook_deliveries WHERE webhook_id = ?`, webhookID).Scan(&count); err != nil {
+		wr.log.Error("Error counting webhook deliveries", logger.Error(err))
+		return nil, 0, err
+	}
+
+	return deliveries, count, nil
+}
+
+// DueDeliveries returns the pending deliveries due at or before now.
+func (wr *WebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return wr.queryDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		models.DeliveryPending, now.UTC(), limit)
+}
+
+// RecordAttempt stores the outcome of posting a delivery, unless it is no
+// longer pending at the time it was read.
+func (wr *WebhookRepo) RecordAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	res, err := wr.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = ?, last_error = ?,
+			response_status = ?, response_body = ?, next_attempt_at = ?, delivered_at = ?
+		WHERE id = ? AND status = ? AND next_attempt_at = ?`,
+		attempt.Status, attempt.Attempts, attempt.LastError, attempt.ResponseStatus, attempt.ResponseBody,
+		nullTime(attempt.NextAttemptAt), nullTime(attempt.DeliveredAt),
+		attempt.ID.Hex(), models.DeliveryPending, attempt.ScheduledAt.UTC())
+	if err != nil {
+		wr.log.Error("Error recording webhook attempt", logger.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (wr *WebhookRepo) query(ctx context.Context, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := wr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		wr.log.Error("Error retrieving webhooks", logger.Error(err))
+		return nil, err
+	}
+	webhooks, err := collectRows(rows, scanWebhook)
+	if err != nil {
+		wr.log.Error("Error decoding webhooks", logger.Error(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (wr *WebhookRepo) queryDeliveries(ctx context.Context, query string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := wr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		wr.log.Error("Error retrieving webhook deliveries", logger.Error(err))
+		return nil, err
+	}
+	deliveries, err := collectRows(rows, scanDelivery)
+	if err != nil {
+		wr.log.Error("Error decoding webhook deliveries", logger.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
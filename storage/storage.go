@@ -2,48 +2,50 @@ package storage
 
 import (
 	"context"
+	"time"
 	"todo/api/models"
 
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type MongoDB struct {
-	userCollection     *mongo.Collection
-	taskCollection     *mongo.Collection
-	taskListCollection *mongo.Collection
-	labelCollection    *mongo.Collection
-}
-
-// NewStorage initializes a new MongoDB instance with the required collections.
-func NewStorage(dbURI string) (*MongoDB, error) {
-	clientOptions := options.Client().ApplyURI(dbURI)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	// Ensure MongoDB connection is established
-	if err := client.Ping(context.TODO(), nil); err != nil {
-		return nil, err
-	}
-
-	db := client.Database("todoapp")
-	return &MongoDB{
-		userCollection:     db.Collection("users"),
-		taskCollection:     db.Collection("tasks"),
-		taskListCollection: db.Collection("task_lists"),
-		labelCollection:    db.Collection("labels"),
-	}, nil
+// Storage bundles the repositories of one storage backend.
+//
+// The GetAll* listings sort by creation time, or the order of the filter for
+// tasks, then by ID, and return the requested page together with the number
+// of items matching the filter, regardless of the page.
+type Storage struct {
+	UserRepo          UserStorage
+	LabelRepo         LabelStorage
+	TaskRepo          TaskStorage
+	TaskListRepo      TaskListStorage
+	TokenRepo         TokenStorage
+	RegistrationRepo  RegistrationStorage
+	PasswordResetRepo PasswordResetStorage
+	SmartListRepo     SmartListStorage
+	SearchRepo        SearchStorage
+	ReminderRepo      ReminderStorage
+	NotificationRepo  NotificationStorage
+	DigestRepo        DigestStorage
+	WebhookRepo       WebhookStorage
 }
 
 // UserStorage defines the methods for user storage operations.
 type UserStorage interface {
 	CreateUser(ctx context.Context, req models.CreateUser) (models.User, error)
 	GetUser(ctx context.Context, userID string) (models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	UpdatePassword(ctx context.Context, userID string, passwordHash string) error
+	UpdateUserRole(ctx context.Context, userID string, role string) error
+	SetUserDisabled(ctx context.Context, userID string, disabled bool) error
+	// BumpTokenVersion invalidates every access token issued to the user so
+	// far.
+	BumpTokenVersion(ctx context.Context, userID string) error
 	UpdateUser(ctx context.Context, req models.UpdateUser) (models.User, error)
-	DeleteUser(ctx context.Context, userID string) error
-	GetAllUsers(ctx context.Context, page, limit uint64) ([]models.User, int64, error)
+	// DeleteUser removes the user with everything they own; dryRun only
+	// reports what would be removed.
+	DeleteUser(ctx context.Context, userID string, dryRun bool) (models.DeleteSummary, error)
+	GetAllUsers(ctx context.Context, page models.Pagination) ([]models.User, int64, error)
+	CountUsersWithRole(ctx context.Context, role string) (int64, error)
 }
 
 // LabelStorage defines the methods for label storage operations.
@@ -51,8 +53,9 @@ type LabelStorage interface {
 	CreateLabel(ctx context.Context, req models.CreateLabel) (models.Label, error)
 	GetLabel(ctx context.Context, labelID string) (models.Label, error)
 	UpdateLabel(ctx context.Context, req models.UpdateLabel) (models.Label, error)
+	// DeleteLabel removes the label and drops it from every task carrying it.
 	DeleteLabel(ctx context.Context, labelID string) error
-	GetAllLabels(ctx context.Context, userID string, search string, page, limit uint64) ([]models.Label, int64, error)
+	GetAllLabels(ctx context.Context, userID string, search string, page models.Pagination) ([]models.Label, int64, error)
 }
 
 // TaskStorage defines the methods for task storage operations.
@@ -60,8 +63,25 @@ type TaskStorage interface {
 	CreateTask(ctx context.Context, req models.CreateTask) (models.Task, error)
 	GetTask(ctx context.Context, taskID string) (models.Task, error)
 	UpdateTask(ctx context.Context, req models.UpdateTask) (models.Task, error)
+	// DeleteTask removes the task together with its subtasks.
 	DeleteTask(ctx context.Context, taskID string) error
-	GetAllTasks(ctx context.Context, userID string, search string, page, limit uint64) ([]models.Task, int64, error)
+	GetAllTasks(ctx context.Context, filter models.TaskFilter, page models.Pagination) ([]models.Task, int64, error)
+	AddTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error)
+	RemoveTaskLabels(ctx context.Context, taskID string, labelIDs []primitive.ObjectID) (models.Task, error)
+	// SetTaskRecurrence makes a task repeat, or stop repeating with nil.
+	SetTaskRecurrence(ctx context.Context, taskID string, recurrence *models.Recurrence) (models.Task, error)
+	// AddChecklistItem appends an open item to the checklist of a task.
+	AddChecklistItem(ctx context.Context, taskID string, req models.CreateChecklistItem) (models.Task, error)
+	UpdateChecklistItem(ctx context.Context, taskID string, req models.UpdateChecklistItem) (models.Task, error)
+	DeleteChecklistItem(ctx context.Context, taskID string, itemID primitive.ObjectID) (models.Task, error)
+	// ReorderChecklist puts the items of a checklist in the order of itemIDs,
+	// which must list every item exactly once.
+	ReorderChecklist(ctx context.Context, taskID string, itemIDs []primitive.ObjectID) (models.Task, error)
+	// CountSubtasks counts the subtasks of each of the parents. Parents
+	// without subtasks are left out.
+	CountSubtasks(ctx context.Context, parentIDs []primitive.ObjectID) (map[primitive.ObjectID]models.SubtaskCount, error)
+	// CompleteSubtasks completes the open subtasks of a task.
+	CompleteSubtasks(ctx context.Context, parentID primitive.ObjectID) error
 }
 
 // TaskListStorage defines the methods for task list storage operations.
@@ -69,6 +89,142 @@ type TaskListStorage interface {
 	CreateTaskList(ctx context.Context, req models.CreateTaskList) (models.TaskList, error)
 	GetTaskList(ctx context.Context, taskListID string) (models.TaskList, error)
 	UpdateTaskList(ctx context.Context, req models.UpdateTaskList) (models.TaskList, error)
-	DeleteTaskList(ctx context.Context, taskListID string) error
-	GetAllTaskLists(ctx context.Context, userID string, page, limit uint64) ([]models.TaskList, int64, error)
+	// DeleteTaskList removes the list with its tasks; dryRun only reports what
+	// would be removed.
+	DeleteTaskList(ctx context.Context, taskListID string, dryRun bool) (models.DeleteSummary, error)
+	GetAllTaskLists(ctx context.Context, userID string, page models.Pagination) ([]models.TaskList, int64, error)
+}
+
+// SmartListStorage defines the methods for smart list storage operations.
+type SmartListStorage interface {
+	CreateSmartList(ctx context.Context, req models.CreateSmartList) (models.SmartList, error)
+	GetSmartList(ctx context.Context, smartListID string) (models.SmartList, error)
+	UpdateSmartList(ctx context.Context, req models.UpdateSmartList) (models.SmartList, error)
+	DeleteSmartList(ctx context.Context, smartListID string) error
+	GetAllSmartLists(ctx context.Context, userID string, page models.Pagination) ([]models.SmartList, int64, error)
+}
+
+// SearchStorage defines the full-text search over a user's tasks, task lists
+// and labels.
+type SearchStorage interface {
+	// Search returns, for each requested type, the best scoring items
+	// matching any of the terms, leaving Snippet empty.
+	Search(ctx context.Context, userID string, query models.SearchQuery) ([]models.SearchResult, error)
+}
+
+// ReminderStorage defines the methods for task reminder storage operations.
+// The SQL backends delete the reminders of a task together with the task; on
+// the others they are left for the scheduler to remove once they fall due.
+type ReminderStorage interface {
+	// SetReminders replaces the reminders of a task with ones firing the
+	// given minutes before dueDate, or never while dueDate is zero. Reminders
+	// whose offset is kept stay as they are, so one already sent is not sent
+	// again.
+	SetReminders(ctx context.Context, userID, taskID primitive.ObjectID, before []int, dueDate time.Time) ([]models.Reminder, error)
+	// GetReminders returns the reminders of a task, the earliest first.
+	GetReminders(ctx context.Context, taskID primitive.ObjectID) ([]models.Reminder, error)
+	// RescheduleReminders moves the reminders of a task to a new due date.
+	// Reminders that move become pending again.
+	RescheduleReminders(ctx context.Context, taskID primitive.ObjectID, dueDate time.Time) error
+	// DueReminders returns up to limit pending reminders due at or before
+	// now, the earliest first.
+	DueReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error)
+	// RecordDelivery stores the outcome of sending a reminder, and moves it
+	// when DeferredTo is set. It fails with ErrReminderNotFound when the
+	// reminder was removed or moved meanwhile.
+	RecordDelivery(ctx context.Context, delivery models.ReminderDelivery) error
+	// DeleteReminders removes the reminders of a task.
+	DeleteReminders(ctx context.Context, taskID primitive.ObjectID) error
+}
+
+// NotificationStorage defines the methods for notification settings storage
+// operations.
+type NotificationStorage interface {
+	// GetNotificationSettings returns the settings of a user, or the defaults
+	// when they never saved any.
+	GetNotificationSettings(ctx context.Context, userID primitive.ObjectID) (models.NotificationSettings, error)
+	// SaveNotificationSettings creates or replaces the settings of a user.
+	SaveNotificationSettings(ctx context.Context, req models.UpdateNotificationSettings) (models.NotificationSettings, error)
+	// GetNotificationPreferences returns the preferences of a user, or the
+	// defaults when they never saved any.
+	GetNotificationPreferences(ctx context.Context, userID primitive.ObjectID) (models.NotificationPreferences, error)
+	// SaveNotificationPreferences creates or replaces the preferences of a
+	// user, leaving their settings as they are.
+	SaveNotificationPreferences(ctx context.Context, req models.UpdateNotificationPreferences) (models.NotificationPreferences, error)
+}
+
+// TokenStorage defines the methods for refresh token storage operations.
+type TokenStorage interface {
+	CreateRefreshToken(ctx context.Context, token models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id string) (bool, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeDeviceTokens(ctx context.Context, userID, deviceID string) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+}
+
+// RegistrationStorage defines the methods for pending registration storage operations.
+type RegistrationStorage interface {
+	// UpsertPendingRegistration stores a pending registration, replacing an
+	// earlier one for the same email only once it expired. While that one is
+	// pending it fails with ErrRegistrationPending.
+	UpsertPendingRegistration(ctx context.Context, reg models.PendingRegistration) error
+	GetPendingRegistration(ctx context.Context, email string) (models.PendingRegistration, error)
+	// IncrementRegistrationAttempts uses up one OTP attempt of a pending
+	// registration. It returns false, counting nothing, once max attempts
+	// were made.
+	IncrementRegistrationAttempts(ctx context.Context, email string, max int) (bool, error)
+	DeletePendingRegistration(ctx context.Context, email string) error
+}
+
+// PasswordResetStorage defines the methods for password reset token storage operations.
+type PasswordResetStorage interface {
+	CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error
+	// UsePasswordReset redeems an unused, unexpired token and uses up every
+	// other reset token of its user in the same step.
+	UsePasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error)
+}
+
+// DigestStorage defines the methods for digest settings storage operations.
+type DigestStorage interface {
+	// GetDigestSettings returns the settings of a user, or the defaults when
+	// they never saved any.
+	GetDigestSettings(ctx context.Context, userID primitive.ObjectID) (models.DigestSettings, error)
+	// SaveDigestSettings creates or replaces the settings of a user.
+	SaveDigestSettings(ctx context.Context, req models.UpdateDigestSettings) (models.DigestSettings, error)
+	// DueDigests returns up to limit settings whose next digest is due at or
+	// before now, the earliest first.
+	DueDigests(ctx context.Context, now time.Time, limit int) ([]models.DigestSettings, error)
+	// RecordDigest stores the outcome of a digest and when the next is sent.
+	// It fails with ErrDigestNotFound when the settings were saved since the
+	// digest fell due.
+	RecordDigest(ctx context.Context, delivery models.DigestDelivery) error
+}
+
+// WebhookStorage defines the methods for webhook storage operations. The
+// deliveries of a webhook are the durable queue of the events to post to it,
+// and its delivery log; they are deleted with the webhook.
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, req models.CreateWebhook) (models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	GetAllWebhooks(ctx context.Context, userID string, page models.Pagination) ([]models.Webhook, int64, error)
+	UpdateWebhook(ctx context.Context, req models.UpdateWebhook) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	// SubscribedWebhooks returns the active webhooks of a user subscribed to
+	// event.
+	SubscribedWebhooks(ctx context.Context, userID primitive.ObjectID, event string) ([]models.Webhook, error)
+	// EnqueueDelivery queues an event for a webhook, pending until it is
+	// posted.
+	EnqueueDelivery(ctx context.Context, req models.EnqueueDelivery) (models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error)
+	// GetDeliveries returns the delivery log of a webhook in DeliverySort
+	// order.
+	GetDeliveries(ctx context.Context, webhookID string, page models.Pagination) ([]models.WebhookDelivery, int64, error)
+	// DueDeliveries returns up to limit pending deliveries due at or before
+	// now, the earliest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// RecordAttempt stores the outcome of posting a delivery. It fails with
+	// ErrDeliveryNotFound when the delivery was removed, or recorded by
+	// someone else, since it fell due.
+	RecordAttempt(ctx context.Context, attempt models.WebhookAttempt) error
 }
@@ -0,0 +1,198 @@
+package storagetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"todo/api/models"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testTokens(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TokenRepo
+	userID := createUser(t, store).ID
+
+	newToken := func(familyID, deviceID string) models.RefreshToken {
+		token := models.RefreshToken{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			FamilyID:  familyID,
+			DeviceID:  deviceID,
+			TokenHash: primitive.NewObjectID().Hex(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		mustNot(t, "CreateRefreshToken", repo.CreateRefreshToken(ctx, token))
+		return token
+	}
+
+	first := newToken("family-1", "phone")
+	got, err := repo.GetRefreshToken(ctx, first.TokenHash)
+	mustNot(t, "GetRefreshToken", err)
+	if got.ID != first.ID || got.UserID != userID || got.FamilyID != "family-1" || got.DeviceID != "phone" || got.Used || got.Revoked {
+		t.Errorf("GetRefreshToken: got %+v, want %+v", got, first)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("CreateRefreshToken: CreatedAt was not set")
+	}
+	if _, err := repo.GetRefreshToken(ctx, "missing"); err == nil {
+		t.Error("GetRefreshToken(missing): got no error")
+	}
+
+	ok, err := repo.MarkRefreshTokenUsed(ctx, first.ID.Hex())
+	mustNot(t, "MarkRefreshTokenUsed", err)
+	if !ok {
+		t.Error("MarkRefreshTokenUsed: first use was rejected")
+	}
+	ok, err = repo.MarkRefreshTokenUsed(ctx, first.ID.Hex())
+	mustNot(t, "MarkRefreshTokenUsed", err)
+	if ok {
+		t.Error("MarkRefreshTokenUsed: second use was accepted")
+	}
+
+	sibling := newToken("family-1", "phone")
+	laptop := newToken("family-2", "laptop")
+	mustNot(t, "RevokeTokenFamily", repo.RevokeTokenFamily(ctx, "family-1"))
+	checkRevoked(t, store, sibling, true)
+	checkRevoked(t, store, laptop, false)
+
+	ok, err = repo.MarkRefreshTokenUsed(ctx, sibling.ID.Hex())
+	mustNot(t, "MarkRefreshTokenUsed", err)
+	if ok {
+		t.Error("MarkRefreshTokenUsed: revoked token was accepted")
+	}
+
+	tablet := newToken("family-3", "tablet")
+	mustNot(t, "RevokeDeviceTokens", repo.RevokeDeviceTokens(ctx, userID.Hex(), "laptop"))
+	checkRevoked(t, store, laptop, true)
+	checkRevoked(t, store, tablet, false)
+
+	mustNot(t, "RevokeUserTokens", repo.RevokeUserTokens(ctx, userID.Hex()))
+	checkRevoked(t, store, tablet, true)
+}
+
+func checkRevoked(t *testing.T, store *storage.Storage, token models.RefreshToken, want bool) {
+	t.Helper()
+	got, err := store.TokenRepo.GetRefreshToken(ctx, token.TokenHash)
+	mustNot(t, "GetRefreshToken", err)
+	if got.Revoked != want {
+		t.Errorf("refresh token %s (%s): revoked is %v, want %v", token.ID.Hex(), token.DeviceID, got.Revoked, want)
+	}
+}
+
+func testRegistrations(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.RegistrationRepo
+	email := "carol@example.com"
+
+	if _, err := repo.GetPendingRegistration(ctx, email); err == nil {
+		t.Error("GetPendingRegistration(missing): got no error")
+	}
+
+	mustNot(t, "UpsertPendingRegistration", repo.UpsertPendingRegistration(ctx, models.PendingRegistration{
+		Username:  "carol",
+		Email:     email,
+		OtpHash:   "otp-1",
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}))
+
+	// Registering again is refused while the first one is pending
+	err := repo.UpsertPendingRegistration(ctx, models.PendingRegistration{
+		Username:  "mallory",
+		Email:     email,
+		OtpHash:   "otp-2",
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	})
+	if !errors.Is(err, models.ErrRegistrationPending) {
+		t.Errorf("UpsertPendingRegistration(pending): got error %v, want %v", err, models.ErrRegistrationPending)
+	}
+
+	for i := 1; i <= 3; i++ {
+		ok, err := repo.IncrementRegistrationAttempts(ctx, email, 2)
+		mustNot(t, "IncrementRegistrationAttempts", err)
+		if ok != (i <= 2) {
+			t.Errorf("IncrementRegistrationAttempts #%d with max 2: got %v", i, ok)
+		}
+	}
+
+	reg, err := repo.GetPendingRegistration(ctx, email)
+	mustNot(t, "GetPendingRegistration", err)
+	if reg.Username != "carol" || reg.OtpHash != "otp-1" || reg.Attempts != 2 || reg.UserID != nil {
+		t.Errorf("GetPendingRegistration: got %+v", reg)
+	}
+
+	mustNot(t, "DeletePendingRegistration", repo.DeletePendingRegistration(ctx, email))
+	if _, err := repo.GetPendingRegistration(ctx, email); err == nil {
+		t.Error("GetPendingRegistration(deleted): got no error")
+	}
+
+	// An expired registration is replaced, here by a change of email
+	mustNot(t, "UpsertPendingRegistration", repo.UpsertPendingRegistration(ctx, models.PendingRegistration{
+		Username:  "carol",
+		Email:     email,
+		OtpHash:   "otp-1",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	if _, err := repo.GetPendingRegistration(ctx, email); err == nil {
+		t.Error("GetPendingRegistration(expired): got no error")
+	}
+	userID := createUser(t, store).ID
+	mustNot(t, "UpsertPendingRegistration", repo.UpsertPendingRegistration(ctx, models.PendingRegistration{
+		UserID:    &userID,
+		Username:  "dave",
+		Email:     email,
+		OtpHash:   "otp-3",
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}))
+	reg, err = repo.GetPendingRegistration(ctx, email)
+	mustNot(t, "GetPendingRegistration", err)
+	if reg.Username != "dave" || reg.OtpHash != "otp-3" || reg.Attempts != 0 || reg.UserID == nil || *reg.UserID != userID {
+		t.Errorf("GetPendingRegistration after replacing the expired one: got %+v", reg)
+	}
+}
+
+func testPasswordResets(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.PasswordResetRepo
+	userID := createUser(t, store).ID
+	otherID := createUser(t, store).ID
+
+	for _, reset := range []models.PasswordReset{
+		{UserID: userID, TokenHash: "valid"},
+		{UserID: userID, TokenHash: "older"},
+		{UserID: otherID, TokenHash: "other"},
+	} {
+		reset.ID = primitive.NewObjectID()
+		reset.ExpiresAt = time.Now().Add(time.Hour)
+		mustNot(t, "CreatePasswordReset", repo.CreatePasswordReset(ctx, reset))
+	}
+	mustNot(t, "CreatePasswordReset", repo.CreatePasswordReset(ctx, models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		TokenHash: "expired",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	reset, err := repo.UsePasswordReset(ctx, "valid")
+	mustNot(t, "UsePasswordReset", err)
+	if reset.UserID != userID || !reset.Used {
+		t.Errorf("UsePasswordReset: got %+v", reset)
+	}
+	if _, err := repo.UsePasswordReset(ctx, "valid"); err == nil {
+		t.Error("UsePasswordReset: second use was accepted")
+	}
+	if _, err := repo.UsePasswordReset(ctx, "older"); err == nil {
+		t.Error("UsePasswordReset: another token of the user was still accepted")
+	}
+	if _, err := repo.UsePasswordReset(ctx, "other"); err != nil {
+		t.Errorf("UsePasswordReset: token of another user: %v", err)
+	}
+	if _, err := repo.UsePasswordReset(ctx, "expired"); err == nil {
+		t.Error("UsePasswordReset: expired token was accepted")
+	}
+	if _, err := repo.UsePasswordReset(ctx, "missing"); err == nil {
+		t.Error("UsePasswordReset(missing): got no error")
+	}
+}
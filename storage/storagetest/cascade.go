@@ -0,0 +1,140 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+	"todo/api/models"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// seedUser creates a user owning two lists with two tasks each, a reminder
+// on every task, one label, one smart list and one refresh token and
+// password reset.
+func seedUser(t *testing.T, store *storage.Storage, email string) (models.User, []models.TaskList) {
+	t.Helper()
+
+	user, err := store.UserRepo.CreateUser(ctx, models.CreateUser{Username: email, Email: email})
+	mustNot(t, "CreateUser", err)
+
+	taskLists := []models.TaskList{}
+	for i := 0; i < 2; i++ {
+		taskList, err := store.TaskListRepo.CreateTaskList(ctx, models.CreateTaskList{UserID: user.ID, Title: "list"})
+		mustNot(t, "CreateTaskList", err)
+		taskLists = append(taskLists, taskList)
+
+		for j := 0; j < 2; j++ {
+			task, err := store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskList.ID, Title: "task"})
+			mustNot(t, "CreateTask", err)
+			_, err = store.ReminderRepo.SetReminders(ctx, user.ID, task.ID, []int{10}, time.Now().Add(time.Hour))
+			mustNot(t, "SetReminders", err)
+		}
+	}
+
+	_, err = store.LabelRepo.CreateLabel(ctx, models.CreateLabel{UserID: user.ID, Name: "label"})
+	mustNot(t, "CreateLabel", err)
+	_, err = store.SmartListRepo.CreateSmartList(ctx, models.CreateSmartList{UserID: user.ID, Name: "smart list", Query: "overdue"})
+	mustNot(t, "CreateSmartList", err)
+	mustNot(t, "CreateRefreshToken", store.TokenRepo.CreateRefreshToken(ctx, models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		FamilyID:  primitive.NewObjectID().Hex(),
+		TokenHash: primitive.NewObjectID().Hex(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	mustNot(t, "CreatePasswordReset", store.PasswordResetRepo.CreatePasswordReset(ctx, models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: primitive.NewObjectID().Hex(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	return user, taskLists
+}
+
+func testCascadeDeletes(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	alice, aliceLists := seedUser(t, store, "alice@example.com")
+	bob, bobLists := seedUser(t, store, "bob@example.com")
+
+	// Task lists
+	want := models.DeleteSummary{DryRun: true, TaskLists: 1, Tasks: 2, Reminders: 2}
+	summary, err := store.TaskListRepo.DeleteTaskList(ctx, aliceLists[0].ID.Hex(), true)
+	mustNot(t, "DeleteTaskList(dry run)", err)
+	if summary != want {
+		t.Errorf("DeleteTaskList(dry run): got %+v, want %+v", summary, want)
+	}
+	countTasks(t, store, aliceLists[0].ID, 2)
+	countReminders(t, store, 8)
+
+	want.DryRun = false
+	summary, err = store.TaskListRepo.DeleteTaskList(ctx, aliceLists[0].ID.Hex(), false)
+	mustNot(t, "DeleteTaskList", err)
+	if summary != want {
+		t.Errorf("DeleteTaskList: got %+v, want %+v", summary, want)
+	}
+	countTasks(t, store, aliceLists[0].ID, 0)
+	countTasks(t, store, aliceLists[1].ID, 2)
+	countReminders(t, store, 6)
+
+	// Users
+	want = models.DeleteSummary{DryRun: true, Users: 1, TaskLists: 2, Tasks: 4, Labels: 1, RefreshTokens: 1, PasswordResets: 1, SmartLists: 1, Reminders: 4}
+	summary, err = store.UserRepo.DeleteUser(ctx, bob.ID.Hex(), true)
+	mustNot(t, "DeleteUser(dry run)", err)
+	if summary != want {
+		t.Errorf("DeleteUser(dry run): got %+v, want %+v", summary, want)
+	}
+	_, err = store.UserRepo.GetUser(ctx, bob.ID.Hex())
+	mustNot(t, "GetUser after dry run", err)
+
+	want.DryRun = false
+	summary, err = store.UserRepo.DeleteUser(ctx, bob.ID.Hex(), false)
+	mustNot(t, "DeleteUser", err)
+	if summary != want {
+		t.Errorf("DeleteUser: got %+v, want %+v", summary, want)
+	}
+
+	_, err = store.UserRepo.GetUser(ctx, bob.ID.Hex())
+	checkNotFound(t, "GetUser(deleted)", err, models.ErrUserNotFound)
+	for _, taskList := range bobLists {
+		_, err = store.TaskListRepo.GetTaskList(ctx, taskList.ID.Hex())
+		checkNotFound(t, "GetTaskList(of deleted user)", err, models.ErrTaskListNotFound)
+		countTasks(t, store, taskList.ID, 0)
+	}
+	_, count, err := store.LabelRepo.GetAllLabels(ctx, bob.ID.Hex(), "", models.Pagination{Limit: 10})
+	mustNot(t, "GetAllLabels", err)
+	if count != 0 {
+		t.Errorf("DeleteUser: %d labels left behind", count)
+	}
+	_, count, err = store.SmartListRepo.GetAllSmartLists(ctx, bob.ID.Hex(), models.Pagination{Limit: 10})
+	mustNot(t, "GetAllSmartLists", err)
+	if count != 0 {
+		t.Errorf("DeleteUser: %d smart lists left behind", count)
+	}
+
+	// Alice is untouched
+	_, err = store.UserRepo.GetUser(ctx, alice.ID.Hex())
+	mustNot(t, "GetUser(other user)", err)
+	countTasks(t, store, aliceLists[1].ID, 2)
+	countReminders(t, store, 2)
+}
+
+// countReminders checks how many reminders are left in the whole store.
+func countReminders(t *testing.T, store *storage.Storage, want int) {
+	t.Helper()
+	reminders, err := store.ReminderRepo.DueReminders(ctx, time.Now().Add(24*time.Hour), 100)
+	mustNot(t, "DueReminders", err)
+	if len(reminders) != want {
+		t.Errorf("store has %d reminders, want %d", len(reminders), want)
+	}
+}
+
+func countTasks(t *testing.T, store *storage.Storage, taskListID primitive.ObjectID, want int64) {
+	t.Helper()
+	_, count, err := store.TaskRepo.GetAllTasks(ctx, models.TaskFilter{TaskListIDs: []primitive.ObjectID{taskListID}}, models.Pagination{Limit: 10})
+	mustNot(t, "GetAllTasks", err)
+	if count != want {
+		t.Errorf("task list %s has %d tasks, want %d", taskListID.Hex(), count, want)
+	}
+}
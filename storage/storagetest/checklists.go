@@ -0,0 +1,180 @@
+package storagetest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testChecklists(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskRepo
+	owner := createUser(t, store).ID
+	taskListID := createTaskList(t, store, owner).ID
+
+	task, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Pack"})
+	mustNot(t, "CreateTask", err)
+	if len(task.Checklist) != 0 {
+		t.Errorf("CreateTask: got checklist %+v, want none", task.Checklist)
+	}
+
+	for _, title := range []string{"socks", "shirts", "shoes"} {
+		pause()
+		updated, err := repo.AddChecklistItem(ctx, task.ID.Hex(), models.CreateChecklistItem{Title: title})
+		mustNot(t, "AddChecklistItem", err)
+		checkUpdated(t, "AddChecklistItem", task.CreatedAt, task.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+		task = updated
+	}
+	if got := itemTitles(task.Checklist); got != "socks shirts shoes" {
+		t.Errorf("AddChecklistItem: got items %q, want them in the order added", got)
+	}
+	for _, item := range task.Checklist {
+		if item.ID.IsZero() || item.Done {
+			t.Errorf("AddChecklistItem: got item %+v, want an open item with an ID", item)
+		}
+	}
+	socks, shirts, shoes := task.Checklist[0].ID, task.Checklist[1].ID, task.Checklist[2].ID
+
+	// Toggling leaves the title alone, renaming leaves the state alone
+	done, title := true, "wool socks"
+	pause()
+	updated, err := repo.UpdateChecklistItem(ctx, task.ID.Hex(), models.UpdateChecklistItem{ID: socks, Done: &done})
+	mustNot(t, "UpdateChecklistItem(done)", err)
+	checkUpdated(t, "UpdateChecklistItem", task.CreatedAt, task.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+	updated, err = repo.UpdateChecklistItem(ctx, task.ID.Hex(), models.UpdateChecklistItem{ID: socks, Title: &title})
+	mustNot(t, "UpdateChecklistItem(title)", err)
+	if item := updated.Checklist[0]; item.ID != socks || item.Title != "wool socks" || !item.Done {
+		t.Errorf("UpdateChecklistItem: got %+v, want done wool socks", item)
+	}
+	if updated.Checklist[1].Done || updated.Checklist[2].Done {
+		t.Errorf("UpdateChecklistItem: other items changed: %+v", updated.Checklist)
+	}
+
+	reordered, err := repo.ReorderChecklist(ctx, task.ID.Hex(), []primitive.ObjectID{shoes, socks, shirts})
+	mustNot(t, "ReorderChecklist", err)
+	if got := itemTitles(reordered.Checklist); got != "shoes wool socks shirts" {
+		t.Errorf("ReorderChecklist: got items %q", got)
+	}
+	got, err := repo.GetTask(ctx, task.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if itemTitles(got.Checklist) != "shoes wool socks shirts" || !got.Checklist[1].Done {
+		t.Errorf("GetTask after ReorderChecklist: got %+v", got.Checklist)
+	}
+
+	for name, ids := range map[string][]primitive.ObjectID{
+		"missing item":   {shoes, socks},
+		"duplicate item": {shoes, socks, socks},
+		"unknown item":   {shoes, socks, primitive.NewObjectID()},
+	} {
+		_, err := repo.ReorderChecklist(ctx, task.ID.Hex(), ids)
+		if !errors.Is(err, models.ErrInvalidInput) {
+			t.Errorf("ReorderChecklist(%s): got error %v, want %v", name, err, models.ErrInvalidInput)
+		}
+	}
+
+	deleted, err := repo.DeleteChecklistItem(ctx, task.ID.Hex(), socks)
+	mustNot(t, "DeleteChecklistItem", err)
+	if got := itemTitles(deleted.Checklist); got != "shoes shirts" {
+		t.Errorf("DeleteChecklistItem: got items %q", got)
+	}
+	added, err := repo.AddChecklistItem(ctx, task.ID.Hex(), models.CreateChecklistItem{Title: "hat"})
+	mustNot(t, "AddChecklistItem", err)
+	if got := itemTitles(added.Checklist); got != "shoes shirts hat" {
+		t.Errorf("AddChecklistItem after reordering: got items %q", got)
+	}
+
+	missing := primitive.NewObjectID()
+	_, err = repo.UpdateChecklistItem(ctx, task.ID.Hex(), models.UpdateChecklistItem{ID: missing, Done: &done})
+	checkNotFound(t, "UpdateChecklistItem(missing item)", err, models.ErrChecklistItemNotFound)
+	_, err = repo.DeleteChecklistItem(ctx, task.ID.Hex(), socks)
+	checkNotFound(t, "DeleteChecklistItem(deleted item)", err, models.ErrChecklistItemNotFound)
+	_, err = repo.AddChecklistItem(ctx, missing.Hex(), models.CreateChecklistItem{Title: "x"})
+	checkNotFound(t, "AddChecklistItem(missing task)", err, models.ErrTaskNotFound)
+	_, err = repo.UpdateChecklistItem(ctx, missing.Hex(), models.UpdateChecklistItem{ID: shoes, Done: &done})
+	checkNotFound(t, "UpdateChecklistItem(missing task)", err, models.ErrTaskNotFound)
+	_, err = repo.DeleteChecklistItem(ctx, "not-an-id", shoes)
+	checkNotFound(t, "DeleteChecklistItem(invalid id)", err, models.ErrTaskNotFound)
+	_, err = repo.ReorderChecklist(ctx, missing.Hex(), nil)
+	checkNotFound(t, "ReorderChecklist(missing task)", err, models.ErrTaskNotFound)
+
+	// Items belong to one task only
+	other, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Other"})
+	mustNot(t, "CreateTask", err)
+	_, err = repo.DeleteChecklistItem(ctx, other.ID.Hex(), shoes)
+	checkNotFound(t, "DeleteChecklistItem(other task)", err, models.ErrChecklistItemNotFound)
+}
+
+func testSubtasks(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskRepo
+	owner := createUser(t, store).ID
+	taskListID := createTaskList(t, store, owner).ID
+
+	create := func(title string, parentID *primitive.ObjectID) models.Task {
+		pause()
+		task, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, ParentID: parentID, Title: title})
+		mustNot(t, "CreateTask", err)
+		return task
+	}
+	parent, other := create("move", nil), create("other", nil)
+	if parent.ParentID != nil {
+		t.Errorf("CreateTask: got parent %v for a top level task", parent.ParentID)
+	}
+	pack, clean, _ := create("pack", &parent.ID), create("clean", &parent.ID), create("unpack", &parent.ID)
+	create("elsewhere", &other.ID)
+
+	got, err := repo.GetTask(ctx, pack.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if got.ParentID == nil || *got.ParentID != parent.ID {
+		t.Errorf("GetTask: got parent %v, want %s", got.ParentID, parent.ID.Hex())
+	}
+
+	subtasks, count, err := repo.GetAllTasks(ctx, models.TaskFilter{TaskListIDs: []primitive.ObjectID{taskListID}, ParentID: &parent.ID}, models.Pagination{})
+	mustNot(t, "GetAllTasks(subtasks)", err)
+	if got := taskTitles(subtasks); got != "pack clean unpack" || count != 3 {
+		t.Errorf("GetAllTasks(subtasks): got %q (count %d), want pack clean unpack", got, count)
+	}
+
+	_, err = repo.UpdateTask(ctx, models.UpdateTask{ID: pack.ID, Title: pack.Title, Completed: true})
+	mustNot(t, "UpdateTask", err)
+	counts, err := repo.CountSubtasks(ctx, []primitive.ObjectID{parent.ID, other.ID, pack.ID})
+	mustNot(t, "CountSubtasks", err)
+	want := map[primitive.ObjectID]models.SubtaskCount{parent.ID: {Total: 3, Completed: 1}, other.ID: {Total: 1}}
+	if len(counts) != len(want) || counts[parent.ID] != want[parent.ID] || counts[other.ID] != want[other.ID] {
+		t.Errorf("CountSubtasks: got %v, want %v", counts, want)
+	}
+
+	mustNot(t, "CompleteSubtasks", repo.CompleteSubtasks(ctx, parent.ID))
+	counts, err = repo.CountSubtasks(ctx, []primitive.ObjectID{parent.ID, other.ID})
+	mustNot(t, "CountSubtasks", err)
+	if counts[parent.ID] != (models.SubtaskCount{Total: 3, Completed: 3}) || counts[other.ID] != (models.SubtaskCount{Total: 1}) {
+		t.Errorf("CountSubtasks after CompleteSubtasks: got %v", counts)
+	}
+	got, err = repo.GetTask(ctx, parent.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if got.Completed {
+		t.Error("CompleteSubtasks: completed the parent too")
+	}
+	got, err = repo.GetTask(ctx, clean.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if got.CompletedAt == nil {
+		t.Error("CompleteSubtasks: completed_at was not set")
+	}
+
+	// Deleting the parent takes its subtasks along
+	mustNot(t, "DeleteTask", repo.DeleteTask(ctx, parent.ID.Hex()))
+	_, err = repo.GetTask(ctx, pack.ID.Hex())
+	checkNotFound(t, "GetTask(subtask of deleted task)", err, models.ErrTaskNotFound)
+	countTasks(t, store, taskListID, 2)
+}
+
+func itemTitles(checklist []models.ChecklistItem) string {
+	titles := []string{}
+	for _, item := range checklist {
+		titles = append(titles, item.Title)
+	}
+	return strings.Join(titles, " ")
+}
@@ -0,0 +1,119 @@
+package storagetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testDigests(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.DigestRepo
+	owner, other := createUser(t, store).ID, createUser(t, store).ID
+
+	settings, err := repo.GetDigestSettings(ctx, owner)
+	mustNot(t, "GetDigestSettings", err)
+	if want := models.DefaultDigestSettings(owner); !reflect.DeepEqual(settings, want) {
+		t.Errorf("GetDigestSettings: got %+v, want the defaults %+v", settings, want)
+	}
+
+	nextAt := time.Date(2030, 1, 7, 7, 0, 0, 0, time.UTC)
+	req := models.UpdateDigestSettings{UserID: owner, Frequency: models.DigestDaily, SendAt: "07:00", Weekday: "monday", Timezone: "Europe/Berlin", NextAt: &nextAt}
+	saved, err := repo.SaveDigestSettings(ctx, req)
+	mustNot(t, "SaveDigestSettings", err)
+	checkDigestSettings(t, "SaveDigestSettings", saved, req)
+	settings, err = repo.GetDigestSettings(ctx, owner)
+	mustNot(t, "GetDigestSettings", err)
+	checkDigestSettings(t, "GetDigestSettings", settings, req)
+
+	otherNextAt := nextAt.Add(-time.Hour)
+	otherReq := models.UpdateDigestSettings{UserID: other, Frequency: models.DigestWeekly, SendAt: "06:00", Weekday: "monday", Timezone: "UTC", NextAt: &otherNextAt}
+	_, err = repo.SaveDigestSettings(ctx, otherReq)
+	mustNot(t, "SaveDigestSettings", err)
+
+	due, err := repo.DueDigests(ctx, nextAt.Add(-time.Minute), 10)
+	mustNot(t, "DueDigests", err)
+	checkDueDigests(t, "DueDigests", due, other)
+	due, err = repo.DueDigests(ctx, nextAt, 10)
+	mustNot(t, "DueDigests", err)
+	checkDueDigests(t, "DueDigests", due, other, owner)
+	due, err = repo.DueDigests(ctx, nextAt, 1)
+	mustNot(t, "DueDigests(limit)", err)
+	checkDueDigests(t, "DueDigests(limit)", due, other)
+
+	// Recording moves the digest to the next day
+	sentAt, tomorrow := nextAt.Add(time.Minute), nextAt.AddDate(0, 0, 1)
+	err = repo.RecordDigest(ctx, models.DigestDelivery{UserID: owner, ScheduledAt: nextAt, SentAt: &sentAt, NextAt: tomorrow})
+	mustNot(t, "RecordDigest", err)
+	err = repo.RecordDigest(ctx, models.DigestDelivery{UserID: owner, ScheduledAt: nextAt, NextAt: tomorrow})
+	checkNotFound(t, "RecordDigest(already recorded)", err, models.ErrDigestNotFound)
+	err = repo.RecordDigest(ctx, models.DigestDelivery{UserID: createUser(t, store).ID, ScheduledAt: nextAt, NextAt: tomorrow})
+	checkNotFound(t, "RecordDigest(missing)", err, models.ErrDigestNotFound)
+	settings, err = repo.GetDigestSettings(ctx, owner)
+	mustNot(t, "GetDigestSettings", err)
+	if settings.NextAt == nil || !sameTime(*settings.NextAt, tomorrow) || settings.LastSentAt == nil || !sameTime(*settings.LastSentAt, sentAt) {
+		t.Errorf("RecordDigest: got next_at %v and last_sent_at %v, want %s and %s", settings.NextAt, settings.LastSentAt, tomorrow, sentAt)
+	}
+
+	// A skipped digest keeps when the last one was sent
+	err = repo.RecordDigest(ctx, models.DigestDelivery{UserID: owner, ScheduledAt: *settings.NextAt, NextAt: tomorrow.AddDate(0, 0, 1)})
+	mustNot(t, "RecordDigest(skipped)", err)
+	settings, err = repo.GetDigestSettings(ctx, owner)
+	mustNot(t, "GetDigestSettings", err)
+	if settings.LastSentAt == nil || !sameTime(*settings.LastSentAt, sentAt) {
+		t.Errorf("RecordDigest(skipped): got last_sent_at %v, want %s", settings.LastSentAt, sentAt)
+	}
+
+	// Turning digests off leaves nothing due, and keeps when the last was sent
+	req = models.UpdateDigestSettings{UserID: owner, Frequency: models.DigestOff, SendAt: "07:00", Weekday: "monday", Timezone: "UTC"}
+	saved, err = repo.SaveDigestSettings(ctx, req)
+	mustNot(t, "SaveDigestSettings(off)", err)
+	checkDigestSettings(t, "SaveDigestSettings(off)", saved, req)
+	if saved.LastSentAt == nil || !sameTime(*saved.LastSentAt, sentAt) {
+		t.Errorf("SaveDigestSettings(off): got last_sent_at %v, want %s", saved.LastSentAt, sentAt)
+	}
+	due, err = repo.DueDigests(ctx, nextAt.AddDate(1, 0, 0), 10)
+	mustNot(t, "DueDigests", err)
+	checkDueDigests(t, "DueDigests after turning off", due, other)
+
+	// The settings go with the user
+	_, err = store.UserRepo.DeleteUser(ctx, other.Hex(), false)
+	mustNot(t, "DeleteUser", err)
+	settings, err = repo.GetDigestSettings(ctx, other)
+	mustNot(t, "GetDigestSettings after DeleteUser", err)
+	if want := models.DefaultDigestSettings(other); !reflect.DeepEqual(settings, want) {
+		t.Errorf("GetDigestSettings after DeleteUser: got %+v, want the defaults %+v", settings, want)
+	}
+}
+
+func checkDigestSettings(t *testing.T, op string, got models.DigestSettings, want models.UpdateDigestSettings) {
+	t.Helper()
+	if got.UserID != want.UserID || got.Frequency != want.Frequency || got.SendAt != want.SendAt ||
+		got.Weekday != want.Weekday || got.Timezone != want.Timezone || got.UpdatedAt.IsZero() {
+		t.Errorf("%s: got %+v, want %+v", op, got, want)
+	}
+	if (got.NextAt == nil) != (want.NextAt == nil) || (got.NextAt != nil && !sameTime(*got.NextAt, *want.NextAt)) {
+		t.Errorf("%s: got next_at %v, want %v", op, got.NextAt, want.NextAt)
+	}
+}
+
+func checkDueDigests(t *testing.T, op string, got []models.DigestSettings, want ...primitive.ObjectID) {
+	t.Helper()
+	ids := []primitive.ObjectID{}
+	for _, settings := range got {
+		ids = append(ids, settings.UserID)
+	}
+	if len(ids) != len(want) {
+		t.Errorf("%s: got users %v, want %v", op, ids, want)
+		return
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("%s: got users %v, want %v", op, ids, want)
+			return
+		}
+	}
+}
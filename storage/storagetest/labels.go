@@ -0,0 +1,84 @@
+package storagetest
+
+import (
+	"testing"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testLabels(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.LabelRepo
+	owner := createUser(t, store).ID
+
+	created, err := repo.CreateLabel(ctx, models.CreateLabel{UserID: owner, Name: "Work", Color: "#ff0000"})
+	mustNot(t, "CreateLabel", err)
+	if created.ID.IsZero() {
+		t.Fatal("CreateLabel: ID was not set")
+	}
+	checkTimestamps(t, "CreateLabel", created.CreatedAt, created.UpdatedAt)
+
+	got, err := repo.GetLabel(ctx, created.ID.Hex())
+	mustNot(t, "GetLabel", err)
+	if got.ID != created.ID || got.UserID != owner || got.Name != "Work" || got.Color != "#ff0000" {
+		t.Errorf("GetLabel: got %+v, want the created label %+v", got, created)
+	}
+
+	pause()
+	updated, err := repo.UpdateLabel(ctx, models.UpdateLabel{ID: created.ID, Name: "Office", Color: "#00ff00"})
+	mustNot(t, "UpdateLabel", err)
+	if updated.Name != "Office" || updated.Color != "#00ff00" || updated.UserID != owner {
+		t.Errorf("UpdateLabel: got %+v", updated)
+	}
+	checkUpdated(t, "UpdateLabel", created.CreatedAt, created.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+
+	missing := primitive.NewObjectID().Hex()
+	_, err = repo.GetLabel(ctx, missing)
+	checkNotFound(t, "GetLabel(missing)", err, models.ErrLabelNotFound)
+	_, err = repo.GetLabel(ctx, "not-an-id")
+	checkNotFound(t, "GetLabel(invalid id)", err, models.ErrLabelNotFound)
+	_, err = repo.UpdateLabel(ctx, models.UpdateLabel{ID: primitive.NewObjectID(), Name: "x"})
+	checkNotFound(t, "UpdateLabel(missing)", err, models.ErrLabelNotFound)
+	checkNotFound(t, "DeleteLabel(missing)", repo.DeleteLabel(ctx, missing), models.ErrLabelNotFound)
+
+	for _, name := range []string{"homework", "Home", "WORKOUT", "errands"} {
+		_, err := repo.CreateLabel(ctx, models.CreateLabel{UserID: owner, Name: name})
+		mustNot(t, "CreateLabel", err)
+	}
+	_, err = repo.CreateLabel(ctx, models.CreateLabel{UserID: createUser(t, store).ID, Name: "work"})
+	mustNot(t, "CreateLabel", err)
+
+	labels, count, err := repo.GetAllLabels(ctx, owner.Hex(), "work", models.Pagination{Limit: 10})
+	mustNot(t, "GetAllLabels", err)
+	if count != 2 || len(labels) != 2 {
+		t.Errorf("GetAllLabels(search=work): got %d labels of %d, want 2 of 2 (homework, WORKOUT)", len(labels), count)
+	}
+
+	checkPages(t, "GetAllLabels", 5, 2, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		labels, count, err := repo.GetAllLabels(ctx, owner.Hex(), "", page)
+		cursors := []models.Cursor{}
+		for _, label := range labels {
+			cursors = append(cursors, label.Cursor())
+		}
+		return cursors, count, err
+	})
+
+	// Deleting a label pulls it from the tasks carrying it
+	taskList, err := store.TaskListRepo.CreateTaskList(ctx, models.CreateTaskList{UserID: owner, Title: "list"})
+	mustNot(t, "CreateTaskList", err)
+	other, err := repo.CreateLabel(ctx, models.CreateLabel{UserID: owner, Name: "other"})
+	mustNot(t, "CreateLabel", err)
+	task, err := store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskList.ID, Title: "task", LabelIDs: []primitive.ObjectID{created.ID, other.ID}})
+	mustNot(t, "CreateTask", err)
+
+	mustNot(t, "DeleteLabel", repo.DeleteLabel(ctx, created.ID.Hex()))
+	_, err = repo.GetLabel(ctx, created.ID.Hex())
+	checkNotFound(t, "GetLabel(deleted)", err, models.ErrLabelNotFound)
+
+	task, err = store.TaskRepo.GetTask(ctx, task.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if len(task.LabelIDs) != 1 || task.LabelIDs[0] != other.ID {
+		t.Errorf("DeleteLabel: task labels are %v, want only %s", task.LabelIDs, other.ID.Hex())
+	}
+}
@@ -0,0 +1,138 @@
+package storagetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"todo/api/models"
+)
+
+func testNotifications(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.NotificationRepo
+	owner := createUser(t, store).ID
+
+	settings, err := repo.GetNotificationSettings(ctx, owner)
+	mustNot(t, "GetNotificationSettings", err)
+	if want := models.DefaultNotificationSettings(owner); !reflect.DeepEqual(settings, want) {
+		t.Errorf("GetNotificationSettings: got %+v, want the defaults %+v", settings, want)
+	}
+
+	req := models.UpdateNotificationSettings{
+		UserID:         owner,
+		Channels:       []string{"email", "telegram"},
+		TelegramChatID: "123456789",
+		WebhookURL:     "https://example.com/hook",
+	}
+	saved, err := repo.SaveNotificationSettings(ctx, req)
+	mustNot(t, "SaveNotificationSettings", err)
+	checkNotificationSettings(t, "SaveNotificationSettings", saved, req)
+	if saved.UpdatedAt.IsZero() {
+		t.Errorf("SaveNotificationSettings: updated_at is not set")
+	}
+
+	settings, err = repo.GetNotificationSettings(ctx, owner)
+	mustNot(t, "GetNotificationSettings", err)
+	checkNotificationSettings(t, "GetNotificationSettings", settings, req)
+	if !sameTime(settings.UpdatedAt, saved.UpdatedAt) {
+		t.Errorf("GetNotificationSettings: got updated_at %s, want %s", settings.UpdatedAt, saved.UpdatedAt)
+	}
+
+	// Saving again replaces the settings, and no channel at all is allowed
+	req = models.UpdateNotificationSettings{UserID: owner, Channels: []string{}}
+	saved, err = repo.SaveNotificationSettings(ctx, req)
+	mustNot(t, "SaveNotificationSettings again", err)
+	checkNotificationSettings(t, "SaveNotificationSettings again", saved, req)
+	settings, err = repo.GetNotificationSettings(ctx, owner)
+	mustNot(t, "GetNotificationSettings", err)
+	checkNotificationSettings(t, "GetNotificationSettings after saving again", settings, req)
+
+	// Preferences share the record with the settings without overwriting them
+	preferences, err := repo.GetNotificationPreferences(ctx, owner)
+	mustNot(t, "GetNotificationPreferences", err)
+	// updated_at is shared with the settings
+	preferences.UpdatedAt = time.Time{}
+	if want := models.DefaultNotificationPreferences(owner); !reflect.DeepEqual(preferences, want) {
+		t.Errorf("GetNotificationPreferences: got %+v, want the defaults %+v", preferences, want)
+	}
+	prefReq := models.UpdateNotificationPreferences{
+		UserID:     owner,
+		Events:     map[string][]string{models.EventReminder: {"email", "telegram"}, models.EventDigest: {}},
+		Timezone:   "Europe/Berlin",
+		QuietHours: &models.QuietHours{Start: "22:00", End: "07:30"},
+	}
+	savedPreferences, err := repo.SaveNotificationPreferences(ctx, prefReq)
+	mustNot(t, "SaveNotificationPreferences", err)
+	checkNotificationPreferences(t, "SaveNotificationPreferences", savedPreferences, prefReq)
+	preferences, err = repo.GetNotificationPreferences(ctx, owner)
+	mustNot(t, "GetNotificationPreferences", err)
+	checkNotificationPreferences(t, "GetNotificationPreferences", preferences, prefReq)
+	settings, err = repo.GetNotificationSettings(ctx, owner)
+	mustNot(t, "GetNotificationSettings", err)
+	checkNotificationSettings(t, "GetNotificationSettings after SaveNotificationPreferences", settings, req)
+
+	req = models.UpdateNotificationSettings{UserID: owner, Channels: []string{"webhook"}, WebhookURL: "https://example.com/other"}
+	_, err = repo.SaveNotificationSettings(ctx, req)
+	mustNot(t, "SaveNotificationSettings", err)
+	preferences, err = repo.GetNotificationPreferences(ctx, owner)
+	mustNot(t, "GetNotificationPreferences", err)
+	checkNotificationPreferences(t, "GetNotificationPreferences after SaveNotificationSettings", preferences, prefReq)
+
+	prefReq = models.UpdateNotificationPreferences{UserID: owner, Timezone: "UTC"}
+	savedPreferences, err = repo.SaveNotificationPreferences(ctx, prefReq)
+	mustNot(t, "SaveNotificationPreferences again", err)
+	checkNotificationPreferences(t, "SaveNotificationPreferences again", savedPreferences, prefReq)
+
+	// Saving preferences first gives the user the default settings
+	newcomer := createUser(t, store).ID
+	_, err = repo.SaveNotificationPreferences(ctx, models.UpdateNotificationPreferences{UserID: newcomer, Timezone: "UTC"})
+	mustNot(t, "SaveNotificationPreferences(no settings)", err)
+	settings, err = repo.GetNotificationSettings(ctx, newcomer)
+	mustNot(t, "GetNotificationSettings", err)
+	defaults := models.DefaultNotificationSettings(newcomer)
+	checkNotificationSettings(t, "GetNotificationSettings after SaveNotificationPreferences", settings,
+		models.UpdateNotificationSettings{UserID: newcomer, Channels: defaults.Channels})
+
+	// The settings go with the user
+	_, err = store.UserRepo.DeleteUser(ctx, owner.Hex(), false)
+	mustNot(t, "DeleteUser", err)
+	settings, err = repo.GetNotificationSettings(ctx, owner)
+	mustNot(t, "GetNotificationSettings after DeleteUser", err)
+	if want := models.DefaultNotificationSettings(owner); !reflect.DeepEqual(settings, want) {
+		t.Errorf("GetNotificationSettings after DeleteUser: got %+v, want the defaults %+v", settings, want)
+	}
+	preferences, err = repo.GetNotificationPreferences(ctx, owner)
+	mustNot(t, "GetNotificationPreferences after DeleteUser", err)
+	if want := models.DefaultNotificationPreferences(owner); !reflect.DeepEqual(preferences, want) {
+		t.Errorf("GetNotificationPreferences after DeleteUser: got %+v, want the defaults %+v", preferences, want)
+	}
+}
+
+func checkNotificationSettings(t *testing.T, op string, got models.NotificationSettings, want models.UpdateNotificationSettings) {
+	t.Helper()
+	if got.UserID != want.UserID || got.TelegramChatID != want.TelegramChatID || got.WebhookURL != want.WebhookURL {
+		t.Errorf("%s: got %+v, want %+v", op, got, want)
+	}
+	if got.Channels == nil || len(got.Channels) != len(want.Channels) {
+		t.Errorf("%s: got channels %q, want %q", op, got.Channels, want.Channels)
+		return
+	}
+	for i := range want.Channels {
+		if got.Channels[i] != want.Channels[i] {
+			t.Errorf("%s: got channels %q, want %q", op, got.Channels, want.Channels)
+			return
+		}
+	}
+}
+
+func checkNotificationPreferences(t *testing.T, op string, got models.NotificationPreferences, want models.UpdateNotificationPreferences) {
+	t.Helper()
+	events := want.Events
+	if events == nil {
+		events = map[string][]string{}
+	}
+	if got.UserID != want.UserID || got.Timezone != want.Timezone || !reflect.DeepEqual(got.Events, events) ||
+		!reflect.DeepEqual(got.QuietHours, want.QuietHours) || got.UpdatedAt.IsZero() {
+		t.Errorf("%s: got %+v, want %+v", op, got, want)
+	}
+}
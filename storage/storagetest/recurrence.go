@@ -0,0 +1,58 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testRecurrence(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskRepo
+	owner := createUser(t, store).ID
+	taskListID := createTaskList(t, store, owner).ID
+	dueDate := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	weekly := &models.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO", Timezone: "Europe/Berlin", Start: dueDate}
+	task, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Water plants", DueDate: dueDate, Recurrence: weekly})
+	mustNot(t, "CreateTask", err)
+	got, err := repo.GetTask(ctx, task.ID.Hex())
+	mustNot(t, "GetTask", err)
+	checkRecurrence(t, "GetTask", got.Recurrence, weekly)
+
+	plain, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Once"})
+	mustNot(t, "CreateTask", err)
+	if plain.Recurrence != nil {
+		t.Errorf("CreateTask: got recurrence %+v for a task that does not repeat", plain.Recurrence)
+	}
+
+	daily := &models.Recurrence{Rule: "FREQ=DAILY;COUNT=3", Start: dueDate.Add(24 * time.Hour)}
+	pause()
+	updated, err := repo.SetTaskRecurrence(ctx, task.ID.Hex(), daily)
+	mustNot(t, "SetTaskRecurrence", err)
+	checkRecurrence(t, "SetTaskRecurrence", updated.Recurrence, daily)
+	checkUpdated(t, "SetTaskRecurrence", task.CreatedAt, task.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+	if updated.Title != "Water plants" || !sameTime(updated.DueDate, dueDate) {
+		t.Errorf("SetTaskRecurrence: other fields changed: %+v", updated)
+	}
+
+	cleared, err := repo.SetTaskRecurrence(ctx, task.ID.Hex(), nil)
+	mustNot(t, "SetTaskRecurrence(nil)", err)
+	got, err = repo.GetTask(ctx, task.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if cleared.Recurrence != nil || got.Recurrence != nil {
+		t.Errorf("SetTaskRecurrence(nil): got recurrence %+v, want none", got.Recurrence)
+	}
+
+	_, err = repo.SetTaskRecurrence(ctx, primitive.NewObjectID().Hex(), daily)
+	checkNotFound(t, "SetTaskRecurrence(missing)", err, models.ErrTaskNotFound)
+}
+
+func checkRecurrence(t *testing.T, what string, got, want *models.Recurrence) {
+	t.Helper()
+	if got == nil || got.Rule != want.Rule || got.Timezone != want.Timezone || !sameTime(got.Start, want.Start) {
+		t.Errorf("%s: got recurrence %+v, want %+v", what, got, want)
+	}
+}
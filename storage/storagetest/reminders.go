@@ -0,0 +1,122 @@
+package storagetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testReminders(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.ReminderRepo
+	owner := createUser(t, store).ID
+	taskListID := createTaskList(t, store, owner).ID
+	dueDate := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	task, err := store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Dentist", DueDate: dueDate})
+	mustNot(t, "CreateTask", err)
+
+	reminders, err := repo.SetReminders(ctx, owner, task.ID, []int{60, 1440}, dueDate)
+	mustNot(t, "SetReminders", err)
+	checkReminders(t, "SetReminders", reminders, "1440 pending 2030-01-06T09:00, 60 pending 2030-01-07T08:00")
+	if reminders[0].TaskID != task.ID || reminders[0].UserID != owner {
+		t.Errorf("SetReminders: got task %s and user %s, want %s and %s", reminders[0].TaskID.Hex(), reminders[0].UserID.Hex(), task.ID.Hex(), owner.Hex())
+	}
+	dayBefore := reminders[0]
+
+	due, err := repo.DueReminders(ctx, dueDate.Add(-2*time.Hour), 10)
+	mustNot(t, "DueReminders", err)
+	checkReminders(t, "DueReminders", due, "1440 pending 2030-01-06T09:00")
+	due, err = repo.DueReminders(ctx, dueDate, 1)
+	mustNot(t, "DueReminders(limit)", err)
+	checkReminders(t, "DueReminders(limit)", due, "1440 pending 2030-01-06T09:00")
+
+	sentAt := dueDate.Add(-23 * time.Hour)
+	err = repo.RecordDelivery(ctx, models.ReminderDelivery{ID: dayBefore.ID, RemindAt: *dayBefore.RemindAt, Status: models.ReminderSent, Attempts: 1, SentAt: &sentAt})
+	mustNot(t, "RecordDelivery", err)
+	err = repo.RecordDelivery(ctx, models.ReminderDelivery{ID: dayBefore.ID, RemindAt: dueDate, Status: models.ReminderSent, Attempts: 2})
+	checkNotFound(t, "RecordDelivery(moved)", err, models.ErrReminderNotFound)
+	err = repo.RecordDelivery(ctx, models.ReminderDelivery{ID: primitive.NewObjectID(), RemindAt: dueDate, Status: models.ReminderSent})
+	checkNotFound(t, "RecordDelivery(missing)", err, models.ErrReminderNotFound)
+
+	due, err = repo.DueReminders(ctx, dueDate, 10)
+	mustNot(t, "DueReminders", err)
+	checkReminders(t, "DueReminders after sending", due, "60 pending 2030-01-07T08:00")
+
+	// Kept offsets keep their state, the others come and go
+	reminders, err = repo.SetReminders(ctx, owner, task.ID, []int{30, 1440}, dueDate)
+	mustNot(t, "SetReminders", err)
+	checkReminders(t, "SetReminders again", reminders, "1440 sent 2030-01-06T09:00, 30 pending 2030-01-07T08:30")
+	if reminders[0].ID != dayBefore.ID || reminders[0].Attempts != 1 || reminders[0].SentAt == nil || !sameTime(*reminders[0].SentAt, sentAt) {
+		t.Errorf("SetReminders: kept reminder changed: %+v", reminders[0])
+	}
+
+	// Saving the same due date leaves sent reminders alone
+	mustNot(t, "RescheduleReminders", repo.RescheduleReminders(ctx, task.ID, dueDate))
+	reminders, err = repo.GetReminders(ctx, task.ID)
+	mustNot(t, "GetReminders", err)
+	checkReminders(t, "RescheduleReminders(same)", reminders, "1440 sent 2030-01-06T09:00, 30 pending 2030-01-07T08:30")
+
+	// Deferring moves a reminder without touching its offset
+	deferredTo := dueDate.Add(-15 * time.Minute)
+	err = repo.RecordDelivery(ctx, models.ReminderDelivery{ID: reminders[1].ID, RemindAt: *reminders[1].RemindAt, Status: models.ReminderPending, DeferredTo: &deferredTo})
+	mustNot(t, "RecordDelivery(deferred)", err)
+	reminders, err = repo.GetReminders(ctx, task.ID)
+	mustNot(t, "GetReminders", err)
+	checkReminders(t, "RecordDelivery(deferred)", reminders, "1440 sent 2030-01-06T09:00, 30 pending 2030-01-07T08:45")
+
+	mustNot(t, "RescheduleReminders", repo.RescheduleReminders(ctx, task.ID, dueDate.Add(24*time.Hour)))
+	reminders, err = repo.GetReminders(ctx, task.ID)
+	mustNot(t, "GetReminders", err)
+	checkReminders(t, "RescheduleReminders", reminders, "1440 pending 2030-01-07T09:00, 30 pending 2030-01-08T08:30")
+	if reminders[0].Attempts != 0 || reminders[0].SentAt != nil {
+		t.Errorf("RescheduleReminders: delivery was not reset: %+v", reminders[0])
+	}
+
+	mustNot(t, "RescheduleReminders(no due date)", repo.RescheduleReminders(ctx, task.ID, time.Time{}))
+	reminders, err = repo.GetReminders(ctx, task.ID)
+	mustNot(t, "GetReminders", err)
+	checkReminders(t, "RescheduleReminders(no due date)", reminders, "1440 pending never, 30 pending never")
+	due, err = repo.DueReminders(ctx, dueDate.AddDate(1, 0, 0), 10)
+	mustNot(t, "DueReminders", err)
+	checkReminders(t, "DueReminders without due date", due, "")
+
+	other, err := store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Someday"})
+	mustNot(t, "CreateTask", err)
+	reminders, err = repo.SetReminders(ctx, owner, other.ID, []int{0}, time.Time{})
+	mustNot(t, "SetReminders(no due date)", err)
+	checkReminders(t, "SetReminders(no due date)", reminders, "0 pending never")
+
+	mustNot(t, "DeleteReminders", repo.DeleteReminders(ctx, task.ID))
+	reminders, err = repo.GetReminders(ctx, task.ID)
+	mustNot(t, "GetReminders", err)
+	checkReminders(t, "DeleteReminders", reminders, "")
+	reminders, err = repo.GetReminders(ctx, other.ID)
+	mustNot(t, "GetReminders", err)
+	checkReminders(t, "DeleteReminders(other task)", reminders, "0 pending never")
+
+	reminders, err = repo.SetReminders(ctx, owner, other.ID, []int{}, time.Time{})
+	mustNot(t, "SetReminders(none)", err)
+	checkReminders(t, "SetReminders(none)", reminders, "")
+}
+
+// checkReminders compares reminders by offset, status and time, written as
+// "1440 pending 2030-01-06T09:00" and joined with commas.
+func checkReminders(t *testing.T, what string, reminders []models.Reminder, want string) {
+	t.Helper()
+	parts := []string{}
+	for _, reminder := range reminders {
+		at := "never"
+		if reminder.RemindAt != nil {
+			at = reminder.RemindAt.UTC().Format("2006-01-02T15:04")
+		}
+		parts = append(parts, fmt.Sprintf("%d %s %s", reminder.Before, reminder.Status, at))
+	}
+	if got := strings.Join(parts, ", "); got != want {
+		t.Errorf("%s: got %q, want %q", what, got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package storagetest
+
+import (
+	"testing"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testSearch(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.SearchRepo
+	owner := createUser(t, store).ID
+
+	taskList, err := store.TaskListRepo.CreateTaskList(ctx, models.CreateTaskList{UserID: owner, Title: "Quarterly report", Description: "Numbers for the board"})
+	mustNot(t, "CreateTaskList", err)
+	inTitle, err := store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskList.ID, Title: "Draft the report", Description: "Start with last quarter"})
+	mustNot(t, "CreateTask", err)
+	inBody, err := store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskList.ID, Title: "Gather figures", Description: "They go into the report"})
+	mustNot(t, "CreateTask", err)
+	_, err = store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: taskList.ID, Title: "Book flights", Description: "Window seat"})
+	mustNot(t, "CreateTask", err)
+	createLabel(t, store, owner, "reports")
+	createLabel(t, store, owner, "Board")
+
+	// Somebody else's matching items are never found
+	other := createUser(t, store).ID
+	otherList, err := store.TaskListRepo.CreateTaskList(ctx, models.CreateTaskList{UserID: other, Title: "report"})
+	mustNot(t, "CreateTaskList", err)
+	_, err = store.TaskRepo.CreateTask(ctx, models.CreateTask{TaskListID: otherList.ID, Title: "report"})
+	mustNot(t, "CreateTask", err)
+	createLabel(t, store, other, "report")
+
+	search := func(what string, types ...string) []models.SearchResult {
+		t.Helper()
+		results, err := repo.Search(ctx, owner.Hex(), models.SearchQuery{Terms: models.SearchTerms(what), Types: types, Limit: 10})
+		mustNot(t, "Search("+what+")", err)
+		return results
+	}
+
+	tasks := search("report", models.SearchTypeTask)
+	if len(tasks) != 2 || tasks[0].ID != inTitle.ID || tasks[1].ID != inBody.ID {
+		t.Fatalf("Search(report, tasks): got %+v, want the title match before the description match", tasks)
+	}
+	if tasks[0].Score <= tasks[1].Score {
+		t.Errorf("Search(report, tasks): title match scores %v, not above description match %v", tasks[0].Score, tasks[1].Score)
+	}
+	if tasks[0].Type != models.SearchTypeTask || tasks[0].Title != inTitle.Title || tasks[0].Body != inTitle.Description ||
+		tasks[0].TaskListID == nil || *tasks[0].TaskListID != taskList.ID {
+		t.Errorf("Search(report, tasks): got %+v, want the task with its list", tasks[0])
+	}
+
+	lists := search("REPORT", models.SearchTypeTaskList)
+	if len(lists) != 1 || lists[0].ID != taskList.ID || lists[0].Type != models.SearchTypeTaskList || lists[0].TaskListID != nil {
+		t.Errorf("Search(REPORT, task lists): got %+v, want the task list", lists)
+	}
+
+	labels := search("board", models.SearchTypeLabel)
+	if len(labels) != 1 || labels[0].Title != "Board" || labels[0].Type != models.SearchTypeLabel {
+		t.Errorf("Search(board, labels): got %+v, want the Board label", labels)
+	}
+
+	// Any term matches; the three types come back together
+	all := search("flights numbers board", models.SearchTypes...)
+	found := map[string]int{}
+	for _, result := range all {
+		found[result.Type]++
+	}
+	if found[models.SearchTypeTask] != 1 || found[models.SearchTypeTaskList] != 1 || found[models.SearchTypeLabel] != 1 {
+		t.Errorf("Search(flights numbers board): got %+v, want a task, a task list and a label", all)
+	}
+
+	if results := search("holiday", models.SearchTypes...); len(results) != 0 {
+		t.Errorf("Search(holiday): got %+v, want nothing", results)
+	}
+	limited, err := repo.Search(ctx, owner.Hex(), models.SearchQuery{Terms: []string{"report"}, Types: []string{models.SearchTypeTask}, Limit: 1})
+	mustNot(t, "Search(limit 1)", err)
+	if len(limited) != 1 || limited[0].ID != inTitle.ID {
+		t.Errorf("Search(report, limit 1): got %+v, want only the best match", limited)
+	}
+
+	// The search parameters of the listings are plain text, not patterns
+	for _, pattern := range []string{"r.ports", "(", "[a-z]+", `report\`} {
+		labels, _, err := store.LabelRepo.GetAllLabels(ctx, owner.Hex(), pattern, models.Pagination{})
+		mustNot(t, "GetAllLabels(search="+pattern+")", err)
+		if len(labels) != 0 {
+			t.Errorf("GetAllLabels(search=%s): got %d labels, want none", pattern, len(labels))
+		}
+		tasks, _, err := store.TaskRepo.GetAllTasks(ctx, models.TaskFilter{TaskListIDs: []primitive.ObjectID{taskList.ID}, Search: pattern}, models.Pagination{})
+		mustNot(t, "GetAllTasks(search="+pattern+")", err)
+		if len(tasks) != 0 {
+			t.Errorf("GetAllTasks(search=%s): got %d tasks, want none", pattern, len(tasks))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+	"todo/api/models"
+	"todo/pkg/taskquery"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testSmartLists(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.SmartListRepo
+	owner := createUser(t, store).ID
+
+	created, err := repo.CreateSmartList(ctx, models.CreateSmartList{UserID: owner, Name: "This week", Query: "due:<7d AND NOT completed", Sort: "due_date"})
+	mustNot(t, "CreateSmartList", err)
+	if created.ID.IsZero() {
+		t.Fatal("CreateSmartList: ID was not set")
+	}
+	checkTimestamps(t, "CreateSmartList", created.CreatedAt, created.UpdatedAt)
+
+	got, err := repo.GetSmartList(ctx, created.ID.Hex())
+	mustNot(t, "GetSmartList", err)
+	if got.ID != created.ID || got.UserID != owner || got.Name != "This week" || got.Query != "due:<7d AND NOT completed" || got.Sort != "due_date" {
+		t.Errorf("GetSmartList: got %+v, want the created smart list %+v", got, created)
+	}
+
+	pause()
+	updated, err := repo.UpdateSmartList(ctx, models.UpdateSmartList{ID: created.ID, Name: "Work", Query: "label:work", Sort: "-created_at"})
+	mustNot(t, "UpdateSmartList", err)
+	if updated.Name != "Work" || updated.Query != "label:work" || updated.Sort != "-created_at" || updated.UserID != owner {
+		t.Errorf("UpdateSmartList: got %+v", updated)
+	}
+	checkUpdated(t, "UpdateSmartList", created.CreatedAt, created.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+
+	missing := primitive.NewObjectID().Hex()
+	_, err = repo.GetSmartList(ctx, missing)
+	checkNotFound(t, "GetSmartList(missing)", err, models.ErrSmartListNotFound)
+	_, err = repo.GetSmartList(ctx, "not-an-id")
+	checkNotFound(t, "GetSmartList(invalid id)", err, models.ErrSmartListNotFound)
+	_, err = repo.UpdateSmartList(ctx, models.UpdateSmartList{ID: primitive.NewObjectID(), Name: "x", Query: "x"})
+	checkNotFound(t, "UpdateSmartList(missing)", err, models.ErrSmartListNotFound)
+	checkNotFound(t, "DeleteSmartList(missing)", repo.DeleteSmartList(ctx, missing), models.ErrSmartListNotFound)
+
+	for i := 0; i < 4; i++ {
+		_, err := repo.CreateSmartList(ctx, models.CreateSmartList{UserID: owner, Name: "list", Query: "completed"})
+		mustNot(t, "CreateSmartList", err)
+	}
+	_, err = repo.CreateSmartList(ctx, models.CreateSmartList{UserID: createUser(t, store).ID, Name: "other", Query: "completed"})
+	mustNot(t, "CreateSmartList", err)
+
+	checkPages(t, "GetAllSmartLists", 5, 2, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		smartLists, count, err := repo.GetAllSmartLists(ctx, owner.Hex(), page)
+		cursors := []models.Cursor{}
+		for _, smartList := range smartLists {
+			cursors = append(cursors, smartList.Cursor())
+		}
+		return cursors, count, err
+	})
+
+	mustNot(t, "DeleteSmartList", repo.DeleteSmartList(ctx, created.ID.Hex()))
+	_, err = repo.GetSmartList(ctx, created.ID.Hex())
+	checkNotFound(t, "GetSmartList(deleted)", err, models.ErrSmartListNotFound)
+}
+
+// testTaskQueries runs parsed queries through GetAllTasks. Label and list
+// names are resolved by the service, so the IDs are filled in here.
+func testTaskQueries(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskRepo
+	owner := createUser(t, store).ID
+	listA, listB := createTaskList(t, store, owner).ID, createTaskList(t, store, owner).ID
+	work, home := createLabel(t, store, owner, "work").ID, createLabel(t, store, owner, "home").ID
+
+	now := time.Now()
+	create := func(taskListID primitive.ObjectID, title string, dueDate time.Time, labelIDs ...primitive.ObjectID) models.Task {
+		pause()
+		task, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: title, DueDate: dueDate, LabelIDs: labelIDs})
+		mustNot(t, "CreateTask", err)
+		return task
+	}
+	create(listA, "Write report", now.Add(-24*time.Hour), work)
+	call := create(listA, "Call plumber", now.Add(24*time.Hour), home)
+	create(listA, "Plan offsite", now.Add(10*24*time.Hour), work, home)
+	create(listB, "Read book", time.Time{})
+	_, err := repo.UpdateTask(ctx, models.UpdateTask{ID: call.ID, Title: call.Title, DueDate: call.DueDate, Completed: true})
+	mustNot(t, "UpdateTask", err)
+
+	ids := map[string][]primitive.ObjectID{"work": {work}, "home": {home}, "a": {listA}, "b": {listB}}
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"completed", "Call plumber"},
+		{"NOT completed", "Write report Plan offsite Read book"},
+		{"overdue", "Write report"},
+		{"due:none", "Read book"},
+		{"due:<7d", "Write report Call plumber"},
+		{"due:>=today", "Call plumber Plan offsite"},
+		{"due:yesterday OR due:tomorrow", "Write report Call plumber"},
+		{"created:<-1h", ""},
+		{"updated:>-1h", "Write report Call plumber Plan offsite Read book"},
+		{"label:work", "Write report Plan offsite"},
+		{"label:work label:home", "Plan offsite"},
+		{"label:missing", ""},
+		{"NOT label:missing", "Write report Call plumber Plan offsite Read book"},
+		{"list:b", "Read book"},
+		{"plumber", "Call plumber"},
+		{`title:"PLAN off"`, "Plan offsite"},
+		{"due:<7d AND label:work AND NOT completed", "Write report"},
+		{"(label:home OR list:b) AND NOT completed", "Plan offsite Read book"},
+	}
+	for _, tc := range cases {
+		query, err := taskquery.Parse(tc.query)
+		mustNot(t, "Parse("+tc.query+")", err)
+		taskquery.Walk(query, func(expr taskquery.Expr) {
+			switch e := expr.(type) {
+			case *taskquery.Label:
+				e.IDs = ids[e.Name]
+			case *taskquery.List:
+				e.IDs = ids[e.Name]
+			}
+		})
+
+		filter := models.TaskFilter{TaskListIDs: []primitive.ObjectID{listA, listB}, Query: query}
+		tasks, count, err := repo.GetAllTasks(ctx, filter, models.Pagination{})
+		mustNot(t, "GetAllTasks("+tc.query+")", err)
+		if got := taskTitles(tasks); got != tc.want || count != int64(len(tasks)) {
+			t.Errorf("GetAllTasks(%s): got %q (count %d), want %q", tc.query, got, count, tc.want)
+		}
+	}
+}
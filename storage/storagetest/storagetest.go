@@ -0,0 +1,233 @@
+// Package storagetest is a conformance suite for storage backends. Every
+// implementation of the interfaces in package storage should pass it, so
+// services behave the same whichever backend they run on.
+//
+// A backend runs the suite from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) *storage.Storage {
+//			return memory.NewStorage()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"todo/api/models"
+	"todo/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Factory returns an empty storage. It is called once per subtest, so
+// backends that share a server must hand out a fresh database each time.
+type Factory func(t *testing.T) *storage.Storage
+
+var ctx = context.Background()
+
+// Run runs the whole suite against the storage returned by newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newStorage) })
+	t.Run("TaskLists", func(t *testing.T) { testTaskLists(t, newStorage) })
+	t.Run("Tasks", func(t *testing.T) { testTasks(t, newStorage) })
+	t.Run("TaskFilters", func(t *testing.T) { testTaskFilters(t, newStorage) })
+	t.Run("TaskQueries", func(t *testing.T) { testTaskQueries(t, newStorage) })
+	t.Run("Checklists", func(t *testing.T) { testChecklists(t, newStorage) })
+	t.Run("Subtasks", func(t *testing.T) { testSubtasks(t, newStorage) })
+	t.Run("Recurrence", func(t *testing.T) { testRecurrence(t, newStorage) })
+	t.Run("Reminders", func(t *testing.T) { testReminders(t, newStorage) })
+	t.Run("Notifications", func(t *testing.T) { testNotifications(t, newStorage) })
+	t.Run("Digests", func(t *testing.T) { testDigests(t, newStorage) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newStorage) })
+	t.Run("WebhookDeliveries", func(t *testing.T) { testWebhookDeliveries(t, newStorage) })
+	t.Run("Labels", func(t *testing.T) { testLabels(t, newStorage) })
+	t.Run("SmartLists", func(t *testing.T) { testSmartLists(t, newStorage) })
+	t.Run("Search", func(t *testing.T) { testSearch(t, newStorage) })
+	t.Run("CascadeDeletes", func(t *testing.T) { testCascadeDeletes(t, newStorage) })
+	t.Run("Tokens", func(t *testing.T) { testTokens(t, newStorage) })
+	t.Run("Registrations", func(t *testing.T) { testRegistrations(t, newStorage) })
+	t.Run("PasswordResets", func(t *testing.T) { testPasswordResets(t, newStorage) })
+}
+
+// timePrecision is the coarsest timestamp precision a backend may store;
+// MongoDB keeps milliseconds.
+const timePrecision = time.Millisecond
+
+func sameTime(a, b time.Time) bool {
+	diff := a.Sub(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff < timePrecision
+}
+
+// checkTimestamps makes sure Create* filled in both timestamps.
+func checkTimestamps(t *testing.T, what string, createdAt, updatedAt time.Time) {
+	t.Helper()
+	if createdAt.IsZero() {
+		t.Errorf("%s: CreatedAt was not set", what)
+	}
+	if updatedAt.IsZero() {
+		t.Errorf("%s: UpdatedAt was not set", what)
+	}
+}
+
+// checkUpdated makes sure Update* kept CreatedAt and moved UpdatedAt forward.
+func checkUpdated(t *testing.T, what string, oldCreatedAt, oldUpdatedAt, createdAt, updatedAt time.Time) {
+	t.Helper()
+	if !sameTime(oldCreatedAt, createdAt) {
+		t.Errorf("%s: CreatedAt changed on update: %v -> %v", what, oldCreatedAt, createdAt)
+	}
+	if !updatedAt.After(oldUpdatedAt) {
+		t.Errorf("%s: UpdatedAt was not moved forward on update: %v -> %v", what, oldUpdatedAt, updatedAt)
+	}
+}
+
+func checkNotFound(t *testing.T, what string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s: got error %v, want %v", what, err, want)
+	}
+}
+
+func mustNot(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+// pause makes sure consecutive writes get distinct timestamps on backends
+// with millisecond precision.
+func pause() {
+	time.Sleep(2 * timePrecision)
+}
+
+// checkPages walks every page of a listing with the given limit, first by
+// offset and then by cursor, and checks the totals, the page sizes and that
+// each item shows up exactly once and in the same order both ways.
+func checkPages(t *testing.T, what string, total int, limit uint64, list func(page models.Pagination) ([]models.Cursor, int64, error)) {
+	t.Helper()
+
+	seen := map[primitive.ObjectID]bool{}
+	order := []models.Cursor{}
+	pages := (uint64(total) + limit - 1) / limit
+	for page := uint64(1); page <= pages+1; page++ {
+		cursors, count, err := list(models.Pagination{Limit: limit, Offset: (page - 1) * limit})
+		if err != nil {
+			t.Fatalf("%s page %d: %v", what, page, err)
+		}
+		if count != int64(total) {
+			t.Errorf("%s page %d: total count %d, want %d", what, page, count, total)
+		}
+
+		want := int(limit)
+		if page == pages && uint64(total)%limit != 0 {
+			want = int(uint64(total) % limit)
+		}
+		if page > pages {
+			want = 0
+		}
+		if len(cursors) != want {
+			t.Errorf("%s page %d: got %d items, want %d", what, page, len(cursors), want)
+		}
+
+		for _, cursor := range cursors {
+			if seen[cursor.ID] {
+				t.Errorf("%s page %d: item %s already returned on an earlier page", what, page, cursor.ID.Hex())
+			}
+			seen[cursor.ID] = true
+		}
+		order = append(order, cursors...)
+	}
+	if len(seen) != total {
+		t.Errorf("%s: pages returned %d distinct items, want %d", what, len(seen), total)
+	}
+
+	walked := walkCursor(t, what, limit, list, nil)
+	if len(walked) != len(order) {
+		t.Fatalf("%s: cursor walk returned %d items, offset pages %d", what, len(walked), len(order))
+	}
+	for i := range order {
+		if walked[i].ID != order[i].ID {
+			t.Errorf("%s: cursor walk item %d is %s, offset pages have %s", what, i, walked[i].ID.Hex(), order[i].ID.Hex())
+		}
+	}
+}
+
+// checkCursorInsert walks a listing of total items by cursor and calls add
+// after the first page. The walk must still return every item exactly once,
+// including the added one, which sorts last.
+func checkCursorInsert(t *testing.T, what string, total int, list func(page models.Pagination) ([]models.Cursor, int64, error), add func() primitive.ObjectID) {
+	t.Helper()
+
+	var added primitive.ObjectID
+	walked := walkCursor(t, what, 2, list, func() { added = add() })
+
+	seen := map[primitive.ObjectID]bool{}
+	for _, cursor := range walked {
+		if seen[cursor.ID] {
+			t.Errorf("%s: cursor walk returned %s twice after an insert", what, cursor.ID.Hex())
+		}
+		seen[cursor.ID] = true
+	}
+	if len(seen) != total+1 || !seen[added] {
+		t.Errorf("%s: cursor walk after an insert returned %d distinct items, want %d including the new one", what, len(seen), total+1)
+	}
+}
+
+// walkCursor follows next pages from the last item of each page until a page
+// comes back empty. afterFirst, if set, runs once the first page is read.
+func walkCursor(t *testing.T, what string, limit uint64, list func(page models.Pagination) ([]models.Cursor, int64, error), afterFirst func()) []models.Cursor {
+	t.Helper()
+
+	walked := []models.Cursor{}
+	page := models.Pagination{Limit: limit}
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatalf("%s: cursor walk does not end", what)
+		}
+		cursors, _, err := list(page)
+		if err != nil {
+			t.Fatalf("%s cursor page %d: %v", what, i+1, err)
+		}
+		if len(cursors) == 0 {
+			return walked
+		}
+		walked = append(walked, cursors...)
+
+		last := cursors[len(cursors)-1]
+		page.After = &last
+		if i == 0 && afterFirst != nil {
+			afterFirst()
+		}
+	}
+}
+
+// Backends may enforce references between records, so tests create the
+// users, task lists and labels they point at instead of inventing IDs.
+
+func createUser(t *testing.T, store *storage.Storage) models.User {
+	t.Helper()
+	email := primitive.NewObjectID().Hex() + "@example.com"
+	user, err := store.UserRepo.CreateUser(ctx, models.CreateUser{Username: email, Email: email})
+	mustNot(t, "CreateUser", err)
+	return user
+}
+
+func createTaskList(t *testing.T, store *storage.Storage, userID primitive.ObjectID) models.TaskList {
+	t.Helper()
+	taskList, err := store.TaskListRepo.CreateTaskList(ctx, models.CreateTaskList{UserID: userID, Title: "list"})
+	mustNot(t, "CreateTaskList", err)
+	return taskList
+}
+
+func createLabel(t *testing.T, store *storage.Storage, userID primitive.ObjectID, name string) models.Label {
+	t.Helper()
+	label, err := store.LabelRepo.CreateLabel(ctx, models.CreateLabel{UserID: userID, Name: name})
+	mustNot(t, "CreateLabel", err)
+	return label
+}
@@ -0,0 +1,109 @@
+package storagetest
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testTaskFilters(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskRepo
+	owner := createUser(t, store).ID
+	listA, listB := createTaskList(t, store, owner).ID, createTaskList(t, store, owner).ID
+
+	now := time.Now().Truncate(time.Second)
+	past, future, far := now.Add(-48*time.Hour), now.Add(48*time.Hour), now.Add(96*time.Hour)
+
+	create := func(taskListID primitive.ObjectID, title string, dueDate time.Time) models.Task {
+		pause()
+		task, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: title, DueDate: dueDate})
+		mustNot(t, "CreateTask", err)
+		return task
+	}
+	create(listA, "alpha", past)
+	bravo := create(listA, "bravo", past)
+	create(listA, "charlie", future)
+	pause()
+	midCreated := time.Now()
+	create(listA, "delta", time.Time{})
+	create(listB, "echo", far)
+
+	pause()
+	beforeUpdate := time.Now()
+	pause()
+	_, err := repo.UpdateTask(ctx, models.UpdateTask{ID: bravo.ID, Title: bravo.Title, DueDate: bravo.DueDate, Completed: true})
+	mustNot(t, "UpdateTask", err)
+
+	yes, no := true, false
+	both := []primitive.ObjectID{listA, listB}
+	cases := []struct {
+		name   string
+		filter models.TaskFilter
+		want   string
+	}{
+		{"all", models.TaskFilter{}, "alpha bravo charlie delta echo"},
+		{"one list", models.TaskFilter{TaskListIDs: []primitive.ObjectID{listB}}, "echo"},
+		{"completed", models.TaskFilter{Completed: &yes}, "bravo"},
+		{"not completed", models.TaskFilter{Completed: &no}, "alpha charlie delta echo"},
+		{"overdue", models.TaskFilter{Overdue: true}, "alpha"},
+		{"no due date", models.TaskFilter{NoDueDate: true}, "delta"},
+		{"due before", models.TaskFilter{DueBefore: &now}, "alpha bravo"},
+		{"due after", models.TaskFilter{DueAfter: &now}, "charlie echo"},
+		{"due between", models.TaskFilter{DueAfter: &now, DueBefore: &far}, "charlie"},
+		{"created before", models.TaskFilter{CreatedBefore: &midCreated}, "alpha bravo charlie"},
+		{"created after", models.TaskFilter{CreatedAfter: &midCreated}, "delta echo"},
+		{"updated before", models.TaskFilter{UpdatedBefore: &beforeUpdate}, "alpha charlie delta echo"},
+		{"updated after", models.TaskFilter{UpdatedAfter: &beforeUpdate}, "bravo"},
+		{"completed after", models.TaskFilter{CompletedAfter: &beforeUpdate}, "bravo"},
+		{"completed before", models.TaskFilter{CompletedBefore: &beforeUpdate}, ""},
+		{"overdue or not, open", models.TaskFilter{Completed: &no, DueBefore: &future}, "alpha"},
+		{"sort title desc", models.TaskFilter{Sort: []models.SortKey{{Field: models.SortTitle, Desc: true}}}, "echo delta charlie bravo alpha"},
+		{"sort due date then newest", models.TaskFilter{Sort: []models.SortKey{{Field: models.SortDueDate}, {Field: models.SortCreatedAt, Desc: true}}}, "delta bravo alpha charlie echo"},
+		{"sort completed then title", models.TaskFilter{Sort: []models.SortKey{{Field: models.SortCompleted}, {Field: models.SortTitle}}}, "alpha charlie delta echo bravo"},
+		{"sort recently updated", models.TaskFilter{Sort: []models.SortKey{{Field: models.SortUpdatedAt, Desc: true}}, CreatedBefore: &midCreated}, "bravo charlie alpha"},
+	}
+	for _, tc := range cases {
+		filter := tc.filter
+		if filter.TaskListIDs == nil {
+			filter.TaskListIDs = both
+		}
+		list := func(page models.Pagination) ([]models.Task, int64, error) {
+			return repo.GetAllTasks(ctx, filter, page)
+		}
+
+		tasks, count, err := list(models.Pagination{})
+		mustNot(t, "GetAllTasks("+tc.name+")", err)
+		if got := taskTitles(tasks); got != tc.want || count != int64(len(tasks)) {
+			t.Errorf("GetAllTasks(%s): got %q (count %d), want %q", tc.name, got, count, tc.want)
+		}
+
+		// Walking by cursor must follow the same order
+		walked := []models.Task{}
+		page := models.Pagination{Limit: 2}
+		for i := 0; i < 10; i++ {
+			tasks, _, err := list(page)
+			mustNot(t, "GetAllTasks("+tc.name+")", err)
+			if len(tasks) == 0 {
+				break
+			}
+			walked = append(walked, tasks...)
+			after := tasks[len(tasks)-1].SortCursor(filter.Sort)
+			page.After = &after
+		}
+		if got := taskTitles(walked); got != tc.want {
+			t.Errorf("GetAllTasks(%s) by cursor: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func taskTitles(tasks []models.Task) string {
+	titles := []string{}
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return strings.Join(titles, " ")
+}
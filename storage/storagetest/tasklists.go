@@ -0,0 +1,86 @@
+package storagetest
+
+import (
+	"fmt"
+	"testing"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testTaskLists(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskListRepo
+	owner := createUser(t, store).ID
+
+	created, err := repo.CreateTaskList(ctx, models.CreateTaskList{UserID: owner, Title: "Groceries", Description: "weekly"})
+	mustNot(t, "CreateTaskList", err)
+	if created.ID.IsZero() {
+		t.Fatal("CreateTaskList: ID was not set")
+	}
+	checkTimestamps(t, "CreateTaskList", created.CreatedAt, created.UpdatedAt)
+
+	got, err := repo.GetTaskList(ctx, created.ID.Hex())
+	mustNot(t, "GetTaskList", err)
+	if got.ID != created.ID || got.UserID != owner || got.Title != "Groceries" || got.Description != "weekly" {
+		t.Errorf("GetTaskList: got %+v, want the created list %+v", got, created)
+	}
+
+	pause()
+	updated, err := repo.UpdateTaskList(ctx, models.UpdateTaskList{ID: created.ID, Title: "Shopping", Description: "daily"})
+	mustNot(t, "UpdateTaskList", err)
+	if updated.Title != "Shopping" || updated.Description != "daily" || updated.UserID != owner {
+		t.Errorf("UpdateTaskList: got %+v", updated)
+	}
+	checkUpdated(t, "UpdateTaskList", created.CreatedAt, created.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+
+	missing := primitive.NewObjectID().Hex()
+	_, err = repo.GetTaskList(ctx, missing)
+	checkNotFound(t, "GetTaskList(missing)", err, models.ErrTaskListNotFound)
+	_, err = repo.GetTaskList(ctx, "not-an-id")
+	checkNotFound(t, "GetTaskList(invalid id)", err, models.ErrTaskListNotFound)
+	_, err = repo.UpdateTaskList(ctx, models.UpdateTaskList{ID: primitive.NewObjectID(), Title: "x"})
+	checkNotFound(t, "UpdateTaskList(missing)", err, models.ErrTaskListNotFound)
+	_, err = repo.DeleteTaskList(ctx, missing, false)
+	checkNotFound(t, "DeleteTaskList(missing)", err, models.ErrTaskListNotFound)
+
+	for i := 0; i < 6; i++ {
+		_, err := repo.CreateTaskList(ctx, models.CreateTaskList{UserID: owner, Title: fmt.Sprint("list", i)})
+		mustNot(t, "CreateTaskList", err)
+	}
+	// Lists of other users must not show up
+	_, err = repo.CreateTaskList(ctx, models.CreateTaskList{UserID: createUser(t, store).ID, Title: "other"})
+	mustNot(t, "CreateTaskList", err)
+
+	checkPages(t, "GetAllTaskLists", 7, 3, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		taskLists, count, err := repo.GetAllTaskLists(ctx, owner.Hex(), page)
+		cursors := []models.Cursor{}
+		for _, taskList := range taskLists {
+			if taskList.UserID != owner {
+				t.Errorf("GetAllTaskLists: returned list %s of another user", taskList.ID.Hex())
+			}
+			cursors = append(cursors, taskList.Cursor())
+		}
+		return cursors, count, err
+	})
+
+	// Lists created while a client pages through must not shift the pages
+	checkCursorInsert(t, "GetAllTaskLists", 7, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		taskLists, count, err := repo.GetAllTaskLists(ctx, owner.Hex(), page)
+		cursors := []models.Cursor{}
+		for _, taskList := range taskLists {
+			cursors = append(cursors, taskList.Cursor())
+		}
+		return cursors, count, err
+	}, func() primitive.ObjectID {
+		pause()
+		taskList, err := repo.CreateTaskList(ctx, models.CreateTaskList{UserID: owner, Title: "added"})
+		mustNot(t, "CreateTaskList", err)
+		return taskList.ID
+	})
+
+	_, err = repo.DeleteTaskList(ctx, created.ID.Hex(), false)
+	mustNot(t, "DeleteTaskList", err)
+	_, err = repo.GetTaskList(ctx, created.ID.Hex())
+	checkNotFound(t, "GetTaskList(deleted)", err, models.ErrTaskListNotFound)
+}
@@ -0,0 +1,135 @@
+package storagetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testTasks(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.TaskRepo
+	owner := createUser(t, store).ID
+	taskListID := createTaskList(t, store, owner).ID
+	dueDate := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	created, err := repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Buy milk", Description: "2 litres", DueDate: dueDate})
+	mustNot(t, "CreateTask", err)
+	if created.ID.IsZero() {
+		t.Fatal("CreateTask: ID was not set")
+	}
+	checkTimestamps(t, "CreateTask", created.CreatedAt, created.UpdatedAt)
+
+	got, err := repo.GetTask(ctx, created.ID.Hex())
+	mustNot(t, "GetTask", err)
+	if got.ID != created.ID || got.TaskListID != taskListID || got.Title != "Buy milk" || got.Description != "2 litres" || !sameTime(got.DueDate, dueDate) || got.Completed {
+		t.Errorf("GetTask: got %+v, want the created task %+v", got, created)
+	}
+
+	pause()
+	updated, err := repo.UpdateTask(ctx, models.UpdateTask{ID: created.ID, Title: "Buy oat milk", Description: "1 litre", DueDate: dueDate.Add(time.Hour), Completed: true})
+	mustNot(t, "UpdateTask", err)
+	if updated.Title != "Buy oat milk" || updated.Description != "1 litre" || !sameTime(updated.DueDate, dueDate.Add(time.Hour)) || !updated.Completed || updated.TaskListID != taskListID {
+		t.Errorf("UpdateTask: got %+v", updated)
+	}
+	checkUpdated(t, "UpdateTask", created.CreatedAt, created.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+	if updated.CompletedAt == nil || !sameTime(*updated.CompletedAt, updated.UpdatedAt) {
+		t.Errorf("UpdateTask: got completed_at %v, want %s", updated.CompletedAt, updated.UpdatedAt)
+	}
+
+	// Saving a completed task again keeps the time it was completed at, and
+	// reopening it clears it
+	pause()
+	again, err := repo.UpdateTask(ctx, models.UpdateTask{ID: created.ID, Title: updated.Title, Description: updated.Description, DueDate: updated.DueDate, Completed: true})
+	mustNot(t, "UpdateTask", err)
+	if again.CompletedAt == nil || !sameTime(*again.CompletedAt, *updated.CompletedAt) {
+		t.Errorf("UpdateTask(completed again): got completed_at %v, want %s", again.CompletedAt, updated.CompletedAt)
+	}
+	reopened, err := repo.UpdateTask(ctx, models.UpdateTask{ID: created.ID, Title: updated.Title, Description: updated.Description, DueDate: updated.DueDate})
+	mustNot(t, "UpdateTask", err)
+	if reopened.CompletedAt != nil {
+		t.Errorf("UpdateTask(reopened): got completed_at %s, want none", reopened.CompletedAt)
+	}
+	updated, err = repo.UpdateTask(ctx, models.UpdateTask{ID: created.ID, Title: updated.Title, Description: updated.Description, DueDate: updated.DueDate, Completed: true})
+	mustNot(t, "UpdateTask", err)
+
+	missing := primitive.NewObjectID().Hex()
+	_, err = repo.GetTask(ctx, missing)
+	checkNotFound(t, "GetTask(missing)", err, models.ErrTaskNotFound)
+	_, err = repo.GetTask(ctx, "not-an-id")
+	checkNotFound(t, "GetTask(invalid id)", err, models.ErrTaskNotFound)
+	_, err = repo.UpdateTask(ctx, models.UpdateTask{ID: primitive.NewObjectID(), Title: "x"})
+	checkNotFound(t, "UpdateTask(missing)", err, models.ErrTaskNotFound)
+	checkNotFound(t, "DeleteTask(missing)", repo.DeleteTask(ctx, missing), models.ErrTaskNotFound)
+	_, err = repo.AddTaskLabels(ctx, missing, []primitive.ObjectID{primitive.NewObjectID()})
+	checkNotFound(t, "AddTaskLabels(missing)", err, models.ErrTaskNotFound)
+	_, err = repo.RemoveTaskLabels(ctx, missing, []primitive.ObjectID{primitive.NewObjectID()})
+	checkNotFound(t, "RemoveTaskLabels(missing)", err, models.ErrTaskNotFound)
+
+	// Labels
+	red, blue := createLabel(t, store, owner, "red").ID, createLabel(t, store, owner, "blue").ID
+	labelled, err := repo.AddTaskLabels(ctx, created.ID.Hex(), []primitive.ObjectID{red, red, blue})
+	mustNot(t, "AddTaskLabels", err)
+	if len(labelled.LabelIDs) != 2 {
+		t.Errorf("AddTaskLabels: got labels %v, want %s and %s once each", labelled.LabelIDs, red.Hex(), blue.Hex())
+	}
+	labelled, err = repo.AddTaskLabels(ctx, created.ID.Hex(), []primitive.ObjectID{red})
+	mustNot(t, "AddTaskLabels", err)
+	if len(labelled.LabelIDs) != 2 {
+		t.Errorf("AddTaskLabels(existing): got labels %v, want them unchanged", labelled.LabelIDs)
+	}
+	labelled, err = repo.RemoveTaskLabels(ctx, created.ID.Hex(), []primitive.ObjectID{red})
+	mustNot(t, "RemoveTaskLabels", err)
+	if len(labelled.LabelIDs) != 1 || labelled.LabelIDs[0] != blue {
+		t.Errorf("RemoveTaskLabels: got labels %v, want only %s", labelled.LabelIDs, blue.Hex())
+	}
+
+	// Listing: created has blue; add tasks with red, red+blue and none
+	_, err = repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "Walk dog", LabelIDs: []primitive.ObjectID{red}})
+	mustNot(t, "CreateTask", err)
+	_, err = repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: "MILK the cow", LabelIDs: []primitive.ObjectID{red, blue}})
+	mustNot(t, "CreateTask", err)
+	for i := 0; i < 2; i++ {
+		_, err = repo.CreateTask(ctx, models.CreateTask{TaskListID: taskListID, Title: fmt.Sprint("chore ", i)})
+		mustNot(t, "CreateTask", err)
+	}
+	_, err = repo.CreateTask(ctx, models.CreateTask{TaskListID: createTaskList(t, store, owner).ID, Title: "milk in another list"})
+	mustNot(t, "CreateTask", err)
+
+	listCases := []struct {
+		name   string
+		search string
+		labels models.LabelFilter
+		want   int64
+	}{
+		{"all", "", models.LabelFilter{}, 5},
+		{"search", "milk", models.LabelFilter{}, 2},
+		{"any label", "", models.LabelFilter{LabelIDs: []primitive.ObjectID{red, blue}, Match: models.LabelMatchAny}, 3},
+		{"all labels", "", models.LabelFilter{LabelIDs: []primitive.ObjectID{red, blue}, Match: models.LabelMatchAll}, 1},
+		{"search and label", "milk", models.LabelFilter{LabelIDs: []primitive.ObjectID{red}, Match: models.LabelMatchAny}, 1},
+	}
+	for _, tc := range listCases {
+		filter := models.TaskFilter{TaskListIDs: []primitive.ObjectID{taskListID}, Search: tc.search, Labels: tc.labels}
+		tasks, count, err := repo.GetAllTasks(ctx, filter, models.Pagination{Limit: 10})
+		mustNot(t, "GetAllTasks("+tc.name+")", err)
+		if count != tc.want || int64(len(tasks)) != tc.want {
+			t.Errorf("GetAllTasks(%s): got %d tasks of %d, want %d", tc.name, len(tasks), count, tc.want)
+		}
+	}
+
+	checkPages(t, "GetAllTasks", 5, 2, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		tasks, count, err := repo.GetAllTasks(ctx, models.TaskFilter{TaskListIDs: []primitive.ObjectID{taskListID}}, page)
+		cursors := []models.Cursor{}
+		for _, task := range tasks {
+			cursors = append(cursors, task.Cursor())
+		}
+		return cursors, count, err
+	})
+
+	mustNot(t, "DeleteTask", repo.DeleteTask(ctx, created.ID.Hex()))
+	_, err = repo.GetTask(ctx, created.ID.Hex())
+	checkNotFound(t, "GetTask(deleted)", err, models.ErrTaskNotFound)
+}
@@ -0,0 +1,92 @@
+package storagetest
+
+import (
+	"fmt"
+	"testing"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testUsers(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.UserRepo
+
+	created, err := repo.CreateUser(ctx, models.CreateUser{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "hash",
+		Role:     "member",
+	})
+	mustNot(t, "CreateUser", err)
+	if created.ID.IsZero() {
+		t.Fatal("CreateUser: ID was not set")
+	}
+	checkTimestamps(t, "CreateUser", created.CreatedAt, created.UpdatedAt)
+
+	got, err := repo.GetUser(ctx, created.ID.Hex())
+	mustNot(t, "GetUser", err)
+	if got.ID != created.ID || got.Username != "alice" || got.Email != "alice@example.com" || got.Password != "hash" || got.Role != "member" || got.Disabled {
+		t.Errorf("GetUser: got %+v, want the created user %+v", got, created)
+	}
+
+	byEmail, err := repo.GetUserByEmail(ctx, "alice@example.com")
+	mustNot(t, "GetUserByEmail", err)
+	if byEmail.ID != created.ID {
+		t.Errorf("GetUserByEmail: got user %s, want %s", byEmail.ID.Hex(), created.ID.Hex())
+	}
+
+	pause()
+	updated, err := repo.UpdateUser(ctx, models.UpdateUser{ID: created.ID, Username: "alice2", Email: "alice2@example.com"})
+	mustNot(t, "UpdateUser", err)
+	if updated.Username != "alice2" || updated.Email != "alice2@example.com" {
+		t.Errorf("UpdateUser: got %+v", updated)
+	}
+	checkUpdated(t, "UpdateUser", created.CreatedAt, created.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+
+	mustNot(t, "UpdatePassword", repo.UpdatePassword(ctx, created.ID.Hex(), "hash2"))
+	mustNot(t, "UpdateUserRole", repo.UpdateUserRole(ctx, created.ID.Hex(), "admin"))
+	mustNot(t, "SetUserDisabled", repo.SetUserDisabled(ctx, created.ID.Hex(), true))
+	mustNot(t, "BumpTokenVersion", repo.BumpTokenVersion(ctx, created.ID.Hex()))
+	mustNot(t, "BumpTokenVersion", repo.BumpTokenVersion(ctx, created.ID.Hex()))
+	got, err = repo.GetUser(ctx, created.ID.Hex())
+	mustNot(t, "GetUser", err)
+	if got.Password != "hash2" || got.Role != "admin" || !got.Disabled || got.TokenVersion != 2 {
+		t.Errorf("GetUser after field updates: got %+v", got)
+	}
+
+	missing := primitive.NewObjectID().Hex()
+	_, err = repo.GetUser(ctx, missing)
+	checkNotFound(t, "GetUser(missing)", err, models.ErrUserNotFound)
+	_, err = repo.GetUser(ctx, "not-an-id")
+	checkNotFound(t, "GetUser(invalid id)", err, models.ErrUserNotFound)
+	_, err = repo.GetUserByEmail(ctx, "nobody@example.com")
+	checkNotFound(t, "GetUserByEmail(missing)", err, models.ErrUserNotFound)
+	_, err = repo.UpdateUser(ctx, models.UpdateUser{ID: primitive.NewObjectID(), Username: "x"})
+	checkNotFound(t, "UpdateUser(missing)", err, models.ErrUserNotFound)
+	checkNotFound(t, "UpdatePassword(missing)", repo.UpdatePassword(ctx, missing, "x"), models.ErrUserNotFound)
+	checkNotFound(t, "UpdateUserRole(missing)", repo.UpdateUserRole(ctx, missing, "x"), models.ErrUserNotFound)
+	checkNotFound(t, "SetUserDisabled(missing)", repo.SetUserDisabled(ctx, missing, true), models.ErrUserNotFound)
+	checkNotFound(t, "BumpTokenVersion(missing)", repo.BumpTokenVersion(ctx, missing), models.ErrUserNotFound)
+	_, err = repo.DeleteUser(ctx, missing, false)
+	checkNotFound(t, "DeleteUser(missing)", err, models.ErrUserNotFound)
+
+	for i := 0; i < 4; i++ {
+		_, err := repo.CreateUser(ctx, models.CreateUser{Username: fmt.Sprint("user", i), Email: fmt.Sprintf("user%d@example.com", i)})
+		mustNot(t, "CreateUser", err)
+	}
+	checkPages(t, "GetAllUsers", 5, 2, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		users, count, err := repo.GetAllUsers(ctx, page)
+		cursors := []models.Cursor{}
+		for _, user := range users {
+			cursors = append(cursors, user.Cursor())
+		}
+		return cursors, count, err
+	})
+
+	admins, err := repo.CountUsersWithRole(ctx, "admin")
+	mustNot(t, "CountUsersWithRole", err)
+	if admins != 1 {
+		t.Errorf("CountUsersWithRole(admin): got %d, want 1", admins)
+	}
+}
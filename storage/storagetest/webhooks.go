@@ -0,0 +1,231 @@
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"todo/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testWebhooks(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.WebhookRepo
+	owner := createUser(t, store).ID
+	other := createUser(t, store).ID
+
+	created, err := repo.CreateWebhook(ctx, models.CreateWebhook{
+		UserID:      owner,
+		URL:         "https://example.com/hook",
+		Events:      []string{models.WebhookTaskCreated, models.WebhookTaskCompleted},
+		Description: "CI",
+		Secret:      "whsec_test",
+	})
+	mustNot(t, "CreateWebhook", err)
+	checkTimestamps(t, "CreateWebhook", created.CreatedAt, created.UpdatedAt)
+	if !created.Active || created.Secret != "whsec_test" || created.UserID != owner {
+		t.Errorf("CreateWebhook: got %+v", created)
+	}
+
+	got, err := repo.GetWebhook(ctx, created.ID.Hex())
+	mustNot(t, "GetWebhook", err)
+	if got.URL != created.URL || strings.Join(got.Events, ",") != "task.created,task.completed" || got.Description != "CI" || got.Secret != "whsec_test" {
+		t.Errorf("GetWebhook: got %+v, want %+v", got, created)
+	}
+	_, err = repo.GetWebhook(ctx, primitive.NewObjectID().Hex())
+	checkNotFound(t, "GetWebhook(missing)", err, models.ErrWebhookNotFound)
+	_, err = repo.GetWebhook(ctx, "not-an-id")
+	checkNotFound(t, "GetWebhook(bad id)", err, models.ErrWebhookNotFound)
+
+	// Leaving Active out keeps the webhook as it was
+	pause()
+	updated, err := repo.UpdateWebhook(ctx, models.UpdateWebhook{ID: created.ID, URL: "https://example.com/v2", Events: []string{models.WebhookTaskCreated}})
+	mustNot(t, "UpdateWebhook", err)
+	checkUpdated(t, "UpdateWebhook", created.CreatedAt, created.UpdatedAt, updated.CreatedAt, updated.UpdatedAt)
+	if updated.URL != "https://example.com/v2" || strings.Join(updated.Events, ",") != "task.created" || updated.Description != "" || !updated.Active {
+		t.Errorf("UpdateWebhook: got %+v", updated)
+	}
+	_, err = repo.UpdateWebhook(ctx, models.UpdateWebhook{ID: primitive.NewObjectID(), URL: "https://example.com", Events: []string{models.WebhookTaskCreated}})
+	checkNotFound(t, "UpdateWebhook(missing)", err, models.ErrWebhookNotFound)
+
+	// Only active webhooks of the user subscribed to the event are returned
+	pause()
+	lists, err := repo.CreateWebhook(ctx, models.CreateWebhook{UserID: owner, URL: "https://example.com/lists", Events: []string{models.WebhookTaskListDeleted, models.WebhookTaskCreated}, Secret: "s"})
+	mustNot(t, "CreateWebhook", err)
+	_, err = repo.CreateWebhook(ctx, models.CreateWebhook{UserID: other, URL: "https://example.com/other", Events: []string{models.WebhookTaskCreated}, Secret: "s"})
+	mustNot(t, "CreateWebhook", err)
+	checkSubscribed(t, "SubscribedWebhooks", repo.SubscribedWebhooks, owner, models.WebhookTaskCreated, created.ID, lists.ID)
+	checkSubscribed(t, "SubscribedWebhooks(other event)", repo.SubscribedWebhooks, owner, models.WebhookTaskListDeleted, lists.ID)
+	// Events match whole names only
+	checkSubscribed(t, "SubscribedWebhooks(prefix)", repo.SubscribedWebhooks, owner, "task")
+
+	paused := false
+	updated, err = repo.UpdateWebhook(ctx, models.UpdateWebhook{ID: created.ID, URL: updated.URL, Events: updated.Events, Active: &paused})
+	mustNot(t, "UpdateWebhook(pause)", err)
+	if updated.Active {
+		t.Errorf("UpdateWebhook(pause): webhook is still active")
+	}
+	checkSubscribed(t, "SubscribedWebhooks(paused)", repo.SubscribedWebhooks, owner, models.WebhookTaskCreated, lists.ID)
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.CreateWebhook(ctx, models.CreateWebhook{UserID: owner, URL: fmt.Sprintf("https://example.com/%d", i), Events: []string{models.WebhookTaskDeleted}, Secret: "s"})
+		mustNot(t, "CreateWebhook", err)
+	}
+	checkPages(t, "GetAllWebhooks", 5, 2, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		webhooks, count, err := repo.GetAllWebhooks(ctx, owner.Hex(), page)
+		cursors := []models.Cursor{}
+		for _, webhook := range webhooks {
+			cursors = append(cursors, webhook.Cursor())
+		}
+		return cursors, count, err
+	})
+}
+
+func testWebhookDeliveries(t *testing.T, newStorage Factory) {
+	store := newStorage(t)
+	repo := store.WebhookRepo
+	owner := createUser(t, store).ID
+	webhook, err := repo.CreateWebhook(ctx, models.CreateWebhook{UserID: owner, URL: "https://example.com/hook", Events: []string{models.WebhookTaskCreated}, Secret: "s"})
+	mustNot(t, "CreateWebhook", err)
+
+	at := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	payload := json.RawMessage(`{"id":"1","event":"task.created","data":{"title":"Dentist"}}`)
+	enqueue := func(nextAttemptAt time.Time, redeliveryOf *primitive.ObjectID) models.WebhookDelivery {
+		t.Helper()
+		pause()
+		delivery, err := repo.EnqueueDelivery(ctx, models.EnqueueDelivery{
+			WebhookID:     webhook.ID,
+			UserID:        owner,
+			EventID:       primitive.NewObjectID(),
+			Event:         models.WebhookTaskCreated,
+			Payload:       payload,
+			RedeliveryOf:  redeliveryOf,
+			NextAttemptAt: nextAttemptAt,
+		})
+		mustNot(t, "EnqueueDelivery", err)
+		return delivery
+	}
+
+	first := enqueue(at, nil)
+	if first.Status != models.DeliveryPending || first.Attempts != 0 || first.NextAttemptAt == nil || !sameTime(*first.NextAttemptAt, at) || first.CreatedAt.IsZero() {
+		t.Errorf("EnqueueDelivery: got %+v", first)
+	}
+	second := enqueue(at.Add(time.Minute), &first.ID)
+
+	got, err := repo.GetDelivery(ctx, second.ID.Hex())
+	mustNot(t, "GetDelivery", err)
+	if string(got.Payload) != string(payload) || got.WebhookID != webhook.ID || got.EventID != second.EventID || got.RedeliveryOf == nil || *got.RedeliveryOf != first.ID {
+		t.Errorf("GetDelivery: got %+v", got)
+	}
+	_, err = repo.GetDelivery(ctx, primitive.NewObjectID().Hex())
+	checkNotFound(t, "GetDelivery(missing)", err, models.ErrDeliveryNotFound)
+
+	due, err := repo.DueDeliveries(ctx, at, 10)
+	mustNot(t, "DueDeliveries", err)
+	checkDeliveryIDs(t, "DueDeliveries", due, first.ID)
+	due, err = repo.DueDeliveries(ctx, at.Add(time.Hour), 1)
+	mustNot(t, "DueDeliveries(limit)", err)
+	checkDeliveryIDs(t, "DueDeliveries(limit)", due, first.ID)
+
+	// A failed attempt moves the delivery; recording it again finds nothing
+	retryAt := at.Add(2 * time.Minute)
+	failed := models.WebhookAttempt{ID: first.ID, ScheduledAt: *due[0].NextAttemptAt, Status: models.DeliveryPending, Attempts: 1,
+		LastError: "webhook answered 500", ResponseStatus: 500, ResponseBody: "oops", NextAttemptAt: &retryAt}
+	mustNot(t, "RecordAttempt", repo.RecordAttempt(ctx, failed))
+	checkNotFound(t, "RecordAttempt(moved)", repo.RecordAttempt(ctx, failed), models.ErrDeliveryNotFound)
+	checkNotFound(t, "RecordAttempt(missing)", repo.RecordAttempt(ctx, models.WebhookAttempt{ID: primitive.NewObjectID(), ScheduledAt: at}), models.ErrDeliveryNotFound)
+
+	got, err = repo.GetDelivery(ctx, first.ID.Hex())
+	mustNot(t, "GetDelivery", err)
+	if got.Attempts != 1 || got.ResponseStatus != 500 || got.ResponseBody != "oops" || got.LastError != failed.LastError || got.NextAttemptAt == nil || !sameTime(*got.NextAttemptAt, retryAt) {
+		t.Errorf("RecordAttempt(failed): got %+v", got)
+	}
+	due, err = repo.DueDeliveries(ctx, at.Add(time.Minute), 10)
+	mustNot(t, "DueDeliveries", err)
+	checkDeliveryIDs(t, "DueDeliveries after a failure", due, second.ID)
+
+	deliveredAt := at.Add(3 * time.Minute)
+	mustNot(t, "RecordAttempt(succeeded)", repo.RecordAttempt(ctx, models.WebhookAttempt{ID: first.ID, ScheduledAt: *got.NextAttemptAt,
+		Status: models.DeliverySucceeded, Attempts: 2, ResponseStatus: 204, DeliveredAt: &deliveredAt}))
+	got, err = repo.GetDelivery(ctx, first.ID.Hex())
+	mustNot(t, "GetDelivery", err)
+	if got.Status != models.DeliverySucceeded || got.NextAttemptAt != nil || got.DeliveredAt == nil || !sameTime(*got.DeliveredAt, deliveredAt) || got.LastError != "" {
+		t.Errorf("RecordAttempt(succeeded): got %+v", got)
+	}
+	due, err = repo.DueDeliveries(ctx, at.AddDate(1, 0, 0), 10)
+	mustNot(t, "DueDeliveries", err)
+	checkDeliveryIDs(t, "DueDeliveries after a success", due, second.ID)
+
+	// The log lists the latest first
+	third := enqueue(at, nil)
+	deliveries, count, err := repo.GetDeliveries(ctx, webhook.ID.Hex(), models.Pagination{})
+	mustNot(t, "GetDeliveries", err)
+	checkDeliveryIDs(t, "GetDeliveries", deliveries, third.ID, second.ID, first.ID)
+	if count != 3 {
+		t.Errorf("GetDeliveries: count %d, want 3", count)
+	}
+	enqueue(at, nil)
+	enqueue(at, nil)
+	checkPages(t, "GetDeliveries", 5, 2, func(page models.Pagination) ([]models.Cursor, int64, error) {
+		deliveries, count, err := repo.GetDeliveries(ctx, webhook.ID.Hex(), page)
+		cursors := []models.Cursor{}
+		for _, delivery := range deliveries {
+			cursors = append(cursors, delivery.Cursor())
+		}
+		return cursors, count, err
+	})
+
+	// Deleting a webhook removes its deliveries
+	mustNot(t, "DeleteWebhook", repo.DeleteWebhook(ctx, webhook.ID.Hex()))
+	_, err = repo.GetWebhook(ctx, webhook.ID.Hex())
+	checkNotFound(t, "GetWebhook(deleted)", err, models.ErrWebhookNotFound)
+	_, err = repo.GetDelivery(ctx, first.ID.Hex())
+	checkNotFound(t, "GetDelivery(webhook deleted)", err, models.ErrDeliveryNotFound)
+	checkNotFound(t, "DeleteWebhook(again)", repo.DeleteWebhook(ctx, webhook.ID.Hex()), models.ErrWebhookNotFound)
+
+	// Deleting a user removes their webhooks and deliveries
+	webhook, err = repo.CreateWebhook(ctx, models.CreateWebhook{UserID: owner, URL: "https://example.com/hook", Events: []string{models.WebhookTaskCreated}, Secret: "s"})
+	mustNot(t, "CreateWebhook", err)
+	delivery := enqueue(at, nil)
+	_, err = store.UserRepo.DeleteUser(ctx, owner.Hex(), false)
+	mustNot(t, "DeleteUser", err)
+	_, err = repo.GetWebhook(ctx, webhook.ID.Hex())
+	checkNotFound(t, "GetWebhook(user deleted)", err, models.ErrWebhookNotFound)
+	_, err = repo.GetDelivery(ctx, delivery.ID.Hex())
+	checkNotFound(t, "GetDelivery(user deleted)", err, models.ErrDeliveryNotFound)
+}
+
+func checkSubscribed(t *testing.T, what string, subscribed func(ctx context.Context, userID primitive.ObjectID, event string) ([]models.Webhook, error), userID primitive.ObjectID, event string, want ...primitive.ObjectID) {
+	t.Helper()
+	webhooks, err := subscribed(ctx, userID, event)
+	mustNot(t, what, err)
+	got := []string{}
+	for _, webhook := range webhooks {
+		got = append(got, webhook.ID.Hex())
+	}
+	checkIDs(t, what, got, want)
+}
+
+func checkDeliveryIDs(t *testing.T, what string, deliveries []models.WebhookDelivery, want ...primitive.ObjectID) {
+	t.Helper()
+	got := []string{}
+	for _, delivery := range deliveries {
+		got = append(got, delivery.ID.Hex())
+	}
+	checkIDs(t, what, got, want)
+}
+
+func checkIDs(t *testing.T, what string, got []string, want []primitive.ObjectID) {
+	t.Helper()
+	wanted := []string{}
+	for _, id := range want {
+		wanted = append(wanted, id.Hex())
+	}
+	if strings.Join(got, ",") != strings.Join(wanted, ",") {
+		t.Errorf("%s: got %v, want %v", what, got, wanted)
+	}
+}